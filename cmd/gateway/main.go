@@ -1,16 +1,25 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"sort"
 	"syscall"
+	"time"
 
 	"github.com/jamesprial/nexus/internal/config"
 	"github.com/jamesprial/nexus/internal/container"
 	"github.com/jamesprial/nexus/internal/gateway"
+	"github.com/jamesprial/nexus/internal/interfaces"
+	"github.com/jamesprial/nexus/internal/utils"
+	"gopkg.in/yaml.v3"
 )
 
 // Build-time variables (set by ldflags)
@@ -19,15 +28,180 @@ var (
 	BuildTime = "unknown"
 )
 
+// resolveConfigPath returns the configuration file path from CONFIG_PATH or the default
+func resolveConfigPath() string {
+	if path := os.Getenv("CONFIG_PATH"); path != "" {
+		return path
+	}
+	return "config.yaml"
+}
+
+// dumpConfig writes the effective configuration as YAML with API keys masked
+func dumpConfig(loader interfaces.ConfigLoader, w io.Writer) error {
+	cfg, err := loader.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Mask credentials on a shallow copy so the loaded config is left untouched
+	masked := *cfg
+	if cfg.APIKeys != nil {
+		masked.APIKeys = make(map[string]string, len(cfg.APIKeys))
+		for clientKey, upstreamKey := range cfg.APIKeys {
+			masked.APIKeys[utils.MaskAPIKey(clientKey)] = utils.MaskAPIKey(upstreamKey)
+		}
+	}
+	if cfg.AdminAccess.APIKeys != nil {
+		masked.AdminAccess.APIKeys = make([]string, len(cfg.AdminAccess.APIKeys))
+		for i, key := range cfg.AdminAccess.APIKeys {
+			masked.AdminAccess.APIKeys[i] = utils.MaskAPIKey(key)
+		}
+	}
+	if cfg.Metrics.SummaryKeys != nil {
+		masked.Metrics.SummaryKeys = make([]string, len(cfg.Metrics.SummaryKeys))
+		for i, key := range cfg.Metrics.SummaryKeys {
+			masked.Metrics.SummaryKeys[i] = utils.MaskAPIKey(key)
+		}
+	}
+	if cfg.PerKeyLimits != nil {
+		masked.PerKeyLimits = make(map[string]interfaces.KeyLimits, len(cfg.PerKeyLimits))
+		for clientKey, limits := range cfg.PerKeyLimits {
+			masked.PerKeyLimits[utils.MaskAPIKey(clientKey)] = limits
+		}
+	}
+	if cfg.PerKeyTargets != nil {
+		masked.PerKeyTargets = make(map[string]string, len(cfg.PerKeyTargets))
+		for clientKey, target := range cfg.PerKeyTargets {
+			masked.PerKeyTargets[utils.MaskAPIKey(clientKey)] = utils.MaskURL(target)
+		}
+	}
+	if cfg.DefaultUpstreamKey != "" {
+		masked.DefaultUpstreamKey = utils.MaskAPIKey(cfg.DefaultUpstreamKey)
+	}
+	// No part of the salt is shown; with it the upstream identities can be
+	// recomputed from client keys
+	masked.UpstreamIdentity.Salt = utils.MaskSensitive(cfg.UpstreamIdentity.Salt, 0)
+
+	data, err := yaml.Marshal(&masked)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// checkConfig loads and validates the configuration without starting the
+// server, writing a report to w. It returns the process exit code.
+func checkConfig(loader interfaces.ConfigLoader, w io.Writer) int {
+	cfg, err := loader.Load()
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "Configuration could not be loaded: %v\n", err)
+		return 1
+	}
+
+	if err := config.Validate(cfg); err != nil {
+		_, _ = fmt.Fprintf(w, "Configuration is invalid:\n")
+		var verr *config.ValidationError
+		if errors.As(err, &verr) {
+			for _, problem := range verr.Problems {
+				_, _ = fmt.Fprintf(w, "  - %s\n", problem)
+			}
+		} else {
+			_, _ = fmt.Fprintf(w, "  - %v\n", err)
+		}
+		return 1
+	}
+
+	_, _ = fmt.Fprintln(w, "Configuration is valid")
+	return 0
+}
+
+// selfTestPath is requested by -selftest; listing models is cheap and still
+// requires a valid upstream key
+const selfTestPath = "/v1/models"
+
+// selfTestTimeout bounds the self-test request
+const selfTestTimeout = 10 * time.Second
+
+// discardResponseWriter records only the status of a response
+type discardResponseWriter struct {
+	header http.Header
+	status int
+}
+
+func (d *discardResponseWriter) Header() http.Header { return d.header }
+
+func (d *discardResponseWriter) Write(b []byte) (int, error) {
+	if d.status == 0 {
+		d.status = http.StatusOK
+	}
+	return len(b), nil
+}
+
+func (d *discardResponseWriter) WriteHeader(status int) {
+	if d.status == 0 {
+		d.status = status
+	}
+}
+
+// selfTest initializes the gateway and sends one request through the full
+// handler chain with the first configured client key, so a bad target URL or
+// upstream key is reported before traffic arrives. It writes a report to w and
+// returns the process exit code.
+func selfTest(loader interfaces.ConfigLoader, w io.Writer) int {
+	cont := container.New()
+	cont.SetConfigLoader(loader)
+	if err := cont.Initialize(); err != nil {
+		_, _ = fmt.Fprintf(w, "Self-test failed: %v\n", err)
+		return 1
+	}
+	if err := config.Validate(cont.Config()); err != nil {
+		_, _ = fmt.Fprintf(w, "Self-test failed: %v\n", err)
+		return 1
+	}
+
+	clientKeys := make([]string, 0, len(cont.Config().APIKeys))
+	for clientKey := range cont.Config().APIKeys {
+		clientKeys = append(clientKeys, clientKey)
+	}
+	if len(clientKeys) == 0 {
+		_, _ = fmt.Fprintln(w, "Self-test failed: no api_keys are configured")
+		return 1
+	}
+	sort.Strings(clientKeys)
+	clientKey := clientKeys[0]
+
+	ctx, cancel := context.WithTimeout(context.Background(), selfTestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, selfTestPath, nil)
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "Self-test failed: %v\n", err)
+		return 1
+	}
+	req.Header.Set("Authorization", "Bearer "+clientKey)
+
+	resp := &discardResponseWriter{header: make(http.Header)}
+	cont.BuildHandler().ServeHTTP(resp, req)
+	if resp.status == 0 {
+		resp.status = http.StatusOK
+	}
+
+	if resp.status < 200 || resp.status >= 300 {
+		_, _ = fmt.Fprintf(w, "Self-test failed: GET %s with key %s returned %d %s\n",
+			selfTestPath, utils.MaskAPIKey(clientKey), resp.status, http.StatusText(resp.status))
+		return 1
+	}
+	_, _ = fmt.Fprintf(w, "Self-test passed: GET %s with key %s returned %d\n",
+		selfTestPath, utils.MaskAPIKey(clientKey), resp.status)
+	return 0
+}
+
 func run() error {
 	// Create dependency injection container
 	cont := container.New()
 
 	// Set up configuration loader
-	configPath := "config.yaml"
-	if path := os.Getenv("CONFIG_PATH"); path != "" {
-		configPath = path
-	}
+	configPath := resolveConfigPath()
 
 	configLoader := config.NewFileLoader(configPath)
 	cont.SetConfigLoader(configLoader)
@@ -37,6 +211,9 @@ func run() error {
 		return err
 	}
 
+	// Pre-dial upstream connections; failures are logged, not fatal
+	cont.WarmUp()
+
 	// Get logger from container after initialization
 	logger := cont.Logger()
 
@@ -72,9 +249,30 @@ func main() {
 	var (
 		showVersion = flag.Bool("version", false, "Show version information")
 		showHelp    = flag.Bool("help", false, "Show help information")
+		showConfig  = flag.Bool("dump-config", false, "Print the effective configuration with secrets masked and exit")
+		checkOnly   = flag.Bool("check-config", false, "Validate the configuration and exit")
+		runSelfTest = flag.Bool("selftest", false, "Send a test request to the upstream with a configured key and exit")
 	)
 	flag.Parse()
 
+	// Check the upstream is reachable and accepts the mapped key, then exit
+	if *runSelfTest {
+		os.Exit(selfTest(config.NewFileLoader(resolveConfigPath()), os.Stdout))
+	}
+
+	// Validate configuration and exit without binding the listen port
+	if *checkOnly {
+		os.Exit(checkConfig(config.NewFileLoader(resolveConfigPath()), os.Stdout))
+	}
+
+	// Dump effective configuration and exit
+	if *showConfig {
+		if err := dumpConfig(config.NewFileLoader(resolveConfigPath()), os.Stdout); err != nil {
+			log.Fatalf("failed to dump config: %v", err)
+		}
+		return
+	}
+
 	// Show version and exit
 	if *showVersion {
 		fmt.Printf("Nexus API Gateway\n")
@@ -94,6 +292,9 @@ func main() {
 		fmt.Println("Options:")
 		fmt.Println("  -help         Show help information")
 		fmt.Println("  -version      Show version information")
+		fmt.Println("  -dump-config  Print the effective configuration (secrets masked) and exit")
+		fmt.Println("  -check-config Validate the configuration and exit non-zero on problems")
+		fmt.Println("  -selftest     Send a test request upstream with a configured key and exit non-zero on failure")
 		fmt.Println()
 		fmt.Println("Environment Variables:")
 		fmt.Println("  CONFIG_PATH   Path to configuration file (default: config.yaml)")
@@ -2,18 +2,59 @@ package config
 
 import (
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	ListenPort int               `yaml:"listen_port"`
-	TargetURL  string            `yaml:"target_url"`
-	LogLevel   string            `yaml:"log_level"`
-	APIKeys    map[string]string `yaml:"api_keys"`
-	Limits     Limits            `yaml:"limits"`
-	TLS        *TLSConfig        `yaml:"tls"`
-	Metrics    MetricsConfig     `yaml:"metrics"`
+	ListenPort    int               `yaml:"listen_port"`
+	AdminPort     int               `yaml:"admin_port"`
+	TargetURL     string            `yaml:"target_url"`
+	LogLevel      string            `yaml:"log_level"`
+	LogOutput     string            `yaml:"log_output"`
+	LogFile       string            `yaml:"log_file"`
+	LogMaxSizeMB  int               `yaml:"log_max_size_mb"`
+	LogMaxBackups int               `yaml:"log_max_backups"`
+	APIKeys       map[string]string `yaml:"api_keys"`
+	Limits        Limits            `yaml:"limits"`
+	TLS           *TLSConfig        `yaml:"tls"`
+	Metrics       MetricsConfig     `yaml:"metrics"`
+	Logging       LoggingConfig     `yaml:"logging"`
+	Alerts        AlertsConfig      `yaml:"alerts"`
+	// PerKeyLimits overrides Limits for specific client keys
+	PerKeyLimits map[string]KeyLimits `yaml:"per_key_limits"`
+	// PerKeyTargets sends specific client keys to their own upstream
+	PerKeyTargets map[string]string `yaml:"per_key_targets"`
+	// TrustedProxyCount is the number of reverse proxies in front of the gateway
+	TrustedProxyCount    int                    `yaml:"trusted_proxy_count"`
+	AdminAccess          AdminAccessConfig      `yaml:"admin_access"`
+	AllowedModels        []string               `yaml:"allowed_models"`
+	ModelAliases         map[string]string      `yaml:"model_aliases"`
+	TargetPool           []PoolTarget           `yaml:"target_pool"`
+	Billing              BillingConfig          `yaml:"billing"`
+	OptionsMode          string                 `yaml:"options_mode"`
+	Tracing              TracingConfig          `yaml:"tracing"`
+	Transforms           []TransformRoute       `yaml:"transforms"`
+	PathRewrites         []PathRewrite          `yaml:"path_rewrites"`
+	Routing              RoutingConfig          `yaml:"routing"`
+	JSONBody             JSONBodyConfig         `yaml:"json_body"`
+	PublicPaths          []string               `yaml:"public_paths"`
+	DefaultUpstreamKey   string                 `yaml:"default_upstream_key"`
+	AuthHeaderPrecedence string                 `yaml:"auth_header_precedence"`
+	BasePath             string                 `yaml:"base_path"`
+	Idempotency          IdempotencyConfig      `yaml:"idempotency"`
+	ResponseCache        ResponseCacheConfig    `yaml:"response_cache"`
+	Proxy                ProxyConfig            `yaml:"proxy"`
+	UpstreamKeys         UpstreamKeysConfig     `yaml:"upstream_keys"`
+	HealthCheck          HealthCheckConfig      `yaml:"health_check"`
+	APIVersion           APIVersionConfig       `yaml:"api_version"`
+	Server               ServerConfig           `yaml:"server"`
+	Features             map[string]bool        `yaml:"features"`
+	Request              RequestConfig          `yaml:"request"`
+	UpstreamIdentity     UpstreamIdentityConfig `yaml:"upstream_identity"`
+	Maintenance          MaintenanceConfig      `yaml:"maintenance"`
+	GRPCHealthPort       int                    `yaml:"grpc_health_port"`
 }
 
 type TLSConfig struct {
@@ -23,19 +64,217 @@ type TLSConfig struct {
 }
 
 type Limits struct {
+	RequestsPerSecond     int           `yaml:"requests_per_second"`
+	Burst                 int           `yaml:"burst"`
+	ModelTokensPerMinute  int           `yaml:"model_tokens_per_minute"`
+	Shadow                bool          `yaml:"shadow"`
+	MaxWait               time.Duration `yaml:"max_wait"`
+	MaxQueue              int           `yaml:"max_queue"`
+	TokenEstimator        string        `yaml:"token_estimator"`
+	RateLimitBy           string        `yaml:"rate_limit_by"`
+	RateLimitAlgorithm    string        `yaml:"rate_limit_algorithm"`
+	MaxConcurrent         int           `yaml:"max_concurrent"`
+	DisableTokenLimit     bool          `yaml:"disable_token_limit"`
+	RateLimitHeaders      bool          `yaml:"rate_limit_headers"`
+	SoftLimitThreshold    float64       `yaml:"soft_limit_threshold"`
+	MaxUpstreamConcurrent int           `yaml:"max_upstream_concurrent"`
+	Scheduling            string        `yaml:"scheduling"`
+}
+
+type MetricsConfig struct {
+	Enabled              bool              `yaml:"enabled"`
+	MetricsEndpoint      string            `yaml:"metrics_endpoint"`
+	PrometheusEnabled    bool              `yaml:"prometheus_enabled"`
+	JSONExportEnabled    bool              `yaml:"json_export_enabled"`
+	CSVExportEnabled     bool              `yaml:"csv_export_enabled"`
+	AuthRequired         bool              `yaml:"auth_required"`
+	MaskAPIKeys          bool              `yaml:"mask_api_keys"`
+	MaxEndpointsPerKey   int               `yaml:"max_endpoints_per_key"`
+	MaxModelsPerKey      int               `yaml:"max_models_per_key"`
+	MaxConcurrentExports int               `yaml:"max_concurrent_exports"`
+	LabelHeaders         map[string]string `yaml:"label_headers"`
+	MaxLabelValuesPerKey int               `yaml:"max_label_values_per_key"`
+	DumpOnShutdownPath   string            `yaml:"dump_on_shutdown_path"`
+	SnapshotPath         string            `yaml:"snapshot_path"`
+	LatencySampleRate    int               `yaml:"latency_sample_rate"`
+	SLOLatency           time.Duration     `yaml:"slo_latency"`
+	LatencyEWMADecay     float64           `yaml:"latency_ewma_decay"`
+	ModelJSONPath        string            `yaml:"model_json_path"`
+	TokensJSONPath       string            `yaml:"tokens_json_path"`
+	SummaryKeys          []string          `yaml:"summary_keys"`
+	SuccessStatusCodes   []string          `yaml:"success_status_codes"`
+	PathTemplates        []PathTemplate    `yaml:"path_templates"`
+	RemoteWrite          RemoteWriteConfig `yaml:"remote_write"`
+	EMF                  EMFConfig         `yaml:"emf"`
+}
+
+type RemoteWriteConfig struct {
+	URL      string        `yaml:"url"`
+	Interval time.Duration `yaml:"interval"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+type EMFConfig struct {
+	Enabled   bool          `yaml:"enabled"`
+	Namespace string        `yaml:"namespace"`
+	Interval  time.Duration `yaml:"interval"`
+}
+
+type LoggingConfig struct {
+	AccessLog            bool          `yaml:"access_log"`
+	SlowRequestThreshold time.Duration `yaml:"slow_request_threshold"`
+}
+
+type TransformRoute struct {
+	PathPrefix  string `yaml:"path_prefix"`
+	Transformer string `yaml:"transformer"`
+}
+
+type PathRewrite struct {
+	PathPrefix  string `yaml:"path_prefix"`
+	Pattern     string `yaml:"pattern"`
+	Replacement string `yaml:"replacement"`
+}
+
+type RoutingConfig struct {
+	Routes    []TargetRoute `yaml:"routes"`
+	OnNoMatch string        `yaml:"on_no_match"`
+}
+
+type TargetRoute struct {
+	PathPrefix string `yaml:"path_prefix"`
+	Target     string `yaml:"target"`
+}
+
+type PathTemplate struct {
+	Pattern  string `yaml:"pattern"`
+	Template string `yaml:"template"`
+}
+
+type JSONBodyConfig struct {
+	Paths    []string `yaml:"paths"`
+	MaxBytes int64    `yaml:"max_bytes"`
+}
+
+type IdempotencyConfig struct {
+	TTL        time.Duration `yaml:"ttl"`
+	MaxEntries int           `yaml:"max_entries"`
+}
+
+type ResponseCacheConfig struct {
+	MaxEntries int          `yaml:"max_entries"`
+	Routes     []CacheRoute `yaml:"routes"`
+}
+
+type CacheRoute struct {
+	PathPrefix string        `yaml:"path_prefix"`
+	TTL        time.Duration `yaml:"ttl"`
+	Methods    []string      `yaml:"methods"`
+}
+
+type ProxyConfig struct {
+	MaxResponseBytes    int64                   `yaml:"max_response_bytes"`
+	WarmUpConnections   int                     `yaml:"warmup_connections"`
+	WarmUpTimeout       time.Duration           `yaml:"warmup_timeout"`
+	BlockedMethods      []string                `yaml:"blocked_methods"`
+	BlockedMethodRoutes []BlockedMethodRoute    `yaml:"blocked_method_routes"`
+	ForwardTrailers     *bool                   `yaml:"forward_trailers"`
+	NormalizePaths      PathNormalizationConfig `yaml:"normalize_paths"`
+	WebSockets          bool                    `yaml:"websockets"`
+}
+
+type PathNormalizationConfig struct {
+	Enabled            bool `yaml:"enabled"`
+	StripTrailingSlash bool `yaml:"strip_trailing_slash"`
+}
+
+type BlockedMethodRoute struct {
+	PathPrefix string   `yaml:"path_prefix"`
+	Methods    []string `yaml:"methods"`
+}
+
+type UpstreamKeysConfig struct {
+	Provider  string        `yaml:"provider"`
+	EnvPrefix string        `yaml:"env_prefix"`
+	CacheTTL  time.Duration `yaml:"cache_ttl"`
+}
+
+type HealthCheckConfig struct {
+	Interval           time.Duration `yaml:"interval"`
+	Timeout            time.Duration `yaml:"timeout"`
+	Path               string        `yaml:"path"`
+	UnhealthyThreshold int           `yaml:"unhealthy_threshold"`
+	HealthyThreshold   int           `yaml:"healthy_threshold"`
+}
+
+type ServerConfig struct {
+	MaxHeaderBytes   int           `yaml:"max_header_bytes"`
+	MaxHeaderCount   int           `yaml:"max_header_count"`
+	PreShutdownDelay time.Duration `yaml:"pre_shutdown_delay"`
+}
+
+type RequestConfig struct {
+	MaxTotalDuration time.Duration `yaml:"max_total_duration"`
+}
+
+type UpstreamIdentityConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Header  string `yaml:"header"`
+	Salt    string `yaml:"salt"`
+}
+
+type MaintenanceConfig struct {
+	Enabled    bool          `yaml:"enabled"`
+	Status     int           `yaml:"status"`
+	Message    string        `yaml:"message"`
+	RetryAfter time.Duration `yaml:"retry_after"`
+}
+
+type APIVersionConfig struct {
+	Header    string   `yaml:"header"`
+	Supported []string `yaml:"supported"`
+	Default   string   `yaml:"default"`
+}
+
+type TracingConfig struct {
+	OTLPEndpoint string `yaml:"otlp_endpoint"`
+}
+
+type KeyLimits struct {
 	RequestsPerSecond    int `yaml:"requests_per_second"`
 	Burst                int `yaml:"burst"`
 	ModelTokensPerMinute int `yaml:"model_tokens_per_minute"`
+	MaxConcurrent        int `yaml:"max_concurrent"`
 }
 
-type MetricsConfig struct {
-	Enabled           bool   `yaml:"enabled"`
-	MetricsEndpoint   string `yaml:"metrics_endpoint"`
-	PrometheusEnabled bool   `yaml:"prometheus_enabled"`
-	JSONExportEnabled bool   `yaml:"json_export_enabled"`
-	CSVExportEnabled  bool   `yaml:"csv_export_enabled"`
-	AuthRequired      bool   `yaml:"auth_required"`
-	MaskAPIKeys       bool   `yaml:"mask_api_keys"`
+type PoolTarget struct {
+	URL    string `yaml:"url"`
+	Weight int    `yaml:"weight"`
+}
+
+type AdminAccessConfig struct {
+	Allow   []string `yaml:"allow"`
+	Deny    []string `yaml:"deny"`
+	APIKeys []string `yaml:"api_keys"`
+}
+
+type AlertsConfig struct {
+	ErrorRateThreshold float64       `yaml:"error_rate_threshold"`
+	WebhookURL         string        `yaml:"webhook_url"`
+	Cooldown           time.Duration `yaml:"cooldown"`
+	Window             time.Duration `yaml:"window"`
+	MinRequests        int           `yaml:"min_requests"`
+	PerKey             bool          `yaml:"per_key"`
+}
+
+type BillingConfig struct {
+	Headers bool                    `yaml:"headers"`
+	Pricing map[string]ModelPricing `yaml:"pricing"`
+}
+
+type ModelPricing struct {
+	InputPer1K  float64 `yaml:"input_per_1k"`
+	OutputPer1K float64 `yaml:"output_per_1k"`
 }
 
 func Load(path string) (*Config, error) {
@@ -1,8 +1,8 @@
 module github.com/jamesprial/nexus
 
-go 1.23.0
+go 1.24.0
 
-toolchain go1.23.11
+toolchain go1.24.5
 
 require gopkg.in/yaml.v3 v3.0.1
 
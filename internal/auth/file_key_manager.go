@@ -3,6 +3,7 @@ package auth
 import (
 	"errors"
 	"strings"
+	"sync"
 
 	"github.com/jamesprial/nexus/internal/config"
 	"github.com/jamesprial/nexus/internal/interfaces"
@@ -13,15 +14,23 @@ var (
 	ErrNoUpstreamKey    = errors.New("no upstream API key found")
 )
 
-// FileKeyManager implements interfaces.KeyManager using configuration file
+// FileKeyManager implements interfaces.KeyManager using configuration file.
+// With an upstream key provider set, client keys are resolved through it
+// instead of api_keys.
 type FileKeyManager struct {
 	apiKeys map[string]string
+	// provider resolves upstream keys in place of apiKeys when set
+	provider interfaces.UpstreamKeyProvider
+	// defaultUpstreamKey is sent upstream for public paths, which carry no client key
+	defaultUpstreamKey string
+	mu                 sync.RWMutex
 }
 
 // NewFileKeyManager creates a new FileKeyManager from configuration
 func NewFileKeyManager(cfg *config.Config) interfaces.KeyManager {
 	manager := &FileKeyManager{
-		apiKeys: make(map[string]string),
+		apiKeys:            make(map[string]string),
+		defaultUpstreamKey: cfg.DefaultUpstreamKey,
 	}
 
 	// Copy API keys from config
@@ -34,37 +43,86 @@ func NewFileKeyManager(cfg *config.Config) interfaces.KeyManager {
 	return manager
 }
 
-// ValidateClientKey checks if a client API key is valid
+// ValidateClientKey checks if a client API key is valid. With a provider set,
+// a key is valid unless the provider reports it unknown; other provider
+// errors are left for GetUpstreamKey to report.
 func (f *FileKeyManager) ValidateClientKey(clientKey string) bool {
+	if provider := f.upstreamKeyProvider(); provider != nil {
+		_, err := provider.GetUpstreamKey(clientKey)
+		return !errors.Is(err, ErrInvalidClientKey)
+	}
 	if !f.IsConfigured() {
 		// If not configured, accept any non-empty key
 		return strings.TrimSpace(clientKey) != ""
 	}
 
+	f.mu.RLock()
+	defer f.mu.RUnlock()
 	_, exists := f.apiKeys[clientKey]
 	return exists
 }
 
 // GetUpstreamKey returns the upstream API key for a client key
 func (f *FileKeyManager) GetUpstreamKey(clientKey string) (string, error) {
+	if provider := f.upstreamKeyProvider(); provider != nil {
+		return provider.GetUpstreamKey(clientKey)
+	}
 	if !f.IsConfigured() {
 		// If not configured, pass through the client key
 		return clientKey, nil
 	}
 
-	upstreamKey, exists := f.apiKeys[clientKey]
-	if !exists {
-		return "", ErrInvalidClientKey
-	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return MapKeyProvider(f.apiKeys).GetUpstreamKey(clientKey)
+}
+
+// IsConfigured returns true if API key management is configured
+func (f *FileKeyManager) IsConfigured() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return len(f.apiKeys) > 0 || f.provider != nil
+}
 
-	if upstreamKey == "" {
-		return "", ErrNoUpstreamKey
+// SetUpstreamKeyProvider resolves client keys through provider instead of
+// api_keys; nil restores api_keys
+func (f *FileKeyManager) SetUpstreamKeyProvider(provider interfaces.UpstreamKeyProvider) {
+	f.mu.Lock()
+	f.provider = provider
+	f.mu.Unlock()
+}
+
+// upstreamKeyProvider returns the provider set by SetUpstreamKeyProvider, if any
+func (f *FileKeyManager) upstreamKeyProvider() interfaces.UpstreamKeyProvider {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.provider
+}
+
+// UpdateKeys replaces the configured key mapping, e.g. on config reload
+func (f *FileKeyManager) UpdateKeys(apiKeys map[string]string) {
+	keys := make(map[string]string, len(apiKeys))
+	for clientKey, upstreamKey := range apiKeys {
+		keys[clientKey] = upstreamKey
 	}
 
-	return upstreamKey, nil
+	f.mu.Lock()
+	f.apiKeys = keys
+	f.mu.Unlock()
 }
 
-// IsConfigured returns true if API key management is configured
-func (f *FileKeyManager) IsConfigured() bool {
-	return len(f.apiKeys) > 0
+// DefaultUpstreamKey returns the upstream key for requests to public paths,
+// or "" when none is configured
+func (f *FileKeyManager) DefaultUpstreamKey() string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.defaultUpstreamKey
+}
+
+// SetDefaultUpstreamKey replaces the upstream key used for public paths, e.g.
+// on config reload
+func (f *FileKeyManager) SetDefaultUpstreamKey(key string) {
+	f.mu.Lock()
+	f.defaultUpstreamKey = key
+	f.mu.Unlock()
 }
@@ -364,4 +364,23 @@ func BenchmarkFileKeyManager_GetUpstreamKey(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		_, _ = manager.GetUpstreamKey("client1")
 	}
-}
\ No newline at end of file
+}
+func TestFileKeyManager_DefaultUpstreamKey(t *testing.T) {
+	manager := NewFileKeyManager(&config.Config{
+		APIKeys:            map[string]string{"client1": "upstream1"},
+		DefaultUpstreamKey: "public-upstream",
+	}).(*FileKeyManager)
+
+	if got := manager.DefaultUpstreamKey(); got != "public-upstream" {
+		t.Errorf("expected default upstream key %q, got %q", "public-upstream", got)
+	}
+	// The default key is not a client key
+	if manager.ValidateClientKey("public-upstream") {
+		t.Error("default upstream key must not validate as a client key")
+	}
+
+	manager.SetDefaultUpstreamKey("")
+	if got := manager.DefaultUpstreamKey(); got != "" {
+		t.Errorf("expected default upstream key to be cleared, got %q", got)
+	}
+}
@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+// Upstream key provider names accepted by upstream_keys.provider
+const (
+	// ProviderConfig maps client keys to upstream keys with api_keys
+	ProviderConfig = "config"
+	// ProviderEnv reads each upstream key from an environment variable
+	ProviderEnv = "env"
+)
+
+// DefaultEnvKeyPrefix prefixes the environment variables read by EnvKeyProvider
+// when upstream_keys.env_prefix is unset
+const DefaultEnvKeyPrefix = "NEXUS_UPSTREAM_KEY_"
+
+// defaultKeyCacheTTL is how long provider lookups are cached when
+// upstream_keys.cache_ttl is unset
+const defaultKeyCacheTTL = 5 * time.Minute
+
+// MapKeyProvider implements interfaces.UpstreamKeyProvider with a fixed map
+// from client keys to upstream keys, as configured by api_keys
+type MapKeyProvider map[string]string
+
+// GetUpstreamKey implements interfaces.UpstreamKeyProvider
+func (m MapKeyProvider) GetUpstreamKey(clientKey string) (string, error) {
+	upstreamKey, exists := m[clientKey]
+	if !exists {
+		return "", ErrInvalidClientKey
+	}
+	if upstreamKey == "" {
+		return "", ErrNoUpstreamKey
+	}
+	return upstreamKey, nil
+}
+
+// EnvKeyProvider implements interfaces.UpstreamKeyProvider by reading the
+// upstream key for each client key from the environment, so keys injected by
+// a secret manager never appear in the config file. The variable name is the
+// prefix followed by the upper-case hex SHA-256 of the client key, so every
+// client key has its own variable and no variant spelling of a key can read
+// another's: client key "team-a" is read from NEXUS_UPSTREAM_KEY_96C2886C...,
+// as printed by `printf %s team-a | sha256sum`.
+type EnvKeyProvider struct {
+	prefix string
+	// lookup reads an environment variable; replaceable in tests
+	lookup func(string) (string, bool)
+}
+
+// NewEnvKeyProvider creates a provider reading variables named with prefix,
+// or DefaultEnvKeyPrefix when prefix is empty
+func NewEnvKeyProvider(prefix string) *EnvKeyProvider {
+	if prefix == "" {
+		prefix = DefaultEnvKeyPrefix
+	}
+	return &EnvKeyProvider{prefix: prefix, lookup: os.LookupEnv}
+}
+
+// GetUpstreamKey implements interfaces.UpstreamKeyProvider. A client key with
+// no variable set is invalid.
+func (e *EnvKeyProvider) GetUpstreamKey(clientKey string) (string, error) {
+	if strings.TrimSpace(clientKey) == "" {
+		return "", ErrInvalidClientKey
+	}
+	upstreamKey, ok := e.lookup(e.VariableName(clientKey))
+	if !ok {
+		return "", ErrInvalidClientKey
+	}
+	if upstreamKey == "" {
+		return "", ErrNoUpstreamKey
+	}
+	return upstreamKey, nil
+}
+
+// VariableName returns the environment variable holding clientKey's upstream key
+func (e *EnvKeyProvider) VariableName(clientKey string) string {
+	sum := sha256.Sum256([]byte(clientKey))
+	return e.prefix + strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// cachedKey is one lookup held by CachingKeyProvider
+type cachedKey struct {
+	upstreamKey string
+	expires     time.Time
+}
+
+// CachingKeyProvider wraps a provider backed by a remote secret store and
+// caches successful lookups for a TTL, so each request doesn't cost a round
+// trip. Failed lookups are not cached, so a newly added key works at once.
+type CachingKeyProvider struct {
+	provider interfaces.UpstreamKeyProvider
+	ttl      time.Duration
+	mu       sync.Mutex
+	cache    map[string]cachedKey
+	// now returns the current time; replaceable in tests
+	now func() time.Time
+}
+
+// NewCachingKeyProvider caches provider's lookups for ttl, or five minutes
+// when ttl is zero or negative
+func NewCachingKeyProvider(provider interfaces.UpstreamKeyProvider, ttl time.Duration) *CachingKeyProvider {
+	if ttl <= 0 {
+		ttl = defaultKeyCacheTTL
+	}
+	return &CachingKeyProvider{
+		provider: provider,
+		ttl:      ttl,
+		cache:    make(map[string]cachedKey),
+		now:      time.Now,
+	}
+}
+
+// GetUpstreamKey implements interfaces.UpstreamKeyProvider
+func (c *CachingKeyProvider) GetUpstreamKey(clientKey string) (string, error) {
+	now := c.now()
+
+	c.mu.Lock()
+	entry, ok := c.cache[clientKey]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.upstreamKey, nil
+	}
+
+	upstreamKey, err := c.provider.GetUpstreamKey(clientKey)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		delete(c.cache, clientKey)
+		return "", err
+	}
+	c.cache[clientKey] = cachedKey{upstreamKey: upstreamKey, expires: now.Add(c.ttl)}
+	return upstreamKey, nil
+}
+
+// KeyProviderFactory builds a provider from the upstream_keys config
+type KeyProviderFactory func(cfg interfaces.UpstreamKeysConfig) (interfaces.UpstreamKeyProvider, error)
+
+// keyProviders maps the names used in upstream_keys.provider to factories
+var (
+	keyProvidersMu sync.RWMutex
+	keyProviders   = map[string]KeyProviderFactory{
+		ProviderEnv: func(cfg interfaces.UpstreamKeysConfig) (interfaces.UpstreamKeyProvider, error) {
+			return NewEnvKeyProvider(cfg.EnvPrefix), nil
+		},
+	}
+)
+
+// RegisterKeyProvider makes a provider, such as one backed by Vault or AWS
+// Secrets Manager, selectable by name in upstream_keys.provider. Registering
+// a name again replaces the earlier factory.
+func RegisterKeyProvider(name string, factory KeyProviderFactory) {
+	keyProvidersMu.Lock()
+	defer keyProvidersMu.Unlock()
+	keyProviders[name] = factory
+}
+
+// NewKeyProvider builds the provider selected by cfg, wrapped in a cache. It
+// returns nil for the config provider, whose keys come from api_keys.
+func NewKeyProvider(cfg interfaces.UpstreamKeysConfig) (interfaces.UpstreamKeyProvider, error) {
+	if cfg.Provider == "" || cfg.Provider == ProviderConfig {
+		return nil, nil
+	}
+
+	keyProvidersMu.RLock()
+	factory, ok := keyProviders[cfg.Provider]
+	keyProvidersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown upstream key provider %q", cfg.Provider)
+	}
+
+	provider, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up upstream key provider %q: %w", cfg.Provider, err)
+	}
+	return NewCachingKeyProvider(provider, cfg.CacheTTL), nil
+}
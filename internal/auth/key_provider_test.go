@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/config"
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+// fakeKeyProvider serves upstream keys from a map and counts lookups
+type fakeKeyProvider struct {
+	keys    map[string]string
+	err     error
+	lookups int
+}
+
+func (f *fakeKeyProvider) GetUpstreamKey(clientKey string) (string, error) {
+	f.lookups++
+	if f.err != nil {
+		return "", f.err
+	}
+	return MapKeyProvider(f.keys).GetUpstreamKey(clientKey)
+}
+
+func TestMapKeyProvider(t *testing.T) {
+	provider := MapKeyProvider{"client": "upstream", "empty": ""}
+
+	if key, err := provider.GetUpstreamKey("client"); err != nil || key != "upstream" {
+		t.Errorf("Expected upstream, got %q, %v", key, err)
+	}
+	if _, err := provider.GetUpstreamKey("unknown"); !errors.Is(err, ErrInvalidClientKey) {
+		t.Errorf("Expected ErrInvalidClientKey, got %v", err)
+	}
+	if _, err := provider.GetUpstreamKey("empty"); !errors.Is(err, ErrNoUpstreamKey) {
+		t.Errorf("Expected ErrNoUpstreamKey, got %v", err)
+	}
+}
+
+func TestEnvKeyProvider(t *testing.T) {
+	provider := NewEnvKeyProvider("TEST_UPSTREAM_")
+	t.Setenv(provider.VariableName("team-a.1"), "sk-team-a")
+	t.Setenv(provider.VariableName("empty"), "")
+
+	// printf %s team-a.1 | sha256sum
+	want := "TEST_UPSTREAM_063C4737C8B3B2DBDB2C32771FA6FBA6E56AB93E8A0EE1AAD6BDA27715223231"
+	if name := provider.VariableName("team-a.1"); name != want {
+		t.Errorf("Expected %s, got %s", want, name)
+	}
+	if key, err := provider.GetUpstreamKey("team-a.1"); err != nil || key != "sk-team-a" {
+		t.Errorf("Expected sk-team-a, got %q, %v", key, err)
+	}
+	if _, err := provider.GetUpstreamKey("team-b"); !errors.Is(err, ErrInvalidClientKey) {
+		t.Errorf("Expected ErrInvalidClientKey for an unset variable, got %v", err)
+	}
+	if _, err := provider.GetUpstreamKey("empty"); !errors.Is(err, ErrNoUpstreamKey) {
+		t.Errorf("Expected ErrNoUpstreamKey for an empty variable, got %v", err)
+	}
+	if _, err := provider.GetUpstreamKey(" "); !errors.Is(err, ErrInvalidClientKey) {
+		t.Errorf("Expected ErrInvalidClientKey for a blank key, got %v", err)
+	}
+
+	if name := NewEnvKeyProvider("").VariableName("abc"); !strings.HasPrefix(name, DefaultEnvKeyPrefix) {
+		t.Errorf("Expected the default prefix, got %s", name)
+	}
+}
+
+func TestEnvKeyProvider_VariantsDoNotCollide(t *testing.T) {
+	provider := NewEnvKeyProvider("TEST_UPSTREAM_")
+	t.Setenv(provider.VariableName("team-a.1"), "sk-team-a")
+
+	names := map[string]string{provider.VariableName("team-a.1"): "team-a.1"}
+	for _, variant := range []string{"TEAM-A.1", "team_a_1", "Team.A.1", "team-a-1", "team a.1"} {
+		if _, err := provider.GetUpstreamKey(variant); !errors.Is(err, ErrInvalidClientKey) {
+			t.Errorf("Expected ErrInvalidClientKey for variant %q, got %v", variant, err)
+		}
+		name := provider.VariableName(variant)
+		if other, exists := names[name]; exists {
+			t.Errorf("Expected distinct variables, %q and %q both map to %s", variant, other, name)
+		}
+		names[name] = variant
+	}
+}
+
+func TestCachingKeyProvider(t *testing.T) {
+	fake := &fakeKeyProvider{keys: map[string]string{"client": "upstream-v1"}}
+	now := time.Unix(1700000000, 0)
+	cache := NewCachingKeyProvider(fake, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if key, err := cache.GetUpstreamKey("client"); err != nil || key != "upstream-v1" {
+			t.Fatalf("Expected upstream-v1, got %q, %v", key, err)
+		}
+	}
+	if fake.lookups != 1 {
+		t.Errorf("Expected one lookup within the TTL, got %d", fake.lookups)
+	}
+
+	// A rotated key is picked up once the entry expires
+	fake.keys["client"] = "upstream-v2"
+	now = now.Add(59 * time.Second)
+	if key, _ := cache.GetUpstreamKey("client"); key != "upstream-v1" {
+		t.Errorf("Expected the cached key before expiry, got %q", key)
+	}
+	now = now.Add(2 * time.Second)
+	if key, _ := cache.GetUpstreamKey("client"); key != "upstream-v2" {
+		t.Errorf("Expected the rotated key after expiry, got %q", key)
+	}
+	if fake.lookups != 2 {
+		t.Errorf("Expected a second lookup after expiry, got %d", fake.lookups)
+	}
+
+	// Failures are not cached
+	for i := 0; i < 2; i++ {
+		if _, err := cache.GetUpstreamKey("unknown"); !errors.Is(err, ErrInvalidClientKey) {
+			t.Errorf("Expected ErrInvalidClientKey, got %v", err)
+		}
+	}
+	if fake.lookups != 4 {
+		t.Errorf("Expected every failed lookup to reach the provider, got %d lookups", fake.lookups)
+	}
+
+	// An expired entry is dropped when the refresh fails
+	fake.err = errors.New("secret store unavailable")
+	now = now.Add(2 * time.Minute)
+	if _, err := cache.GetUpstreamKey("client"); err == nil {
+		t.Error("Expected the provider error once the entry expired")
+	}
+}
+
+func TestNewKeyProvider(t *testing.T) {
+	for _, name := range []string{"", ProviderConfig} {
+		provider, err := NewKeyProvider(interfaces.UpstreamKeysConfig{Provider: name})
+		if err != nil || provider != nil {
+			t.Errorf("Expected no provider for %q, got %v, %v", name, provider, err)
+		}
+	}
+
+	if _, err := NewKeyProvider(interfaces.UpstreamKeysConfig{Provider: "vault"}); err == nil {
+		t.Error("Expected an error for an unregistered provider")
+	}
+
+	t.Setenv(NewEnvKeyProvider("TEST_PREFIX_").VariableName("client"), "sk-env")
+	provider, err := NewKeyProvider(interfaces.UpstreamKeysConfig{Provider: ProviderEnv, EnvPrefix: "TEST_PREFIX_"})
+	if err != nil {
+		t.Fatalf("Failed to build env provider: %v", err)
+	}
+	if key, err := provider.GetUpstreamKey("client"); err != nil || key != "sk-env" {
+		t.Errorf("Expected sk-env, got %q, %v", key, err)
+	}
+
+	fake := &fakeKeyProvider{keys: map[string]string{"client": "sk-fake"}}
+	RegisterKeyProvider("test-fake", func(cfg interfaces.UpstreamKeysConfig) (interfaces.UpstreamKeyProvider, error) {
+		return fake, nil
+	})
+	provider, err = NewKeyProvider(interfaces.UpstreamKeysConfig{Provider: "test-fake"})
+	if err != nil {
+		t.Fatalf("Failed to build registered provider: %v", err)
+	}
+	_, _ = provider.GetUpstreamKey("client")
+	_, _ = provider.GetUpstreamKey("client")
+	if fake.lookups != 1 {
+		t.Errorf("Expected registered providers to be cached, got %d lookups", fake.lookups)
+	}
+}
+
+func TestFileKeyManager_UpstreamKeyProvider(t *testing.T) {
+	manager := NewFileKeyManager(&config.Config{
+		APIKeys: map[string]string{"yaml-client": "yaml-upstream"},
+	}).(*FileKeyManager)
+	fake := &fakeKeyProvider{keys: map[string]string{"vault-client": "vault-upstream"}}
+	manager.SetUpstreamKeyProvider(fake)
+
+	if !manager.IsConfigured() {
+		t.Error("Expected a manager with a provider to be configured")
+	}
+	if !manager.ValidateClientKey("vault-client") {
+		t.Error("Expected the provider's client key to be valid")
+	}
+	if manager.ValidateClientKey("yaml-client") {
+		t.Error("Expected api_keys to be ignored while a provider is set")
+	}
+	if key, err := manager.GetUpstreamKey("vault-client"); err != nil || key != "vault-upstream" {
+		t.Errorf("Expected vault-upstream, got %q, %v", key, err)
+	}
+
+	// Provider outages fail the lookup rather than rejecting the key as unknown
+	fake.err = errors.New("secret store unavailable")
+	if !manager.ValidateClientKey("vault-client") {
+		t.Error("Expected a provider outage not to invalidate the key")
+	}
+	if _, err := manager.GetUpstreamKey("vault-client"); err == nil {
+		t.Error("Expected the provider error from GetUpstreamKey")
+	}
+
+	manager.SetUpstreamKeyProvider(nil)
+	if key, err := manager.GetUpstreamKey("yaml-client"); err != nil || key != "yaml-upstream" {
+		t.Errorf("Expected api_keys again once the provider is cleared, got %q, %v", key, err)
+	}
+}
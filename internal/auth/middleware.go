@@ -3,15 +3,40 @@ package auth
 import (
 	"net/http"
 	"strings"
+	"sync/atomic"
 
 	"github.com/jamesprial/nexus/internal/interfaces"
+	"github.com/jamesprial/nexus/internal/metrics"
 	"github.com/jamesprial/nexus/internal/utils"
 )
 
+// AnonymousKey identifies requests to public paths in metrics and rate limits
+const AnonymousKey = "anonymous"
+
+// APIKeyHeader is accepted as an alternative to the Authorization header
+const APIKeyHeader = "X-API-Key"
+
+// Header precedence values, set with the auth_header_precedence config key,
+// deciding which header wins when a request carries both
+const (
+	// PrecedenceAuthorization prefers the Authorization header (the default)
+	PrecedenceAuthorization = "authorization"
+	// PrecedenceAPIKey prefers the X-API-Key header
+	PrecedenceAPIKey = "x-api-key"
+)
+
+// defaultKeyProvider is implemented by key managers that supply an upstream
+// key for public paths
+type defaultKeyProvider interface {
+	DefaultUpstreamKey() string
+}
+
 // AuthMiddleware handles API key authentication and transformation
 type AuthMiddleware struct {
-	keyManager interfaces.KeyManager
-	logger     interfaces.Logger
+	keyManager   interfaces.KeyManager
+	logger       interfaces.Logger
+	publicPaths  atomic.Pointer[[]string]
+	preferAPIKey atomic.Bool
 }
 
 // NewAuthMiddleware creates a new authentication middleware
@@ -22,17 +47,91 @@ func NewAuthMiddleware(keyManager interfaces.KeyManager, logger interfaces.Logge
 	}
 }
 
+// SetPublicPaths replaces the paths served without authentication. A path
+// ending in "*" matches every path with that prefix; any other path must
+// match exactly. It is safe to call while requests are being served.
+func (a *AuthMiddleware) SetPublicPaths(paths []string) {
+	paths = append([]string(nil), paths...)
+	a.publicPaths.Store(&paths)
+}
+
+// SetHeaderPrecedence chooses which header supplies the client key when a
+// request carries both Authorization and X-API-Key. It is safe to call while
+// requests are being served.
+func (a *AuthMiddleware) SetHeaderPrecedence(precedence string) {
+	a.preferAPIKey.Store(precedence == PrecedenceAPIKey)
+}
+
+// clientKey extracts the client key from the Authorization or X-API-Key
+// header. When both carry a key and they differ, the preferred header wins
+// and a warning is logged with only the chosen key, masked. fromAuthorization
+// reports which header the key came from.
+func (a *AuthMiddleware) clientKey(r *http.Request) (key string, fromAuthorization bool) {
+	authKey := strings.TrimSpace(r.Header.Get("Authorization"))
+	if strings.HasPrefix(authKey, "Bearer ") {
+		authKey = strings.TrimSpace(authKey[7:])
+	}
+	headerKey := strings.TrimSpace(r.Header.Get(APIKeyHeader))
+
+	switch {
+	case headerKey == "":
+		return authKey, true
+	case authKey == "":
+		return headerKey, false
+	}
+
+	key, fromAuthorization, header := authKey, true, "Authorization"
+	if a.preferAPIKey.Load() {
+		key, fromAuthorization, header = headerKey, false, APIKeyHeader
+	}
+	if authKey != headerKey && a.logger != nil {
+		a.logger.Warn("Conflicting API keys in Authorization and X-API-Key headers", map[string]any{
+			"path":       r.URL.Path,
+			"method":     r.Method,
+			"used":       header,
+			"client_key": utils.MaskAPIKey(key),
+		})
+	}
+	return key, fromAuthorization
+}
+
+// isPublicPath reports whether path is served without authentication
+func (a *AuthMiddleware) isPublicPath(path string) bool {
+	paths := a.publicPaths.Load()
+	if paths == nil {
+		return false
+	}
+	for _, pattern := range *paths {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if path == pattern {
+			return true
+		}
+	}
+	return false
+}
+
 // Middleware returns the HTTP middleware function
 func (a *AuthMiddleware) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Extract client API key from Authorization header
-		authHeader := r.Header.Get("Authorization")
-		clientKey := strings.TrimSpace(authHeader)
-		
-		// Remove "Bearer " prefix if present
-		if strings.HasPrefix(clientKey, "Bearer ") {
-			clientKey = strings.TrimSpace(clientKey[7:])
+		// Public paths skip validation and are limited and recorded under the
+		// anonymous key. They carry the default upstream key when one is
+		// configured, and otherwise keep their Authorization header.
+		if a.isPublicPath(r.URL.Path) {
+			if p, ok := a.keyManager.(defaultKeyProvider); ok {
+				if key := p.DefaultUpstreamKey(); key != "" {
+					r.Header.Set("Authorization", "Bearer "+key)
+				}
+			}
+			next.ServeHTTP(w, metrics.SetAPIKey(r, AnonymousKey))
+			return
 		}
+
+		// Extract client API key from the Authorization or X-API-Key header
+		authHeader := r.Header.Get("Authorization")
+		clientKey, fromAuthorization := a.clientKey(r)
 		
 		// Check if key is provided
 		if clientKey == "" {
@@ -74,11 +173,13 @@ func (a *AuthMiddleware) Middleware(next http.Handler) http.Handler {
 		
 		// Replace Authorization header with upstream key
 		// Preserve Bearer prefix if it was in the original
-		if strings.HasPrefix(authHeader, "Bearer ") {
-			r.Header.Set("Authorization", "Bearer "+upstreamKey)
-		} else {
+		if fromAuthorization && !strings.HasPrefix(authHeader, "Bearer ") {
 			r.Header.Set("Authorization", upstreamKey)
+		} else {
+			r.Header.Set("Authorization", "Bearer "+upstreamKey)
 		}
+		// The client key must not reach the upstream through either header
+		r.Header.Del(APIKeyHeader)
 		
 		if a.logger != nil {
 			a.logger.Debug("API key authenticated and transformed", map[string]any{
@@ -89,6 +190,10 @@ func (a *AuthMiddleware) Middleware(next http.Handler) http.Handler {
 			})
 		}
 		
+		// Make the validated client key available to downstream middleware,
+		// since the Authorization header now carries the upstream key
+		r = metrics.SetAPIKey(r, clientKey)
+		
 		// Continue to next handler
 		next.ServeHTTP(w, r)
 	})
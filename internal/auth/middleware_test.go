@@ -1,10 +1,14 @@
 package auth
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/jamesprial/nexus/internal/config"
+	"github.com/jamesprial/nexus/internal/metrics"
 )
 
 // mockKeyManager implements interfaces.KeyManager for testing
@@ -241,6 +245,191 @@ func TestAuthMiddleware_MissingAPIKey(t *testing.T) {
 	}
 }
 
+func TestAuthMiddleware_PublicPaths(t *testing.T) {
+	keyManager := &mockKeyManager{
+		apiKeys:    map[string]string{"valid-key": "upstream-key"},
+		configured: true,
+	}
+	middleware := NewAuthMiddleware(keyManager, &mockLogger{})
+	middleware.SetPublicPaths([]string{"/v1/models", "/public/*"})
+
+	tests := []struct {
+		name         string
+		path         string
+		authHeader   string
+		expectStatus int
+		expectKey    string
+		expectAuth   string
+	}{
+		{name: "exact public path", path: "/v1/models", expectStatus: http.StatusOK, expectKey: AnonymousKey},
+		{name: "public path keeps Authorization", path: "/v1/models", authHeader: "Bearer client-token", expectStatus: http.StatusOK, expectKey: AnonymousKey, expectAuth: "Bearer client-token"},
+		{name: "prefix public path", path: "/public/docs/index", expectStatus: http.StatusOK, expectKey: AnonymousKey},
+		{name: "exact match does not cover subpaths", path: "/v1/models/gpt-4", expectStatus: http.StatusUnauthorized},
+		{name: "protected path without key", path: "/v1/chat/completions", expectStatus: http.StatusUnauthorized},
+		{name: "protected path with key", path: "/v1/chat/completions", authHeader: "Bearer valid-key", expectStatus: http.StatusOK, expectKey: "valid-key", expectAuth: "Bearer upstream-key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+
+			var gotKey, gotAuth string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotKey = metrics.GetAPIKey(r)
+				gotAuth = r.Header.Get("Authorization")
+				w.WriteHeader(http.StatusOK)
+			})
+
+			rr := httptest.NewRecorder()
+			middleware.Middleware(next).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectStatus {
+				t.Errorf("expected status %d, got %d", tt.expectStatus, rr.Code)
+			}
+			if gotKey != tt.expectKey {
+				t.Errorf("expected API key %q in context, got %q", tt.expectKey, gotKey)
+			}
+			if gotAuth != tt.expectAuth {
+				t.Errorf("expected Authorization %q, got %q", tt.expectAuth, gotAuth)
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_PublicPathsUseDefaultUpstreamKey(t *testing.T) {
+	keyManager := NewFileKeyManager(&config.Config{
+		APIKeys:            map[string]string{"valid-key": "upstream-key"},
+		DefaultUpstreamKey: "public-upstream-key",
+	})
+	middleware := NewAuthMiddleware(keyManager, &mockLogger{})
+	middleware.SetPublicPaths([]string{"/v1/models"})
+
+	tests := []struct {
+		name         string
+		path         string
+		authHeader   string
+		expectStatus int
+		expectAuth   string
+	}{
+		{name: "public path without key", path: "/v1/models", expectStatus: http.StatusOK, expectAuth: "Bearer public-upstream-key"},
+		{name: "public path replaces client header", path: "/v1/models", authHeader: "Bearer whatever", expectStatus: http.StatusOK, expectAuth: "Bearer public-upstream-key"},
+		{name: "protected path without key", path: "/v1/chat/completions", expectStatus: http.StatusUnauthorized},
+		{name: "protected path with key", path: "/v1/chat/completions", authHeader: "Bearer valid-key", expectStatus: http.StatusOK, expectAuth: "Bearer upstream-key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+
+			var gotAuth string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotAuth = r.Header.Get("Authorization")
+				w.WriteHeader(http.StatusOK)
+			})
+
+			rr := httptest.NewRecorder()
+			middleware.Middleware(next).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectStatus {
+				t.Errorf("expected status %d, got %d", tt.expectStatus, rr.Code)
+			}
+			if gotAuth != tt.expectAuth {
+				t.Errorf("expected Authorization %q, got %q", tt.expectAuth, gotAuth)
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_KeyHeaderPrecedence(t *testing.T) {
+	keyManager := &mockKeyManager{
+		apiKeys: map[string]string{
+			"header-key": "upstream-a",
+			"bearer-key": "upstream-b",
+		},
+		configured: true,
+	}
+
+	tests := []struct {
+		name         string
+		precedence   string
+		authHeader   string
+		apiKeyHeader string
+		expectStatus int
+		expectKey    string
+		expectAuth   string
+		expectWarn   bool
+	}{
+		{name: "authorization only", authHeader: "Bearer bearer-key", expectStatus: http.StatusOK, expectKey: "bearer-key", expectAuth: "Bearer upstream-b"},
+		{name: "x-api-key only", apiKeyHeader: "header-key", expectStatus: http.StatusOK, expectKey: "header-key", expectAuth: "Bearer upstream-a"},
+		{name: "matching headers", authHeader: "Bearer header-key", apiKeyHeader: "header-key", expectStatus: http.StatusOK, expectKey: "header-key", expectAuth: "Bearer upstream-a"},
+		{name: "conflict prefers authorization by default", authHeader: "Bearer bearer-key", apiKeyHeader: "header-key", expectStatus: http.StatusOK, expectKey: "bearer-key", expectAuth: "Bearer upstream-b", expectWarn: true},
+		{name: "conflict prefers x-api-key when configured", precedence: PrecedenceAPIKey, authHeader: "Bearer bearer-key", apiKeyHeader: "header-key", expectStatus: http.StatusOK, expectKey: "header-key", expectAuth: "Bearer upstream-a", expectWarn: true},
+		{name: "conflict uses primary even when invalid", authHeader: "Bearer wrong-key", apiKeyHeader: "header-key", expectStatus: http.StatusUnauthorized, expectWarn: true},
+		{name: "empty primary falls back", precedence: PrecedenceAPIKey, authHeader: "Bearer bearer-key", apiKeyHeader: "  ", expectStatus: http.StatusOK, expectKey: "bearer-key", expectAuth: "Bearer upstream-b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &mockLogger{}
+			middleware := NewAuthMiddleware(keyManager, logger)
+			middleware.SetHeaderPrecedence(tt.precedence)
+
+			req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			if tt.apiKeyHeader != "" {
+				req.Header.Set(APIKeyHeader, tt.apiKeyHeader)
+			}
+
+			var gotKey, gotAuth, gotAPIKey string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotKey = metrics.GetAPIKey(r)
+				gotAuth = r.Header.Get("Authorization")
+				gotAPIKey = r.Header.Get(APIKeyHeader)
+				w.WriteHeader(http.StatusOK)
+			})
+
+			rr := httptest.NewRecorder()
+			middleware.Middleware(next).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectStatus {
+				t.Errorf("expected status %d, got %d", tt.expectStatus, rr.Code)
+			}
+			if gotKey != tt.expectKey {
+				t.Errorf("expected API key %q in context, got %q", tt.expectKey, gotKey)
+			}
+			if gotAuth != tt.expectAuth {
+				t.Errorf("expected Authorization %q, got %q", tt.expectAuth, gotAuth)
+			}
+			if gotAPIKey != "" {
+				t.Errorf("expected %s to be stripped before forwarding, got %q", APIKeyHeader, gotAPIKey)
+			}
+			if warned := logger.hasLogMessage("Conflicting API keys"); warned != tt.expectWarn {
+				t.Errorf("expected conflict warning %v, got %v", tt.expectWarn, warned)
+			}
+
+			// Neither raw key may appear in any log entry
+			for _, entry := range logger.logs {
+				for _, value := range entry.fields {
+					text := fmt.Sprint(value)
+					for _, raw := range []string{"header-key", "bearer-key", "wrong-key"} {
+						if strings.Contains(text, raw) {
+							t.Errorf("raw key %q logged in %q: %v", raw, entry.message, entry.fields)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
 func TestAuthMiddleware_InvalidAPIKey(t *testing.T) {
 	keyManager := &mockKeyManager{
 		apiKeys: map[string]string{
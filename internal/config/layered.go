@@ -0,0 +1,100 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+	"gopkg.in/yaml.v3"
+)
+
+// LayeredLoader loads configuration from several YAML files, such as a base
+// file followed by environment overrides, merged into one config
+type LayeredLoader struct {
+	paths []string
+}
+
+// NewLayeredLoader creates a loader that merges paths in order, later files
+// overriding earlier ones. A directory stands for every *.yaml file in it,
+// in name order.
+func NewLayeredLoader(paths ...string) *LayeredLoader {
+	return &LayeredLoader{
+		paths: append([]string(nil), paths...),
+	}
+}
+
+// Load implements interfaces.ConfigLoader. Mappings such as api_keys and tls
+// are merged key by key, while scalars and lists from a later file replace
+// the earlier value.
+func (l *LayeredLoader) Load() (*interfaces.Config, error) {
+	files, err := l.files()
+	if err != nil {
+		return nil, err
+	}
+
+	merged := map[string]any{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var layer map[string]any
+		if err := yaml.Unmarshal(data, &layer); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		mergeYAML(merged, layer)
+	}
+
+	// Round-trip through YAML so the merged tree decodes exactly like a single file
+	data, err := yaml.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return convertConfig(&cfg), nil
+}
+
+// files expands directories into their *.yaml files
+func (l *LayeredLoader) files() ([]string, error) {
+	var files []string
+	for _, path := range l.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		// Glob returns matches in name order
+		matches, err := filepath.Glob(filepath.Join(path, "*.yaml"))
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no *.yaml files in config directory %s", path)
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no config files given")
+	}
+	return files, nil
+}
+
+// mergeYAML merges src into dst, recursing into mappings present in both
+func mergeYAML(dst, src map[string]any) {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]any)
+		dstMap, dstIsMap := dst[key].(map[string]any)
+		if srcIsMap && dstIsMap {
+			mergeYAML(dstMap, srcMap)
+			continue
+		}
+		dst[key] = value
+	}
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const baseLayer = `
+listen_port: 8080
+target_url: "http://base.example.com"
+log_level: "info"
+api_keys:
+  "client1": "upstream1"
+  "client2": "upstream2"
+limits:
+  requests_per_second: 10
+  burst: 20
+tls:
+  enabled: false
+  cert_file: "/etc/ssl/base-cert.pem"
+  key_file: "/etc/ssl/base-key.pem"
+allowed_models: ["gpt-4", "gpt-3.5-turbo"]
+`
+
+const overrideLayer = `
+target_url: "http://prod.example.com"
+api_keys:
+  "client2": "upstream2-prod"
+  "client3": "upstream3"
+limits:
+  burst: 50
+tls:
+  enabled: true
+  cert_file: "/etc/ssl/prod-cert.pem"
+allowed_models: ["gpt-4o"]
+metrics:
+  slo_latency: 250ms
+`
+
+func writeLayer(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLayeredLoader_MergePrecedence(t *testing.T) {
+	dir := t.TempDir()
+	base := writeLayer(t, dir, "base.yaml", baseLayer)
+	override := writeLayer(t, dir, "prod.yaml", overrideLayer)
+
+	cfg, err := NewLayeredLoader(base, override).Load()
+	if err != nil {
+		t.Fatalf("Failed to load layers: %v", err)
+	}
+
+	// Scalars: later files win, unset ones keep the earlier value
+	if cfg.TargetURL != "http://prod.example.com" {
+		t.Errorf("Expected the override target URL, got %s", cfg.TargetURL)
+	}
+	if cfg.ListenPort != 8080 || cfg.LogLevel != "info" {
+		t.Errorf("Expected base scalars to survive, got port %d and level %q", cfg.ListenPort, cfg.LogLevel)
+	}
+	if cfg.Limits.RequestsPerSecond != 10 || cfg.Limits.Burst != 50 {
+		t.Errorf("Expected limits merged field by field, got %+v", cfg.Limits)
+	}
+	if cfg.Metrics.SLOLatency != 250*time.Millisecond {
+		t.Errorf("Expected a section only in the override to load, got %v", cfg.Metrics.SLOLatency)
+	}
+
+	// Maps merge key by key
+	wantKeys := map[string]string{"client1": "upstream1", "client2": "upstream2-prod", "client3": "upstream3"}
+	if len(cfg.APIKeys) != len(wantKeys) {
+		t.Errorf("Expected %d API keys, got %v", len(wantKeys), cfg.APIKeys)
+	}
+	for client, upstream := range wantKeys {
+		if cfg.APIKeys[client] != upstream {
+			t.Errorf("Expected api_keys[%s]=%s, got %q", client, upstream, cfg.APIKeys[client])
+		}
+	}
+
+	// The TLS sub-struct merges like any other mapping
+	if cfg.TLS == nil {
+		t.Fatal("Expected TLS config, got nil")
+	}
+	if !cfg.TLS.Enabled || cfg.TLS.CertFile != "/etc/ssl/prod-cert.pem" || cfg.TLS.KeyFile != "/etc/ssl/base-key.pem" {
+		t.Errorf("Expected TLS merged field by field, got %+v", *cfg.TLS)
+	}
+
+	// Lists are replaced rather than appended
+	if len(cfg.AllowedModels) != 1 || cfg.AllowedModels[0] != "gpt-4o" {
+		t.Errorf("Expected the override model list, got %v", cfg.AllowedModels)
+	}
+
+	// Reversing the order reverses the precedence
+	cfg, err = NewLayeredLoader(override, base).Load()
+	if err != nil {
+		t.Fatalf("Failed to load layers: %v", err)
+	}
+	if cfg.TargetURL != "http://base.example.com" || cfg.APIKeys["client2"] != "upstream2" || cfg.TLS.Enabled {
+		t.Errorf("Expected the base to win when loaded last, got %s, %v, %+v", cfg.TargetURL, cfg.APIKeys, *cfg.TLS)
+	}
+}
+
+func TestLayeredLoader_Directory(t *testing.T) {
+	dir := t.TempDir()
+	// Loaded in name order, so 20-prod.yaml overrides 10-base.yaml
+	writeLayer(t, dir, "20-prod.yaml", overrideLayer)
+	writeLayer(t, dir, "10-base.yaml", baseLayer)
+	writeLayer(t, dir, "notes.txt", "target_url: ignored")
+
+	local := writeLayer(t, t.TempDir(), "local.yaml", `target_url: "http://localhost:9000"`)
+
+	cfg, err := NewLayeredLoader(dir, local).Load()
+	if err != nil {
+		t.Fatalf("Failed to load directory: %v", err)
+	}
+	if cfg.TargetURL != "http://localhost:9000" {
+		t.Errorf("Expected the file after the directory to win, got %s", cfg.TargetURL)
+	}
+	if cfg.APIKeys["client2"] != "upstream2-prod" || cfg.ListenPort != 8080 {
+		t.Errorf("Expected directory files merged in name order, got %v and port %d", cfg.APIKeys, cfg.ListenPort)
+	}
+}
+
+func TestLayeredLoader_Errors(t *testing.T) {
+	dir := t.TempDir()
+	invalid := writeLayer(t, dir, "invalid.yaml", "listen_port: [")
+
+	tests := []struct {
+		name  string
+		paths []string
+	}{
+		{name: "no paths"},
+		{name: "missing file", paths: []string{filepath.Join(dir, "missing.yaml")}},
+		{name: "empty directory", paths: []string{t.TempDir()}},
+		{name: "invalid YAML", paths: []string{invalid}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewLayeredLoader(tt.paths...).Load(); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
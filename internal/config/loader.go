@@ -1,6 +1,8 @@
 package config
 
 import (
+	"sync"
+
 	rootconfig "github.com/jamesprial/nexus/config"
 	"github.com/jamesprial/nexus/internal/interfaces"
 )
@@ -17,6 +19,54 @@ type Limits = rootconfig.Limits
 // MetricsConfig re-exports the root metrics config type
 type MetricsConfig = rootconfig.MetricsConfig
 
+// LoggingConfig re-exports the root logging config type
+type LoggingConfig = rootconfig.LoggingConfig
+
+// AlertsConfig re-exports the root alerts config type
+type AlertsConfig = rootconfig.AlertsConfig
+
+// TransformRoute re-exports the root transform route type
+type TransformRoute = rootconfig.TransformRoute
+
+// PathRewrite re-exports the root path rewrite type
+type PathRewrite = rootconfig.PathRewrite
+
+// JSONBodyConfig re-exports the root JSON body check config type
+type JSONBodyConfig = rootconfig.JSONBodyConfig
+
+// IdempotencyConfig re-exports the root idempotency config type
+type IdempotencyConfig = rootconfig.IdempotencyConfig
+
+// ResponseCacheConfig re-exports the root response cache config type
+type ResponseCacheConfig = rootconfig.ResponseCacheConfig
+
+// CacheRoute re-exports the root cache route type
+type CacheRoute = rootconfig.CacheRoute
+
+// ProxyConfig re-exports the root proxy config type
+type ProxyConfig = rootconfig.ProxyConfig
+
+// APIVersionConfig re-exports the root API version config type
+type APIVersionConfig = rootconfig.APIVersionConfig
+
+// BlockedMethodRoute re-exports the root blocked method route type
+type BlockedMethodRoute = rootconfig.BlockedMethodRoute
+
+// TracingConfig re-exports the root tracing config type
+type TracingConfig = rootconfig.TracingConfig
+
+// KeyLimits re-exports the root per-key limits type
+type KeyLimits = rootconfig.KeyLimits
+
+// BillingConfig re-exports the root billing config type
+type BillingConfig = rootconfig.BillingConfig
+
+// PoolTarget re-exports the root target pool entry type
+type PoolTarget = rootconfig.PoolTarget
+
+// AdminAccessConfig re-exports the root admin access config type
+type AdminAccessConfig = rootconfig.AdminAccessConfig
+
 // Load re-exports the root config Load function
 var Load = rootconfig.Load
 
@@ -40,16 +90,37 @@ func (f *FileLoader) Load() (*interfaces.Config, error) {
 		return nil, err
 	}
 
-	// Convert to interface config
+	return convertConfig(cfg), nil
+}
+
+// convertConfig converts a root config into the interfaces form
+func convertConfig(cfg *Config) *interfaces.Config {
 	result := &interfaces.Config{
-		ListenPort: cfg.ListenPort,
-		TargetURL:  cfg.TargetURL,
-		LogLevel:   cfg.LogLevel,
-		APIKeys:    cfg.APIKeys,
+		ListenPort:    cfg.ListenPort,
+		AdminPort:     cfg.AdminPort,
+		TargetURL:     cfg.TargetURL,
+		LogLevel:      cfg.LogLevel,
+		LogOutput:     cfg.LogOutput,
+		LogFile:       cfg.LogFile,
+		LogMaxSizeMB:  cfg.LogMaxSizeMB,
+		LogMaxBackups: cfg.LogMaxBackups,
+		APIKeys:       cfg.APIKeys,
 		Limits: interfaces.Limits{
-			RequestsPerSecond:    cfg.Limits.RequestsPerSecond,
-			Burst:                cfg.Limits.Burst,
-			ModelTokensPerMinute: cfg.Limits.ModelTokensPerMinute,
+			RequestsPerSecond:     cfg.Limits.RequestsPerSecond,
+			Burst:                 cfg.Limits.Burst,
+			ModelTokensPerMinute:  cfg.Limits.ModelTokensPerMinute,
+			Shadow:                cfg.Limits.Shadow,
+			MaxWait:               cfg.Limits.MaxWait,
+			MaxQueue:              cfg.Limits.MaxQueue,
+			TokenEstimator:        cfg.Limits.TokenEstimator,
+			RateLimitBy:           cfg.Limits.RateLimitBy,
+			RateLimitAlgorithm:    cfg.Limits.RateLimitAlgorithm,
+			MaxConcurrent:         cfg.Limits.MaxConcurrent,
+			DisableTokenLimit:     cfg.Limits.DisableTokenLimit,
+			RateLimitHeaders:      cfg.Limits.RateLimitHeaders,
+			SoftLimitThreshold:    cfg.Limits.SoftLimitThreshold,
+			MaxUpstreamConcurrent: cfg.Limits.MaxUpstreamConcurrent,
+			Scheduling:            cfg.Limits.Scheduling,
 		},
 	}
 	
@@ -64,21 +135,230 @@ func (f *FileLoader) Load() (*interfaces.Config, error) {
 	
 	// Convert Metrics config
 	result.Metrics = interfaces.MetricsConfig{
-		Enabled:           cfg.Metrics.Enabled,
-		MetricsEndpoint:   cfg.Metrics.MetricsEndpoint,
-		PrometheusEnabled: cfg.Metrics.PrometheusEnabled,
-		JSONExportEnabled: cfg.Metrics.JSONExportEnabled,
-		CSVExportEnabled:  cfg.Metrics.CSVExportEnabled,
-		AuthRequired:      cfg.Metrics.AuthRequired,
-		MaskAPIKeys:       cfg.Metrics.MaskAPIKeys,
+		Enabled:              cfg.Metrics.Enabled,
+		MetricsEndpoint:      cfg.Metrics.MetricsEndpoint,
+		PrometheusEnabled:    cfg.Metrics.PrometheusEnabled,
+		JSONExportEnabled:    cfg.Metrics.JSONExportEnabled,
+		CSVExportEnabled:     cfg.Metrics.CSVExportEnabled,
+		AuthRequired:         cfg.Metrics.AuthRequired,
+		MaskAPIKeys:          cfg.Metrics.MaskAPIKeys,
+		MaxEndpointsPerKey:   cfg.Metrics.MaxEndpointsPerKey,
+		MaxModelsPerKey:      cfg.Metrics.MaxModelsPerKey,
+		MaxConcurrentExports: cfg.Metrics.MaxConcurrentExports,
+		LabelHeaders:         cfg.Metrics.LabelHeaders,
+		MaxLabelValuesPerKey: cfg.Metrics.MaxLabelValuesPerKey,
+		DumpOnShutdownPath:   cfg.Metrics.DumpOnShutdownPath,
+		SnapshotPath:         cfg.Metrics.SnapshotPath,
+		LatencySampleRate:    cfg.Metrics.LatencySampleRate,
+		SLOLatency:           cfg.Metrics.SLOLatency,
+		LatencyEWMADecay:     cfg.Metrics.LatencyEWMADecay,
+		ModelJSONPath:        cfg.Metrics.ModelJSONPath,
+		TokensJSONPath:       cfg.Metrics.TokensJSONPath,
+		SummaryKeys:          cfg.Metrics.SummaryKeys,
+		SuccessStatusCodes:   cfg.Metrics.SuccessStatusCodes,
+		RemoteWrite: interfaces.RemoteWriteConfig{
+			URL:      cfg.Metrics.RemoteWrite.URL,
+			Interval: cfg.Metrics.RemoteWrite.Interval,
+			Timeout:  cfg.Metrics.RemoteWrite.Timeout,
+		},
+		EMF: interfaces.EMFConfig{
+			Enabled:   cfg.Metrics.EMF.Enabled,
+			Namespace: cfg.Metrics.EMF.Namespace,
+			Interval:  cfg.Metrics.EMF.Interval,
+		},
+	}
+	for _, t := range cfg.Metrics.PathTemplates {
+		result.Metrics.PathTemplates = append(result.Metrics.PathTemplates, interfaces.PathTemplate{
+			Pattern:  t.Pattern,
+			Template: t.Template,
+		})
+	}
+
+	// Convert Logging config
+	result.Logging = interfaces.LoggingConfig{
+		AccessLog:            cfg.Logging.AccessLog,
+		SlowRequestThreshold: cfg.Logging.SlowRequestThreshold,
+	}
+
+	// Convert per-key limits
+	if cfg.PerKeyLimits != nil {
+		result.PerKeyLimits = make(map[string]interfaces.KeyLimits, len(cfg.PerKeyLimits))
+		for k, v := range cfg.PerKeyLimits {
+			result.PerKeyLimits[k] = interfaces.KeyLimits{
+				RequestsPerSecond:    v.RequestsPerSecond,
+				Burst:                v.Burst,
+				ModelTokensPerMinute: v.ModelTokensPerMinute,
+				MaxConcurrent:        v.MaxConcurrent,
+			}
+		}
+	}
+	result.PerKeyTargets = cfg.PerKeyTargets
+
+	// Convert admin access config
+	result.TrustedProxyCount = cfg.TrustedProxyCount
+	result.AdminAccess = interfaces.AdminAccessConfig{
+		Allow:   cfg.AdminAccess.Allow,
+		Deny:    cfg.AdminAccess.Deny,
+		APIKeys: cfg.AdminAccess.APIKeys,
+	}
+
+	// Convert model policy
+	result.AllowedModels = cfg.AllowedModels
+	result.ModelAliases = cfg.ModelAliases
+	result.OptionsMode = cfg.OptionsMode
+	result.PublicPaths = cfg.PublicPaths
+	result.DefaultUpstreamKey = cfg.DefaultUpstreamKey
+	result.AuthHeaderPrecedence = cfg.AuthHeaderPrecedence
+	result.BasePath = cfg.BasePath
+
+	// Convert target pool
+	for _, t := range cfg.TargetPool {
+		result.TargetPool = append(result.TargetPool, interfaces.PoolTarget{
+			URL:    t.URL,
+			Weight: t.Weight,
+		})
+	}
+
+	// Convert transform routes
+	for _, t := range cfg.Transforms {
+		result.Transforms = append(result.Transforms, interfaces.TransformRoute{
+			PathPrefix:  t.PathPrefix,
+			Transformer: t.Transformer,
+		})
+	}
+
+	// Convert path rewrites
+	for _, rw := range cfg.PathRewrites {
+		result.PathRewrites = append(result.PathRewrites, interfaces.PathRewrite{
+			PathPrefix:  rw.PathPrefix,
+			Pattern:     rw.Pattern,
+			Replacement: rw.Replacement,
+		})
+	}
+
+	// Convert routing
+	result.Routing.OnNoMatch = cfg.Routing.OnNoMatch
+	for _, route := range cfg.Routing.Routes {
+		result.Routing.Routes = append(result.Routing.Routes, interfaces.TargetRoute{
+			PathPrefix: route.PathPrefix,
+			Target:     route.Target,
+		})
+	}
+
+	// Convert idempotency config
+	result.Idempotency = interfaces.IdempotencyConfig{
+		TTL:        cfg.Idempotency.TTL,
+		MaxEntries: cfg.Idempotency.MaxEntries,
+	}
+
+	// Convert response cache config
+	result.ResponseCache.MaxEntries = cfg.ResponseCache.MaxEntries
+	for _, route := range cfg.ResponseCache.Routes {
+		result.ResponseCache.Routes = append(result.ResponseCache.Routes, interfaces.CacheRoute{
+			PathPrefix: route.PathPrefix,
+			TTL:        route.TTL,
+			Methods:    route.Methods,
+		})
+	}
+
+	// Convert proxy config
+	result.Proxy = interfaces.ProxyConfig{
+		MaxResponseBytes:  cfg.Proxy.MaxResponseBytes,
+		WarmUpConnections: cfg.Proxy.WarmUpConnections,
+		WarmUpTimeout:     cfg.Proxy.WarmUpTimeout,
+		BlockedMethods:    cfg.Proxy.BlockedMethods,
+		ForwardTrailers:   cfg.Proxy.ForwardTrailers,
+		NormalizePaths: interfaces.PathNormalizationConfig{
+			Enabled:            cfg.Proxy.NormalizePaths.Enabled,
+			StripTrailingSlash: cfg.Proxy.NormalizePaths.StripTrailingSlash,
+		},
+		WebSockets: cfg.Proxy.WebSockets,
+	}
+	for _, route := range cfg.Proxy.BlockedMethodRoutes {
+		result.Proxy.BlockedMethodRoutes = append(result.Proxy.BlockedMethodRoutes, interfaces.BlockedMethodRoute{
+			PathPrefix: route.PathPrefix,
+			Methods:    route.Methods,
+		})
+	}
+	result.UpstreamKeys = interfaces.UpstreamKeysConfig{
+		Provider:  cfg.UpstreamKeys.Provider,
+		EnvPrefix: cfg.UpstreamKeys.EnvPrefix,
+		CacheTTL:  cfg.UpstreamKeys.CacheTTL,
+	}
+	result.HealthCheck = interfaces.HealthCheckConfig{
+		Interval:           cfg.HealthCheck.Interval,
+		Timeout:            cfg.HealthCheck.Timeout,
+		Path:               cfg.HealthCheck.Path,
+		UnhealthyThreshold: cfg.HealthCheck.UnhealthyThreshold,
+		HealthyThreshold:   cfg.HealthCheck.HealthyThreshold,
+	}
+	result.APIVersion = interfaces.APIVersionConfig{
+		Header:    cfg.APIVersion.Header,
+		Supported: cfg.APIVersion.Supported,
+		Default:   cfg.APIVersion.Default,
+	}
+	result.Server = interfaces.ServerConfig{
+		MaxHeaderBytes:   cfg.Server.MaxHeaderBytes,
+		MaxHeaderCount:   cfg.Server.MaxHeaderCount,
+		PreShutdownDelay: cfg.Server.PreShutdownDelay,
+	}
+	result.Features = cfg.Features
+	result.Request = interfaces.RequestConfig{
+		MaxTotalDuration: cfg.Request.MaxTotalDuration,
+	}
+	result.UpstreamIdentity = interfaces.UpstreamIdentityConfig{
+		Enabled: cfg.UpstreamIdentity.Enabled,
+		Header:  cfg.UpstreamIdentity.Header,
+		Salt:    cfg.UpstreamIdentity.Salt,
+	}
+	result.Maintenance = interfaces.MaintenanceConfig{
+		Enabled:    cfg.Maintenance.Enabled,
+		Status:     cfg.Maintenance.Status,
+		Message:    cfg.Maintenance.Message,
+		RetryAfter: cfg.Maintenance.RetryAfter,
+	}
+	result.GRPCHealthPort = cfg.GRPCHealthPort
+
+	// Convert JSON body check
+	result.JSONBody = interfaces.JSONBodyConfig{
+		Paths:    cfg.JSONBody.Paths,
+		MaxBytes: cfg.JSONBody.MaxBytes,
+	}
+
+	// Convert tracing config
+	result.Tracing = interfaces.TracingConfig{
+		OTLPEndpoint: cfg.Tracing.OTLPEndpoint,
+	}
+
+	// Convert Alerts config
+	result.Alerts = interfaces.AlertsConfig{
+		ErrorRateThreshold: cfg.Alerts.ErrorRateThreshold,
+		WebhookURL:         cfg.Alerts.WebhookURL,
+		Cooldown:           cfg.Alerts.Cooldown,
+		Window:             cfg.Alerts.Window,
+		MinRequests:        cfg.Alerts.MinRequests,
+		PerKey:             cfg.Alerts.PerKey,
+	}
+
+	// Convert billing config
+	result.Billing.Headers = cfg.Billing.Headers
+	if cfg.Billing.Pricing != nil {
+		result.Billing.Pricing = make(map[string]interfaces.ModelPricing, len(cfg.Billing.Pricing))
+		for model, p := range cfg.Billing.Pricing {
+			result.Billing.Pricing[model] = interfaces.ModelPricing{
+				InputPer1K:  p.InputPer1K,
+				OutputPer1K: p.OutputPer1K,
+			}
+		}
 	}
 	
-	return result, nil
+	return result
 }
 
-// MemoryLoader loads configuration from memory (useful for testing)
+// MemoryLoader loads configuration from memory (useful for testing and embedding)
 type MemoryLoader struct {
 	config *interfaces.Config
+	mu     sync.RWMutex
 }
 
 // NewMemoryLoader creates a new in-memory configuration loader
@@ -90,34 +370,179 @@ func NewMemoryLoader(config *interfaces.Config) *MemoryLoader {
 
 // Load implements interfaces.ConfigLoader
 func (m *MemoryLoader) Load() (*interfaces.Config, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	// Return a copy to prevent modification
+	return copyConfig(m.config), nil
+}
+
+// Update replaces the configuration returned by subsequent Load calls.
+// The loader keeps its own copy, so later changes to cfg have no effect.
+// It is safe to call concurrently with Load.
+func (m *MemoryLoader) Update(cfg *interfaces.Config) {
+	updated := copyConfig(cfg)
+
+	m.mu.Lock()
+	m.config = updated
+	m.mu.Unlock()
+}
+
+// copyConfig returns a deep copy of cfg
+func copyConfig(cfg *interfaces.Config) *interfaces.Config {
 	result := &interfaces.Config{
-		ListenPort: m.config.ListenPort,
-		TargetURL:  m.config.TargetURL,
-		LogLevel:   m.config.LogLevel,
+		ListenPort:    cfg.ListenPort,
+		AdminPort:     cfg.AdminPort,
+		TargetURL:     cfg.TargetURL,
+		LogLevel:      cfg.LogLevel,
+		LogOutput:     cfg.LogOutput,
+		LogFile:       cfg.LogFile,
+		LogMaxSizeMB:  cfg.LogMaxSizeMB,
+		LogMaxBackups: cfg.LogMaxBackups,
+		OptionsMode:   cfg.OptionsMode,
 		Limits: interfaces.Limits{
-			RequestsPerSecond:    m.config.Limits.RequestsPerSecond,
-			Burst:                m.config.Limits.Burst,
-			ModelTokensPerMinute: m.config.Limits.ModelTokensPerMinute,
+			RequestsPerSecond:    cfg.Limits.RequestsPerSecond,
+			Burst:                cfg.Limits.Burst,
+			ModelTokensPerMinute: cfg.Limits.ModelTokensPerMinute,
+			Shadow:               cfg.Limits.Shadow,
+			MaxWait:              cfg.Limits.MaxWait,
+			MaxQueue:             cfg.Limits.MaxQueue,
+			TokenEstimator:       cfg.Limits.TokenEstimator,
+			RateLimitBy:          cfg.Limits.RateLimitBy,
+			RateLimitAlgorithm:   cfg.Limits.RateLimitAlgorithm,
+			MaxConcurrent:        cfg.Limits.MaxConcurrent,
+			DisableTokenLimit:    cfg.Limits.DisableTokenLimit,
+			RateLimitHeaders:     cfg.Limits.RateLimitHeaders,
+			SoftLimitThreshold:   cfg.Limits.SoftLimitThreshold,
+			Scheduling:           cfg.Limits.Scheduling,
 		},
 	}
+	result.Limits.MaxUpstreamConcurrent = cfg.Limits.MaxUpstreamConcurrent
 	
 	// Deep copy API keys map
-	if m.config.APIKeys != nil {
-		result.APIKeys = make(map[string]string, len(m.config.APIKeys))
-		for k, v := range m.config.APIKeys {
+	if cfg.APIKeys != nil {
+		result.APIKeys = make(map[string]string, len(cfg.APIKeys))
+		for k, v := range cfg.APIKeys {
 			result.APIKeys[k] = v
 		}
 	}
 	
+	// Deep copy per-key limits map
+	if cfg.PerKeyLimits != nil {
+		result.PerKeyLimits = make(map[string]interfaces.KeyLimits, len(cfg.PerKeyLimits))
+		for k, v := range cfg.PerKeyLimits {
+			result.PerKeyLimits[k] = v
+		}
+	}
+	if cfg.PerKeyTargets != nil {
+		result.PerKeyTargets = make(map[string]string, len(cfg.PerKeyTargets))
+		for k, v := range cfg.PerKeyTargets {
+			result.PerKeyTargets[k] = v
+		}
+	}
+	
+	// Copy model policy
+	result.AllowedModels = append([]string(nil), cfg.AllowedModels...)
+	if cfg.ModelAliases != nil {
+		result.ModelAliases = make(map[string]string, len(cfg.ModelAliases))
+		for k, v := range cfg.ModelAliases {
+			result.ModelAliases[k] = v
+		}
+	}
+	
+	// Copy target pool, transform routes and path rewrites
+	result.TargetPool = append([]interfaces.PoolTarget(nil), cfg.TargetPool...)
+	result.Transforms = append([]interfaces.TransformRoute(nil), cfg.Transforms...)
+	result.PathRewrites = append([]interfaces.PathRewrite(nil), cfg.PathRewrites...)
+	result.Routing = cfg.Routing
+	result.Routing.Routes = append([]interfaces.TargetRoute(nil), cfg.Routing.Routes...)
+
+	// Copy response cache routes
+	result.ResponseCache.MaxEntries = cfg.ResponseCache.MaxEntries
+	for _, route := range cfg.ResponseCache.Routes {
+		route.Methods = append([]string(nil), route.Methods...)
+		result.ResponseCache.Routes = append(result.ResponseCache.Routes, route)
+	}
+
+	// Copy public paths
+	result.PublicPaths = append([]string(nil), cfg.PublicPaths...)
+	result.DefaultUpstreamKey = cfg.DefaultUpstreamKey
+	result.AuthHeaderPrecedence = cfg.AuthHeaderPrecedence
+	result.BasePath = cfg.BasePath
+
+	// Copy JSON body check
+	result.JSONBody = interfaces.JSONBodyConfig{
+		Paths:    append([]string(nil), cfg.JSONBody.Paths...),
+		MaxBytes: cfg.JSONBody.MaxBytes,
+	}
+
+	// Copy admin access lists
+	result.TrustedProxyCount = cfg.TrustedProxyCount
+	result.AdminAccess = interfaces.AdminAccessConfig{
+		Allow:   append([]string(nil), cfg.AdminAccess.Allow...),
+		Deny:    append([]string(nil), cfg.AdminAccess.Deny...),
+		APIKeys: append([]string(nil), cfg.AdminAccess.APIKeys...),
+	}
+	
 	// Copy TLS config if present
-	if m.config.TLS != nil {
+	if cfg.TLS != nil {
 		result.TLS = &interfaces.TLSConfig{
-			Enabled:  m.config.TLS.Enabled,
-			CertFile: m.config.TLS.CertFile,
-			KeyFile:  m.config.TLS.KeyFile,
+			Enabled:  cfg.TLS.Enabled,
+			CertFile: cfg.TLS.CertFile,
+			KeyFile:  cfg.TLS.KeyFile,
+		}
+	}
+
+	result.Metrics = cfg.Metrics
+	result.Metrics.SummaryKeys = append([]string(nil), cfg.Metrics.SummaryKeys...)
+	result.Metrics.SuccessStatusCodes = append([]string(nil), cfg.Metrics.SuccessStatusCodes...)
+	result.Metrics.PathTemplates = append([]interfaces.PathTemplate(nil), cfg.Metrics.PathTemplates...)
+	if cfg.Metrics.LabelHeaders != nil {
+		result.Metrics.LabelHeaders = make(map[string]string, len(cfg.Metrics.LabelHeaders))
+		for header, label := range cfg.Metrics.LabelHeaders {
+			result.Metrics.LabelHeaders[header] = label
+		}
+	}
+	// Logging, Alerts, Tracing, Idempotency, UpstreamKeys, HealthCheck, Server, Request, UpstreamIdentity and Maintenance configs hold only values, so a plain copy is sufficient
+	result.Logging = cfg.Logging
+	result.Alerts = cfg.Alerts
+	result.Tracing = cfg.Tracing
+	result.Idempotency = cfg.Idempotency
+	result.Proxy = cfg.Proxy
+	result.Proxy.BlockedMethods = append([]string(nil), cfg.Proxy.BlockedMethods...)
+	result.Proxy.BlockedMethodRoutes = nil
+	for _, route := range cfg.Proxy.BlockedMethodRoutes {
+		route.Methods = append([]string(nil), route.Methods...)
+		result.Proxy.BlockedMethodRoutes = append(result.Proxy.BlockedMethodRoutes, route)
+	}
+	if cfg.Proxy.ForwardTrailers != nil {
+		forwardTrailers := *cfg.Proxy.ForwardTrailers
+		result.Proxy.ForwardTrailers = &forwardTrailers
+	}
+	result.UpstreamKeys = cfg.UpstreamKeys
+	result.HealthCheck = cfg.HealthCheck
+	result.APIVersion = cfg.APIVersion
+	result.APIVersion.Supported = append([]string(nil), cfg.APIVersion.Supported...)
+	result.Server = cfg.Server
+	result.Request = cfg.Request
+	result.UpstreamIdentity = cfg.UpstreamIdentity
+	result.Maintenance = cfg.Maintenance
+	result.GRPCHealthPort = cfg.GRPCHealthPort
+	if cfg.Features != nil {
+		result.Features = make(map[string]bool, len(cfg.Features))
+		for name, enabled := range cfg.Features {
+			result.Features[name] = enabled
+		}
+	}
+
+	// Copy billing pricing
+	result.Billing.Headers = cfg.Billing.Headers
+	if cfg.Billing.Pricing != nil {
+		result.Billing.Pricing = make(map[string]interfaces.ModelPricing, len(cfg.Billing.Pricing))
+		for model, p := range cfg.Billing.Pricing {
+			result.Billing.Pricing[model] = p
 		}
 	}
 	
-	return result, nil
+	return result
 }
\ No newline at end of file
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"sync"
 	"testing"
 
 	"github.com/jamesprial/nexus/internal/interfaces"
@@ -433,6 +434,55 @@ tls:
 	}
 }
 
+func TestMemoryLoader_Update(t *testing.T) {
+	loader := NewMemoryLoader(&interfaces.Config{
+		ListenPort: 8080,
+		TargetURL:  "http://example.com",
+		APIKeys:    map[string]string{"client1": "upstream1"},
+	})
+
+	updated := &interfaces.Config{
+		ListenPort: 8080,
+		TargetURL:  "http://example.org",
+		APIKeys:    map[string]string{"client2": "upstream2"},
+	}
+	loader.Update(updated)
+
+	// Changes to the caller's config after Update must not leak into the loader
+	updated.APIKeys["client3"] = "upstream3"
+
+	cfg, err := loader.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.TargetURL != "http://example.org" {
+		t.Errorf("Expected updated target URL, got %s", cfg.TargetURL)
+	}
+	if len(cfg.APIKeys) != 1 || cfg.APIKeys["client2"] != "upstream2" {
+		t.Errorf("Expected only client2 after update, got %v", cfg.APIKeys)
+	}
+}
+
+func TestMemoryLoader_ConcurrentUpdate(t *testing.T) {
+	loader := NewMemoryLoader(&interfaces.Config{ListenPort: 8080})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(port int) {
+			defer wg.Done()
+			loader.Update(&interfaces.Config{ListenPort: port})
+		}(9000 + i)
+		go func() {
+			defer wg.Done()
+			if _, err := loader.Load(); err != nil {
+				t.Errorf("Failed to load config: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 func BenchmarkMemoryLoader_Load(b *testing.B) {
 	config := &interfaces.Config{
 		ListenPort: 8080,
@@ -0,0 +1,488 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+	"github.com/jamesprial/nexus/internal/metrics"
+	"github.com/jamesprial/nexus/internal/middleware"
+	"github.com/jamesprial/nexus/internal/proxy"
+	"github.com/jamesprial/nexus/internal/utils"
+)
+
+// labelNamePattern matches the label names Prometheus accepts
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// ValidationError reports every problem found in a configuration
+type ValidationError struct {
+	Problems []string
+}
+
+// Error implements the error interface, listing one problem per line
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate checks a loaded configuration for values the gateway cannot run with.
+// It returns a *ValidationError listing all problems, or nil if the config is valid.
+func Validate(cfg *interfaces.Config) error {
+	if cfg == nil {
+		return &ValidationError{Problems: []string{"configuration is empty"}}
+	}
+
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if cfg.ListenPort < 1 || cfg.ListenPort > 65535 {
+		add("listen_port must be between 1 and 65535, got %d", cfg.ListenPort)
+	}
+
+	if cfg.AdminPort != 0 {
+		if cfg.AdminPort < 1 || cfg.AdminPort > 65535 {
+			add("admin_port must be between 1 and 65535, got %d", cfg.AdminPort)
+		} else if cfg.AdminPort == cfg.ListenPort {
+			add("admin_port must differ from listen_port")
+		}
+	}
+
+	if cfg.GRPCHealthPort != 0 {
+		if cfg.GRPCHealthPort < 1 || cfg.GRPCHealthPort > 65535 {
+			add("grpc_health_port must be between 1 and 65535, got %d", cfg.GRPCHealthPort)
+		} else if cfg.GRPCHealthPort == cfg.ListenPort || cfg.GRPCHealthPort == cfg.AdminPort {
+			add("grpc_health_port must differ from listen_port and admin_port")
+		}
+	}
+
+	// A target pool replaces target_url, which may then be left empty
+	if len(cfg.TargetPool) == 0 || cfg.TargetURL != "" {
+		if err := validateURL(cfg.TargetURL); err != nil {
+			add("target_url %v", err)
+		}
+	}
+	for i, t := range cfg.TargetPool {
+		if err := validateURL(t.URL); err != nil {
+			add("target_pool[%d].url %v", i, err)
+		}
+		if t.Weight < 0 {
+			add("target_pool[%d].weight must not be negative, got %d", i, t.Weight)
+		}
+	}
+
+	switch cfg.LogLevel {
+	case "", "debug", "info", "warn", "error":
+	default:
+		add("log_level must be one of debug, info, warn, error, got %q", cfg.LogLevel)
+	}
+
+	switch cfg.LogOutput {
+	case "", "stdout":
+	case "file":
+		if strings.TrimSpace(cfg.LogFile) == "" {
+			add("log_file is required when log_output is file")
+		}
+	default:
+		add("log_output must be one of stdout, file, got %q", cfg.LogOutput)
+	}
+	if cfg.LogMaxSizeMB < 0 {
+		add("log_max_size_mb must not be negative, got %d", cfg.LogMaxSizeMB)
+	}
+	if cfg.LogMaxBackups < 0 {
+		add("log_max_backups must not be negative, got %d", cfg.LogMaxBackups)
+	}
+
+	for clientKey, upstreamKey := range cfg.APIKeys {
+		if strings.TrimSpace(clientKey) == "" {
+			add("api_keys contains an empty client key")
+		}
+		if strings.TrimSpace(upstreamKey) == "" {
+			add("api_keys entry for a client key has an empty upstream key")
+		}
+	}
+
+	if cfg.Limits.RequestsPerSecond <= 0 {
+		add("limits.requests_per_second must be positive, got %d", cfg.Limits.RequestsPerSecond)
+	}
+	if cfg.Limits.Burst <= 0 {
+		add("limits.burst must be positive, got %d", cfg.Limits.Burst)
+	}
+	if cfg.Limits.ModelTokensPerMinute <= 0 && !cfg.Limits.DisableTokenLimit {
+		add("limits.model_tokens_per_minute must be positive, got %d", cfg.Limits.ModelTokensPerMinute)
+	}
+	if cfg.Limits.MaxWait < 0 {
+		add("limits.max_wait must not be negative, got %v", cfg.Limits.MaxWait)
+	}
+	if cfg.Limits.MaxConcurrent < 0 {
+		add("limits.max_concurrent must not be negative, got %d", cfg.Limits.MaxConcurrent)
+	}
+	if cfg.Limits.MaxQueue < 0 {
+		add("limits.max_queue must not be negative, got %d", cfg.Limits.MaxQueue)
+	}
+	if cfg.Limits.MaxUpstreamConcurrent < 0 {
+		add("limits.max_upstream_concurrent must not be negative, got %d", cfg.Limits.MaxUpstreamConcurrent)
+	}
+	switch cfg.Limits.Scheduling {
+	case "", proxy.SchedulingFIFO, proxy.SchedulingFair:
+		if cfg.Limits.Scheduling != "" && cfg.Limits.MaxUpstreamConcurrent == 0 {
+			add("limits.scheduling requires limits.max_upstream_concurrent")
+		}
+	default:
+		add("limits.scheduling must be one of %s, %s, got %q",
+			proxy.SchedulingFIFO, proxy.SchedulingFair, cfg.Limits.Scheduling)
+	}
+	if cfg.Limits.SoftLimitThreshold < 0 || cfg.Limits.SoftLimitThreshold > 1 {
+		add("limits.soft_limit_threshold must be between 0 and 1, got %v", cfg.Limits.SoftLimitThreshold)
+	}
+	if _, err := proxy.NewTokenEstimator(cfg.Limits.TokenEstimator); err != nil {
+		add("limits.token_estimator %v", err)
+	}
+	switch cfg.Limits.RateLimitBy {
+	case "", proxy.RateLimitByAPIKey, proxy.RateLimitByIP:
+	default:
+		add("limits.rate_limit_by must be one of %s, %s, got %q",
+			proxy.RateLimitByAPIKey, proxy.RateLimitByIP, cfg.Limits.RateLimitBy)
+	}
+	switch cfg.Limits.RateLimitAlgorithm {
+	case "", proxy.RateLimitAlgorithmTokenBucket, proxy.RateLimitAlgorithmFixedWindow, proxy.RateLimitAlgorithmSlidingWindow:
+	default:
+		add("limits.rate_limit_algorithm must be one of %s, %s, %s, got %q",
+			proxy.RateLimitAlgorithmTokenBucket, proxy.RateLimitAlgorithmFixedWindow,
+			proxy.RateLimitAlgorithmSlidingWindow, cfg.Limits.RateLimitAlgorithm)
+	}
+	for _, l := range cfg.PerKeyLimits {
+		if l.RequestsPerSecond < 0 || l.Burst < 0 || l.ModelTokensPerMinute < 0 || l.MaxConcurrent < 0 {
+			add("per_key_limits values must not be negative")
+			break
+		}
+	}
+
+	if len(cfg.PerKeyTargets) > 0 && len(cfg.TargetPool) > 0 {
+		add("per_key_targets is not supported with target_pool")
+	}
+	keys := make([]string, 0, len(cfg.PerKeyTargets))
+	for key := range cfg.PerKeyTargets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := validateURL(cfg.PerKeyTargets[key]); err != nil {
+			add("per_key_targets for key %s %v", utils.MaskAPIKey(key), err)
+		}
+	}
+
+	if len(cfg.AllowedModels) > 0 {
+		allowed := make(map[string]bool, len(cfg.AllowedModels))
+		for _, model := range cfg.AllowedModels {
+			allowed[model] = true
+		}
+		for alias, model := range cfg.ModelAliases {
+			if !allowed[model] {
+				add("model_aliases maps %q to %q, which is not in allowed_models", alias, model)
+			}
+		}
+	}
+
+	for model, p := range cfg.Billing.Pricing {
+		if p.InputPer1K < 0 || p.OutputPer1K < 0 {
+			add("billing.pricing for %q must not be negative", model)
+		}
+	}
+
+	if cfg.TLS != nil && cfg.TLS.Enabled {
+		if cfg.TLS.CertFile == "" {
+			add("tls.cert_file is required when TLS is enabled")
+		}
+		if cfg.TLS.KeyFile == "" {
+			add("tls.key_file is required when TLS is enabled")
+		}
+	}
+
+	if cfg.TrustedProxyCount < 0 {
+		add("trusted_proxy_count must not be negative, got %d", cfg.TrustedProxyCount)
+	}
+
+	if err := middleware.ValidateOptionsMode(cfg.OptionsMode); err != nil {
+		add("options_mode %v", err)
+	}
+
+	for i, path := range cfg.PublicPaths {
+		if !strings.HasPrefix(path, "/") {
+			add("public_paths[%d] must start with '/', got %q", i, path)
+		}
+	}
+
+	if cfg.DefaultUpstreamKey != "" && len(cfg.PublicPaths) == 0 {
+		add("default_upstream_key is only used for public_paths, but none are configured")
+	}
+
+	// Mirrors the auth package's precedence values, which can't be imported here
+	switch cfg.AuthHeaderPrecedence {
+	case "", "authorization", "x-api-key":
+	default:
+		add("auth_header_precedence must be one of authorization, x-api-key, got %q", cfg.AuthHeaderPrecedence)
+	}
+
+	if cfg.BasePath != "" && (!strings.HasPrefix(cfg.BasePath, "/") || strings.HasSuffix(cfg.BasePath, "/")) {
+		add("base_path must start with '/' and not end with one, got %q", cfg.BasePath)
+	}
+
+	if cfg.Idempotency.TTL < 0 {
+		add("idempotency.ttl must not be negative, got %s", cfg.Idempotency.TTL)
+	}
+	if cfg.Idempotency.MaxEntries < 0 {
+		add("idempotency.max_entries must not be negative, got %d", cfg.Idempotency.MaxEntries)
+	}
+
+	if cfg.ResponseCache.MaxEntries < 0 {
+		add("response_cache.max_entries must not be negative, got %d", cfg.ResponseCache.MaxEntries)
+	}
+	for i, route := range cfg.ResponseCache.Routes {
+		if !strings.HasPrefix(route.PathPrefix, "/") {
+			add("response_cache.routes[%d].path_prefix must start with '/', got %q", i, route.PathPrefix)
+		}
+		if route.TTL <= 0 {
+			add("response_cache.routes[%d].ttl must be positive, got %s", i, route.TTL)
+		}
+		for _, method := range route.Methods {
+			if !strings.EqualFold(method, "GET") && !strings.EqualFold(method, "HEAD") {
+				add("response_cache.routes[%d].methods may only contain GET and HEAD, got %q", i, method)
+			}
+		}
+	}
+
+	if cfg.Proxy.MaxResponseBytes < 0 {
+		add("proxy.max_response_bytes must not be negative, got %d", cfg.Proxy.MaxResponseBytes)
+	}
+	if cfg.Proxy.WarmUpConnections < 0 {
+		add("proxy.warmup_connections must not be negative, got %d", cfg.Proxy.WarmUpConnections)
+	}
+	if cfg.Proxy.WarmUpTimeout < 0 {
+		add("proxy.warmup_timeout must not be negative, got %s", cfg.Proxy.WarmUpTimeout)
+	}
+	if len(cfg.APIVersion.Supported) > 0 {
+		supported := make(map[string]bool, len(cfg.APIVersion.Supported))
+		for i, version := range cfg.APIVersion.Supported {
+			if version == "" {
+				add("api_version.supported[%d] must not be empty", i)
+			}
+			supported[version] = true
+		}
+		if cfg.APIVersion.Default != "" && !supported[cfg.APIVersion.Default] {
+			add("api_version.default %q must be one of api_version.supported", cfg.APIVersion.Default)
+		}
+	} else if cfg.APIVersion.Default != "" {
+		add("api_version.default requires api_version.supported")
+	}
+
+	if err := middleware.ValidateMethods(cfg.Proxy.BlockedMethods); err != nil {
+		add("proxy.blocked_methods: %v", err)
+	}
+	for i, route := range cfg.Proxy.BlockedMethodRoutes {
+		if !strings.HasPrefix(route.PathPrefix, "/") {
+			add("proxy.blocked_method_routes[%d].path_prefix must start with /, got %q", i, route.PathPrefix)
+		}
+		if len(route.Methods) == 0 {
+			add("proxy.blocked_method_routes[%d].methods must not be empty", i)
+		}
+		if err := middleware.ValidateMethods(route.Methods); err != nil {
+			add("proxy.blocked_method_routes[%d].methods: %v", i, err)
+		}
+	}
+
+	if cfg.UpstreamKeys.CacheTTL < 0 {
+		add("upstream_keys.cache_ttl must not be negative, got %s", cfg.UpstreamKeys.CacheTTL)
+	}
+
+	hc := cfg.HealthCheck
+	if hc.Interval < 0 {
+		add("health_check.interval must not be negative, got %s", hc.Interval)
+	}
+	if hc.Timeout < 0 {
+		add("health_check.timeout must not be negative, got %s", hc.Timeout)
+	}
+	if hc.Path != "" && !strings.HasPrefix(hc.Path, "/") {
+		add("health_check.path must start with '/', got %q", hc.Path)
+	}
+	if hc.UnhealthyThreshold < 0 {
+		add("health_check.unhealthy_threshold must not be negative, got %d", hc.UnhealthyThreshold)
+	}
+	if hc.HealthyThreshold < 0 {
+		add("health_check.healthy_threshold must not be negative, got %d", hc.HealthyThreshold)
+	}
+
+	if cfg.Server.MaxHeaderBytes < 0 {
+		add("server.max_header_bytes must not be negative, got %d", cfg.Server.MaxHeaderBytes)
+	}
+	if cfg.Server.MaxHeaderCount < 0 {
+		add("server.max_header_count must not be negative, got %d", cfg.Server.MaxHeaderCount)
+	}
+	if cfg.Server.PreShutdownDelay < 0 {
+		add("server.pre_shutdown_delay must not be negative, got %s", cfg.Server.PreShutdownDelay)
+	}
+	if cfg.Request.MaxTotalDuration < 0 {
+		add("request.max_total_duration must not be negative, got %s", cfg.Request.MaxTotalDuration)
+	}
+	if cfg.UpstreamIdentity.Enabled && cfg.UpstreamIdentity.Salt == "" {
+		add("upstream_identity.salt is required when upstream_identity is enabled")
+	}
+	if s := cfg.Maintenance.Status; s != 0 && (s < 400 || s > 599) {
+		add("maintenance.status must be between 400 and 599, got %d", s)
+	}
+	if cfg.Maintenance.RetryAfter < 0 {
+		add("maintenance.retry_after must not be negative, got %s", cfg.Maintenance.RetryAfter)
+	}
+
+	for i, path := range cfg.JSONBody.Paths {
+		if !strings.HasPrefix(path, "/") {
+			add("json_body.paths[%d] must start with '/', got %q", i, path)
+		}
+	}
+	if cfg.JSONBody.MaxBytes < 0 {
+		add("json_body.max_bytes must not be negative, got %d", cfg.JSONBody.MaxBytes)
+	}
+
+	if err := middleware.ValidateCIDRs(cfg.AdminAccess.Allow); err != nil {
+		add("admin_access.allow: %v", err)
+	}
+	if err := middleware.ValidateCIDRs(cfg.AdminAccess.Deny); err != nil {
+		add("admin_access.deny: %v", err)
+	}
+
+	if cfg.Metrics.MetricsEndpoint != "" && !strings.HasPrefix(cfg.Metrics.MetricsEndpoint, "/") {
+		add("metrics.metrics_endpoint must start with '/', got %q", cfg.Metrics.MetricsEndpoint)
+	}
+
+	if cfg.Metrics.MaxEndpointsPerKey < 0 {
+		add("metrics.max_endpoints_per_key must not be negative, got %d", cfg.Metrics.MaxEndpointsPerKey)
+	}
+	if cfg.Metrics.MaxConcurrentExports < 0 {
+		add("metrics.max_concurrent_exports must not be negative, got %d", cfg.Metrics.MaxConcurrentExports)
+	}
+	if cfg.Metrics.MaxModelsPerKey < 0 {
+		add("metrics.max_models_per_key must not be negative, got %d", cfg.Metrics.MaxModelsPerKey)
+	}
+	if cfg.Metrics.MaxLabelValuesPerKey < 0 {
+		add("metrics.max_label_values_per_key must not be negative, got %d", cfg.Metrics.MaxLabelValuesPerKey)
+	}
+	for header, label := range cfg.Metrics.LabelHeaders {
+		if strings.TrimSpace(header) == "" {
+			add("metrics.label_headers must not contain an empty header name")
+		}
+		if !labelNamePattern.MatchString(label) {
+			add("metrics.label_headers[%s] must be a valid label name, got %q", header, label)
+		}
+	}
+	if cfg.Metrics.LatencySampleRate < 0 {
+		add("metrics.latency_sample_rate must not be negative, got %d", cfg.Metrics.LatencySampleRate)
+	}
+	if cfg.Metrics.SLOLatency < 0 {
+		add("metrics.slo_latency must not be negative, got %v", cfg.Metrics.SLOLatency)
+	}
+	if cfg.Metrics.LatencyEWMADecay < 0 || cfg.Metrics.LatencyEWMADecay > 1 {
+		add("metrics.latency_ewma_decay must be between 0 and 1, got %v", cfg.Metrics.LatencyEWMADecay)
+	}
+	if _, err := proxy.NewUsagePaths(cfg.Metrics.ModelJSONPath, cfg.Metrics.TokensJSONPath); err != nil {
+		add("metrics.%v", err)
+	}
+	if cfg.Metrics.RemoteWrite.URL != "" {
+		if err := validateURL(cfg.Metrics.RemoteWrite.URL); err != nil {
+			add("metrics.remote_write.url %v", err)
+		}
+	}
+	if cfg.Metrics.RemoteWrite.Interval < 0 {
+		add("metrics.remote_write.interval must not be negative, got %v", cfg.Metrics.RemoteWrite.Interval)
+	}
+	if cfg.Metrics.RemoteWrite.Timeout < 0 {
+		add("metrics.remote_write.timeout must not be negative, got %v", cfg.Metrics.RemoteWrite.Timeout)
+	}
+	if cfg.Metrics.EMF.Interval < 0 {
+		add("metrics.emf.interval must not be negative, got %v", cfg.Metrics.EMF.Interval)
+	}
+	if cfg.Logging.SlowRequestThreshold < 0 {
+		add("logging.slow_request_threshold must not be negative, got %v", cfg.Logging.SlowRequestThreshold)
+	}
+	for i, key := range cfg.Metrics.SummaryKeys {
+		if key == "" {
+			add("metrics.summary_keys[%d] must not be empty", i)
+		}
+	}
+	if _, err := metrics.ParseStatusRanges(cfg.Metrics.SuccessStatusCodes); err != nil {
+		add("metrics.success_status_codes has an %v", err)
+	}
+	if _, err := metrics.NewPathTemplates(cfg.Metrics.PathTemplates); err != nil {
+		add("metrics.%v", err)
+	}
+
+	if cfg.Alerts.ErrorRateThreshold < 0 || cfg.Alerts.ErrorRateThreshold > 1 {
+		add("alerts.error_rate_threshold must be between 0 and 1, got %v", cfg.Alerts.ErrorRateThreshold)
+	}
+	if cfg.Alerts.WebhookURL != "" {
+		if err := validateURL(cfg.Alerts.WebhookURL); err != nil {
+			add("alerts.webhook_url %v", err)
+		}
+	}
+
+	for i, t := range cfg.Transforms {
+		if !strings.HasPrefix(t.PathPrefix, "/") {
+			add("transforms[%d].path_prefix must start with /, got %q", i, t.PathPrefix)
+		}
+		if _, err := proxy.LookupTransformer(t.Transformer); err != nil {
+			add("transforms[%d].transformer %v", i, err)
+		}
+	}
+
+	for i, rw := range cfg.PathRewrites {
+		if _, err := proxy.NewPathRewrite(rw); err != nil {
+			add("path_rewrites[%d]: %v", i, err)
+		}
+	}
+
+	for i, route := range cfg.Routing.Routes {
+		if !strings.HasPrefix(route.PathPrefix, "/") {
+			add("routing.routes[%d].path_prefix must start with /, got %q", i, route.PathPrefix)
+		}
+		if err := validateURL(route.Target); err != nil {
+			add("routing.routes[%d].target %v", i, err)
+		}
+	}
+	if len(cfg.Routing.Routes) > 0 && len(cfg.TargetPool) > 0 {
+		add("routing.routes cannot be combined with target_pool")
+	}
+	if err := middleware.ValidateOnNoMatch(cfg.Routing.OnNoMatch); err != nil {
+		add("routing.on_no_match %v", err)
+	}
+
+	if cfg.Tracing.OTLPEndpoint != "" {
+		if err := validateURL(cfg.Tracing.OTLPEndpoint); err != nil {
+			add("tracing.otlp_endpoint %v", err)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// validateURL checks that a URL is absolute with an http(s) scheme and a host
+func validateURL(raw string) error {
+	if strings.TrimSpace(raw) == "" {
+		return fmt.Errorf("is required")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("is not a valid URL: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("must use http or https, got %q", raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("must include a host, got %q", raw)
+	}
+	return nil
+}
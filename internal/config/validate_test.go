@@ -0,0 +1,652 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+func validConfig() *interfaces.Config {
+	return &interfaces.Config{
+		ListenPort: 8080,
+		TargetURL:  "https://api.openai.com",
+		LogLevel:   "info",
+		APIKeys:    map[string]string{"client": "upstream"},
+		Limits: interfaces.Limits{
+			RequestsPerSecond:    10,
+			Burst:                20,
+			ModelTokensPerMinute: 6000,
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		mutate   func(cfg *interfaces.Config)
+		problems []string
+	}{
+		{
+			name:   "valid configuration",
+			mutate: func(cfg *interfaces.Config) {},
+		},
+		{
+			name:     "invalid port",
+			mutate:   func(cfg *interfaces.Config) { cfg.ListenPort = 70000 },
+			problems: []string{"listen_port"},
+		},
+		{
+			name:     "missing target URL",
+			mutate:   func(cfg *interfaces.Config) { cfg.TargetURL = "" },
+			problems: []string{"target_url is required"},
+		},
+		{
+			name:     "target URL without scheme",
+			mutate:   func(cfg *interfaces.Config) { cfg.TargetURL = "api.openai.com" },
+			problems: []string{"target_url must use http or https"},
+		},
+		{
+			name:     "unknown log level",
+			mutate:   func(cfg *interfaces.Config) { cfg.LogLevel = "verbose" },
+			problems: []string{"log_level"},
+		},
+		{
+			name: "target pool replaces target URL",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.TargetURL = ""
+				cfg.TargetPool = []interfaces.PoolTarget{{URL: "https://a.example.com", Weight: 2}}
+			},
+		},
+		{
+			name: "invalid target pool entries",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.TargetPool = []interfaces.PoolTarget{
+					{URL: "ftp://a.example.com"},
+					{URL: "https://b.example.com", Weight: -1},
+				}
+			},
+			problems: []string{"target_pool[0].url", "target_pool[1].weight"},
+		},
+		{
+			name: "per-key targets",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.PerKeyTargets = map[string]string{"customer-a-key": "https://eu.example.com/v1"}
+			},
+		},
+		{
+			name: "invalid per-key target",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.PerKeyTargets = map[string]string{"customer-a-key": "eu.example.com"}
+			},
+			problems: []string{"per_key_targets for key"},
+		},
+		{
+			name: "per-key targets with target pool",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.TargetPool = []interfaces.PoolTarget{{URL: "https://a.example.com"}}
+				cfg.PerKeyTargets = map[string]string{"customer-a-key": "https://eu.example.com"}
+			},
+			problems: []string{"per_key_targets is not supported with target_pool"},
+		},
+		{
+			name: "negative billing price",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Billing.Pricing = map[string]interfaces.ModelPricing{"gpt-4": {InputPer1K: -0.03}}
+			},
+			problems: []string{"billing.pricing"},
+		},
+		{
+			name:     "unknown log output",
+			mutate:   func(cfg *interfaces.Config) { cfg.LogOutput = "syslog" },
+			problems: []string{"log_output"},
+		},
+		{
+			name:     "file log output without path",
+			mutate:   func(cfg *interfaces.Config) { cfg.LogOutput = "file" },
+			problems: []string{"log_file"},
+		},
+		{
+			name:     "empty upstream key",
+			mutate:   func(cfg *interfaces.Config) { cfg.APIKeys["client"] = "" },
+			problems: []string{"empty upstream key"},
+		},
+		{
+			name: "zero limits",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Limits = interfaces.Limits{}
+			},
+			problems: []string{"requests_per_second", "burst", "model_tokens_per_minute"},
+		},
+		{
+			name: "token limit disabled",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Limits.ModelTokensPerMinute = 0
+				cfg.Limits.DisableTokenLimit = true
+			},
+		},
+		{
+			name: "TLS without files",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.TLS = &interfaces.TLSConfig{Enabled: true}
+			},
+			problems: []string{"tls.cert_file", "tls.key_file"},
+		},
+		{
+			name: "invalid admin access entries",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.AdminAccess.Allow = []string{"10.0.0.0/8", "bogus"}
+				cfg.AdminAccess.Deny = []string{"300.0.0.1"}
+			},
+			problems: []string{"admin_access.allow", "admin_access.deny"},
+		},
+		{
+			name: "alias to disallowed model",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.AllowedModels = []string{"gpt-4"}
+				cfg.ModelAliases = map[string]string{"gpt4": "gpt-4", "cheap": "gpt-3.5-turbo"}
+			},
+			problems: []string{`"cheap" to "gpt-3.5-turbo"`},
+		},
+		{
+			name:     "alert threshold out of range",
+			mutate:   func(cfg *interfaces.Config) { cfg.Alerts.ErrorRateThreshold = 1.5 },
+			problems: []string{"alerts.error_rate_threshold"},
+		},
+		{
+			name: "negative concurrency caps",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Limits.MaxConcurrent = -1
+				cfg.PerKeyLimits = map[string]interfaces.KeyLimits{"client": {MaxConcurrent: -2}}
+			},
+			problems: []string{"limits.max_concurrent", "per_key_limits"},
+		},
+		{
+			name:     "negative latency sample rate",
+			mutate:   func(cfg *interfaces.Config) { cfg.Metrics.LatencySampleRate = -5 },
+			problems: []string{"metrics.latency_sample_rate"},
+		},
+		{
+			name:     "negative slo latency",
+			mutate:   func(cfg *interfaces.Config) { cfg.Metrics.SLOLatency = -time.Second },
+			problems: []string{"metrics.slo_latency"},
+		},
+		{
+			name:     "latency ewma decay above one",
+			mutate:   func(cfg *interfaces.Config) { cfg.Metrics.LatencyEWMADecay = 1.5 },
+			problems: []string{"metrics.latency_ewma_decay"},
+		},
+		{
+			name:     "negative slow request threshold",
+			mutate:   func(cfg *interfaces.Config) { cfg.Logging.SlowRequestThreshold = -time.Second },
+			problems: []string{"logging.slow_request_threshold"},
+		},
+		{
+			name: "invalid remote write settings",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Metrics.RemoteWrite = interfaces.RemoteWriteConfig{URL: "ftp://metrics.example.com", Interval: -time.Second, Timeout: -time.Second}
+			},
+			problems: []string{"metrics.remote_write.url", "metrics.remote_write.interval", "metrics.remote_write.timeout"},
+		},
+		{
+			name: "emf export",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Metrics.EMF = interfaces.EMFConfig{Enabled: true, Namespace: "Gateway", Interval: time.Minute}
+			},
+		},
+		{
+			name: "negative emf interval",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Metrics.EMF = interfaces.EMFConfig{Enabled: true, Interval: -time.Second}
+			},
+			problems: []string{"metrics.emf.interval"},
+		},
+		{
+			name: "label headers",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Metrics.LabelHeaders = map[string]string{"X-Tenant-ID": "tenant"}
+				cfg.Metrics.MaxLabelValuesPerKey = 50
+			},
+		},
+		{
+			name: "invalid label headers",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Metrics.LabelHeaders = map[string]string{"X-Tenant-ID": "tenant-id"}
+				cfg.Metrics.MaxLabelValuesPerKey = -1
+			},
+			problems: []string{"metrics.label_headers[X-Tenant-ID]", "metrics.max_label_values_per_key"},
+		},
+		{
+			name: "usage json paths",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Metrics.ModelJSONPath = "request.model"
+				cfg.Metrics.TokensJSONPath = "meta.billing.0.tokens"
+			},
+		},
+		{
+			name:     "usage json path with empty segment",
+			mutate:   func(cfg *interfaces.Config) { cfg.Metrics.TokensJSONPath = "usage..total" },
+			problems: []string{"metrics.tokens_json_path"},
+		},
+		{
+			name: "api versions",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.APIVersion = interfaces.APIVersionConfig{Supported: []string{"v1", "v2"}, Default: "v1"}
+			},
+		},
+		{
+			name: "api version default not supported",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.APIVersion = interfaces.APIVersionConfig{Supported: []string{"v1", ""}, Default: "v3"}
+			},
+			problems: []string{"api_version.supported[1]", "api_version.default"},
+		},
+		{
+			name:     "api version default without supported versions",
+			mutate:   func(cfg *interfaces.Config) { cfg.APIVersion.Default = "v1" },
+			problems: []string{"api_version.default"},
+		},
+		{
+			name: "blocked methods",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Proxy.BlockedMethods = []string{"DELETE"}
+				cfg.Proxy.BlockedMethodRoutes = []interfaces.BlockedMethodRoute{{PathPrefix: "/v1/files", Methods: []string{"PUT"}}}
+			},
+		},
+		{
+			name: "invalid blocked methods",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Proxy.BlockedMethods = []string{"delete"}
+				cfg.Proxy.BlockedMethodRoutes = []interfaces.BlockedMethodRoute{{PathPrefix: "v1/files"}}
+			},
+			problems: []string{"proxy.blocked_methods", "proxy.blocked_method_routes[0].path_prefix", "proxy.blocked_method_routes[0].methods"},
+		},
+		{
+			name:     "empty summary key",
+			mutate:   func(cfg *interfaces.Config) { cfg.Metrics.SummaryKeys = []string{"dashboard", ""} },
+			problems: []string{"metrics.summary_keys[1]"},
+		},
+		{
+			name:   "success status codes",
+			mutate: func(cfg *interfaces.Config) { cfg.Metrics.SuccessStatusCodes = []string{"200-299", "422"} },
+		},
+		{
+			name:     "invalid success status codes",
+			mutate:   func(cfg *interfaces.Config) { cfg.Metrics.SuccessStatusCodes = []string{"299-200"} },
+			problems: []string{"metrics.success_status_codes"},
+		},
+		{
+			name:     "negative max concurrent exports",
+			mutate:   func(cfg *interfaces.Config) { cfg.Metrics.MaxConcurrentExports = -1 },
+			problems: []string{"metrics.max_concurrent_exports"},
+		},
+		{
+			name: "upstream identity",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.UpstreamIdentity = interfaces.UpstreamIdentityConfig{Enabled: true, Salt: "pepper"}
+			},
+		},
+		{
+			name:     "upstream identity without salt",
+			mutate:   func(cfg *interfaces.Config) { cfg.UpstreamIdentity.Enabled = true },
+			problems: []string{"upstream_identity.salt"},
+		},
+		{
+			name: "maintenance mode",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Maintenance = interfaces.MaintenanceConfig{Enabled: true, Status: 503, RetryAfter: 10 * time.Minute}
+			},
+		},
+		{
+			name:     "maintenance status outside errors",
+			mutate:   func(cfg *interfaces.Config) { cfg.Maintenance.Status = 200 },
+			problems: []string{"maintenance.status"},
+		},
+		{
+			name:     "negative maintenance retry after",
+			mutate:   func(cfg *interfaces.Config) { cfg.Maintenance.RetryAfter = -time.Second },
+			problems: []string{"maintenance.retry_after"},
+		},
+		{
+			name:   "grpc health port",
+			mutate: func(cfg *interfaces.Config) { cfg.GRPCHealthPort = 9090 },
+		},
+		{
+			name:     "grpc health port out of range",
+			mutate:   func(cfg *interfaces.Config) { cfg.GRPCHealthPort = 70000 },
+			problems: []string{"grpc_health_port"},
+		},
+		{
+			name: "grpc health port shared with admin port",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.AdminPort = 9090
+				cfg.GRPCHealthPort = 9090
+			},
+			problems: []string{"grpc_health_port must differ"},
+		},
+		{
+			name: "metrics path templates",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Metrics.PathTemplates = []interfaces.PathTemplate{{Pattern: "^/v1/files/[^/]+$", Template: "/v1/files/:id"}}
+			},
+		},
+		{
+			name: "invalid metrics path template",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Metrics.PathTemplates = []interfaces.PathTemplate{{Pattern: "^/v1/files/(", Template: "/v1/files/:id"}}
+			},
+			problems: []string{"metrics.path_templates[0]"},
+		},
+		{
+			name:   "rate limit by ip",
+			mutate: func(cfg *interfaces.Config) { cfg.Limits.RateLimitBy = "ip" },
+		},
+		{
+			name:     "unknown rate limit key",
+			mutate:   func(cfg *interfaces.Config) { cfg.Limits.RateLimitBy = "user" },
+			problems: []string{"limits.rate_limit_by"},
+		},
+		{
+			name:   "sliding window rate limiting",
+			mutate: func(cfg *interfaces.Config) { cfg.Limits.RateLimitAlgorithm = "sliding_window_log" },
+		},
+		{
+			name:     "unknown rate limit algorithm",
+			mutate:   func(cfg *interfaces.Config) { cfg.Limits.RateLimitAlgorithm = "leaky_bucket" },
+			problems: []string{"limits.rate_limit_algorithm"},
+		},
+		{
+			name: "fair scheduling",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Limits.MaxUpstreamConcurrent = 64
+				cfg.Limits.Scheduling = "fair"
+			},
+		},
+		{
+			name:     "unknown scheduling",
+			mutate:   func(cfg *interfaces.Config) { cfg.Limits.MaxUpstreamConcurrent = 64; cfg.Limits.Scheduling = "lifo" },
+			problems: []string{"limits.scheduling"},
+		},
+		{
+			name:     "scheduling without upstream capacity",
+			mutate:   func(cfg *interfaces.Config) { cfg.Limits.Scheduling = "fair" },
+			problems: []string{"limits.scheduling"},
+		},
+		{
+			name:   "soft limit threshold",
+			mutate: func(cfg *interfaces.Config) { cfg.Limits.SoftLimitThreshold = 0.8 },
+		},
+		{
+			name:     "soft limit threshold above one",
+			mutate:   func(cfg *interfaces.Config) { cfg.Limits.SoftLimitThreshold = 80 },
+			problems: []string{"limits.soft_limit_threshold"},
+		},
+		{
+			name:   "options passthrough",
+			mutate: func(cfg *interfaces.Config) { cfg.OptionsMode = "passthrough" },
+		},
+		{
+			name:     "unknown options mode",
+			mutate:   func(cfg *interfaces.Config) { cfg.OptionsMode = "allow" },
+			problems: []string{"options_mode"},
+		},
+		{
+			name: "noop transform",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Transforms = []interfaces.TransformRoute{{PathPrefix: "/v1/chat", Transformer: "none"}}
+			},
+		},
+		{
+			name: "invalid transform routes",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Transforms = []interfaces.TransformRoute{{PathPrefix: "v1", Transformer: "anthropic-dialect"}}
+			},
+			problems: []string{"transforms[0].path_prefix", "transforms[0].transformer"},
+		},
+		{
+			name: "path rewrites",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.PathRewrites = []interfaces.PathRewrite{
+					{PathPrefix: "/v1", Replacement: "/openai/v1"},
+					{Pattern: "^/v2/(.*)$", Replacement: "/openai/$1"},
+				}
+			},
+		},
+		{
+			name: "invalid path rewrites",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.PathRewrites = []interfaces.PathRewrite{{PathPrefix: "v1"}, {Pattern: "(["}}
+			},
+			problems: []string{"path_rewrites[0]", "path_rewrites[1]"},
+		},
+		{
+			name: "routing",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Routing = interfaces.RoutingConfig{
+					Routes:    []interfaces.TargetRoute{{PathPrefix: "/v1/chat", Target: "https://chat.example.com"}},
+					OnNoMatch: "reject",
+				}
+			},
+		},
+		{
+			name: "invalid routing",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Routing = interfaces.RoutingConfig{
+					Routes:    []interfaces.TargetRoute{{PathPrefix: "v1", Target: "chat.example.com"}},
+					OnNoMatch: "drop",
+				}
+			},
+			problems: []string{"routing.routes[0].path_prefix", "routing.routes[0].target", "routing.on_no_match"},
+		},
+		{
+			name: "routing with target pool",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Routing.Routes = []interfaces.TargetRoute{{PathPrefix: "/v1/chat", Target: "https://chat.example.com"}}
+				cfg.TargetPool = []interfaces.PoolTarget{{URL: "https://a.example.com"}}
+			},
+			problems: []string{"routing.routes cannot be combined with target_pool"},
+		},
+		{
+			name:   "public paths",
+			mutate: func(cfg *interfaces.Config) { cfg.PublicPaths = []string{"/v1/models", "/v1/models/*"} },
+		},
+		{
+			name:     "public path without leading slash",
+			mutate:   func(cfg *interfaces.Config) { cfg.PublicPaths = []string{"v1/models"} },
+			problems: []string{"public_paths[0]"},
+		},
+		{
+			name: "default upstream key for public paths",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.PublicPaths = []string{"/v1/models"}
+				cfg.DefaultUpstreamKey = "sk-public"
+			},
+		},
+		{
+			name:     "default upstream key without public paths",
+			mutate:   func(cfg *interfaces.Config) { cfg.DefaultUpstreamKey = "sk-public" },
+			problems: []string{"default_upstream_key"},
+		},
+		{
+			name:   "x-api-key header precedence",
+			mutate: func(cfg *interfaces.Config) { cfg.AuthHeaderPrecedence = "x-api-key" },
+		},
+		{
+			name:     "unknown header precedence",
+			mutate:   func(cfg *interfaces.Config) { cfg.AuthHeaderPrecedence = "cookie" },
+			problems: []string{"auth_header_precedence"},
+		},
+		{
+			name:   "base path",
+			mutate: func(cfg *interfaces.Config) { cfg.BasePath = "/ai" },
+		},
+		{
+			name:     "base path with trailing slash",
+			mutate:   func(cfg *interfaces.Config) { cfg.BasePath = "/ai/" },
+			problems: []string{"base_path"},
+		},
+		{
+			name:     "relative base path",
+			mutate:   func(cfg *interfaces.Config) { cfg.BasePath = "ai" },
+			problems: []string{"base_path"},
+		},
+		{
+			name: "idempotency cache",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Idempotency = interfaces.IdempotencyConfig{TTL: time.Hour, MaxEntries: 100}
+			},
+		},
+		{
+			name: "negative idempotency settings",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Idempotency = interfaces.IdempotencyConfig{TTL: -time.Second, MaxEntries: -1}
+			},
+			problems: []string{"idempotency.ttl", "idempotency.max_entries"},
+		},
+		{
+			name: "response cache routes",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.ResponseCache.Routes = []interfaces.CacheRoute{{PathPrefix: "/v1/models", TTL: time.Minute, Methods: []string{"GET", "head"}}}
+			},
+		},
+		{
+			name: "invalid response cache routes",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.ResponseCache = interfaces.ResponseCacheConfig{
+					MaxEntries: -1,
+					Routes:     []interfaces.CacheRoute{{PathPrefix: "v1/models", Methods: []string{"POST"}}},
+				}
+			},
+			problems: []string{"response_cache.max_entries", "routes[0].path_prefix", "routes[0].ttl", "routes[0].methods"},
+		},
+		{
+			name:     "negative max response bytes",
+			mutate:   func(cfg *interfaces.Config) { cfg.Proxy.MaxResponseBytes = -1 },
+			problems: []string{"proxy.max_response_bytes"},
+		},
+		{
+			name: "negative warm-up settings",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Proxy.WarmUpConnections = -1
+				cfg.Proxy.WarmUpTimeout = -time.Second
+			},
+			problems: []string{"proxy.warmup_connections", "proxy.warmup_timeout"},
+		},
+		{
+			name: "negative upstream key cache ttl",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.UpstreamKeys = interfaces.UpstreamKeysConfig{Provider: "env", CacheTTL: -time.Minute}
+			},
+			problems: []string{"upstream_keys.cache_ttl"},
+		},
+		{
+			name: "health check",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.HealthCheck = interfaces.HealthCheckConfig{Interval: 10 * time.Second, Path: "/health", UnhealthyThreshold: 3, HealthyThreshold: 2}
+			},
+		},
+		{
+			name: "invalid health check",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.HealthCheck = interfaces.HealthCheckConfig{
+					Interval:           -time.Second,
+					Timeout:            -time.Second,
+					Path:               "health",
+					UnhealthyThreshold: -1,
+					HealthyThreshold:   -1,
+				}
+			},
+			problems: []string{
+				"health_check.interval",
+				"health_check.timeout",
+				"health_check.path",
+				"health_check.unhealthy_threshold",
+				"health_check.healthy_threshold",
+			},
+		},
+		{
+			name: "server limits",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Server = interfaces.ServerConfig{MaxHeaderBytes: 64 << 10, MaxHeaderCount: 50, PreShutdownDelay: 5 * time.Second}
+			},
+		},
+		{
+			name: "negative server limits",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Server = interfaces.ServerConfig{MaxHeaderBytes: -1, MaxHeaderCount: -1, PreShutdownDelay: -time.Second}
+			},
+			problems: []string{"server.max_header_bytes", "server.max_header_count", "server.pre_shutdown_delay"},
+		},
+		{
+			name: "request time budget",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Request.MaxTotalDuration = 30 * time.Second
+			},
+		},
+		{
+			name: "negative request time budget",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.Request.MaxTotalDuration = -time.Second
+			},
+			problems: []string{"request.max_total_duration"},
+		},
+		{
+			name: "json body paths",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.JSONBody = interfaces.JSONBodyConfig{Paths: []string{"/v1/chat"}, MaxBytes: 1 << 20}
+			},
+		},
+		{
+			name: "invalid json body config",
+			mutate: func(cfg *interfaces.Config) {
+				cfg.JSONBody = interfaces.JSONBodyConfig{Paths: []string{"v1/chat"}, MaxBytes: -1}
+			},
+			problems: []string{"json_body.paths[0]", "json_body.max_bytes"},
+		},
+		{
+			name:   "tracing endpoint",
+			mutate: func(cfg *interfaces.Config) { cfg.Tracing.OTLPEndpoint = "http://localhost:4318" },
+		},
+		{
+			name:     "tracing endpoint without scheme",
+			mutate:   func(cfg *interfaces.Config) { cfg.Tracing.OTLPEndpoint = "localhost:4318" },
+			problems: []string{"tracing.otlp_endpoint"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.mutate(cfg)
+
+			err := Validate(cfg)
+			if len(tt.problems) == 0 {
+				if err != nil {
+					t.Fatalf("Expected valid config, got: %v", err)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Expected *ValidationError, got: %v", err)
+			}
+			if len(verr.Problems) != len(tt.problems) {
+				t.Errorf("Expected %d problems, got %d: %v", len(tt.problems), len(verr.Problems), verr.Problems)
+			}
+			for _, want := range tt.problems {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected error to mention %q, got: %v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidate_NilConfig(t *testing.T) {
+	if err := Validate(nil); err == nil {
+		t.Error("Expected error for nil config")
+	}
+}
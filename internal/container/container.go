@@ -1,10 +1,16 @@
 package container
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
-	"net/http/httputil"
 	"net/url"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jamesprial/nexus/internal/config"
@@ -14,6 +20,7 @@ import (
 	"github.com/jamesprial/nexus/internal/metrics"
 	"github.com/jamesprial/nexus/internal/middleware"
 	"github.com/jamesprial/nexus/internal/proxy"
+	"github.com/jamesprial/nexus/internal/tracing"
 	"golang.org/x/time/rate"
 )
 
@@ -30,6 +37,125 @@ type Container struct {
 	authMiddleware    *auth.AuthMiddleware
 	metricsCollector  interfaces.MetricsCollector
 	metricsMiddleware func(http.Handler) http.Handler
+	// concurrencyLimiter caps in-flight requests per key; nil when uncapped
+	concurrencyLimiter interfaces.RateLimiter
+	// tracer records a span per request; nil when tracing is disabled
+	tracer *tracing.Tracer
+	// responseCache serves configured paths from memory
+	responseCache *middleware.ResponseCache
+	// validationRejections counts requests rejected by the validation layers, by reason
+	validationRejections *middleware.ValidationRejections
+	// apiVersions enforces api_version.supported; nil when no versions are configured
+	apiVersions *middleware.APIVersionPolicy
+	// routeCheck handles requests matching no routing.routes entry; nil when
+	// no routes are configured
+	routeCheck *middleware.RouteCheck
+	// maintenance turns proxy traffic away while maintenance.enabled is set
+	maintenance *middleware.Maintenance
+	// chain names the layers assembled by BuildHandler, outermost first
+	chain []string
+	// healthCheckers probe each upstream when health_check.interval is set;
+	// stopHealthChecks cancels probes started by StartHealthChecks
+	healthCheckers   []*proxy.HealthChecker
+	stopHealthChecks context.CancelFunc
+	// remoteWriter pushes metrics when metrics.remote_write.url is set;
+	// stopRemoteWrite cancels pushes started by StartRemoteWrite
+	remoteWriter    *metrics.RemoteWriter
+	stopRemoteWrite context.CancelFunc
+	// closers release resources on Close, most recently registered first;
+	// closed makes Close idempotent
+	closers []func() error
+	closed  bool
+	// lifetime is canceled by Close to stop every goroutine started with
+	// goBackground; tasks waits for them, active counts them by name and
+	// panics counts the panics they recovered from by name. tasksMu guards
+	// all five and is never held while taking mu.
+	lifetime       context.Context
+	cancelLifetime context.CancelFunc
+	tasks          sync.WaitGroup
+	active         map[string]int
+	panics         map[string]int64
+	tasksMu        sync.Mutex
+	// reloads and reloadErrors count Reload calls and their failures;
+	// lastReload is the Unix time of the last successful configuration load
+	reloads      atomic.Int64
+	reloadErrors atomic.Int64
+	lastReload   atomic.Int64
+	// mu guards config, which Reload replaces while requests are served, and chain
+	mu sync.RWMutex
+}
+
+// billingSetter is implemented by proxies that add billing headers to responses
+type billingSetter interface {
+	SetBilling(interfaces.BillingConfig)
+}
+
+// transformSetter is implemented by proxies that rewrite bodies by request path
+type transformSetter interface {
+	SetTransforms([]proxy.TransformRoute)
+}
+
+// targetRouteSetter is implemented by proxies that choose an upstream by path
+type targetRouteSetter interface {
+	SetTargetRoutes([]proxy.TargetRoute)
+}
+
+// pathRewriteSetter is implemented by proxies that rewrite upstream paths
+type pathRewriteSetter interface {
+	SetPathRewrites([]proxy.PathRewrite)
+}
+
+// usagePathSetter is implemented by proxies that read token usage from responses
+type usagePathSetter interface {
+	SetUsagePaths(proxy.UsagePaths)
+}
+
+// keyTargetSetter is implemented by proxies that route client keys to their own upstreams
+type keyTargetSetter interface {
+	SetKeyTargets(map[string]*url.URL)
+}
+
+// responseLimitSetter is implemented by proxies that cap upstream response sizes
+type responseLimitSetter interface {
+	SetMaxResponseBytes(int64)
+}
+
+// trailerSetter is implemented by proxies that can drop upstream response trailers
+type trailerSetter interface {
+	SetForwardTrailers(bool)
+}
+
+// webSocketSetter is implemented by proxies that can relay WebSocket upgrades
+type webSocketSetter interface {
+	SetWebSockets(bool)
+}
+
+// upstreamStatusReporter is implemented by proxies that count upstream responses by status
+type upstreamStatusReporter interface {
+	UpstreamStatuses() map[int]int64
+}
+
+// warmUpper is implemented by proxies that can pre-dial upstream connections
+type warmUpper interface {
+	WarmUp(ctx context.Context, n int) (int, error)
+}
+
+// defaultWarmUpTimeout bounds the startup warm-up when proxy.warmup_timeout is unset
+const defaultWarmUpTimeout = 5 * time.Second
+
+// upstreamErrorReporter is implemented by proxies that count failed upstream round-trips
+type upstreamErrorReporter interface {
+	UpstreamErrors() map[string]int64
+}
+
+// hasConcurrencyOverrides reports whether any per-key limit sets a concurrency cap
+func hasConcurrencyOverrides(limits map[string]interfaces.KeyLimits) bool {
+	for _, l := range limits {
+		if l.MaxConcurrent > 0 {
+			return true
+		}
+	}
+	return false
 }
 
 // New creates a new dependency injection container
@@ -47,6 +173,11 @@ func (c *Container) SetLogger(logger interfaces.Logger) {
 	c.logger = logger
 }
 
+// SetTracer sets the request tracer, taking precedence over tracing.otlp_endpoint
+func (c *Container) SetTracer(tracer *tracing.Tracer) {
+	c.tracer = tracer
+}
+
 // ConfigLoader returns the configuration loader
 func (c *Container) ConfigLoader() interfaces.ConfigLoader {
 	return c.configLoader
@@ -59,6 +190,8 @@ func (c *Container) Logger() interfaces.Logger {
 
 // Config returns the loaded configuration
 func (c *Container) Config() *interfaces.Config {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.config
 }
 
@@ -92,6 +225,332 @@ func (c *Container) MetricsMiddleware() func(http.Handler) http.Handler {
 	return c.metricsMiddleware
 }
 
+// ShutdownTracing flushes spans still waiting to be exported
+func (c *Container) ShutdownTracing(ctx context.Context) error {
+	if c.tracer == nil {
+		return nil
+	}
+	return c.tracer.Shutdown(ctx)
+}
+
+// WarmUp pre-dials proxy.warmup_connections connections to each upstream so
+// the first requests after startup skip connection setup. It gives up after
+// proxy.warmup_timeout and only logs the outcome, since a cold pool is slower
+// but still works. Call it after Initialize.
+func (c *Container) WarmUp() {
+	cfg := c.Config()
+	if cfg == nil || cfg.Proxy.WarmUpConnections <= 0 {
+		return
+	}
+	p, ok := c.proxy.(warmUpper)
+	if !ok {
+		return
+	}
+
+	timeout := cfg.Proxy.WarmUpTimeout
+	if timeout <= 0 {
+		timeout = defaultWarmUpTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	warmed, err := p.WarmUp(ctx, cfg.Proxy.WarmUpConnections)
+	fields := map[string]any{
+		"connections": warmed,
+		"requested":   cfg.Proxy.WarmUpConnections,
+		"duration":    time.Since(start).String(),
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+		c.logger.Warn("Upstream warm-up incomplete", fields)
+		return
+	}
+	c.logger.Info("Upstream connections warmed up", fields)
+}
+
+// StartHealthChecks probes each upstream every health_check.interval in the
+// background until StopHealthChecks is called. Call it after Initialize.
+func (c *Container) StartHealthChecks() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.healthCheckers) == 0 || c.stopHealthChecks != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.lifetimeContext())
+	c.stopHealthChecks = cancel
+	for _, checker := range c.healthCheckers {
+		c.goBackground(ctx, "health_check", checker.Run)
+	}
+}
+
+// StopHealthChecks stops the probes started by StartHealthChecks
+func (c *Container) StopHealthChecks() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopHealthChecks != nil {
+		c.stopHealthChecks()
+		c.stopHealthChecks = nil
+	}
+}
+
+// UpstreamHealthy reports whether any upstream is healthy according to its
+// health checks, and whether health checks are configured at all
+func (c *Container) UpstreamHealthy() (healthy, checked bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, checker := range c.healthCheckers {
+		if checker.Healthy() {
+			return true, true
+		}
+	}
+	return false, len(c.healthCheckers) > 0
+}
+
+// StartRemoteWrite pushes metrics to metrics.remote_write.url every
+// interval in the background until StopRemoteWrite is called. Call it after
+// Initialize.
+func (c *Container) StartRemoteWrite() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.remoteWriter == nil || c.stopRemoteWrite != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.lifetimeContext())
+	c.stopRemoteWrite = cancel
+	c.goBackground(ctx, "remote_write", c.remoteWriter.Run)
+}
+
+// StopRemoteWrite stops the pushes started by StartRemoteWrite
+func (c *Container) StopRemoteWrite() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopRemoteWrite != nil {
+		c.stopRemoteWrite()
+		c.stopRemoteWrite = nil
+	}
+}
+
+// closeTimeout bounds waiting for background goroutines and flushing
+// buffered spans when the container is closed
+const closeTimeout = 5 * time.Second
+
+// lifetimeContext returns the context canceled by Close
+func (c *Container) lifetimeContext() context.Context {
+	c.tasksMu.Lock()
+	defer c.tasksMu.Unlock()
+	if c.lifetime == nil {
+		c.lifetime, c.cancelLifetime = context.WithCancel(context.Background())
+	}
+	return c.lifetime
+}
+
+// A background task that panics is restarted after a backoff that starts at
+// minRestartBackoff and doubles with each consecutive panic up to
+// maxRestartBackoff. A run lasting longer than maxRestartBackoff resets it.
+// Variables so tests can shorten them.
+var (
+	minRestartBackoff = time.Second
+	maxRestartBackoff = time.Minute
+)
+
+// goBackground runs fn in a goroutine counted by ActiveGoroutines under name.
+// fn must return once ctx is done; ctx must derive from lifetimeContext so
+// Close stops it. If fn panics the panic is logged, counted in
+// BackgroundPanics and fn is run again after a backoff, so a faulty task
+// cannot take down the gateway.
+func (c *Container) goBackground(ctx context.Context, name string, fn func(ctx context.Context)) {
+	c.tasksMu.Lock()
+	if c.active == nil {
+		c.active = make(map[string]int)
+	}
+	c.active[name]++
+	c.tasks.Add(1)
+	c.tasksMu.Unlock()
+
+	go func() {
+		defer func() {
+			c.tasksMu.Lock()
+			if c.active[name]--; c.active[name] == 0 {
+				delete(c.active, name)
+			}
+			c.tasksMu.Unlock()
+			c.tasks.Done()
+		}()
+
+		backoff := minRestartBackoff
+		for {
+			started := time.Now()
+			if !c.runRecovered(ctx, name, fn) {
+				return
+			}
+			if time.Since(started) > maxRestartBackoff {
+				backoff = minRestartBackoff
+			}
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			backoff = min(backoff*2, maxRestartBackoff)
+		}
+	}()
+}
+
+// runRecovered runs fn, reporting whether it panicked. The panic is logged
+// with its stack and counted under name.
+func (c *Container) runRecovered(ctx context.Context, name string, fn func(ctx context.Context)) (panicked bool) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		panicked = true
+		c.tasksMu.Lock()
+		if c.panics == nil {
+			c.panics = make(map[string]int64)
+		}
+		c.panics[name]++
+		c.tasksMu.Unlock()
+		if c.logger != nil {
+			c.logger.Error("Background task panicked; restarting", map[string]any{
+				"task":  name,
+				"panic": fmt.Sprint(r),
+				"stack": string(debug.Stack()),
+			})
+		}
+	}()
+	fn(ctx)
+	return false
+}
+
+// BackgroundPanics returns the number of panics recovered in background
+// tasks, by task
+func (c *Container) BackgroundPanics() map[string]int64 {
+	c.tasksMu.Lock()
+	defer c.tasksMu.Unlock()
+	panics := make(map[string]int64, len(c.panics))
+	for name, n := range c.panics {
+		panics[name] = n
+	}
+	return panics
+}
+
+// ActiveGoroutines returns the number of background goroutines the container
+// started that are still running, by task. It is empty once Close returns
+// unless a task ignored cancellation.
+func (c *Container) ActiveGoroutines() map[string]int {
+	c.tasksMu.Lock()
+	defer c.tasksMu.Unlock()
+	active := make(map[string]int, len(c.active))
+	for name, n := range c.active {
+		active[name] = n
+	}
+	return active
+}
+
+// stopBackground cancels the lifetime context and waits up to timeout for
+// the goroutines started with goBackground to return
+func (c *Container) stopBackground(timeout time.Duration) error {
+	c.lifetimeContext()
+	c.tasksMu.Lock()
+	c.cancelLifetime()
+	c.tasksMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		c.tasks.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("background goroutines still running: %v", c.ActiveGoroutines())
+	}
+}
+
+// OnClose registers fn to release a resource, such as a background goroutine
+// or an external connection, when the container is closed. Hooks run in
+// reverse order of registration.
+func (c *Container) OnClose(fn func() error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closers = append(c.closers, fn)
+}
+
+// RegisterCleanupHandler registers handler to run during Close, like OnClose,
+// for teardown that cannot fail. It returns an error once the container has
+// been closed, since the handler would never run.
+func (c *Container) RegisterCleanupHandler(handler func()) error {
+	if handler == nil {
+		return fmt.Errorf("cleanup handler must not be nil")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return fmt.Errorf("container already closed")
+	}
+	c.closers = append(c.closers, func() error {
+		handler()
+		return nil
+	})
+	return nil
+}
+
+// Close stops health checks, remote writes and the other background
+// goroutines, waiting for them to return, flushes buffered spans and runs the
+// hooks registered with OnClose and RegisterCleanupHandler,
+// returning their errors joined. A hook that panics is logged and reported as
+// an error without stopping the rest. Only the first call does anything.
+func (c *Container) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	closers := c.closers
+	c.closers = nil
+	c.mu.Unlock()
+
+	c.StopHealthChecks()
+	c.StopRemoteWrite()
+
+	var errs []error
+	if err := c.stopBackground(closeTimeout); err != nil {
+		errs = append(errs, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	if err := c.ShutdownTracing(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to flush traces: %w", err))
+	}
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := c.runCloser(closers[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runCloser runs one close hook, recovering a panic into an error
+func (c *Container) runCloser(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("cleanup handler panicked: %v", r)
+			if c.logger != nil {
+				c.logger.Error("Cleanup handler panicked", map[string]any{"panic": fmt.Sprint(r)})
+			}
+		}
+	}()
+	return fn()
+}
+
 // Initialize loads configuration and sets up all dependencies
 func (c *Container) Initialize() error {
 	// Load configuration
@@ -104,22 +563,151 @@ func (c *Container) Initialize() error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 	c.config = cfg
+	c.lastReload.Store(time.Now().Unix())
 
 	// Set up logger if not already set
 	if c.logger == nil {
-		c.logger = logging.NewSlogLogger(cfg.LogLevel)
+		if cfg.LogOutput == "file" {
+			fileLogger, err := logging.NewFileLogger(cfg.LogFile, cfg.LogMaxSizeMB, cfg.LogMaxBackups)
+			if err != nil {
+				return fmt.Errorf("failed to set up file logger: %w", err)
+			}
+			fileLogger.SetLevel(cfg.LogLevel)
+			c.logger = fileLogger
+		} else {
+			c.logger = logging.NewSlogLogger(cfg.LogLevel)
+		}
+	}
+
+	c.warnUnknownFeatures(cfg)
+
+	// Set up request tracing if a collector is configured
+	if c.tracer == nil && cfg.Tracing.OTLPEndpoint != "" && featureEnabled(cfg, FeatureTracing) {
+		exporter, err := tracing.NewOTLPExporter(cfg.Tracing.OTLPEndpoint, c.logger)
+		if err != nil {
+			return fmt.Errorf("failed to set up tracing: %w", err)
+		}
+		c.tracer = tracing.NewTracer(exporter)
 	}
 
 	// Set up key manager and auth middleware
 	// Convert from interfaces.Config to config.Config to maintain compatibility
 	configForAuth := &config.Config{
-		APIKeys: cfg.APIKeys,
+		APIKeys:            cfg.APIKeys,
+		DefaultUpstreamKey: cfg.DefaultUpstreamKey,
+	}
+	keyManager := auth.NewFileKeyManager(configForAuth)
+	provider, err := auth.NewKeyProvider(cfg.UpstreamKeys)
+	if err != nil {
+		return err
 	}
-	c.keyManager = auth.NewFileKeyManager(configForAuth)
+	if km, ok := keyManager.(*auth.FileKeyManager); ok && provider != nil {
+		km.SetUpstreamKeyProvider(provider)
+	}
+	c.keyManager = keyManager
 	c.authMiddleware = auth.NewAuthMiddleware(c.keyManager, c.logger)
+	c.authMiddleware.SetPublicPaths(cfg.PublicPaths)
+	c.authMiddleware.SetHeaderPrecedence(cfg.AuthHeaderPrecedence)
 
-	// Set up token counter
-	c.tokenCounter = &proxy.DefaultTokenCounter{}
+	// Set up token counter with the configured estimator
+	estimator, err := proxy.NewTokenEstimator(cfg.Limits.TokenEstimator)
+	if err != nil {
+		return fmt.Errorf("failed to set up token estimator: %w", err)
+	}
+	c.tokenCounter = &proxy.DefaultTokenCounter{Estimator: estimator}
+
+	// Set up metrics collector if enabled; the limiters record rejections into it
+	var collector *metrics.MetricsCollector
+	if cfg.Metrics.Enabled {
+		collector = metrics.NewMetricsCollector()
+		collector.SetBreakdownLimits(cfg.Metrics.MaxEndpointsPerKey, cfg.Metrics.MaxModelsPerKey)
+		collector.SetLabelValueLimit(cfg.Metrics.MaxLabelValuesPerKey)
+		collector.SetLatencySampleRate(cfg.Metrics.LatencySampleRate)
+		collector.SetSLOLatency(cfg.Metrics.SLOLatency)
+		collector.SetLatencyEWMADecay(cfg.Metrics.LatencyEWMADecay)
+		// Validated with the rest of the configuration
+		successRanges, _ := metrics.ParseStatusRanges(cfg.Metrics.SuccessStatusCodes)
+		collector.SetSuccessStatusRanges(successRanges)
+		if cfg.Alerts.WebhookURL != "" && cfg.Alerts.ErrorRateThreshold > 0 {
+			watcher := metrics.NewErrorRateWatcher(cfg.Alerts, c.logger)
+			collector.SetAlertWatcher(watcher)
+			// Abort and wait for webhook deliveries in flight on Close
+			c.goBackground(c.lifetimeContext(), "alert_webhook", func(ctx context.Context) {
+				<-ctx.Done()
+				watcher.Close()
+			})
+		}
+		if cfg.Metrics.SnapshotPath != "" {
+			c.loadMetricsSnapshot(collector, cfg.Metrics.SnapshotPath)
+		}
+		c.remoteWriter = nil
+		if cfg.Metrics.RemoteWrite.URL != "" {
+			c.remoteWriter = metrics.NewRemoteWriter(collector, cfg.Metrics.RemoteWrite, c.logger)
+			c.remoteWriter.SetAPIKeyMasking(cfg.Metrics.MaskAPIKeys)
+		}
+		c.metricsCollector = collector
+
+		// Record off the request path so a slow collector never delays responses
+		recorder := metrics.NewAsyncRecorder(collector, metrics.DefaultRecordBufferSize)
+		// Drain the buffered records on Close
+		c.goBackground(c.lifetimeContext(), "metrics_recorder", func(ctx context.Context) {
+			<-ctx.Done()
+			recorder.Close()
+		})
+		collector.AddCounterFunc(
+			"nexus_metrics_dropped_total",
+			"Request records dropped because the metrics buffer was full",
+			nil,
+			func() float64 { return float64(recorder.Dropped()) },
+		)
+		pathTemplates, err := metrics.NewPathTemplates(cfg.Metrics.PathTemplates)
+		if err != nil {
+			return fmt.Errorf("failed to set up metrics path templates: %w", err)
+		}
+		opts := []metrics.MiddlewareOption{
+			metrics.WithLabelHeaders(cfg.Metrics.LabelHeaders),
+			metrics.WithPathTemplates(pathTemplates),
+		}
+		if cfg.Metrics.EMF.Enabled {
+			emf := metrics.NewEMFExporter(cfg.Metrics.EMF, c.logger)
+			emf.SetAPIKeyMasking(cfg.Metrics.MaskAPIKeys)
+			emf.SetSuccessStatusRanges(successRanges)
+			opts = append(opts, metrics.WithSink(emf))
+			// Writes the last interval's lines on Close
+			c.goBackground(c.lifetimeContext(), "emf_export", emf.Run)
+		}
+		c.metricsMiddleware = metrics.AsyncMetricsMiddleware(recorder, opts...)
+
+		collector.AddCounterFunc(
+			"nexus_config_reload_total",
+			"Configuration reloads attempted",
+			nil,
+			func() float64 { return float64(c.reloads.Load()) },
+		)
+		collector.AddCounterFunc(
+			"nexus_config_reload_errors_total",
+			"Configuration reloads rejected or failed",
+			nil,
+			func() float64 { return float64(c.reloadErrors.Load()) },
+		)
+		collector.AddGaugeFunc(
+			"nexus_config_last_reload_timestamp",
+			"Unix time of the last successful configuration load",
+			func() float64 { return float64(c.lastReload.Load()) },
+		)
+		collector.AddCounterVecFunc(
+			"nexus_background_panics_total",
+			"Panics recovered in background tasks, by task",
+			"task",
+			func() map[string]float64 {
+				counts := make(map[string]float64)
+				for name, n := range c.BackgroundPanics() {
+					counts[name] = float64(n)
+				}
+				return counts
+			},
+		)
+	}
 
 	// Set up rate limiter with TTL (1 hour)
 	ttl := 1 * time.Hour
@@ -127,73 +715,623 @@ func (c *Container) Initialize() error {
 		rate.Limit(cfg.Limits.RequestsPerSecond),
 		cfg.Limits.Burst,
 		ttl,
+		c.metricsCollector,
 		c.logger,
 	)
+	if err := perClientLimiter.SetAlgorithm(cfg.Limits.RateLimitAlgorithm); err != nil {
+		return fmt.Errorf("failed to set up rate limiter: %w", err)
+	}
+	perClientLimiter.SetKeyLimits(cfg.PerKeyLimits)
+	if cfg.Limits.RateLimitBy == proxy.RateLimitByIP {
+		perClientLimiter.SetLimitByIP(cfg.TrustedProxyCount)
+	}
+	if cfg.Limits.RateLimitHeaders {
+		perClientLimiter.SetHeaders(true)
+	}
+	if cfg.Limits.MaxWait > 0 {
+		perClientLimiter.SetQueue(cfg.Limits.MaxWait, cfg.Limits.MaxQueue)
+		if collector != nil {
+			collector.AddGaugeFunc(
+				"nexus_rate_limit_queue_depth",
+				"Number of requests waiting for a rate limit token",
+				func() float64 { return float64(perClientLimiter.QueueDepth()) },
+			)
+		}
+	}
 	c.rateLimiter = perClientLimiter
 
 	// Start cleanup routine for per-client rate limiter
-	stopChan := make(chan struct{})
-	go perClientLimiter.StartCleanup(5*time.Minute, stopChan)
+	c.goBackground(c.lifetimeContext(), "rate_limit_cleanup", func(ctx context.Context) {
+		perClientLimiter.StartCleanup(5*time.Minute, ctx.Done())
+	})
 
 	// Set up token limiter with proper burst calculation and TTL
-	tokenBurst := max(cfg.Limits.ModelTokensPerMinute/6, 100)
+	tokenBurst := proxy.DefaultTokenBurst(cfg.Limits.ModelTokensPerMinute)
 
 	tokenLimiter := proxy.NewTokenLimiterWithTTL(
 		cfg.Limits.ModelTokensPerMinute,
 		tokenBurst,
 		c.tokenCounter,
 		ttl,
+		c.metricsCollector,
 		c.logger,
 	)
+	tokenLimiter.SetKeyLimits(cfg.PerKeyLimits)
 	c.tokenLimiter = tokenLimiter
 
+	// Warn clients nearing their limits while still serving them
+	if cfg.Limits.SoftLimitThreshold > 0 {
+		perClientLimiter.SetSoftLimit(cfg.Limits.SoftLimitThreshold)
+		tokenLimiter.SetSoftLimit(cfg.Limits.SoftLimitThreshold)
+		if collector != nil {
+			collector.AddCounterVecFunc(
+				"nexus_rate_limit_soft_warnings_total",
+				"Served requests that crossed the soft limit threshold, by limiter",
+				"limiter",
+				func() map[string]float64 {
+					return map[string]float64{
+						proxy.LimiterTypeRate:  float64(perClientLimiter.SoftWarningCount()),
+						proxy.LimiterTypeToken: float64(tokenLimiter.SoftWarningCount()),
+					}
+				},
+			)
+		}
+	}
+
+	// In shadow mode limits are evaluated and counted but never enforced
+	if cfg.Limits.Shadow {
+		perClientLimiter.SetShadowMode(true)
+		tokenLimiter.SetShadowMode(true)
+		c.logger.Info("Rate limiters running in shadow mode", map[string]any{})
+	}
+
+	// Cap in-flight requests per key, and across all keys, when a cap is configured
+	if cfg.Limits.MaxConcurrent > 0 || hasConcurrencyOverrides(cfg.PerKeyLimits) || cfg.Limits.MaxUpstreamConcurrent > 0 {
+		concurrencyLimiter := proxy.NewConcurrencyLimiter(cfg.Limits.MaxConcurrent, c.metricsCollector, c.logger)
+		concurrencyLimiter.SetKeyLimits(cfg.PerKeyLimits)
+		concurrencyLimiter.SetShadowMode(cfg.Limits.Shadow)
+		if cfg.Limits.MaxUpstreamConcurrent > 0 {
+			scheduling := cfg.Limits.Scheduling
+			if !featureEnabled(cfg, FeatureFairScheduling) {
+				scheduling = proxy.SchedulingFIFO
+			}
+			concurrencyLimiter.SetUpstreamCapacity(cfg.Limits.MaxUpstreamConcurrent, scheduling)
+			if collector != nil {
+				collector.AddGaugeFunc(
+					"nexus_upstream_queue_depth",
+					"Number of requests waiting for an upstream slot",
+					func() float64 { return float64(concurrencyLimiter.UpstreamQueueDepth()) },
+				)
+			}
+		}
+		c.concurrencyLimiter = concurrencyLimiter
+	}
+
+	// Shadow mode can be switched on by a reload, so the family is always
+	// registered; it stays at zero while limits are enforced
+	if collector != nil {
+		concurrencyLimiter, _ := c.concurrencyLimiter.(*proxy.ConcurrencyLimiter)
+		collector.AddCounterVecFunc(
+			"nexus_rate_limit_would_reject_total",
+			"Requests that shadow mode let through but a limiter would have rejected, by limiter",
+			"limiter",
+			func() map[string]float64 {
+				counts := map[string]float64{
+					proxy.LimiterTypeRate:  float64(perClientLimiter.WouldRejectCount()),
+					proxy.LimiterTypeToken: float64(tokenLimiter.WouldRejectCount()),
+				}
+				if concurrencyLimiter != nil {
+					counts[proxy.LimiterTypeConcurrency] = float64(concurrencyLimiter.WouldRejectCount())
+				}
+				return counts
+			},
+		)
+	}
+
 	// Start cleanup routine for token limiter
-	stopChan2 := make(chan struct{})
-	go tokenLimiter.StartCleanup(5*time.Minute, stopChan2)
+	c.goBackground(c.lifetimeContext(), "token_limit_cleanup", func(ctx context.Context) {
+		tokenLimiter.StartCleanup(5*time.Minute, ctx.Done())
+	})
+
+	// Set up proxy; a target pool takes over from the single target URL
+	if len(cfg.TargetPool) > 0 {
+		pool, err := proxy.NewTargetPool(cfg.TargetPool, c.logger)
+		if err != nil {
+			return fmt.Errorf("failed to set up target pool: %w", err)
+		}
+		if collector != nil {
+			for i, targetURL := range pool.Targets() {
+				collector.AddCounterFunc(
+					"nexus_upstream_requests_total",
+					"Requests sent to each target pool upstream",
+					map[string]string{"target": targetURL},
+					pool.TargetRequests(i),
+				)
+			}
+		}
+		c.proxy = pool
+	} else {
+		target, err := url.Parse(cfg.TargetURL)
+		if err != nil {
+			return fmt.Errorf("failed to parse target URL: %w", err)
+		}
+
+		c.proxy = proxy.NewHTTPProxy(target, c.logger)
+	}
+	if p, ok := c.proxy.(billingSetter); ok {
+		p.SetBilling(cfg.Billing)
+	}
+	if p, ok := c.proxy.(usagePathSetter); ok {
+		paths, err := proxy.NewUsagePaths(cfg.Metrics.ModelJSONPath, cfg.Metrics.TokensJSONPath)
+		if err != nil {
+			return fmt.Errorf("failed to set up usage paths: %w", err)
+		}
+		p.SetUsagePaths(paths)
+	}
+	if p, ok := c.proxy.(responseLimitSetter); ok {
+		p.SetMaxResponseBytes(cfg.Proxy.MaxResponseBytes)
+	}
+	if p, ok := c.proxy.(trailerSetter); ok {
+		p.SetForwardTrailers(cfg.Proxy.ForwardTrailers == nil || *cfg.Proxy.ForwardTrailers)
+	}
+	// Not reloaded: sessions need their own handler chain, built at startup
+	if p, ok := c.proxy.(webSocketSetter); ok {
+		p.SetWebSockets(cfg.Proxy.WebSockets)
+	}
+	if p, ok := c.proxy.(keyTargetSetter); ok && len(cfg.PerKeyTargets) > 0 {
+		targets, err := proxy.NewKeyTargets(cfg.PerKeyTargets)
+		if err != nil {
+			return fmt.Errorf("failed to set up per-key targets: %w", err)
+		}
+		p.SetKeyTargets(targets)
+	}
+	if p, ok := c.proxy.(transformSetter); ok && len(cfg.Transforms) > 0 {
+		routes, err := proxy.NewTransformRoutes(cfg.Transforms)
+		if err != nil {
+			return fmt.Errorf("failed to set up transforms: %w", err)
+		}
+		p.SetTransforms(routes)
+	}
+	c.routeCheck = nil
+	if len(cfg.Routing.Routes) > 0 {
+		p, ok := c.proxy.(targetRouteSetter)
+		if !ok {
+			return fmt.Errorf("routing.routes is not supported by the configured proxy")
+		}
+		routes, err := proxy.NewTargetRoutes(cfg.Routing.Routes)
+		if err != nil {
+			return fmt.Errorf("failed to set up routes: %w", err)
+		}
+		p.SetTargetRoutes(routes)
+
+		routeCheck := middleware.NewRouteCheck(cfg.Routing, c.logger)
+		c.routeCheck = routeCheck
+		if collector != nil {
+			collector.AddCounterFunc(
+				"nexus_unrouted_requests_total",
+				"Requests that matched no configured route",
+				nil,
+				func() float64 { return float64(routeCheck.Unrouted()) },
+			)
+		}
+	}
+	if p, ok := c.proxy.(pathRewriteSetter); ok && len(cfg.PathRewrites) > 0 {
+		rewrites, err := proxy.NewPathRewrites(cfg.PathRewrites)
+		if err != nil {
+			return fmt.Errorf("failed to set up path rewrites: %w", err)
+		}
+		p.SetPathRewrites(rewrites)
+	}
+	if p, ok := c.proxy.(upstreamErrorReporter); ok && collector != nil {
+		for _, kind := range proxy.UpstreamErrorKinds {
+			collector.AddCounterFunc(
+				"nexus_upstream_errors_total",
+				"Failed upstream round-trips by cause",
+				map[string]string{"kind": kind},
+				func() float64 { return float64(p.UpstreamErrors()[kind]) },
+			)
+		}
+	}
+	if p, ok := c.proxy.(upstreamStatusReporter); ok && collector != nil {
+		collector.AddCounterVecFunc(
+			"nexus_upstream_status_total",
+			"Upstream responses by the status code the upstream returned",
+			"status",
+			func() map[string]float64 {
+				counts := make(map[string]float64)
+				for status, n := range p.UpstreamStatuses() {
+					counts[strconv.Itoa(status)] = float64(n)
+				}
+				return counts
+			},
+		)
+	}
+
+	c.healthCheckers = nil
+	if cfg.HealthCheck.Interval > 0 {
+		targets := []string{cfg.TargetURL}
+		if len(cfg.TargetPool) > 0 {
+			targets = targets[:0]
+			for _, t := range cfg.TargetPool {
+				targets = append(targets, t.URL)
+			}
+		}
+		for _, target := range targets {
+			c.healthCheckers = append(c.healthCheckers, proxy.NewHealthChecker(target, cfg.HealthCheck, c.logger))
+		}
+	}
+
+	c.validationRejections = middleware.NewValidationRejections()
+	if collector != nil {
+		collector.AddCounterVecFunc(
+			"nexus_validation_rejections_total",
+			"Requests rejected by request validation before reaching the upstream, by reason",
+			"reason",
+			func() map[string]float64 {
+				counts := make(map[string]float64)
+				for reason, n := range c.validationRejections.Counts() {
+					counts[reason] = float64(n)
+				}
+				return counts
+			},
+		)
+	}
+
+	c.maintenance = middleware.NewMaintenance(cfg.Maintenance)
+	if collector != nil {
+		collector.AddGaugeFunc(
+			"nexus_maintenance_enabled",
+			"Whether maintenance mode is turning proxy traffic away (1) or not (0)",
+			func() float64 {
+				if c.maintenance.Enabled() {
+					return 1
+				}
+				return 0
+			},
+		)
+	}
+
+	c.apiVersions = nil
+	if len(cfg.APIVersion.Supported) > 0 {
+		c.apiVersions = middleware.NewAPIVersionPolicy(cfg.APIVersion, c.validationRejections)
+		if collector != nil {
+			collector.AddCounterVecFunc(
+				"nexus_api_version_requests_total",
+				"Requests accepted by the API version check, by version",
+				"version",
+				func() map[string]float64 {
+					counts := make(map[string]float64)
+					for version, n := range c.apiVersions.Counts() {
+						counts[version] = float64(n)
+					}
+					return counts
+				},
+			)
+		}
+	}
+
+	c.responseCache = middleware.NewResponseCache(cfg.ResponseCache.Routes, cfg.ResponseCache.MaxEntries)
+	if collector != nil && len(cfg.ResponseCache.Routes) > 0 {
+		results := map[string]func() int64{"hit": c.responseCache.Hits, "miss": c.responseCache.Misses}
+		for result, count := range results {
+			collector.AddCounterFunc(
+				"nexus_response_cache_requests_total",
+				"Cacheable requests by whether they were served from the response cache",
+				map[string]string{"result": result},
+				func() float64 { return float64(count()) },
+			)
+		}
+	}
+
+	return nil
+}
+
+// loadMetricsSnapshot restores counters saved by a previous run. A missing or
+// unreadable snapshot is logged and the collector starts empty.
+func (c *Container) loadMetricsSnapshot(collector *metrics.MetricsCollector, path string) {
+	err := collector.LoadSnapshotFile(path)
+	switch {
+	case err == nil:
+		c.logger.Info("Loaded metrics snapshot", map[string]any{"path": path})
+	case errors.Is(err, os.ErrNotExist):
+		c.logger.Info("No metrics snapshot found; starting with empty metrics", map[string]any{"path": path})
+	default:
+		c.logger.Warn("Failed to load metrics snapshot; starting with empty metrics", map[string]any{
+			"path":  path,
+			"error": err.Error(),
+		})
+	}
+}
 
-	// Set up proxy
-	target, err := url.Parse(cfg.TargetURL)
+// Reload loads the configuration again and applies the settings that can change
+// at runtime: API keys, target URL, per-key limits, shadow mode, billing,
+// transforms and path rewrites.
+// Settings that shape the server or middleware chain, such as ports, TLS and
+// global limits, take effect only on restart. An invalid configuration is
+// rejected and the current one stays in place. Attempts and failures are
+// counted in the reload metrics.
+func (c *Container) Reload() error {
+	c.reloads.Add(1)
+	if err := c.reload(); err != nil {
+		c.reloadErrors.Add(1)
+		return err
+	}
+	c.lastReload.Store(time.Now().Unix())
+	return nil
+}
+
+// reload applies a freshly loaded configuration for Reload
+func (c *Container) reload() error {
+	if c.proxy == nil {
+		return fmt.Errorf("container not initialized")
+	}
+
+	cfg, err := c.configLoader.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := config.Validate(cfg); err != nil {
+		return err
+	}
+	transforms, err := proxy.NewTransformRoutes(cfg.Transforms)
+	if err != nil {
+		return fmt.Errorf("failed to set up transforms: %w", err)
+	}
+	rewrites, err := proxy.NewPathRewrites(cfg.PathRewrites)
+	if err != nil {
+		return fmt.Errorf("failed to set up path rewrites: %w", err)
+	}
+	usagePaths, err := proxy.NewUsagePaths(cfg.Metrics.ModelJSONPath, cfg.Metrics.TokensJSONPath)
 	if err != nil {
-		return fmt.Errorf("failed to parse target URL: %w", err)
+		return fmt.Errorf("failed to set up usage paths: %w", err)
+	}
+	keyTargets, err := proxy.NewKeyTargets(cfg.PerKeyTargets)
+	if err != nil {
+		return fmt.Errorf("failed to set up per-key targets: %w", err)
 	}
 
-	reverseProxy := httputil.NewSingleHostReverseProxy(target)
-	c.proxy = &proxy.HTTPProxy{
-		ReverseProxy: reverseProxy,
-		Logger:       c.logger,
+	if cfg.TargetURL != c.Config().TargetURL {
+		if err := c.proxy.SetTarget(cfg.TargetURL); err != nil {
+			return fmt.Errorf("failed to update target URL: %w", err)
+		}
 	}
 
-	// Set up metrics collector if enabled
-	if cfg.Metrics.Enabled {
-		c.metricsCollector = metrics.NewMetricsCollector()
-		c.metricsMiddleware = metrics.MetricsMiddleware(c.metricsCollector)
+	if p, ok := c.proxy.(billingSetter); ok {
+		p.SetBilling(cfg.Billing)
+	}
+	if p, ok := c.proxy.(transformSetter); ok {
+		p.SetTransforms(transforms)
+	}
+	if p, ok := c.proxy.(pathRewriteSetter); ok {
+		p.SetPathRewrites(rewrites)
+	}
+	if p, ok := c.proxy.(usagePathSetter); ok {
+		p.SetUsagePaths(usagePaths)
+	}
+	if p, ok := c.proxy.(keyTargetSetter); ok {
+		p.SetKeyTargets(keyTargets)
+	}
+	if p, ok := c.proxy.(responseLimitSetter); ok {
+		p.SetMaxResponseBytes(cfg.Proxy.MaxResponseBytes)
+	}
+	if p, ok := c.proxy.(trailerSetter); ok {
+		p.SetForwardTrailers(cfg.Proxy.ForwardTrailers == nil || *cfg.Proxy.ForwardTrailers)
+	}
+
+	if km, ok := c.keyManager.(*auth.FileKeyManager); ok {
+		km.UpdateKeys(cfg.APIKeys)
+		km.SetDefaultUpstreamKey(cfg.DefaultUpstreamKey)
+	}
+	c.authMiddleware.SetPublicPaths(cfg.PublicPaths)
+	c.authMiddleware.SetHeaderPrecedence(cfg.AuthHeaderPrecedence)
+
+	if cfg.Maintenance.Enabled != c.maintenance.Enabled() {
+		c.logger.Info("Maintenance mode changed", map[string]any{
+			"enabled": cfg.Maintenance.Enabled,
+		})
+	}
+	c.maintenance.SetConfig(cfg.Maintenance)
+
+	for _, limiter := range []interfaces.RateLimiter{c.rateLimiter, c.tokenLimiter, c.concurrencyLimiter} {
+		if l, ok := limiter.(interface {
+			SetKeyLimits(map[string]interfaces.KeyLimits)
+		}); ok {
+			l.SetKeyLimits(cfg.PerKeyLimits)
+		}
+		if l, ok := limiter.(interface{ SetShadowMode(bool) }); ok {
+			l.SetShadowMode(cfg.Limits.Shadow)
+		}
 	}
 
+	c.mu.Lock()
+	c.config = cfg
+	c.mu.Unlock()
+
+	c.logger.Info("Configuration reloaded", map[string]any{
+		"api_keys": len(cfg.APIKeys),
+	})
 	return nil
 }
 
-// BuildHandler creates the complete middleware chain
+// BuildHandler creates the complete middleware chain. Before Initialize has
+// succeeded it returns a handler that responds 503 to every request.
 func (c *Container) BuildHandler() http.Handler {
-	if c.proxy == nil {
-		panic("container not initialized")
+	if c.config == nil || c.proxy == nil {
+		return c.notInitializedHandler()
 	}
 
-	// Build middleware chain: validation -> auth -> metrics -> rateLimiter -> tokenLimiter -> proxy
+	// Build middleware chain: tracing -> accessLog -> maintenance -> websocket -> timeBudget -> headerLimit -> methodFilter -> validation -> jsonBody -> apiVersion -> options -> auth -> metrics -> routing -> idempotency -> responseCache -> modelPolicy -> rateLimiter -> concurrencyLimiter -> tokenLimiter -> upstream tracing -> proxy
+	// Layers are added innermost first; chain records the active ones outermost first
 	var handler http.Handler = http.HandlerFunc(c.proxy.ServeHTTP)
-	handler = c.tokenLimiter.Middleware(handler)
-	handler = c.rateLimiter.Middleware(handler)
-	
+	chain := []string{"proxy"}
+	wrap := func(name string, mw func(http.Handler) http.Handler) {
+		handler = mw(handler)
+		chain = append([]string{name}, chain...)
+	}
+
+	// Switched-off features are left out even when configured
+	tracingOn := c.tracer != nil && featureEnabled(c.config, FeatureTracing)
+	if tracingOn {
+		wrap("upstream_tracing", tracing.UpstreamMiddleware(c.tracer))
+	}
+	upstream := handler
+	var sessions http.Handler
+	if c.config.Proxy.WebSockets {
+		sessions = c.webSocketHandler(upstream)
+	}
+	if !c.config.Limits.DisableTokenLimit {
+		wrap("token_limit", c.tokenLimiter.Middleware)
+	}
+	if c.concurrencyLimiter != nil {
+		wrap("concurrency_limit", c.concurrencyLimiter.Middleware)
+	}
+	wrap("rate_limit", c.rateLimiter.Middleware)
+
+	// Enforce the model allow-list and aliases if configured
+	if len(c.config.AllowedModels) > 0 || len(c.config.ModelAliases) > 0 {
+		wrap("model_policy", middleware.NewModelPolicyMiddleware(c.config.AllowedModels, c.config.ModelAliases, c.logger, c.validationRejections))
+	}
+
+	// Serve cacheable paths from memory before any limits apply
+	if len(c.config.ResponseCache.Routes) > 0 && featureEnabled(c.config, FeatureResponseCache) {
+		wrap("response_cache", c.responseCache.Middleware)
+	}
+
+	// Replay responses for retried Idempotency-Key requests before any limits apply
+	if c.config.Idempotency.TTL > 0 {
+		wrap("idempotency", middleware.NewIdempotencyMiddleware(c.config.Idempotency.TTL, c.config.Idempotency.MaxEntries))
+	}
+
+	// Handle unrouted requests before any cache or limit sees them
+	if c.routeCheck != nil {
+		wrap("routing", c.routeCheck.Middleware)
+	}
+
 	// Add metrics middleware if available
 	if c.metricsMiddleware != nil {
-		handler = c.metricsMiddleware(handler)
+		wrap("metrics", c.metricsMiddleware)
 	}
-	
-	handler = c.authMiddleware.Middleware(handler)
-	
+
+	// Identify the validated client to the upstream by a hash of their key
+	if c.config.UpstreamIdentity.Enabled {
+		wrap("upstream_identity", middleware.NewUpstreamIdentityMiddleware(c.config.UpstreamIdentity.Header, c.config.UpstreamIdentity.Salt))
+	}
+
+	wrap("auth", c.authMiddleware.Middleware)
+
+	// OPTIONS may bypass auth to reach the upstream or be answered here
+	if c.config.OptionsMode != "" && c.config.OptionsMode != middleware.OptionsAuthenticate {
+		wrap("options", middleware.NewOptionsMiddleware(c.config.OptionsMode, upstream))
+	}
+
+	// Reject unsupported API versions before any quota is spent
+	if c.apiVersions != nil {
+		wrap("api_version", c.apiVersions.Middleware)
+	}
+
+	// Reject malformed JSON on configured write paths before any quota is spent
+	if len(c.config.JSONBody.Paths) > 0 {
+		wrap("json_body", middleware.NewJSONBodyMiddleware(c.config.JSONBody.Paths, c.config.JSONBody.MaxBytes, c.validationRejections))
+	}
+
 	// Add request validation as the outermost middleware
 	// Default to 10MB max body size
-	validationMiddleware := middleware.NewRequestValidationMiddleware(10 * 1024 * 1024)
-	handler = validationMiddleware(handler)
+	if c.streamsRequestBodies() {
+		wrap("validation", middleware.NewStreamingValidationMiddleware(10*1024*1024, c.validationRejections))
+	} else {
+		wrap("validation", middleware.NewRequestValidationMiddleware(10*1024*1024, c.validationRejections))
+	}
+
+	// Turn away blocked methods before any body is read
+	if len(c.config.Proxy.BlockedMethods) > 0 || len(c.config.Proxy.BlockedMethodRoutes) > 0 {
+		wrap("method_filter", middleware.NewMethodFilterMiddleware(c.config.Proxy.BlockedMethods, c.config.Proxy.BlockedMethodRoutes, c.validationRejections))
+	}
+
+	// Reject header bombs before any middleware walks the headers
+	wrap("header_limit", middleware.NewHeaderLimitMiddleware(c.config.Server.MaxHeaderCount, c.validationRejections))
+
+	// Start the request's time budget before any layer can make it wait
+	if c.config.Request.MaxTotalDuration > 0 {
+		wrap("time_budget", middleware.NewTimeBudgetMiddleware(c.config.Request.MaxTotalDuration))
+	}
+
+	// Divert WebSocket upgrades to their own chain before the time budget or
+	// any body-reading layer applies
+	if sessions != nil {
+		wrap("websocket", middleware.NewWebSocketMiddleware(sessions))
+	}
+
+	// Always installed so a reload can switch maintenance on
+	wrap("maintenance", c.maintenance.Middleware)
+
+	// Access logging wraps everything so it records the final status and duration
+	if c.config.Logging.AccessLog || c.config.Logging.SlowRequestThreshold > 0 {
+		wrap("access_log", metrics.RequestLogMiddleware(c.logger, c.config.Logging.AccessLog, c.config.Logging.SlowRequestThreshold))
+	}
+
+	// The request span covers the whole chain, rejections included
+	if tracingOn {
+		wrap("tracing", tracing.Middleware(c.tracer))
+	}
+
+	c.mu.Lock()
+	c.chain = chain
+	c.mu.Unlock()
 
 	return handler
 }
+
+// webSocketHandler builds the chain WebSocket sessions take to upstream:
+// header_limit -> auth -> upstream_identity -> metrics -> rate_limit ->
+// concurrency_limit. It leaves out the layers that read the request body or
+// buffer the response, which a relayed session has neither of, and the
+// concurrency limit holds a slot for as long as the session is open.
+func (c *Container) webSocketHandler(upstream http.Handler) http.Handler {
+	handler := upstream
+	if c.concurrencyLimiter != nil {
+		handler = c.concurrencyLimiter.Middleware(handler)
+	}
+	handler = c.rateLimiter.Middleware(handler)
+	if c.routeCheck != nil {
+		handler = c.routeCheck.Middleware(handler)
+	}
+	if c.metricsMiddleware != nil {
+		handler = c.metricsMiddleware(handler)
+	}
+	if c.config.UpstreamIdentity.Enabled {
+		handler = middleware.NewUpstreamIdentityMiddleware(c.config.UpstreamIdentity.Header, c.config.UpstreamIdentity.Salt)(handler)
+	}
+	handler = c.authMiddleware.Middleware(handler)
+	return middleware.NewHeaderLimitMiddleware(c.config.Server.MaxHeaderCount, c.validationRejections)(handler)
+}
+
+// notInitializedHandler answers every request with 503 when BuildHandler is
+// called before Initialize has loaded a configuration, so an embedding
+// application gets an error response instead of a panic
+func (c *Container) notInitializedHandler() http.Handler {
+	if c.logger != nil {
+		c.logger.Error("BuildHandler called before the configuration was loaded", map[string]any{})
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "configuration not loaded", http.StatusServiceUnavailable)
+	})
+}
+
+// streamsRequestBodies reports whether no configured layer reads the request
+// body, so it can be streamed to the upstream instead of buffered in memory.
+// Token limiting, the JSON body check and the model policy all read it.
+func (c *Container) streamsRequestBodies() bool {
+	return c.config.Limits.DisableTokenLimit &&
+		len(c.config.JSONBody.Paths) == 0 &&
+		len(c.config.AllowedModels) == 0 &&
+		len(c.config.ModelAliases) == 0
+}
+
+// MiddlewareChain returns the names of the layers the last BuildHandler call
+// assembled, outermost first and ending with the proxy. Layers that were not
+// configured are left out.
+func (c *Container) MiddlewareChain() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string(nil), c.chain...)
+}
@@ -0,0 +1,1852 @@
+package container
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/auth"
+	"github.com/jamesprial/nexus/internal/config"
+	"github.com/jamesprial/nexus/internal/interfaces"
+	"github.com/jamesprial/nexus/internal/metrics"
+	"github.com/jamesprial/nexus/internal/middleware"
+	"github.com/jamesprial/nexus/internal/proxy"
+	"github.com/jamesprial/nexus/internal/tracing"
+)
+
+// noopLogger discards all log output
+type noopLogger struct{}
+
+func (noopLogger) Debug(msg string, fields map[string]any) {}
+func (noopLogger) Info(msg string, fields map[string]any)  {}
+func (noopLogger) Warn(msg string, fields map[string]any)  {}
+func (noopLogger) Error(msg string, fields map[string]any) {}
+
+func TestContainer_ReloadPicksUpNewAPIKeys(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	cfg := &interfaces.Config{
+		ListenPort: 8080,
+		TargetURL:  upstream.URL,
+		LogLevel:   "info",
+		APIKeys:    map[string]string{"old-client": "upstream-key"},
+		Limits: interfaces.Limits{
+			RequestsPerSecond:    100,
+			Burst:                100,
+			ModelTokensPerMinute: 100000,
+		},
+	}
+	loader := config.NewMemoryLoader(cfg)
+
+	c := New()
+	c.SetConfigLoader(loader)
+	c.SetLogger(noopLogger{})
+	if err := c.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize container: %v", err)
+	}
+	handler := c.BuildHandler()
+
+	status := func(clientKey string) int {
+		req := httptest.NewRequest("GET", "/v1/models", nil)
+		req.Header.Set("Authorization", "Bearer "+clientKey)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := status("old-client"); code != http.StatusOK {
+		t.Fatalf("Expected old key to be accepted before reload, got %d", code)
+	}
+	if code := status("new-client"); code != http.StatusUnauthorized {
+		t.Fatalf("Expected new key to be rejected before reload, got %d", code)
+	}
+
+	cfg.APIKeys = map[string]string{"new-client": "upstream-key"}
+	loader.Update(cfg)
+	if err := c.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	if code := status("new-client"); code != http.StatusOK {
+		t.Errorf("Expected new key to be accepted after reload, got %d", code)
+	}
+	if code := status("old-client"); code != http.StatusUnauthorized {
+		t.Errorf("Expected old key to be rejected after reload, got %d", code)
+	}
+	if _, ok := c.Config().APIKeys["new-client"]; !ok {
+		t.Error("Expected Config() to reflect the reloaded configuration")
+	}
+}
+
+func TestContainer_ReloadRejectsInvalidConfig(t *testing.T) {
+	cfg := &interfaces.Config{
+		ListenPort: 8080,
+		TargetURL:  "http://localhost:9999",
+		APIKeys:    map[string]string{"client": "upstream"},
+		Limits: interfaces.Limits{
+			RequestsPerSecond:    10,
+			Burst:                10,
+			ModelTokensPerMinute: 1000,
+		},
+	}
+	loader := config.NewMemoryLoader(cfg)
+
+	c := New()
+	c.SetConfigLoader(loader)
+	c.SetLogger(noopLogger{})
+	if err := c.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize container: %v", err)
+	}
+
+	invalid := *cfg
+	invalid.TargetURL = ""
+	loader.Update(&invalid)
+
+	if err := c.Reload(); err == nil {
+		t.Fatal("Expected reload of invalid config to fail")
+	}
+	if c.Config().TargetURL != "http://localhost:9999" {
+		t.Errorf("Expected previous config to stay in place, got target %q", c.Config().TargetURL)
+	}
+}
+
+// blockingBody is a request body whose reads never return
+type blockingBody struct{ done chan struct{} }
+
+func (b blockingBody) Read(p []byte) (int, error) {
+	<-b.done
+	return 0, io.EOF
+}
+
+func (b blockingBody) Close() error { return nil }
+
+func TestContainer_HeadAndOptionsRequests(t *testing.T) {
+	var methods []string
+	var mu sync.Mutex
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods = append(methods, r.Method)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	cfg := &interfaces.Config{
+		ListenPort:  8080,
+		TargetURL:   upstream.URL,
+		APIKeys:     map[string]string{"client": "upstream-key"},
+		OptionsMode: middleware.OptionsPassthrough,
+		Limits: interfaces.Limits{
+			RequestsPerSecond:    100,
+			Burst:                100,
+			ModelTokensPerMinute: 100000,
+		},
+		Metrics: interfaces.MetricsConfig{Enabled: true},
+	}
+
+	c := New()
+	c.SetConfigLoader(config.NewMemoryLoader(cfg))
+	c.SetLogger(noopLogger{})
+	if err := c.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize container: %v", err)
+	}
+	handler := c.BuildHandler()
+
+	// A HEAD request must not wait on a body that never ends
+	body := blockingBody{done: make(chan struct{})}
+	defer close(body.done)
+	req := httptest.NewRequest(http.MethodHead, "/v1/models", nil)
+	req.Body = body
+	req.Header.Set("Authorization", "Bearer client")
+	rr := httptest.NewRecorder()
+
+	served := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(rr, req)
+		close(served)
+	}()
+	select {
+	case <-served:
+	case <-time.After(5 * time.Second):
+		t.Fatal("HEAD request blocked reading its body")
+	}
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected HEAD to succeed, got %d", rr.Code)
+	}
+
+	// Passthrough OPTIONS reaches the upstream without credentials
+	req = httptest.NewRequest(http.MethodOptions, "/v1/chat/completions", nil)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected passthrough OPTIONS to succeed, got %d", rr.Code)
+	}
+
+	mu.Lock()
+	if len(methods) != 2 || methods[0] != http.MethodHead || methods[1] != http.MethodOptions {
+		t.Errorf("Expected upstream to see HEAD then OPTIONS, got %v", methods)
+	}
+	mu.Unlock()
+
+	// Metrics are recorded off the request path, so wait for the HEAD request
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		km, ok := c.MetricsCollector().GetMetricsForKey("client")
+		if ok && km.TotalRequests == 1 && km.SuccessfulRequests == 1 && km.PerEndpoint["/v1/models"] != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected HEAD request recorded for client, got %+v", km)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestContainer_PublicPathsBypassAuth(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	cfg := &interfaces.Config{
+		ListenPort:  8080,
+		TargetURL:   upstream.URL,
+		APIKeys:     map[string]string{"client": "upstream-key"},
+		PublicPaths: []string{"/v1/models"},
+		Limits: interfaces.Limits{
+			RequestsPerSecond:    1,
+			Burst:                2,
+			ModelTokensPerMinute: 100000,
+		},
+		Metrics: interfaces.MetricsConfig{Enabled: true},
+	}
+
+	c := New()
+	c.SetConfigLoader(config.NewMemoryLoader(cfg))
+	c.SetLogger(noopLogger{})
+	if err := c.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize container: %v", err)
+	}
+	handler := c.BuildHandler()
+
+	// Anonymous requests share one bucket, so the third exceeds the burst
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+		if rr.Code != want {
+			t.Errorf("Public request %d: expected %d, got %d", i, want, rr.Code)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/files", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected protected path to require a key, got %d", rr.Code)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		km, ok := c.MetricsCollector().GetMetricsForKey(auth.AnonymousKey)
+		if ok && km.TotalRequests == 3 && km.ThrottledRequests["rate"] == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected public requests recorded under %q, got %+v", auth.AnonymousKey, km)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestContainer_InitializeLoadsMetricsSnapshot(t *testing.T) {
+	path := t.TempDir() + "/metrics.snapshot"
+	previous := metrics.NewMetricsCollector()
+	previous.RecordRequest("client", "/v1/models", "", 0, http.StatusOK, time.Millisecond)
+	if err := previous.SaveSnapshotFile(path); err != nil {
+		t.Fatalf("Failed to save snapshot: %v", err)
+	}
+
+	cfg := &interfaces.Config{
+		ListenPort: 8080,
+		TargetURL:  "http://localhost:9999",
+		Limits: interfaces.Limits{
+			RequestsPerSecond:    10,
+			Burst:                10,
+			ModelTokensPerMinute: 1000,
+		},
+		Metrics: interfaces.MetricsConfig{Enabled: true, SnapshotPath: path},
+	}
+
+	c := New()
+	c.SetConfigLoader(config.NewMemoryLoader(cfg))
+	c.SetLogger(noopLogger{})
+	if err := c.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize container: %v", err)
+	}
+
+	km, ok := c.MetricsCollector().GetMetricsForKey("client")
+	if !ok || km.TotalRequests != 1 {
+		t.Errorf("Expected restored metrics for client, got %+v", km)
+	}
+}
+
+func TestContainer_TracesRequestsThroughTheChain(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(tracing.TraceparentHeader) == "" {
+			t.Error("Expected traceparent to be forwarded upstream")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	cfg := &interfaces.Config{
+		ListenPort: 8080,
+		TargetURL:  upstream.URL,
+		APIKeys:    map[string]string{"client": "upstream-key"},
+		Limits: interfaces.Limits{
+			RequestsPerSecond:    10,
+			Burst:                10,
+			ModelTokensPerMinute: 1000,
+		},
+	}
+
+	exporter := tracing.NewInMemoryExporter()
+	c := New()
+	c.SetConfigLoader(config.NewMemoryLoader(cfg))
+	c.SetLogger(noopLogger{})
+	c.SetTracer(tracing.NewTracer(exporter))
+	if err := c.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize container: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/v1/models", nil)
+	req.Header.Set("Authorization", "Bearer client")
+	rr := httptest.NewRecorder()
+	c.BuildHandler().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rr.Code)
+	}
+
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected server and upstream spans, got %d", len(spans))
+	}
+	if spans[0].Kind != tracing.SpanKindClient || spans[1].Kind != tracing.SpanKindServer {
+		t.Errorf("Expected the upstream span to end before the server span")
+	}
+	if spans[0].Parent != spans[1].SpanContext.SpanID {
+		t.Errorf("Expected the upstream span to be a child of the server span")
+	}
+}
+
+// gatherValue returns the value of the unlabeled metric name from collector
+func gatherValue(t *testing.T, collector *metrics.MetricsCollector, name string) float64 {
+	t.Helper()
+	return gatherLabeledValue(t, collector, name, nil)
+}
+
+// gatherLabeledValue returns the value of the first series of name carrying
+// all of labels
+func gatherLabeledValue(t *testing.T, collector *metrics.MetricsCollector, name string, labels map[string]string) float64 {
+	t.Helper()
+	if err := collector.Register(); err != nil {
+		t.Fatalf("Failed to register collector: %v", err)
+	}
+	families, err := collector.Registry().Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+	for _, mf := range families {
+		if mf.GetName() != name {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			matched := 0
+			for _, lp := range m.GetLabel() {
+				if want, ok := labels[lp.GetName()]; ok && want == lp.GetValue() {
+					matched++
+				}
+			}
+			if matched != len(labels) {
+				continue
+			}
+			if m.GetCounter() != nil {
+				return m.GetCounter().GetValue()
+			}
+			return m.GetGauge().GetValue()
+		}
+	}
+	t.Fatalf("Metric %s%v not found", name, labels)
+	return 0
+}
+
+func TestContainer_ReloadMetrics(t *testing.T) {
+	cfg := &interfaces.Config{
+		ListenPort: 8080,
+		TargetURL:  "http://localhost:9999",
+		APIKeys:    map[string]string{"client": "upstream"},
+		Limits: interfaces.Limits{
+			RequestsPerSecond:    10,
+			Burst:                10,
+			ModelTokensPerMinute: 1000,
+		},
+		Metrics: interfaces.MetricsConfig{Enabled: true},
+	}
+	loader := config.NewMemoryLoader(cfg)
+
+	c := New()
+	c.SetConfigLoader(loader)
+	c.SetLogger(noopLogger{})
+	if err := c.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize container: %v", err)
+	}
+	collector := c.MetricsCollector().(*metrics.MetricsCollector)
+
+	if got := gatherValue(t, collector, "nexus_config_last_reload_timestamp"); got <= 0 {
+		t.Errorf("Expected the initial load to set the reload timestamp, got %v", got)
+	}
+	c.lastReload.Store(1)
+
+	if err := c.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	invalid := *cfg
+	invalid.TargetURL = ""
+	loader.Update(&invalid)
+	if err := c.Reload(); err == nil {
+		t.Fatal("Expected reload of invalid config to fail")
+	}
+
+	if got := gatherValue(t, collector, "nexus_config_reload_total"); got != 2 {
+		t.Errorf("Expected 2 reloads, got %v", got)
+	}
+	if got := gatherValue(t, collector, "nexus_config_reload_errors_total"); got != 1 {
+		t.Errorf("Expected 1 failed reload, got %v", got)
+	}
+	if got := gatherValue(t, collector, "nexus_config_last_reload_timestamp"); got <= 1 {
+		t.Errorf("Expected the successful reload to update the timestamp, got %v", got)
+	}
+}
+
+func TestContainer_ResponseCacheMetrics(t *testing.T) {
+	var calls atomic.Int64
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"object":"list","data":[]}`)
+	}))
+	defer upstream.Close()
+
+	cfg := &interfaces.Config{
+		ListenPort: 8080,
+		TargetURL:  upstream.URL,
+		APIKeys:    map[string]string{"client": "upstream-key"},
+		Limits: interfaces.Limits{
+			RequestsPerSecond:    100,
+			Burst:                100,
+			ModelTokensPerMinute: 100000,
+		},
+		Metrics: interfaces.MetricsConfig{Enabled: true},
+		ResponseCache: interfaces.ResponseCacheConfig{
+			Routes: []interfaces.CacheRoute{{PathPrefix: "/v1/models", TTL: time.Minute}},
+		},
+	}
+
+	c := New()
+	c.SetConfigLoader(config.NewMemoryLoader(cfg))
+	c.SetLogger(noopLogger{})
+	if err := c.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize container: %v", err)
+	}
+	handler := c.BuildHandler()
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+		req.Header.Set("Authorization", "Bearer client")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200, got %d", i, rr.Code)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Expected the upstream to be called once, got %d", got)
+	}
+
+	collector := c.MetricsCollector().(*metrics.MetricsCollector)
+	if got := gatherLabeledValue(t, collector, "nexus_response_cache_requests_total", map[string]string{"result": "hit"}); got != 2 {
+		t.Errorf("Expected 2 cache hits, got %v", got)
+	}
+	if got := gatherLabeledValue(t, collector, "nexus_response_cache_requests_total", map[string]string{"result": "miss"}); got != 1 {
+		t.Errorf("Expected 1 cache miss, got %v", got)
+	}
+}
+
+func TestContainer_FeatureFlags(t *testing.T) {
+	tests := []struct {
+		name          string
+		features      map[string]bool
+		expectCalls   int64
+		expectCache   bool
+		expectTracing bool
+	}{
+		{name: "unlisted features stay on", features: nil, expectCalls: 1, expectCache: true, expectTracing: true},
+		{name: "enabled", features: map[string]bool{FeatureResponseCache: true, FeatureTracing: true}, expectCalls: 1, expectCache: true, expectTracing: true},
+		{name: "response cache off", features: map[string]bool{FeatureResponseCache: false}, expectCalls: 3, expectCache: false, expectTracing: true},
+		{name: "tracing off", features: map[string]bool{FeatureTracing: false}, expectCalls: 1, expectCache: true, expectTracing: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int64
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = io.WriteString(w, `{"object":"list","data":[]}`)
+			}))
+			defer upstream.Close()
+
+			cfg := &interfaces.Config{
+				ListenPort: 8080,
+				TargetURL:  upstream.URL,
+				APIKeys:    map[string]string{"client": "upstream-key"},
+				Limits: interfaces.Limits{
+					RequestsPerSecond:    100,
+					Burst:                100,
+					ModelTokensPerMinute: 100000,
+				},
+				ResponseCache: interfaces.ResponseCacheConfig{
+					Routes: []interfaces.CacheRoute{{PathPrefix: "/v1/models", TTL: time.Minute}},
+				},
+				Features: tt.features,
+			}
+
+			exporter := tracing.NewInMemoryExporter()
+			c := New()
+			c.SetConfigLoader(config.NewMemoryLoader(cfg))
+			c.SetLogger(noopLogger{})
+			c.SetTracer(tracing.NewTracer(exporter))
+			if err := c.Initialize(); err != nil {
+				t.Fatalf("Failed to initialize container: %v", err)
+			}
+			handler := c.BuildHandler()
+
+			for i := 0; i < 3; i++ {
+				req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+				req.Header.Set("Authorization", "Bearer client")
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+			}
+			if got := calls.Load(); got != tt.expectCalls {
+				t.Errorf("Expected %d upstream calls, got %d", tt.expectCalls, got)
+			}
+			if got := len(exporter.Spans()) > 0; got != tt.expectTracing {
+				t.Errorf("Expected spans recorded = %v, got %v", tt.expectTracing, got)
+			}
+
+			chain := strings.Join(c.MiddlewareChain(), ",")
+			if got := strings.Contains(chain, "response_cache"); got != tt.expectCache {
+				t.Errorf("Expected response_cache in chain = %v, got chain %s", tt.expectCache, chain)
+			}
+			if got := strings.Contains(chain, "tracing"); got != tt.expectTracing {
+				t.Errorf("Expected tracing in chain = %v, got chain %s", tt.expectTracing, chain)
+			}
+		})
+	}
+}
+
+func TestContainer_UnknownFeatureWarns(t *testing.T) {
+	cfg := &interfaces.Config{
+		ListenPort: 8080,
+		TargetURL:  "https://upstream.example.com",
+		APIKeys:    map[string]string{"client": "upstream-key"},
+		Limits: interfaces.Limits{
+			RequestsPerSecond:    10,
+			Burst:                10,
+			ModelTokensPerMinute: 1000,
+		},
+		Features: map[string]bool{"respons_cache": false},
+	}
+
+	logger := &messageLogger{}
+	c := New()
+	c.SetConfigLoader(config.NewMemoryLoader(cfg))
+	c.SetLogger(logger)
+	if err := c.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize container: %v", err)
+	}
+	if !logger.logged("warn", "Ignoring unknown feature flags") {
+		t.Errorf("Expected a warning for the unknown feature, got %v", logger.messages)
+	}
+}
+
+func TestContainer_PerKeyTargets(t *testing.T) {
+	newUpstream := func(name string, seenAuth *string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*seenAuth = r.Header.Get("Authorization")
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"upstream":"`+name+`","path":"`+r.URL.Path+`"}`)
+		}))
+	}
+	var defaultAuth, euAuth string
+	defaultUpstream := newUpstream("default", &defaultAuth)
+	defer defaultUpstream.Close()
+	euUpstream := newUpstream("eu", &euAuth)
+	defer euUpstream.Close()
+
+	cfg := &interfaces.Config{
+		ListenPort: 8080,
+		TargetURL:  defaultUpstream.URL,
+		APIKeys:    map[string]string{"customer-a": "upstream-a", "customer-b": "upstream-b"},
+		Limits: interfaces.Limits{
+			RequestsPerSecond:    100,
+			Burst:                100,
+			ModelTokensPerMinute: 100000,
+		},
+		Metrics:       interfaces.MetricsConfig{Enabled: true},
+		PerKeyTargets: map[string]string{"customer-b": euUpstream.URL + "/eu"},
+	}
+
+	c := New()
+	c.SetConfigLoader(config.NewMemoryLoader(cfg))
+	c.SetLogger(noopLogger{})
+	if err := c.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize container: %v", err)
+	}
+	handler := c.BuildHandler()
+
+	tests := []struct {
+		key        string
+		expectBody string
+		seenAuth   *string
+		expectAuth string
+	}{
+		{key: "customer-a", expectBody: `{"upstream":"default","path":"/v1/models"}`, seenAuth: &defaultAuth, expectAuth: "Bearer upstream-a"},
+		{key: "customer-b", expectBody: `{"upstream":"eu","path":"/eu/v1/models"}`, seenAuth: &euAuth, expectAuth: "Bearer upstream-b"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+		req.Header.Set("Authorization", "Bearer "+tt.key)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Body.String() != tt.expectBody {
+			t.Errorf("%s: expected %s, got %s", tt.key, tt.expectBody, rr.Body.String())
+		}
+		if *tt.seenAuth != tt.expectAuth {
+			t.Errorf("%s: expected the upstream to see %q, got %q", tt.key, tt.expectAuth, *tt.seenAuth)
+		}
+	}
+
+	// Records are written asynchronously; Close drains them
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	for _, key := range []string{"customer-a", "customer-b"} {
+		km, ok := c.MetricsCollector().GetMetricsForKey(key)
+		if !ok || km.TotalRequests != 1 {
+			t.Errorf("Expected one request recorded for %s, got %+v", key, km)
+		}
+	}
+}
+
+func TestContainer_MiddlewareChainMatchesDocumentedOrder(t *testing.T) {
+	cfg := &interfaces.Config{
+		ListenPort: 8080,
+		TargetURL:  "https://upstream.example.com",
+		APIKeys:    map[string]string{"client": "upstream-key"},
+		Limits: interfaces.Limits{
+			RequestsPerSecond:    100,
+			Burst:                100,
+			ModelTokensPerMinute: 100000,
+			MaxConcurrent:        4,
+		},
+		Metrics:       interfaces.MetricsConfig{Enabled: true},
+		Logging:       interfaces.LoggingConfig{AccessLog: true},
+		OptionsMode:   middleware.OptionsRespond,
+		JSONBody:      interfaces.JSONBodyConfig{Paths: []string{"/v1"}},
+		Idempotency:   interfaces.IdempotencyConfig{TTL: time.Minute},
+		ResponseCache: interfaces.ResponseCacheConfig{Routes: []interfaces.CacheRoute{{PathPrefix: "/v1/models", TTL: time.Minute}}},
+		AllowedModels: []string{"gpt-4"},
+	}
+
+	c := New()
+	c.SetConfigLoader(config.NewMemoryLoader(cfg))
+	c.SetLogger(noopLogger{})
+	c.SetTracer(tracing.NewTracer(tracing.NewInMemoryExporter()))
+	if err := c.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize container: %v", err)
+	}
+
+	if chain := c.MiddlewareChain(); len(chain) != 0 {
+		t.Errorf("Expected no chain before BuildHandler, got %v", chain)
+	}
+	c.BuildHandler()
+
+	// The order documented in BuildHandler, with every optional layer enabled
+	want := []string{
+		"tracing", "access_log", "maintenance", "header_limit", "validation", "json_body", "options", "auth", "metrics",
+		"idempotency", "response_cache", "model_policy", "rate_limit", "concurrency_limit",
+		"token_limit", "upstream_tracing", "proxy",
+	}
+	got := c.MiddlewareChain()
+	if len(got) != len(want) {
+		t.Fatalf("Expected chain %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected %s at position %d, got %s (chain %v)", want[i], i, got[i], got)
+		}
+	}
+}
+
+// messageLogger records log messages by level
+type messageLogger struct {
+	noopLogger
+	mu       sync.Mutex
+	messages map[string][]string
+}
+
+func (l *messageLogger) record(level, msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.messages == nil {
+		l.messages = make(map[string][]string)
+	}
+	l.messages[level] = append(l.messages[level], msg)
+}
+
+func (l *messageLogger) Info(msg string, fields map[string]any) { l.record("info", msg) }
+func (l *messageLogger) Warn(msg string, fields map[string]any) { l.record("warn", msg) }
+
+func (l *messageLogger) logged(level, msg string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, m := range l.messages[level] {
+		if m == msg {
+			return true
+		}
+	}
+	return false
+}
+
+func TestContainer_WarmUp(t *testing.T) {
+	var dials atomic.Int64
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	upstream.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			dials.Add(1)
+		}
+	}
+	upstream.Start()
+	defer upstream.Close()
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	tests := []struct {
+		name        string
+		target      string
+		connections int
+		expectDials int64
+		expectLog   [2]string
+	}{
+		{name: "disabled", target: upstream.URL},
+		{name: "warms the configured count", target: upstream.URL, connections: 3, expectDials: 3, expectLog: [2]string{"info", "Upstream connections warmed up"}},
+		{name: "unreachable upstream only warns", target: closed.URL, connections: 2, expectLog: [2]string{"warn", "Upstream warm-up incomplete"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dials.Store(0)
+			logger := &messageLogger{}
+			c := New()
+			c.SetLogger(logger)
+			c.SetConfigLoader(config.NewMemoryLoader(&interfaces.Config{
+				TargetURL: tt.target,
+				Limits:    interfaces.Limits{RequestsPerSecond: 10, Burst: 10, ModelTokensPerMinute: 1000},
+				Proxy:     interfaces.ProxyConfig{WarmUpConnections: tt.connections, WarmUpTimeout: 2 * time.Second},
+			}))
+			if err := c.Initialize(); err != nil {
+				t.Fatalf("Failed to initialize container: %v", err)
+			}
+
+			c.WarmUp()
+
+			if got := dials.Load(); got != tt.expectDials {
+				t.Errorf("Expected %d dials, got %d", tt.expectDials, got)
+			}
+			if tt.expectLog[1] != "" && !logger.logged(tt.expectLog[0], tt.expectLog[1]) {
+				t.Errorf("Expected %s log %q, got %v", tt.expectLog[0], tt.expectLog[1], logger.messages)
+			}
+		})
+	}
+}
+
+// statusMappingProxy stands in for a gateway feature that rewrites upstream
+// statuses, answering clients with 503 where the upstream sent 502
+type statusMappingProxy struct {
+	interfaces.Proxy
+}
+
+func (p statusMappingProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.Proxy.ServeHTTP(&statusMappingWriter{ResponseWriter: w}, r)
+}
+
+type statusMappingWriter struct {
+	http.ResponseWriter
+}
+
+func (w *statusMappingWriter) WriteHeader(status int) {
+	if status == http.StatusBadGateway {
+		status = http.StatusServiceUnavailable
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func TestContainer_UpstreamStatusSeparateFromClientStatus(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer upstream.Close()
+
+	cfg := &interfaces.Config{
+		ListenPort: 8080,
+		TargetURL:  upstream.URL,
+		APIKeys:    map[string]string{"client": "upstream-key"},
+		Limits: interfaces.Limits{
+			RequestsPerSecond:    100,
+			Burst:                100,
+			ModelTokensPerMinute: 100000,
+		},
+		Metrics: interfaces.MetricsConfig{Enabled: true},
+	}
+
+	c := New()
+	c.SetConfigLoader(config.NewMemoryLoader(cfg))
+	c.SetLogger(noopLogger{})
+	if err := c.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize container: %v", err)
+	}
+	c.proxy = statusMappingProxy{Proxy: c.proxy}
+	handler := c.BuildHandler()
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+		req.Header.Set("Authorization", "Bearer client")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusServiceUnavailable {
+			t.Fatalf("Expected the client to see 503, got %d", rr.Code)
+		}
+	}
+
+	// Client-facing statuses are recorded asynchronously
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		km, ok := c.MetricsCollector().GetMetricsForKey("client")
+		if ok && km.TotalRequests == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 2 requests recorded for client, got %+v", km)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	collector := c.MetricsCollector().(*metrics.MetricsCollector)
+	if got := gatherLabeledValue(t, collector, "nexus_upstream_status_total", map[string]string{"status": "502"}); got != 2 {
+		t.Errorf("Expected 2 upstream 502s, got %v", got)
+	}
+	if got := gatherLabeledValue(t, collector, "nexus_responses_total", map[string]string{"status": "503"}); got != 2 {
+		t.Errorf("Expected 2 client-facing 503s, got %v", got)
+	}
+
+	families, err := collector.Registry().Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			for _, lp := range m.GetLabel() {
+				if lp.GetName() != "status" {
+					continue
+				}
+				if mf.GetName() == "nexus_upstream_status_total" && lp.GetValue() != "502" {
+					t.Errorf("Unexpected upstream status %s", lp.GetValue())
+				}
+				if mf.GetName() == "nexus_responses_total" && lp.GetValue() != "503" {
+					t.Errorf("Unexpected client-facing status %s", lp.GetValue())
+				}
+			}
+		}
+	}
+}
+
+func TestContainer_StreamsRequestBodiesWithoutBodyMiddleware(t *testing.T) {
+	const chunk = 64 * 1024
+	const chunks = 32
+
+	firstBytes := make(chan struct{})
+	var received atomic.Int64
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := make([]byte, chunk)
+		var signalled bool
+		for {
+			n, err := r.Body.Read(buf)
+			received.Add(int64(n))
+			if n > 0 && !signalled {
+				close(firstBytes)
+				signalled = true
+			}
+			if err != nil {
+				break
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	cfg := &interfaces.Config{
+		ListenPort: 8080,
+		TargetURL:  upstream.URL,
+		APIKeys:    map[string]string{"client": "upstream-key"},
+		Limits: interfaces.Limits{
+			RequestsPerSecond: 100,
+			Burst:             100,
+			DisableTokenLimit: true,
+		},
+	}
+
+	c := New()
+	c.SetConfigLoader(config.NewMemoryLoader(cfg))
+	c.SetLogger(noopLogger{})
+	if err := c.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize container: %v", err)
+	}
+	handler := c.BuildHandler()
+	for _, name := range c.MiddlewareChain() {
+		if name == "token_limit" {
+			t.Errorf("Expected no token limit layer, got chain %v", c.MiddlewareChain())
+		}
+	}
+
+	// The client holds back everything after the first chunk until the
+	// upstream has seen bytes, which only happens if nothing buffers the body
+	body, writer := io.Pipe()
+	req := httptest.NewRequest(http.MethodPost, "/v1/files", body)
+	req.Header.Set("Authorization", "Bearer client")
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(rr, req)
+	}()
+
+	payload := make([]byte, chunk)
+	if _, err := writer.Write(payload); err != nil {
+		t.Fatalf("Failed to write first chunk: %v", err)
+	}
+	select {
+	case <-firstBytes:
+	case <-time.After(2 * time.Second):
+		_ = writer.CloseWithError(io.ErrUnexpectedEOF)
+		<-done
+		t.Fatal("Expected the upstream to receive bytes before the client finished sending")
+	}
+	for i := 1; i < chunks; i++ {
+		if _, err := writer.Write(payload); err != nil {
+			t.Fatalf("Failed to write chunk %d: %v", i, err)
+		}
+	}
+	_ = writer.Close()
+	<-done
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", rr.Code)
+	}
+	if got := received.Load(); got != chunk*chunks {
+		t.Errorf("Expected the upstream to receive %d bytes, got %d", chunk*chunks, got)
+	}
+}
+
+func TestContainer_StreamsRequestBodiesDetection(t *testing.T) {
+	cfg := &interfaces.Config{
+		ListenPort: 8080,
+		TargetURL:  "http://upstream.example.com",
+		Limits: interfaces.Limits{
+			RequestsPerSecond: 100,
+			Burst:             100,
+			DisableTokenLimit: true,
+		},
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*interfaces.Config)
+		stream bool
+	}{
+		{name: "no body middleware", stream: true},
+		{name: "token limit", mutate: func(cfg *interfaces.Config) {
+			cfg.Limits.DisableTokenLimit = false
+			cfg.Limits.ModelTokensPerMinute = 1000
+		}},
+		{name: "json body check", mutate: func(cfg *interfaces.Config) { cfg.JSONBody.Paths = []string{"/v1/"} }},
+		{name: "model allow-list", mutate: func(cfg *interfaces.Config) { cfg.AllowedModels = []string{"gpt-4"} }},
+		{name: "model aliases", mutate: func(cfg *interfaces.Config) { cfg.ModelAliases = map[string]string{"fast": "gpt-4o-mini"} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader := config.NewMemoryLoader(cfg)
+			if tt.mutate != nil {
+				updated, _ := loader.Load()
+				tt.mutate(updated)
+				loader.Update(updated)
+			}
+			c := New()
+			c.SetConfigLoader(loader)
+			c.SetLogger(noopLogger{})
+			if err := c.Initialize(); err != nil {
+				t.Fatalf("Failed to initialize container: %v", err)
+			}
+			if got := c.streamsRequestBodies(); got != tt.stream {
+				t.Errorf("Expected streaming %v, got %v", tt.stream, got)
+			}
+		})
+	}
+}
+
+func TestContainer_ValidationRejectionsMetric(t *testing.T) {
+	cfg := &interfaces.Config{
+		ListenPort:    8080,
+		TargetURL:     "http://example.com",
+		APIKeys:       map[string]string{"client": "upstream-key"},
+		AllowedModels: []string{"gpt-4"},
+		Limits: interfaces.Limits{
+			RequestsPerSecond:    100,
+			Burst:                100,
+			ModelTokensPerMinute: 100000,
+		},
+		Metrics: interfaces.MetricsConfig{Enabled: true},
+	}
+
+	c := New()
+	c.SetConfigLoader(config.NewMemoryLoader(cfg))
+	c.SetLogger(noopLogger{})
+	if err := c.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize container: %v", err)
+	}
+	handler := c.BuildHandler()
+
+	send := func(contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer client")
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := send("text/plain", `{}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for the wrong content type, got %d", rr.Code)
+	}
+	for i := 0; i < 2; i++ {
+		rr := send("application/json", `{"model":"gpt-3.5-turbo","input":"hi"}`)
+		if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), `"reason":"model_not_allowed"`) {
+			t.Errorf("Expected a model_not_allowed rejection, got %d: %s", rr.Code, rr.Body.String())
+		}
+	}
+
+	collector := c.MetricsCollector().(*metrics.MetricsCollector)
+	wants := map[string]float64{
+		middleware.ReasonContentType:     1,
+		middleware.ReasonModelNotAllowed: 2,
+		middleware.ReasonInvalidJSON:     0,
+	}
+	for reason, want := range wants {
+		if got := gatherLabeledValue(t, collector, "nexus_validation_rejections_total", map[string]string{"reason": reason}); got != want {
+			t.Errorf("Expected %v rejections for %s, got %v", want, reason, got)
+		}
+	}
+}
+
+func TestContainer_UpstreamKeyProvider(t *testing.T) {
+	var gotAuth atomic.Value
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth.Store(r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	t.Setenv(auth.NewEnvKeyProvider("TEST_CONTAINER_KEY_").VariableName("team-a"), "sk-from-env")
+	cfg := &interfaces.Config{
+		ListenPort: 8080,
+		TargetURL:  upstream.URL,
+		Limits: interfaces.Limits{
+			RequestsPerSecond:    100,
+			Burst:                100,
+			ModelTokensPerMinute: 100000,
+		},
+		UpstreamKeys: interfaces.UpstreamKeysConfig{Provider: "env", EnvPrefix: "TEST_CONTAINER_KEY_"},
+	}
+
+	c := New()
+	c.SetConfigLoader(config.NewMemoryLoader(cfg))
+	c.SetLogger(noopLogger{})
+	if err := c.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize container: %v", err)
+	}
+	handler := c.BuildHandler()
+
+	send := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := send("team-a"); code != http.StatusOK {
+		t.Fatalf("Expected 200 for a key the provider knows, got %d", code)
+	}
+	if got := gotAuth.Load(); got != "Bearer sk-from-env" {
+		t.Errorf("Expected the upstream key from the environment, got %v", got)
+	}
+	if code := send("team-b"); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a key the provider doesn't know, got %d", code)
+	}
+
+	cfg.UpstreamKeys.Provider = "unregistered"
+	c = New()
+	c.SetConfigLoader(config.NewMemoryLoader(cfg))
+	c.SetLogger(noopLogger{})
+	if err := c.Initialize(); err == nil {
+		t.Error("Expected Initialize to fail for an unknown provider")
+	}
+}
+
+func TestContainer_APIVersionMetric(t *testing.T) {
+	cfg := &interfaces.Config{
+		ListenPort: 8080,
+		TargetURL:  "http://example.com",
+		APIKeys:    map[string]string{"client": "upstream-key"},
+		Limits: interfaces.Limits{
+			RequestsPerSecond:    100,
+			Burst:                100,
+			ModelTokensPerMinute: 100000,
+		},
+		Metrics:    interfaces.MetricsConfig{Enabled: true},
+		APIVersion: interfaces.APIVersionConfig{Supported: []string{"v1", "v2"}},
+	}
+
+	c := New()
+	c.SetConfigLoader(config.NewMemoryLoader(cfg))
+	c.SetLogger(noopLogger{})
+	if err := c.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize container: %v", err)
+	}
+	handler := c.BuildHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	req.Header.Set("Authorization", "Bearer client")
+	req.Header.Set("X-API-Version", "v3")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unsupported version, got %d", rr.Code)
+	}
+
+	collector := c.MetricsCollector().(*metrics.MetricsCollector)
+	if got := gatherLabeledValue(t, collector, "nexus_validation_rejections_total", map[string]string{"reason": middleware.ReasonUnsupportedAPIVersion}); got != 1 {
+		t.Errorf("Expected one unsupported_api_version rejection, got %v", got)
+	}
+	if got := gatherLabeledValue(t, collector, "nexus_api_version_requests_total", map[string]string{"version": "v1"}); got != 0 {
+		t.Errorf("Expected no v1 requests, got %v", got)
+	}
+}
+
+func TestContainer_BuildHandlerUninitialized(t *testing.T) {
+	c := New()
+	handler := c.BuildHandler()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 before Initialize, got %d", rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "configuration not loaded") {
+		t.Errorf("Expected a configuration not loaded message, got %q", rr.Body.String())
+	}
+}
+
+func TestContainer_Close(t *testing.T) {
+	cfg := &interfaces.Config{
+		ListenPort: 8080,
+		TargetURL:  "http://example.com",
+		Limits: interfaces.Limits{
+			RequestsPerSecond:    10,
+			Burst:                10,
+			ModelTokensPerMinute: 1000,
+		},
+	}
+
+	c := New()
+	c.SetConfigLoader(config.NewMemoryLoader(cfg))
+	c.SetLogger(noopLogger{})
+	if err := c.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize container: %v", err)
+	}
+
+	var order []string
+	c.OnClose(func() error {
+		order = append(order, "first")
+		return nil
+	})
+	hookErr := errors.New("connection already closed")
+	c.OnClose(func() error {
+		order = append(order, "second")
+		return hookErr
+	})
+
+	if err := c.Close(); !errors.Is(err, hookErr) {
+		t.Errorf("Expected the hook's error from Close, got %v", err)
+	}
+	if strings.Join(order, ",") != "second,first" {
+		t.Errorf("Expected hooks to run in reverse order once, got %v", order)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Errorf("Expected a second Close to succeed, got %v", err)
+	}
+	if len(order) != 2 {
+		t.Errorf("Expected hooks not to run again, got %v", order)
+	}
+}
+
+// assertNoActiveGoroutines fails t if c still has background goroutines
+// running, naming them
+func assertNoActiveGoroutines(t *testing.T, c *Container) {
+	t.Helper()
+	if active := c.ActiveGoroutines(); len(active) > 0 {
+		t.Errorf("Expected no background goroutines after Close, got %v", active)
+	}
+}
+
+func TestContainer_CloseStopsBackgroundGoroutines(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer upstream.Close()
+
+	cfg := &interfaces.Config{
+		ListenPort: 8080,
+		TargetURL:  upstream.URL,
+		Limits: interfaces.Limits{
+			RequestsPerSecond:    10,
+			Burst:                10,
+			ModelTokensPerMinute: 1000,
+		},
+		Metrics: interfaces.MetricsConfig{
+			Enabled:     true,
+			RemoteWrite: interfaces.RemoteWriteConfig{URL: upstream.URL, Interval: time.Hour},
+		},
+		HealthCheck: interfaces.HealthCheckConfig{Interval: time.Hour},
+		Alerts:      interfaces.AlertsConfig{ErrorRateThreshold: 0.5, WebhookURL: upstream.URL},
+	}
+
+	c := New()
+	c.SetConfigLoader(config.NewMemoryLoader(cfg))
+	c.SetLogger(noopLogger{})
+	if err := c.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize container: %v", err)
+	}
+	c.StartHealthChecks()
+	c.StartRemoteWrite()
+
+	active := c.ActiveGoroutines()
+	for _, name := range []string{"rate_limit_cleanup", "token_limit_cleanup", "metrics_recorder", "alert_webhook", "health_check", "remote_write"} {
+		if active[name] != 1 {
+			t.Errorf("Expected one %s goroutine, got %v", name, active)
+		}
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	assertNoActiveGoroutines(t, c)
+
+	// Starting again after Close must not leave anything running
+	c.StartHealthChecks()
+	if err := c.stopBackground(time.Second); err != nil {
+		t.Errorf("Expected goroutines started after Close to stop, got %v", err)
+	}
+}
+
+func TestContainer_BackgroundPanicsRestartTask(t *testing.T) {
+	defer func(minBackoff, maxBackoff time.Duration) {
+		minRestartBackoff, maxRestartBackoff = minBackoff, maxBackoff
+	}(minRestartBackoff, maxRestartBackoff)
+	minRestartBackoff, maxRestartBackoff = time.Millisecond, 5*time.Millisecond
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer upstream.Close()
+
+	c := New()
+	c.SetConfigLoader(config.NewMemoryLoader(&interfaces.Config{
+		ListenPort: 8080,
+		TargetURL:  upstream.URL,
+		Limits: interfaces.Limits{
+			RequestsPerSecond:    10,
+			Burst:                10,
+			ModelTokensPerMinute: 1000,
+		},
+		Metrics: interfaces.MetricsConfig{Enabled: true},
+	}))
+	c.SetLogger(noopLogger{})
+	if err := c.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize container: %v", err)
+	}
+
+	// The task panics on its first two iterations, then runs until Close
+	var runs atomic.Int32
+	running := make(chan struct{})
+	c.goBackground(c.lifetimeContext(), "faulty", func(ctx context.Context) {
+		if runs.Add(1) <= 2 {
+			panic("eviction index corrupted")
+		}
+		close(running)
+		<-ctx.Done()
+	})
+
+	select {
+	case <-running:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the task to be restarted after panicking, ran %d times", runs.Load())
+	}
+	if got := c.BackgroundPanics()["faulty"]; got != 2 {
+		t.Errorf("Expected 2 panics recorded, got %d", got)
+	}
+	collector := c.MetricsCollector().(*metrics.MetricsCollector)
+	if got := gatherLabeledValue(t, collector, "nexus_background_panics_total", map[string]string{"task": "faulty"}); got != 2 {
+		t.Errorf("Expected nexus_background_panics_total 2, got %v", got)
+	}
+	if active := c.ActiveGoroutines(); active["faulty"] != 1 {
+		t.Errorf("Expected the restarted task to be running, got %v", active)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	assertNoActiveGoroutines(t, c)
+}
+
+func TestContainer_TimeBudget(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer upstream.Close()
+
+	const budget = 300 * time.Millisecond
+	c := New()
+	c.SetConfigLoader(config.NewMemoryLoader(&interfaces.Config{
+		ListenPort: 8080,
+		TargetURL:  upstream.URL,
+		APIKeys:    map[string]string{"client": "upstream-key"},
+		Limits: interfaces.Limits{
+			RequestsPerSecond:    2,
+			Burst:                1,
+			ModelTokensPerMinute: 100000,
+			MaxWait:              5 * time.Second,
+		},
+		Request: interfaces.RequestConfig{MaxTotalDuration: budget},
+	}))
+	c.SetLogger(noopLogger{})
+	if err := c.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize container: %v", err)
+	}
+	defer c.Close()
+	handler := c.BuildHandler()
+
+	// The first request waits on the upstream. The second queues ~200ms for a
+	// rate limit token, then waits on the upstream; the queueing counts
+	// against its budget, so it is still answered at the budget.
+	for _, name := range []string{"slow upstream", "queued then slow upstream"} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+		req.Header.Set("Authorization", "Bearer client")
+		rr := httptest.NewRecorder()
+		start := time.Now()
+		handler.ServeHTTP(rr, req)
+		elapsed := time.Since(start)
+
+		if rr.Code != http.StatusGatewayTimeout {
+			t.Errorf("%s: expected 504, got %d", name, rr.Code)
+		}
+		if elapsed < budget || elapsed > budget+time.Second {
+			t.Errorf("%s: expected a response at the %s budget, took %s", name, budget, elapsed)
+		}
+	}
+}
+
+func TestContainer_RegisterCleanupHandler(t *testing.T) {
+	c := New()
+	c.SetLogger(noopLogger{})
+
+	var ran []int
+	for i := 1; i <= 3; i++ {
+		i := i
+		err := c.RegisterCleanupHandler(func() {
+			ran = append(ran, i)
+			if i == 2 {
+				panic("exporter already stopped")
+			}
+		})
+		if err != nil {
+			t.Fatalf("RegisterCleanupHandler() error = %v", err)
+		}
+	}
+
+	err := c.Close()
+	if err == nil || !strings.Contains(err.Error(), "exporter already stopped") {
+		t.Errorf("Expected the panic to be reported by Close, got %v", err)
+	}
+	if len(ran) != 3 || ran[0] != 3 || ran[1] != 2 || ran[2] != 1 {
+		t.Errorf("Expected every handler to run in reverse order, got %v", ran)
+	}
+
+	if err := c.RegisterCleanupHandler(func() {}); err == nil {
+		t.Error("Expected an error registering a handler after Close")
+	}
+	if err := c.RegisterCleanupHandler(nil); err == nil {
+		t.Error("Expected an error registering a nil handler")
+	}
+}
+
+func TestContainer_UnroutedRequests(t *testing.T) {
+	var defaultHits, chatHits atomic.Int64
+	defaultUpstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defaultHits.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer defaultUpstream.Close()
+	chatUpstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chatHits.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer chatUpstream.Close()
+
+	tests := []struct {
+		onNoMatch      string
+		expectStatus   int
+		expectFallback int64
+	}{
+		{onNoMatch: middleware.OnNoMatchFallback, expectStatus: http.StatusOK, expectFallback: 1},
+		{onNoMatch: middleware.OnNoMatchReject, expectStatus: http.StatusNotFound, expectFallback: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.onNoMatch, func(t *testing.T) {
+			defaultHits.Store(0)
+			chatHits.Store(0)
+			cfg := &interfaces.Config{
+				ListenPort: 8080,
+				TargetURL:  defaultUpstream.URL,
+				APIKeys:    map[string]string{"client": "upstream-key"},
+				Limits: interfaces.Limits{
+					RequestsPerSecond:    100,
+					Burst:                100,
+					ModelTokensPerMinute: 100000,
+				},
+				Metrics: interfaces.MetricsConfig{Enabled: true},
+				Routing: interfaces.RoutingConfig{
+					Routes:    []interfaces.TargetRoute{{PathPrefix: "/v1/chat", Target: chatUpstream.URL}},
+					OnNoMatch: tt.onNoMatch,
+				},
+			}
+
+			c := New()
+			c.SetConfigLoader(config.NewMemoryLoader(cfg))
+			c.SetLogger(noopLogger{})
+			if err := c.Initialize(); err != nil {
+				t.Fatalf("Failed to initialize container: %v", err)
+			}
+			handler := c.BuildHandler()
+
+			send := func(path string) int {
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				req.Header.Set("Authorization", "Bearer client")
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, req)
+				return rr.Code
+			}
+
+			if code := send("/v1/chat/completions"); code != http.StatusOK {
+				t.Errorf("Expected a routed request to succeed, got %d", code)
+			}
+			if code := send("/v1/models"); code != tt.expectStatus {
+				t.Errorf("Expected %d for an unrouted request, got %d", tt.expectStatus, code)
+			}
+
+			if chatHits.Load() != 1 {
+				t.Errorf("Expected the routed request at the route's upstream, got %d", chatHits.Load())
+			}
+			if defaultHits.Load() != tt.expectFallback {
+				t.Errorf("Expected %d requests at target_url, got %d", tt.expectFallback, defaultHits.Load())
+			}
+			collector := c.MetricsCollector().(*metrics.MetricsCollector)
+			if got := gatherValue(t, collector, "nexus_unrouted_requests_total"); got != 1 {
+				t.Errorf("Expected 1 unrouted request, got %v", got)
+			}
+		})
+	}
+}
+
+func TestContainer_ForwardsTrailers(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Checksum")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"object":"list","data":[]}`)
+		w.Header().Set("X-Checksum", "abc123")
+	}))
+	defer upstream.Close()
+
+	disabled := false
+	for _, tt := range []struct {
+		name            string
+		forwardTrailers *bool
+		want            string
+	}{
+		{name: "forwarded by default", want: "abc123"},
+		{name: "disabled", forwardTrailers: &disabled},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			c.SetConfigLoader(config.NewMemoryLoader(&interfaces.Config{
+				ListenPort: 8080,
+				TargetURL:  upstream.URL,
+				APIKeys:    map[string]string{"client": "upstream-key"},
+				Limits:     interfaces.Limits{RequestsPerSecond: 100, Burst: 100, ModelTokensPerMinute: 100000},
+				Metrics:    interfaces.MetricsConfig{Enabled: true},
+				Proxy:      interfaces.ProxyConfig{ForwardTrailers: tt.forwardTrailers},
+			}))
+			c.SetLogger(noopLogger{})
+			if err := c.Initialize(); err != nil {
+				t.Fatalf("Failed to initialize container: %v", err)
+			}
+			defer c.Close()
+			// Trailers only exist on the wire, so serve the chain over HTTP
+			gateway := httptest.NewServer(c.BuildHandler())
+			defer gateway.Close()
+
+			req, err := http.NewRequest(http.MethodGet, gateway.URL+"/v1/models", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer client")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				t.Fatalf("Failed to read body: %v", err)
+			}
+			if resp.StatusCode != http.StatusOK || string(body) != `{"object":"list","data":[]}` {
+				t.Fatalf("Expected the upstream response, got %d %q", resp.StatusCode, body)
+			}
+			// Trailers are only populated once the body has been read
+			if got := resp.Trailer.Get("X-Checksum"); got != tt.want {
+				t.Errorf("Expected X-Checksum trailer %q, got %q", tt.want, got)
+			}
+
+			// Metrics are recorded off the request path
+			deadline := time.Now().Add(2 * time.Second)
+			for {
+				km, ok := c.MetricsCollector().GetMetricsForKey("client")
+				if ok && km.TotalRequests == 1 && km.SuccessfulRequests == 1 {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("Expected the request recorded for client, got %+v", km)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestContainer_UpstreamIdentity(t *testing.T) {
+	var identity, authorization string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity = r.Header.Get("X-Upstream-User")
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer upstream.Close()
+
+	c := New()
+	c.SetConfigLoader(config.NewMemoryLoader(&interfaces.Config{
+		ListenPort:       8080,
+		TargetURL:        upstream.URL,
+		APIKeys:          map[string]string{"client": "upstream-key"},
+		Limits:           interfaces.Limits{RequestsPerSecond: 100, Burst: 100, ModelTokensPerMinute: 100000},
+		UpstreamIdentity: interfaces.UpstreamIdentityConfig{Enabled: true, Salt: "pepper"},
+	}))
+	c.SetLogger(noopLogger{})
+	if err := c.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize container: %v", err)
+	}
+	defer c.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	req.Header.Set("Authorization", "Bearer client")
+	rr := httptest.NewRecorder()
+	c.BuildHandler().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rr.Code)
+	}
+	if want := middleware.UpstreamIdentity("client", "pepper"); identity != want {
+		t.Errorf("Expected upstream identity %q, got %q", want, identity)
+	}
+	if authorization != "Bearer upstream-key" {
+		t.Errorf("Expected the upstream key to be sent, got %q", authorization)
+	}
+}
+
+// webSocketGUID is appended to the client's key to derive Sec-WebSocket-Accept
+const webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// webSocketAccept returns the Sec-WebSocket-Accept value for a client key
+func webSocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + webSocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// writeFrame writes a final text frame with a payload under 126 bytes,
+// masked as clients must
+func writeFrame(w io.Writer, payload []byte, mask []byte) error {
+	frame := []byte{0x81, byte(len(payload))}
+	if mask != nil {
+		frame[1] |= 0x80
+		frame = append(frame, mask...)
+	}
+	for i, b := range payload {
+		if mask != nil {
+			b ^= mask[i%4]
+		}
+		frame = append(frame, b)
+	}
+	_, err := w.Write(frame)
+	return err
+}
+
+// readFrame reads a frame written by writeFrame and returns its unmasked payload
+func readFrame(r io.Reader) ([]byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	var mask []byte
+	if header[1]&0x80 != 0 {
+		mask = make([]byte, 4)
+		if _, err := io.ReadFull(r, mask); err != nil {
+			return nil, err
+		}
+	}
+	payload := make([]byte, header[1]&0x7f)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	for i := range payload {
+		if mask != nil {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return payload, nil
+}
+
+func TestContainer_WebSockets(t *testing.T) {
+	// The upstream completes the handshake and echoes each frame back
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer upstream-key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + webSocketAccept(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+		_ = rw.Flush()
+		for {
+			payload, err := readFrame(rw)
+			if err != nil {
+				return
+			}
+			if err := writeFrame(conn, payload, nil); err != nil {
+				return
+			}
+		}
+	}))
+	defer upstream.Close()
+
+	for _, tt := range []struct {
+		name       string
+		websockets bool
+		wantStatus int
+	}{
+		{name: "relayed when enabled", websockets: true, wantStatus: http.StatusSwitchingProtocols},
+		{name: "refused by default", wantStatus: http.StatusNotImplemented},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			c.SetConfigLoader(config.NewMemoryLoader(&interfaces.Config{
+				ListenPort: 8080,
+				TargetURL:  upstream.URL,
+				APIKeys:    map[string]string{"client": "upstream-key"},
+				Limits:     interfaces.Limits{RequestsPerSecond: 100, Burst: 100, ModelTokensPerMinute: 100000},
+				Metrics:    interfaces.MetricsConfig{Enabled: true},
+				Request:    interfaces.RequestConfig{MaxTotalDuration: time.Second},
+				Proxy:      interfaces.ProxyConfig{WebSockets: tt.websockets},
+			}))
+			c.SetLogger(noopLogger{})
+			if err := c.Initialize(); err != nil {
+				t.Fatalf("Failed to initialize container: %v", err)
+			}
+			defer c.Close()
+			gateway := httptest.NewServer(c.BuildHandler())
+			defer gateway.Close()
+
+			conn, err := net.Dial("tcp", gateway.Listener.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+			key := "dGhlIHNhbXBsZSBub25jZQ=="
+			_, err = io.WriteString(conn, "GET /v1/realtime HTTP/1.1\r\nHost: gateway\r\n"+
+				"Authorization: Bearer client\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n"+
+				"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: "+key+"\r\n\r\n")
+			if err != nil {
+				t.Fatal(err)
+			}
+			br := bufio.NewReader(conn)
+			resp, err := http.ReadResponse(br, nil)
+			if err != nil {
+				t.Fatalf("Failed to read handshake response: %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+			}
+			if !tt.websockets {
+				return
+			}
+			if got := resp.Header.Get("Sec-WebSocket-Accept"); got != webSocketAccept(key) {
+				t.Fatalf("Expected the upstream's Sec-WebSocket-Accept, got %q", got)
+			}
+
+			// Outlive the time budget to show sessions are not bound by it
+			time.Sleep(1100 * time.Millisecond)
+			for _, msg := range []string{"hello", "world"} {
+				if err := writeFrame(conn, []byte(msg), []byte{1, 2, 3, 4}); err != nil {
+					t.Fatal(err)
+				}
+				payload, err := readFrame(br)
+				if err != nil {
+					t.Fatalf("Failed to read echo: %v", err)
+				}
+				if string(payload) != msg {
+					t.Errorf("Expected echo %q, got %q", msg, payload)
+				}
+			}
+			conn.Close()
+
+			// The session is recorded once it ends, off the request path
+			deadline := time.Now().Add(2 * time.Second)
+			for {
+				km, ok := c.MetricsCollector().GetMetricsForKey("client")
+				if ok && km.WebSocketSessions == 1 && km.SuccessfulRequests == 1 {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("Expected one websocket session recorded for client, got %+v", km)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestContainer_ShadowWouldRejectMetric(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	cfg := &interfaces.Config{
+		ListenPort: 8080,
+		TargetURL:  upstream.URL,
+		APIKeys:    map[string]string{"client": "upstream-key"},
+		Limits: interfaces.Limits{
+			RequestsPerSecond:    1,
+			Burst:                1,
+			ModelTokensPerMinute: 100000,
+			MaxConcurrent:        5,
+			Shadow:               true,
+		},
+		Metrics: interfaces.MetricsConfig{Enabled: true},
+	}
+
+	c := New()
+	c.SetConfigLoader(config.NewMemoryLoader(cfg))
+	c.SetLogger(noopLogger{})
+	if err := c.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize container: %v", err)
+	}
+	handler := c.BuildHandler()
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+		req.Header.Set("Authorization", "Bearer client")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Errorf("Expected shadow mode to serve request %d, got %d", i, rr.Code)
+		}
+	}
+
+	collector := c.MetricsCollector().(*metrics.MetricsCollector)
+	wants := map[string]float64{
+		proxy.LimiterTypeRate:        2,
+		proxy.LimiterTypeToken:       0,
+		proxy.LimiterTypeConcurrency: 0,
+	}
+	for limiter, want := range wants {
+		if got := gatherLabeledValue(t, collector, "nexus_rate_limit_would_reject_total", map[string]string{"limiter": limiter}); got != want {
+			t.Errorf("Expected %v would-reject for %s, got %v", want, limiter, got)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package container
+
+import (
+	"sort"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+// Names accepted in the features config, each switching an experimental
+// layer. A feature set to false is left out even when it is configured;
+// unlisted features stay on, so existing configs keep their behavior.
+const (
+	// FeatureTracing gates both request and upstream spans
+	FeatureTracing = "tracing"
+	// FeatureResponseCache gates the response cache layer
+	FeatureResponseCache = "response_cache"
+	// FeatureFairScheduling gates fair scheduling of upstream slots; with it
+	// off, waiting requests are dispatched in arrival order
+	FeatureFairScheduling = "fair_scheduling"
+)
+
+// knownFeatures lists every name the features config accepts
+var knownFeatures = map[string]bool{
+	FeatureTracing:        true,
+	FeatureResponseCache:  true,
+	FeatureFairScheduling: true,
+}
+
+// featureEnabled reports whether cfg leaves the named feature switched on
+func featureEnabled(cfg *interfaces.Config, name string) bool {
+	enabled, ok := cfg.Features[name]
+	return !ok || enabled
+}
+
+// warnUnknownFeatures logs the names in cfg's features config that switch
+// nothing, which are usually typos
+func (c *Container) warnUnknownFeatures(cfg *interfaces.Config) {
+	var unknown []string
+	for name := range cfg.Features {
+		if !knownFeatures[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return
+	}
+
+	sort.Strings(unknown)
+	known := make([]string, 0, len(knownFeatures))
+	for name := range knownFeatures {
+		known = append(known, name)
+	}
+	sort.Strings(known)
+	c.logger.Warn("Ignoring unknown feature flags", map[string]any{
+		"unknown": unknown,
+		"known":   known,
+	})
+}
@@ -0,0 +1,158 @@
+package gateway
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Paths of the grpc.health.v1.Health methods
+const (
+	grpcHealthCheckPath = "/grpc.health.v1.Health/Check"
+	grpcHealthWatchPath = "/grpc.health.v1.Health/Watch"
+)
+
+// HealthCheckResponse.ServingStatus values
+const (
+	grpcServing    = 1
+	grpcNotServing = 2
+)
+
+// gRPC status codes returned in the grpc-status trailer
+const (
+	grpcStatusOK              = 0
+	grpcStatusInvalidArgument = 3
+	grpcStatusNotFound        = 5
+	grpcStatusUnimplemented   = 12
+)
+
+// maxGRPCHealthRequest bounds the request body; a HealthCheckRequest only
+// carries a service name
+const maxGRPCHealthRequest = 4 << 10
+
+// newGRPCHealthServer creates a server for grpc.health.v1.Health/Check on
+// addr. gRPC clients speak HTTP/2 with prior knowledge, so it serves
+// unencrypted HTTP/2 only, answering SERVING while serving reports true and
+// NOT_SERVING otherwise. Only the overall health, service "", is known.
+func newGRPCHealthServer(addr string, serving func() bool) *http.Server {
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	return &http.Server{
+		Addr:              addr,
+		Handler:           grpcHealthHandler(serving),
+		Protocols:         &protocols,
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+}
+
+// grpcHealthHandler answers Check with the status from serving. Watch is not
+// supported; probes and meshes poll Check.
+func grpcHealthHandler(serving func() bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			http.Error(w, "Unsupported Media Type", http.StatusUnsupportedMediaType)
+			return
+		}
+		w.Header().Set("Content-Type", "application/grpc")
+
+		switch r.URL.Path {
+		case grpcHealthCheckPath:
+		case grpcHealthWatchPath:
+			writeGRPCStatus(w, grpcStatusUnimplemented, "Watch is not supported; use Check")
+			return
+		default:
+			writeGRPCStatus(w, grpcStatusUnimplemented, "unknown method "+r.URL.Path)
+			return
+		}
+
+		service, err := readHealthCheckRequest(io.LimitReader(r.Body, maxGRPCHealthRequest))
+		if err != nil {
+			writeGRPCStatus(w, grpcStatusInvalidArgument, err.Error())
+			return
+		}
+		if service != "" {
+			writeGRPCStatus(w, grpcStatusNotFound, "unknown service")
+			return
+		}
+
+		status := byte(grpcNotServing)
+		if serving() {
+			status = grpcServing
+		}
+		// A length-prefixed HealthCheckResponse with field 1, status, set
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte{0, 0, 0, 0, 2, 0x08, status})
+		w.Header().Set("Grpc-Status", strconv.Itoa(grpcStatusOK))
+	})
+}
+
+// writeGRPCStatus sends a response with no message, carrying the status in
+// the headers as gRPC's trailers-only form
+func writeGRPCStatus(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	w.Header().Set("Grpc-Message", message)
+	w.WriteHeader(http.StatusOK)
+}
+
+// readHealthCheckRequest reads one length-prefixed HealthCheckRequest and
+// returns its service field
+func readHealthCheckRequest(body io.Reader) (string, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < 5 {
+		return "", errors.New("truncated message")
+	}
+	if data[0] != 0 {
+		return "", errors.New("compressed messages are not supported")
+	}
+	msg := data[5:]
+	if int(binary.BigEndian.Uint32(data[1:5])) != len(msg) {
+		return "", errors.New("message length mismatch")
+	}
+
+	service := ""
+	for len(msg) > 0 {
+		tag, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return "", errors.New("malformed message")
+		}
+		msg = msg[n:]
+
+		var field []byte
+		switch tag & 7 {
+		case 0: // varint
+			if _, n = binary.Uvarint(msg); n <= 0 {
+				return "", errors.New("malformed message")
+			}
+		case 1: // 64-bit
+			n = 8
+		case 2: // length-delimited
+			size, m := binary.Uvarint(msg)
+			if m <= 0 || size > uint64(len(msg)-m) {
+				return "", errors.New("malformed message")
+			}
+			field = msg[m : m+int(size)]
+			n = m + int(size)
+		case 5: // 32-bit
+			n = 4
+		default:
+			return "", errors.New("malformed message")
+		}
+		if n > len(msg) {
+			return "", errors.New("malformed message")
+		}
+		if tag == 1<<3|2 {
+			service = string(field)
+		}
+		msg = msg[n:]
+	}
+	return service, nil
+}
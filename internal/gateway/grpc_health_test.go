@@ -0,0 +1,168 @@
+package gateway
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/config"
+	"github.com/jamesprial/nexus/internal/container"
+	"github.com/jamesprial/nexus/internal/interfaces"
+	"github.com/jamesprial/nexus/internal/logging"
+)
+
+// grpcHealthCheck calls grpc.health.v1.Health/Check for service the way a
+// gRPC client does, over HTTP/2 with prior knowledge, and returns the
+// serving status (0 when no message came back) and the grpc-status
+func grpcHealthCheck(t *testing.T, url, service string) (int, string) {
+	t.Helper()
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: &protocols}, Timeout: 2 * time.Second}
+	defer client.CloseIdleConnections()
+
+	msg := []byte{}
+	if service != "" {
+		msg = append([]byte{0x0a, byte(len(service))}, service...)
+	}
+	body := append([]byte{0, 0, 0, 0, byte(len(msg))}, msg...)
+	req, err := http.NewRequest(http.MethodPost, url+grpcHealthCheckPath, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Health check failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("Expected HTTP/2, got %s", resp.Proto)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read health check response: %v", err)
+	}
+
+	grpcStatus := resp.Header.Get("Grpc-Status")
+	if grpcStatus == "" {
+		grpcStatus = resp.Trailer.Get("Grpc-Status")
+	}
+	if len(data) == 0 {
+		return 0, grpcStatus
+	}
+	if len(data) != 7 || data[4] != 2 || data[5] != 0x08 {
+		t.Fatalf("Unexpected HealthCheckResponse %x", data)
+	}
+	return int(data[6]), grpcStatus
+}
+
+func TestGRPCHealthHandler(t *testing.T) {
+	var serving atomic.Bool
+	serving.Store(true)
+	server := httptest.NewUnstartedServer(grpcHealthHandler(serving.Load))
+	server.Config.Protocols = new(http.Protocols)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	defer server.Close()
+
+	if status, code := grpcHealthCheck(t, server.URL, ""); status != grpcServing || code != "0" {
+		t.Errorf("Expected SERVING with status 0, got %d with status %q", status, code)
+	}
+	serving.Store(false)
+	if status, code := grpcHealthCheck(t, server.URL, ""); status != grpcNotServing || code != "0" {
+		t.Errorf("Expected NOT_SERVING with status 0, got %d with status %q", status, code)
+	}
+	if status, code := grpcHealthCheck(t, server.URL, "nexus.Unknown"); status != 0 || code != "5" {
+		t.Errorf("Expected NOT_FOUND for an unknown service, got %d with status %q", status, code)
+	}
+}
+
+func TestReadHealthCheckRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    []byte
+		want    string
+		wantErr bool
+	}{
+		{name: "empty message", body: []byte{0, 0, 0, 0, 0}},
+		{name: "service", body: []byte{0, 0, 0, 0, 3, 0x0a, 1, 'a'}, want: "a"},
+		{name: "unknown field skipped", body: []byte{0, 0, 0, 0, 5, 0x10, 7, 0x0a, 1, 'b'}, want: "b"},
+		{name: "truncated prefix", body: []byte{0, 0}, wantErr: true},
+		{name: "compressed", body: []byte{1, 0, 0, 0, 0}, wantErr: true},
+		{name: "length mismatch", body: []byte{0, 0, 0, 0, 4, 0x0a, 1, 'a'}, wantErr: true},
+		{name: "field overruns message", body: []byte{0, 0, 0, 0, 3, 0x0a, 9, 'a'}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readHealthCheckRequest(bytes.NewReader(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected service %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGRPCHealthNotServingDuringDrain(t *testing.T) {
+	const delay = 500 * time.Millisecond
+	testConfig := &interfaces.Config{
+		ListenPort:     8217,
+		GRPCHealthPort: 8218,
+		TargetURL:      "http://example.com",
+		Limits: interfaces.Limits{
+			RequestsPerSecond:    10,
+			Burst:                10,
+			ModelTokensPerMinute: 60000,
+		},
+		Server: interfaces.ServerConfig{PreShutdownDelay: delay},
+	}
+
+	cont := container.New()
+	cont.SetLogger(logging.NewNoOpLogger())
+	cont.SetConfigLoader(config.NewMemoryLoader(testConfig))
+	if err := cont.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize container: %v", err)
+	}
+
+	service := NewService(cont)
+	if err := service.Start(); err != nil {
+		t.Fatalf("Failed to start service: %v", err)
+	}
+
+	const healthURL = "http://localhost:8218"
+	if status, _ := grpcHealthCheck(t, healthURL, ""); status != grpcServing {
+		t.Fatalf("Expected SERVING while ready, got %d", status)
+	}
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- service.Stop() }()
+
+	// The status flips while the gateway still serves out the delay
+	deadline := time.Now().Add(delay / 2)
+	for {
+		status, _ := grpcHealthCheck(t, healthURL, "")
+		if status == grpcNotServing {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected NOT_SERVING as soon as Stop began")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := <-stopped; err != nil {
+		t.Fatalf("Failed to stop service: %v", err)
+	}
+	if _, err := http.Get(healthURL); err == nil {
+		t.Error("Expected the gRPC health server to be shut down after Stop")
+	}
+}
@@ -2,20 +2,61 @@ package gateway
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/jamesprial/nexus/internal/interfaces"
 	"github.com/jamesprial/nexus/internal/metrics"
+	"github.com/jamesprial/nexus/internal/middleware"
+	"github.com/jamesprial/nexus/internal/utils"
 )
 
 // Service implements interfaces.Gateway using dependency injection
 type Service struct {
-	container interfaces.Container
-	server    *http.Server
-	logger    interfaces.Logger
+	container   interfaces.Container
+	server      *http.Server
+	adminServer *http.Server
+	// grpcHealthServer serves grpc.health.v1 when grpc_health_port is set
+	grpcHealthServer *http.Server
+	logger      interfaces.Logger
+	// systemPaths lists the system endpoints registered by Start
+	systemPaths []string
+	// startedAt is when Start last brought the gateway up
+	startedAt time.Time
+	// stopping is set once Stop begins, failing readiness while connections drain
+	stopping atomic.Bool
+}
+
+// version is the gateway version reported by the health and status endpoints
+const version = "1.0.0"
+
+// upstreamHealthChecker is implemented by containers that actively probe
+// their upstreams
+type upstreamHealthChecker interface {
+	StartHealthChecks()
+	StopHealthChecks()
+	UpstreamHealthy() (healthy, checked bool)
+}
+
+// remoteWriteRunner is implemented by containers that push metrics to a
+// remote-write endpoint
+type remoteWriteRunner interface {
+	StartRemoteWrite()
+	StopRemoteWrite()
+}
+
+// chainReporter is implemented by containers that record the middleware
+// chain they build
+type chainReporter interface {
+	MiddlewareChain() []string
 }
 
 // NewService creates a new gateway service with dependency injection
@@ -35,102 +76,358 @@ func (s *Service) Start() error {
 
 	// Create main handler
 	mainHandler := s.container.BuildHandler()
+
+	// Create mux for system endpoints (health, metrics)
+	systemMux := http.NewServeMux()
+	systemPaths := s.registerSystemEndpoints(systemMux, config)
+	s.systemPaths = systemPaths
+
+	// Restrict system endpoints by client IP if configured
+	ipFilter, err := middleware.NewIPFilterMiddleware(
+		config.AdminAccess.Allow,
+		config.AdminAccess.Deny,
+		config.TrustedProxyCount,
+		s.logger,
+	)
+	if err != nil {
+		return fmt.Errorf("invalid admin access configuration: %w", err)
+	}
+	adminHandler := ipFilter(systemMux)
 	
-	// Create mux for routing
 	mux := http.NewServeMux()
-	
-	// Register health endpoint
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		health := map[string]string{
-			"status":  "healthy",
-			"version": "1.0.0",
-		}
-		if err := json.NewEncoder(w).Encode(health); err != nil {
-			s.logger.Error("Failed to encode health response", map[string]any{"error": err})
+	for _, path := range systemPaths {
+		if config.AdminPort > 0 {
+			// Main port serves only proxy traffic; system paths are hidden
+			mux.Handle(path, http.NotFoundHandler())
+		} else {
+			// Without a separate admin port, system endpoints share the main port
+			mux.Handle(path, adminHandler)
 		}
-	})
-	
-	// Register metrics endpoints if metrics are enabled
-	if config.Metrics.Enabled {
-		s.registerMetricsEndpoints(mux, config)
 	}
 	
 	// Register catch-all handler for proxy
 	mux.Handle("/", mainHandler)
+
+	// Normalize paths ahead of the mux, which would redirect repeated slashes
+	var handler http.Handler = mux
+	if config.Proxy.NormalizePaths.Enabled {
+		handler = middleware.NewPathNormalizationMiddleware(config.Proxy.NormalizePaths.StripTrailingSlash)(mux)
+	}
 	
 	listenAddr := fmt.Sprintf(":%d", config.ListenPort)
 	
 	s.server = &http.Server{
-		Addr:         listenAddr,
-		Handler:      mux,
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		Addr:           listenAddr,
+		Handler:        mountAt(config.BasePath, handler),
+		ReadTimeout:    30 * time.Second,
+		WriteTimeout:   30 * time.Second,
+		IdleTimeout:    60 * time.Second,
+		MaxHeaderBytes: maxHeaderBytes(config),
 	}
 
 	if s.logger != nil {
 		s.logger.Info("Starting Nexus gateway", map[string]any{
 			"listen_addr": listenAddr,
 			"target_url":  config.TargetURL,
+			"base_path":   config.BasePath,
 		})
 	}
 
-	// Start server in goroutine so Start() doesn't block
-	errCh := make(chan error, 1)
+	if err := s.serve(s.server, config.TLS); err != nil {
+		return err
+	}
+	s.startedAt = time.Now()
+	s.stopping.Store(false)
+
+	if config.AdminPort > 0 {
+		adminAddr := fmt.Sprintf(":%d", config.AdminPort)
+		s.adminServer = &http.Server{
+			Addr:           adminAddr,
+			Handler:        mountAt(config.BasePath, adminHandler),
+			ReadTimeout:    30 * time.Second,
+			WriteTimeout:   30 * time.Second,
+			IdleTimeout:    60 * time.Second,
+			MaxHeaderBytes: maxHeaderBytes(config),
+		}
+
+		if s.logger != nil {
+			s.logger.Info("Starting admin server", map[string]any{
+				"admin_addr": adminAddr,
+			})
+		}
+
+		if err := s.serve(s.adminServer, config.TLS); err != nil {
+			_ = s.server.Close()
+			return err
+		}
+	}
+
+	if config.GRPCHealthPort > 0 {
+		grpcHealthAddr := fmt.Sprintf(":%d", config.GRPCHealthPort)
+		server := newGRPCHealthServer(grpcHealthAddr, func() bool { return s.readiness() == "ready" })
+
+		if s.logger != nil {
+			s.logger.Info("Starting gRPC health server", map[string]any{
+				"grpc_health_addr": grpcHealthAddr,
+			})
+		}
+
+		// Probes speak plaintext HTTP/2, so TLS never applies here
+		if err := s.serve(server, nil); err != nil {
+			_ = s.server.Close()
+			if s.adminServer != nil {
+				_ = s.adminServer.Close()
+			}
+			return err
+		}
+		s.grpcHealthServer = server
+	}
+
+	// Background work starts only once every listener is bound, so a failed
+	// Start leaves nothing running
+	if hc, ok := s.container.(upstreamHealthChecker); ok {
+		hc.StartHealthChecks()
+	}
+	if rw, ok := s.container.(remoteWriteRunner); ok {
+		rw.StartRemoteWrite()
+	}
+
+	return nil
+}
+
+// maxHeaderBytes is the configured request header size limit, defaulting to
+// net/http's 1MB. Larger requests are answered with 431 by the server.
+func maxHeaderBytes(config *interfaces.Config) int {
+	if config.Server.MaxHeaderBytes > 0 {
+		return config.Server.MaxHeaderBytes
+	}
+	return http.DefaultMaxHeaderBytes
+}
+
+// mountAt serves h under basePath. The prefix is stripped, so routing,
+// middleware and the upstream all see paths as if the gateway were mounted at
+// "/"; requests outside basePath get 404.
+func mountAt(basePath string, h http.Handler) http.Handler {
+	if basePath == "" {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, ok := strings.CutPrefix(r.URL.Path, basePath)
+		if !ok || (rest != "" && !strings.HasPrefix(rest, "/")) {
+			http.NotFound(w, r)
+			return
+		}
+		if rest == "" {
+			rest = "/"
+		}
+
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = rest
+		if r.URL.RawPath != "" {
+			r2.URL.RawPath = strings.TrimPrefix(r.URL.RawPath, basePath)
+			if r2.URL.RawPath == "" {
+				r2.URL.RawPath = "/"
+			}
+		}
+		h.ServeHTTP(w, r2)
+	})
+}
+
+// serve binds the server's address and then serves it in a goroutine. Binding
+// and loading TLS certificates happen synchronously, so a port that is already
+// in use or a bad certificate is reported to the caller instead of being lost.
+func (s *Service) serve(server *http.Server, tlsConfig *interfaces.TLSConfig) error {
+	useTLS := tlsConfig != nil && tlsConfig.Enabled
+	if useTLS {
+		cert, err := tls.LoadX509KeyPair(tlsConfig.CertFile, tlsConfig.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
+	ln, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to start server: %w", err)
+	}
+
 	go func() {
 		var err error
-		if config.TLS != nil && config.TLS.Enabled {
+		if useTLS {
 			if s.logger != nil {
 				s.logger.Info("Starting HTTPS server", map[string]any{
-					"cert_file": config.TLS.CertFile,
-					"key_file":  config.TLS.KeyFile,
+					"addr":      server.Addr,
+					"cert_file": tlsConfig.CertFile,
+					"key_file":  tlsConfig.KeyFile,
 				})
 			}
-			err = s.server.ListenAndServeTLS(config.TLS.CertFile, config.TLS.KeyFile)
+			err = server.ServeTLS(ln, "", "")
 		} else {
 			if s.logger != nil {
-				s.logger.Info("Starting HTTP server (no TLS)", map[string]any{})
+				s.logger.Info("Starting HTTP server (no TLS)", map[string]any{
+					"addr": server.Addr,
+				})
 			}
-			err = s.server.ListenAndServe()
+			err = server.Serve(ln)
 		}
-		
-		if err != nil && err != http.ErrServerClosed {
-			errCh <- err
+
+		if err != nil && err != http.ErrServerClosed && s.logger != nil {
+			s.logger.Error("Server stopped unexpectedly", map[string]any{
+				"addr":  server.Addr,
+				"error": err,
+			})
 		}
-		close(errCh)
 	}()
 
-	// Give the server a moment to start
-	select {
-	case err := <-errCh:
-		return fmt.Errorf("failed to start server: %w", err)
-	case <-time.After(100 * time.Millisecond):
-		// Server started successfully
-		return nil
+	return nil
+}
+
+// registerSystemEndpoints registers health and metrics endpoints on the mux and
+// returns the paths it registered.
+func (s *Service) registerSystemEndpoints(mux *http.ServeMux, config *interfaces.Config) []string {
+	// Register health endpoint
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		status := s.status()
+		w.Header().Set("Content-Type", "application/json")
+		if status != "healthy" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		health := map[string]string{
+			"status":  status,
+			"version": version,
+		}
+		if err := json.NewEncoder(w).Encode(health); err != nil {
+			s.logger.Error("Failed to encode health response", map[string]any{"error": err})
+		}
+	})
+	paths := []string{"/health"}
+
+	// Register readiness endpoint; it fails as soon as Stop begins so load
+	// balancers stop routing here before connections are drained
+	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		status := s.readiness()
+		w.Header().Set("Content-Type", "application/json")
+		if status != "ready" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"status": status})
+	})
+	paths = append(paths, "/ready")
+	
+	// Register metrics endpoints if metrics are enabled
+	if config.Metrics.Enabled {
+		paths = append(paths, s.registerMetricsEndpoints(mux, config)...)
+	}
+
+	// Register admin endpoints behind admin key authentication
+	adminAuth := middleware.NewAdminAuthMiddleware(config.AdminAccess.APIKeys, s.logger)
+	mux.Handle("/admin/routes", adminAuth(http.HandlerFunc(s.handleRoutes)))
+	paths = append(paths, "/admin/routes")
+	mux.Handle("/admin/status", adminAuth(http.HandlerFunc(s.handleStatus)))
+	paths = append(paths, "/admin/status")
+	if _, ok := s.container.(chainReporter); ok {
+		mux.Handle("/admin/chain", adminAuth(http.HandlerFunc(s.handleChain)))
+		paths = append(paths, "/admin/chain")
+	}
+	if config.Metrics.Enabled && s.container.MetricsCollector() != nil {
+		mux.Handle("/admin/keys", adminAuth(http.HandlerFunc(s.handleKeys)))
+		paths = append(paths, "/admin/keys")
+	}
+
+	return paths
+}
+
+// handleRoutes serves the route table as JSON
+func (s *Service) handleRoutes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"routes": s.Routes()}); err != nil {
+		s.logger.Error("Failed to encode routes response", map[string]any{"error": err})
+	}
+}
+
+// handleStatus serves Status as JSON
+func (s *Service) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.Status()); err != nil {
+		s.logger.Error("Failed to encode status response", map[string]any{"error": err})
 	}
 }
 
+// handleChain serves the middleware wrapping the proxy as JSON, outermost first
+func (s *Service) handleChain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	chain := s.container.(chainReporter).MiddlewareChain()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"middleware": chain}); err != nil {
+		s.logger.Error("Failed to encode chain response", map[string]any{"error": err})
+	}
+}
+
+// handleKeys serves the per-key metrics as JSON with API keys masked. The
+// optional prefix query parameter limits the keys to those starting with it.
+func (s *Service) handleKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	exporter := metrics.NewMetricsExporter(s.container.MetricsCollector())
+	data, err := exporter.ExportJSONFiltered(metrics.ExportFilter{Prefix: r.URL.Query().Get("prefix")})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to export keys: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
 // Stop implements interfaces.Gateway.Stop
 func (s *Service) Stop() error {
 	if s.server == nil {
 		return nil
 	}
 
+	// Fail readiness first so load balancers stop sending new requests
+	s.stopping.Store(true)
+
 	if s.logger != nil {
 		s.logger.Info("Stopping Nexus gateway", map[string]any{})
 	}
 
-	// Export metrics before shutdown if enabled
+	// Log a summary of the final metrics before shutdown if enabled
 	config := s.container.Config()
-	if config != nil && config.Metrics.Enabled {
-		collector := s.container.MetricsCollector()
-		if collector != nil {
-			metricsData := collector.GetMetrics()
-			if s.logger != nil {
-				s.logger.Info("Final metrics before shutdown", metricsData)
-			}
+	if config != nil && config.Metrics.Enabled && s.logger != nil {
+		if summary, ok := s.metricsSummary(); ok {
+			s.logger.Info("Final metrics before shutdown", summary.LogFields())
+		}
+	}
+
+	// Keep serving while load balancers notice the failed readiness
+	if config != nil && config.Server.PreShutdownDelay > 0 {
+		if s.logger != nil {
+			s.logger.Info("Waiting before shutdown", map[string]any{
+				"pre_shutdown_delay": config.Server.PreShutdownDelay.String(),
+			})
 		}
+		time.Sleep(config.Server.PreShutdownDelay)
 	}
 
 	// Create context with timeout for graceful shutdown
@@ -143,6 +440,17 @@ func (s *Service) Stop() error {
 	// Shutdown will wait for active connections to complete
 	shutdownErr := s.server.Shutdown(ctx)
 
+	// The admin and gRPC health servers shut down alongside the main server
+	for _, server := range []*http.Server{s.adminServer, s.grpcHealthServer} {
+		if server == nil {
+			continue
+		}
+		server.SetKeepAlivesEnabled(false)
+		if err := server.Shutdown(ctx); err != nil && shutdownErr == nil {
+			shutdownErr = err
+		}
+	}
+
 	if s.logger != nil {
 		if shutdownErr != nil {
 			s.logger.Error("Error during graceful shutdown", map[string]any{"error": shutdownErr})
@@ -151,15 +459,115 @@ func (s *Service) Stop() error {
 		}
 	}
 
+	// Persist a final snapshot once no more requests can be recorded
+	if config != nil && config.Metrics.Enabled && config.Metrics.DumpOnShutdownPath != "" {
+		s.dumpMetrics(config.Metrics.DumpOnShutdownPath)
+	}
+	if config != nil && config.Metrics.Enabled && config.Metrics.SnapshotPath != "" {
+		s.saveMetricsSnapshot(config.Metrics.SnapshotPath)
+	}
+
+	// Stop background work, send spans from the last requests to the
+	// collector and release the container's connections
+	if closer, ok := s.container.(io.Closer); ok {
+		if err := closer.Close(); err != nil && s.logger != nil {
+			s.logger.Warn("Failed to release resources", map[string]any{"error": err.Error()})
+		}
+	}
+
 	return shutdownErr
 }
 
-// Health implements interfaces.Gateway.Health
+// dumpMetrics writes the final metrics export to path. A failure is logged
+// rather than returned so it never holds up shutdown.
+func (s *Service) dumpMetrics(path string) {
+	collector := s.container.MetricsCollector()
+	if collector == nil {
+		return
+	}
+
+	if err := metrics.NewMetricsExporter(collector).WriteFile(path); err != nil {
+		if s.logger != nil {
+			s.logger.Error("Failed to dump metrics on shutdown", map[string]any{
+				"path":  path,
+				"error": err.Error(),
+			})
+		}
+		return
+	}
+	if s.logger != nil {
+		s.logger.Info("Dumped metrics on shutdown", map[string]any{"path": path})
+	}
+}
+
+// saveMetricsSnapshot saves the collector's counters to path so the next start
+// can continue from them. A failure is logged rather than returned.
+func (s *Service) saveMetricsSnapshot(path string) {
+	collector, ok := s.container.MetricsCollector().(*metrics.MetricsCollector)
+	if !ok {
+		return
+	}
+
+	if err := collector.SaveSnapshotFile(path); err != nil {
+		if s.logger != nil {
+			s.logger.Error("Failed to save metrics snapshot", map[string]any{
+				"path":  path,
+				"error": err.Error(),
+			})
+		}
+		return
+	}
+	if s.logger != nil {
+		s.logger.Info("Saved metrics snapshot", map[string]any{"path": path})
+	}
+}
+
+// metricsSummary returns the collector's summary, reporting false when
+// metrics are disabled
+func (s *Service) metricsSummary() (metrics.Summary, bool) {
+	collector, ok := s.container.MetricsCollector().(*metrics.MetricsCollector)
+	if !ok {
+		return metrics.Summary{}, false
+	}
+	return collector.Summary(), true
+}
+
+// readiness returns "ready" while the gateway should receive traffic,
+// "shutting_down" once Stop begins, and "unhealthy" otherwise. It backs
+// /ready and the gRPC health server.
+func (s *Service) readiness() string {
+	switch {
+	case s.stopping.Load():
+		return "shutting_down"
+	case s.status() != "healthy":
+		return "unhealthy"
+	}
+	return "ready"
+}
+
+// status is "unhealthy" once health checks have marked every upstream
+// unhealthy, and "healthy" otherwise
+func (s *Service) status() string {
+	if hc, ok := s.container.(upstreamHealthChecker); ok {
+		if healthy, checked := hc.UpstreamHealthy(); checked && !healthy {
+			return "unhealthy"
+		}
+	}
+	return "healthy"
+}
+
+// Health implements interfaces.Gateway.Health. With health checks configured,
+// "upstream" reports their stable state.
 func (s *Service) Health() map[string]any {
 	health := map[string]any{
-		"status":    "healthy",
+		"status":    s.status(),
 		"timestamp": time.Now().Format(time.RFC3339),
 	}
+	if hc, ok := s.container.(upstreamHealthChecker); ok {
+		if healthy, checked := hc.UpstreamHealthy(); checked {
+			health["upstream"] = map[string]any{"healthy": healthy}
+		}
+	}
 
 	config := s.container.Config()
 	if config != nil {
@@ -169,14 +577,128 @@ func (s *Service) Health() map[string]any {
 		}
 	}
 
+	if summary, ok := s.metricsSummary(); ok {
+		health["metrics"] = summary
+	}
+
 	return health
 }
 
-// registerMetricsEndpoints registers metrics endpoints with the mux
-func (s *Service) registerMetricsEndpoints(mux *http.ServeMux, config *interfaces.Config) {
+// Status combines health, the metrics summary, uptime and the configuration
+// that shapes traffic into one report for dashboards. Upstream URLs are
+// masked and keys are only counted, so the report is safe to hand to anyone
+// holding an admin key.
+func (s *Service) Status() map[string]any {
+	health := s.Health()
+	// Health reports the raw target URL; the masked one is under "config"
+	delete(health, "config")
+	delete(health, "metrics")
+
+	status := map[string]any{
+		"version": version,
+		"health":  health,
+	}
+	if !s.startedAt.IsZero() {
+		status["started_at"] = s.startedAt.Format(time.RFC3339)
+		status["uptime_seconds"] = int64(time.Since(s.startedAt).Seconds())
+	}
+	if summary, ok := s.metricsSummary(); ok {
+		status["summary"] = summary
+	}
+
+	config := s.container.Config()
+	if config == nil {
+		return status
+	}
+
+	targets := make([]string, 0, len(config.TargetPool))
+	for _, t := range config.TargetPool {
+		targets = append(targets, utils.MaskURL(t.URL))
+	}
+	status["config"] = map[string]any{
+		"listen_port":     config.ListenPort,
+		"admin_port":      config.AdminPort,
+		"base_path":       config.BasePath,
+		"target_url":      utils.MaskURL(config.TargetURL),
+		"target_pool":     targets,
+		"api_keys":        len(config.APIKeys),
+		"tls_enabled":     config.TLS != nil && config.TLS.Enabled,
+		"metrics_enabled": config.Metrics.Enabled,
+		"tracing_enabled": config.Tracing.OTLPEndpoint != "",
+	}
+
+	limits := config.Limits
+	status["limits"] = map[string]any{
+		"requests_per_second":     limits.RequestsPerSecond,
+		"burst":                   limits.Burst,
+		"model_tokens_per_minute": limits.ModelTokensPerMinute,
+		"disable_token_limit":     limits.DisableTokenLimit,
+		"rate_limit_by":           limits.RateLimitBy,
+		"max_concurrent":          limits.MaxConcurrent,
+		"max_upstream_concurrent": limits.MaxUpstreamConcurrent,
+		"scheduling":              limits.Scheduling,
+		"max_wait":                limits.MaxWait.String(),
+		"max_queue":               limits.MaxQueue,
+		"shadow":                  limits.Shadow,
+		"soft_limit_threshold":    limits.SoftLimitThreshold,
+		"per_key_overrides":       len(config.PerKeyLimits),
+	}
+	return status
+}
+
+// Routes implements interfaces.Gateway.Routes. It lists the proxy route with
+// its upstream target, credentials masked, followed by the system endpoints
+// registered by Start, all beneath base_path.
+func (s *Service) Routes() []interfaces.Route {
+	config := s.container.Config()
+	if config == nil {
+		return nil
+	}
+
+	systemPort := config.ListenPort
+	if config.AdminPort > 0 {
+		systemPort = config.AdminPort
+	}
+
+	target := utils.MaskURL(config.TargetURL)
+	if len(config.TargetPool) > 0 {
+		targets := make([]string, len(config.TargetPool))
+		for i, t := range config.TargetPool {
+			targets[i] = utils.MaskURL(t.URL)
+		}
+		target = strings.Join(targets, ",")
+	}
+
+	routes := []interfaces.Route{{
+		Path:   config.BasePath + "/",
+		Type:   "proxy",
+		Target: target,
+		Port:   config.ListenPort,
+	}}
+	for _, route := range config.Routing.Routes {
+		routes = append(routes, interfaces.Route{
+			Path:   config.BasePath + route.PathPrefix,
+			Type:   "proxy",
+			Target: utils.MaskURL(route.Target),
+			Port:   config.ListenPort,
+		})
+	}
+	for _, path := range s.systemPaths {
+		routes = append(routes, interfaces.Route{
+			Path: config.BasePath + path,
+			Type: "system",
+			Port: systemPort,
+		})
+	}
+	return routes
+}
+
+// registerMetricsEndpoints registers metrics endpoints with the mux and
+// returns the registered paths, or nil if no collector is available
+func (s *Service) registerMetricsEndpoints(mux *http.ServeMux, config *interfaces.Config) []string {
 	collector := s.container.MetricsCollector()
 	if collector == nil {
-		return
+		return nil
 	}
 
 	// Create exporter
@@ -200,6 +722,17 @@ func (s *Service) registerMetricsEndpoints(mux *http.ServeMux, config *interface
 	
 	metricsHandler := metrics.AuthenticatedExportHandler(exporter, &config.Metrics, allowedKeys)
 	mux.Handle(metricsEndpoint, metricsHandler)
+	paths := []string{metricsEndpoint}
+
+	// The aggregate summary accepts its own, less privileged keys as well as
+	// the full metrics keys, and never includes per-key data
+	if concrete, ok := collector.(*metrics.MetricsCollector); ok {
+		summaryKeys := append(append([]string(nil), config.Metrics.SummaryKeys...), allowedKeys...)
+		requireAuth := config.Metrics.AuthRequired || len(config.Metrics.SummaryKeys) > 0
+		summaryEndpoint := strings.TrimSuffix(metricsEndpoint, "/") + "/summary"
+		mux.Handle(summaryEndpoint, metrics.SummaryHandler(concrete, requireAuth, summaryKeys))
+		paths = append(paths, summaryEndpoint)
+	}
 
 	if s.logger != nil {
 		s.logger.Info("Registered metrics endpoints", map[string]any{
@@ -208,6 +741,9 @@ func (s *Service) registerMetricsEndpoints(mux *http.ServeMux, config *interface
 			"json_export_enabled": config.Metrics.JSONExportEnabled,
 			"csv_export_enabled":  config.Metrics.CSVExportEnabled,
 			"auth_required":       config.Metrics.AuthRequired,
+			"summary_keys":        len(config.Metrics.SummaryKeys),
 		})
 	}
+
+	return paths
 }
\ No newline at end of file
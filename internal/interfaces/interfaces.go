@@ -12,26 +12,152 @@ type ConfigLoader interface {
 
 // Config represents the application configuration
 type Config struct {
-	ListenPort int
-	TargetURL  string
-	LogLevel   string `yaml:"log_level"`
-	APIKeys    map[string]string
-	Limits     Limits
-	TLS        *TLSConfig
-	Metrics    MetricsConfig `yaml:"metrics"`
+	ListenPort int `yaml:"listen_port"`
+	// AdminPort, when set, serves health/metrics/admin endpoints on a separate port
+	AdminPort int               `yaml:"admin_port"`
+	TargetURL string            `yaml:"target_url"`
+	LogLevel  string            `yaml:"log_level"`
+	// LogOutput selects where logs go: "stdout" (default) or "file"
+	LogOutput string `yaml:"log_output"`
+	// LogFile is the log path when LogOutput is "file"
+	LogFile string `yaml:"log_file"`
+	// LogMaxSizeMB and LogMaxBackups control file rotation; zero uses 100 MB and 5 backups
+	LogMaxSizeMB  int               `yaml:"log_max_size_mb"`
+	LogMaxBackups int               `yaml:"log_max_backups"`
+	APIKeys       map[string]string `yaml:"api_keys"`
+	Limits        Limits            `yaml:"limits"`
+	TLS           *TLSConfig        `yaml:"tls"`
+	Metrics       MetricsConfig     `yaml:"metrics"`
+	Logging       LoggingConfig     `yaml:"logging"`
+	Alerts        AlertsConfig      `yaml:"alerts"`
+	// PerKeyLimits overrides Limits for specific client keys
+	PerKeyLimits map[string]KeyLimits `yaml:"per_key_limits"`
+	// PerKeyTargets sends the requests of specific client keys to their own
+	// upstream URL instead of target_url, such as a customer's own account
+	// or region. Not supported with target_pool.
+	PerKeyTargets map[string]string `yaml:"per_key_targets"`
+	// TrustedProxyCount is the number of reverse proxies in front of the gateway,
+	// used to derive the client IP from X-Forwarded-For
+	TrustedProxyCount int               `yaml:"trusted_proxy_count"`
+	AdminAccess       AdminAccessConfig `yaml:"admin_access"`
+	// AllowedModels restricts which models clients may request; empty allows all
+	AllowedModels []string `yaml:"allowed_models"`
+	// ModelAliases rewrites requested model names before the allow-list check
+	ModelAliases map[string]string `yaml:"model_aliases"`
+	// TargetPool spreads proxy traffic across equivalent upstreams; when set it
+	// is used instead of TargetURL
+	TargetPool []PoolTarget  `yaml:"target_pool"`
+	Billing    BillingConfig `yaml:"billing"`
+	// OptionsMode selects how OPTIONS requests are handled: "authenticate"
+	// (default) like any other request, "passthrough" to the upstream without
+	// auth, or "respond" to answer CORS preflights at the gateway
+	OptionsMode string        `yaml:"options_mode"`
+	Tracing     TracingConfig `yaml:"tracing"`
+	// Transforms selects body transformers by request path prefix
+	Transforms []TransformRoute `yaml:"transforms"`
+	// PathRewrites change the path requests are sent upstream with
+	PathRewrites []PathRewrite `yaml:"path_rewrites"`
+	// Routing sends requests to upstreams by path prefix
+	Routing RoutingConfig `yaml:"routing"`
+	// JSONBody rejects malformed JSON bodies before they reach the upstream
+	JSONBody JSONBodyConfig `yaml:"json_body"`
+	// PublicPaths are served without authentication and accounted under the
+	// anonymous key; a trailing "*" matches by prefix
+	PublicPaths []string `yaml:"public_paths"`
+	// DefaultUpstreamKey is sent upstream for requests to PublicPaths, which
+	// have no client key to map; it is never used for other paths
+	DefaultUpstreamKey string `yaml:"default_upstream_key"`
+	// AuthHeaderPrecedence picks the header used when a request carries both
+	// Authorization and X-API-Key: "authorization" (default) or "x-api-key"
+	AuthHeaderPrecedence string `yaml:"auth_header_precedence"`
+	// BasePath mounts the gateway under a path prefix, such as "/ai": the
+	// prefix is stripped before middleware and proxying, and system endpoints
+	// are served beneath it
+	BasePath string `yaml:"base_path"`
+	// Idempotency replays cached responses for retried Idempotency-Key requests
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	// ResponseCache serves repeated requests to rarely changing endpoints from memory
+	ResponseCache ResponseCacheConfig `yaml:"response_cache"`
+	Proxy         ProxyConfig         `yaml:"proxy"`
+	// UpstreamKeys selects where upstream keys are resolved from
+	UpstreamKeys UpstreamKeysConfig `yaml:"upstream_keys"`
+	// HealthCheck probes upstreams so Health reports their stable state
+	HealthCheck HealthCheckConfig `yaml:"health_check"`
+	// APIVersion restricts the API versions clients may request
+	APIVersion APIVersionConfig `yaml:"api_version"`
+	// Server bounds the request headers the HTTP servers accept and orders
+	// their shutdown
+	Server ServerConfig `yaml:"server"`
+	// Features switches experimental layers ("tracing", "response_cache",
+	// "fair_scheduling") on or off by name; unlisted features stay on
+	Features map[string]bool `yaml:"features"`
+	// Request bounds how long the gateway spends on each request
+	Request RequestConfig `yaml:"request"`
+	// UpstreamIdentity tells the upstream which user sent each request
+	// without revealing their key
+	UpstreamIdentity UpstreamIdentityConfig `yaml:"upstream_identity"`
+	// Maintenance turns proxy traffic away during planned upstream work
+	Maintenance MaintenanceConfig `yaml:"maintenance"`
+	// GRPCHealthPort, when set, serves the grpc.health.v1 protocol over
+	// plaintext HTTP/2 on its own port, reporting the readiness /ready does
+	GRPCHealthPort int `yaml:"grpc_health_port"`
 }
 
 // TLSConfig represents TLS configuration
 type TLSConfig struct {
-	Enabled  bool
-	CertFile string
-	KeyFile  string
+	Enabled  bool   `yaml:"enabled"`
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
 }
 
 type Limits struct {
-	RequestsPerSecond    int
-	Burst                int
-	ModelTokensPerMinute int
+	RequestsPerSecond    int `yaml:"requests_per_second"`
+	Burst                int `yaml:"burst"`
+	ModelTokensPerMinute int `yaml:"model_tokens_per_minute"`
+	// Shadow evaluates limits and counts would-be rejections without enforcing them
+	Shadow bool `yaml:"shadow"`
+	// MaxWait is how long a request may queue for a rate limit token before
+	// being rejected; zero rejects immediately
+	MaxWait time.Duration `yaml:"max_wait"`
+	// MaxQueue caps how many requests may wait at once when MaxWait is set
+	MaxQueue int `yaml:"max_queue"`
+	// TokenEstimator selects how request tokens are counted: "heuristic" (default) or "tiktoken"
+	TokenEstimator string `yaml:"token_estimator"`
+	// RateLimitBy selects how the request rate limiter buckets clients:
+	// "api_key" (default) or "ip", using TrustedProxyCount to find the client IP
+	RateLimitBy string `yaml:"rate_limit_by"`
+	// RateLimitAlgorithm selects how the request rate limiter counts each
+	// client's budget: "token_bucket" (default), "fixed_window" or
+	// "sliding_window_log". Read at startup.
+	RateLimitAlgorithm string `yaml:"rate_limit_algorithm"`
+	// MaxConcurrent caps in-flight requests per key; zero is unlimited
+	MaxConcurrent int `yaml:"max_concurrent"`
+	// DisableTokenLimit turns off token counting and limiting, and with it the
+	// need to buffer request bodies; ModelTokensPerMinute is then ignored
+	DisableTokenLimit bool `yaml:"disable_token_limit"`
+	// RateLimitHeaders adds X-RateLimit-Limit, X-RateLimit-Remaining and
+	// X-RateLimit-Reset to responses from the request rate limiter, and
+	// Retry-After to its rejections
+	RateLimitHeaders bool `yaml:"rate_limit_headers"`
+	// SoftLimitThreshold is the fraction of a key's request or token burst,
+	// between 0 and 1, past which requests are still served but carry an
+	// X-RateLimit-Warning header; zero disables the warning
+	SoftLimitThreshold float64 `yaml:"soft_limit_threshold"`
+	// MaxUpstreamConcurrent caps requests in flight across all keys; requests
+	// beyond it wait for a slot. Zero is unlimited.
+	MaxUpstreamConcurrent int `yaml:"max_upstream_concurrent"`
+	// Scheduling orders requests waiting for an upstream slot: "fifo"
+	// (default) by arrival, or "fair" round-robin between waiting keys
+	Scheduling string `yaml:"scheduling"`
+}
+
+// KeyLimits overrides the global limits for a single client key.
+// Zero values fall back to the corresponding global limit.
+type KeyLimits struct {
+	RequestsPerSecond    int `yaml:"requests_per_second"`
+	Burst                int `yaml:"burst"`
+	ModelTokensPerMinute int `yaml:"model_tokens_per_minute"`
+	MaxConcurrent        int `yaml:"max_concurrent"`
 }
 
 // RateLimiter provides rate limiting functionality
@@ -52,6 +178,24 @@ type TokenCounter interface {
 	CountTokens(r *http.Request) (int, error)
 }
 
+// TokenEstimator approximates token counts from text when exact usage is unavailable
+type TokenEstimator interface {
+	// EstimateTokens returns the approximate number of tokens in text for the given model
+	EstimateTokens(model, text string) int
+}
+
+// RequestTransformer rewrites a client request body into the upstream's API dialect
+type RequestTransformer interface {
+	// TransformRequest returns the body to send upstream in place of body
+	TransformRequest(r *http.Request, body []byte) ([]byte, error)
+}
+
+// ResponseTransformer rewrites an upstream response body into the client's API dialect
+type ResponseTransformer interface {
+	// TransformResponse returns the body to send the client in place of body
+	TransformResponse(resp *http.Response, body []byte) ([]byte, error)
+}
+
 // Proxy handles forwarding requests to upstream services
 type Proxy interface {
 	// ServeHTTP implements http.Handler to proxy requests
@@ -71,6 +215,22 @@ type Gateway interface {
 
 	// Health returns the health status of the gateway
 	Health() map[string]any
+
+	// Routes returns the paths the gateway serves
+	Routes() []Route
+}
+
+// Route describes a path served by the gateway
+type Route struct {
+	// Path is the URL path pattern
+	Path string `json:"path"`
+	// Type is "proxy" for upstream traffic or "system" for gateway endpoints
+	Type string `json:"type"`
+	// Target is the upstream URL for proxy routes, or a comma-separated list for a
+	// target pool, with credentials masked
+	Target string `json:"target,omitempty"`
+	// Port is the port the route is served on
+	Port int `json:"port"`
 }
 
 // Logger provides structured logging
@@ -93,6 +253,21 @@ type KeyManager interface {
 	IsConfigured() bool
 }
 
+// UpstreamKeyProvider resolves the upstream API key for a client key from a
+// source other than the config file, such as a secret manager
+type UpstreamKeyProvider interface {
+	// GetUpstreamKey returns the upstream API key for a client key
+	GetUpstreamKey(clientKey string) (string, error)
+}
+
+// RequestSink receives a record of each completed request. Every
+// MetricsCollector is a RequestSink; lighter sinks suit tests and custom
+// aggregation.
+type RequestSink interface {
+	// RecordRequest records metrics for a completed request
+	RecordRequest(apiKey string, endpoint string, model string, tokens int, statusCode int, duration time.Duration)
+}
+
 // MetricsCollector collects and aggregates metrics for API requests
 type MetricsCollector interface {
 	// RecordRequest records metrics for a completed request
@@ -109,6 +284,13 @@ type MetricsCollector interface {
 	
 	// ResetMetricsForKey clears metrics for a specific API key
 	ResetMetricsForKey(apiKey string)
+
+	// RecordThrottle records a request rejected by a rate limiter of the given type
+	RecordThrottle(apiKey string, limiterType string)
+
+	// GetStats returns statistics about the collector itself, such as how
+	// many keys it is tracking
+	GetStats() map[string]any
 }
 
 // MetricsExporter exports metrics in various formats
@@ -134,9 +316,49 @@ type KeyMetrics struct {
 	TotalRequests       int64 `json:"total_requests"`
 	SuccessfulRequests  int64 `json:"successful_requests"`
 	FailedRequests      int64 `json:"failed_requests"`
+	// CanceledRequests counts the failed requests that were abandoned by the
+	// client before completion rather than failed by the gateway or upstream
+	CanceledRequests    int64 `json:"canceled_requests"`
+	// WebSocketSessions counts the successful requests that upgraded to a
+	// WebSocket session relayed to the upstream
+	WebSocketSessions   int64 `json:"websocket_sessions"`
 	TotalTokensConsumed int64 `json:"total_tokens_consumed"`
+	// TotalPromptTokens and TotalCompletionTokens split the tokens of requests
+	// whose upstream reported input and output usage separately; requests
+	// recorded with only a total count toward TotalTokensConsumed alone
+	TotalPromptTokens     int64 `json:"total_prompt_tokens"`
+	TotalCompletionTokens int64 `json:"total_completion_tokens"`
 	PerEndpoint         map[string]*EndpointMetrics `json:"per_endpoint"`
 	PerModel            map[string]*ModelMetrics `json:"per_model"`
+	// ThrottledRequests counts requests rejected by the rate limiters, keyed by limiter type
+	ThrottledRequests map[string]int64 `json:"throttled_requests,omitempty"`
+	// PerLabel counts requests by the values of the configured label headers,
+	// keyed by label name and then value
+	PerLabel map[string]map[string]int64 `json:"per_label,omitempty"`
+	// LastRequest is when the most recent request for the key was recorded
+	LastRequest time.Time `json:"last_request"`
+	// LastErrorStatus, LastErrorEndpoint and LastErrorTime describe the key's
+	// most recent failed request; they are zero until one is recorded
+	LastErrorStatus   int       `json:"last_error_status"`
+	LastErrorEndpoint string    `json:"last_error_endpoint"`
+	LastErrorTime     time.Time `json:"last_error_time"`
+	// LatencyEWMAMs is an exponentially weighted moving average of request
+	// latency in milliseconds, weighting recent requests more
+	LatencyEWMAMs float64 `json:"latency_ewma_ms"`
+	// Apdex grades the key's latency against the SLO threshold; nil when no
+	// threshold is configured
+	Apdex *ApdexMetrics `json:"apdex,omitempty"`
+}
+
+// ApdexMetrics counts requests by how their latency compares to the SLO
+// threshold T: satisfied within T, tolerated within 4T, and frustrated when
+// slower or failed
+type ApdexMetrics struct {
+	Satisfied  int64 `json:"satisfied"`
+	Tolerated  int64 `json:"tolerated"`
+	Frustrated int64 `json:"frustrated"`
+	// Score is (satisfied + tolerated/2) / total, from 0 (worst) to 1 (best)
+	Score float64 `json:"score"`
 }
 
 // EndpointMetrics holds metrics for a specific endpoint
@@ -160,6 +382,354 @@ type MetricsConfig struct {
 	CSVExportEnabled  bool   `yaml:"csv_export_enabled"`
 	AuthRequired      bool   `yaml:"auth_required"`
 	MaskAPIKeys       bool   `yaml:"mask_api_keys"`
+	// MaxEndpointsPerKey caps distinct endpoints tracked per key; overflow is
+	// folded into an "__other__" entry. Zero means unlimited.
+	MaxEndpointsPerKey int `yaml:"max_endpoints_per_key"`
+	// MaxModelsPerKey caps distinct models tracked per key, like MaxEndpointsPerKey
+	MaxModelsPerKey int `yaml:"max_models_per_key"`
+	// MaxConcurrentExports caps metrics exports in progress at once; further
+	// scrapes get 503 with Retry-After. Zero means 4.
+	MaxConcurrentExports int `yaml:"max_concurrent_exports"`
+	// LabelHeaders maps request header names to label names; each key's
+	// requests are broken down by the values of those headers
+	LabelHeaders map[string]string `yaml:"label_headers"`
+	// MaxLabelValuesPerKey caps distinct values tracked per label and key,
+	// like MaxEndpointsPerKey
+	MaxLabelValuesPerKey int `yaml:"max_label_values_per_key"`
+	// DumpOnShutdownPath, when set, receives a full export during shutdown:
+	// CSV if the path ends in ".csv", JSON otherwise
+	DumpOnShutdownPath string `yaml:"dump_on_shutdown_path"`
+	// SnapshotPath, when set, keeps per-key counters across restarts: they are
+	// loaded from it at startup and saved to it on shutdown
+	SnapshotPath string `yaml:"snapshot_path"`
+	// LatencySampleRate observes one request in every N in the latency
+	// histogram; counters stay exact. Zero or one observes every request.
+	LatencySampleRate int `yaml:"latency_sample_rate"`
+	// SLOLatency is the apdex threshold T reported per key; zero disables apdex
+	SLOLatency time.Duration `yaml:"slo_latency"`
+	// LatencyEWMADecay is the weight, between 0 and 1, of each new request in
+	// the per-key moving average latency; zero means 0.1
+	LatencyEWMADecay float64 `yaml:"latency_ewma_decay"`
+	// ModelJSONPath and TokensJSONPath locate the model and total token count
+	// in upstream JSON responses as dot paths, with numeric segments indexing
+	// arrays; empty means "model" and "usage.total_tokens"
+	ModelJSONPath  string `yaml:"model_json_path"`
+	TokensJSONPath string `yaml:"tokens_json_path"`
+	// SummaryKeys may read the aggregate-only {metrics_endpoint}/summary
+	// without being allowed the full per-key export. When empty, the summary
+	// is open unless AuthRequired is set.
+	SummaryKeys []string `yaml:"summary_keys"`
+	// SuccessStatusCodes lists the response status codes ("422") and
+	// inclusive ranges ("200-299") counted as successful requests; empty
+	// means 200-299
+	SuccessStatusCodes []string `yaml:"success_status_codes"`
+	// PathTemplates record requests whose path matches a pattern under a
+	// shared endpoint; the first match applies, and unmatched paths are
+	// recorded as they are
+	PathTemplates []PathTemplate `yaml:"path_templates"`
+	// RemoteWrite pushes metrics to a Prometheus remote-write endpoint
+	RemoteWrite RemoteWriteConfig `yaml:"remote_write"`
+	// EMF writes metrics to stdout in CloudWatch Embedded Metric Format
+	EMF EMFConfig `yaml:"emf"`
+}
+
+// RemoteWriteConfig controls pushing metrics in the Prometheus remote-write
+// format, for deployments that can't be scraped
+type RemoteWriteConfig struct {
+	// URL receives the pushes; empty disables remote write
+	URL string `yaml:"url"`
+	// Interval between pushes; zero means 1m
+	Interval time.Duration `yaml:"interval"`
+	// Timeout bounds each push; zero means 10s
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// EMFConfig controls writing per-key, per-model request metrics as
+// CloudWatch Embedded Metric Format log lines on stdout, for deployments that
+// ingest metrics from their logs
+type EMFConfig struct {
+	// Enabled turns the EMF log lines on
+	Enabled bool `yaml:"enabled"`
+	// Namespace is the CloudWatch namespace of the metrics; empty means "Nexus"
+	Namespace string `yaml:"namespace"`
+	// Interval between writes, each covering the requests since the last;
+	// zero means 1m
+	Interval time.Duration `yaml:"interval"`
+}
+
+// LoggingConfig represents request logging configuration
+type LoggingConfig struct {
+	// AccessLog enables a single structured log line per completed request
+	AccessLog bool `yaml:"access_log"`
+	// SlowRequestThreshold logs a "slow request" warning for requests taking
+	// longer than this; zero disables it
+	SlowRequestThreshold time.Duration `yaml:"slow_request_threshold"`
+}
+
+// PoolTarget is one upstream in a target pool
+type PoolTarget struct {
+	URL string `yaml:"url"`
+	// Weight is the target's relative share of traffic; zero counts as 1
+	Weight int `yaml:"weight"`
+}
+
+// AdminAccessConfig restricts which client IPs may reach admin endpoints
+type AdminAccessConfig struct {
+	// Allow lists CIDR ranges or addresses admitted; empty admits all not denied
+	Allow []string `yaml:"allow"`
+	// Deny lists CIDR ranges or addresses rejected, taking precedence over Allow
+	Deny []string `yaml:"deny"`
+	// APIKeys lists bearer tokens accepted by /admin endpoints; empty rejects all
+	APIKeys []string `yaml:"api_keys"`
+}
+
+// BillingConfig controls per-request usage headers for downstream billing
+type BillingConfig struct {
+	// Headers adds X-Nexus-Tokens, and X-Nexus-Cost-USD when the model is
+	// priced, to proxied responses. Leave off when clients are untrusted.
+	Headers bool `yaml:"headers"`
+	// Pricing maps model names to their token prices
+	Pricing map[string]ModelPricing `yaml:"pricing"`
+}
+
+// ModelPricing is the USD price of a model's tokens
+type ModelPricing struct {
+	// InputPer1K is the price per 1,000 prompt tokens
+	InputPer1K float64 `yaml:"input_per_1k"`
+	// OutputPer1K is the price per 1,000 completion tokens
+	OutputPer1K float64 `yaml:"output_per_1k"`
+}
+
+// TransformRoute applies a named transformer to requests under a path prefix
+type TransformRoute struct {
+	// PathPrefix matches request paths; the longest matching prefix wins
+	PathPrefix string `yaml:"path_prefix"`
+	// Transformer names a transformer registered with the proxy package
+	Transformer string `yaml:"transformer"`
+}
+
+// PathTemplate records paths matching Pattern, a regular expression, under
+// the endpoint given by Template, which may refer to groups as $1
+type PathTemplate struct {
+	Pattern  string `yaml:"pattern"`
+	Template string `yaml:"template"`
+}
+
+// PathRewrite maps client-facing paths to upstream paths. Set either
+// PathPrefix or Pattern.
+type PathRewrite struct {
+	// PathPrefix is replaced by Replacement in matching paths
+	PathPrefix string `yaml:"path_prefix"`
+	// Pattern is a regular expression whose matches are replaced by
+	// Replacement, which may refer to groups as $1
+	Pattern     string `yaml:"pattern"`
+	Replacement string `yaml:"replacement"`
+}
+
+// RoutingConfig sends requests to an upstream chosen by path. Requests that
+// match no route fall back to TargetURL or are rejected with 404.
+type RoutingConfig struct {
+	// Routes select an upstream by path prefix; the longest matching prefix
+	// wins. Empty disables routing.
+	Routes []TargetRoute `yaml:"routes"`
+	// OnNoMatch is "fallback" (default), sending unrouted requests to
+	// TargetURL, or "reject"
+	OnNoMatch string `yaml:"on_no_match"`
+}
+
+// TargetRoute sends requests under a path prefix to its own upstream
+type TargetRoute struct {
+	PathPrefix string `yaml:"path_prefix"`
+	Target     string `yaml:"target"`
+}
+
+// JSONBodyConfig selects which write requests must carry a well-formed JSON body
+type JSONBodyConfig struct {
+	// Paths are request path prefixes whose POST, PUT and PATCH bodies are
+	// checked; empty disables the check
+	Paths []string `yaml:"paths"`
+	// MaxBytes caps how much of a body is read for the check; larger bodies
+	// are rejected. Zero uses the default of 10MB.
+	MaxBytes int64 `yaml:"max_bytes"`
+}
+
+// IdempotencyConfig controls the Idempotency-Key response cache
+type IdempotencyConfig struct {
+	// TTL is how long a successful response is replayed for; zero disables
+	// idempotency key support
+	TTL time.Duration `yaml:"ttl"`
+	// MaxEntries bounds how many responses are cached; zero uses the default of 1000
+	MaxEntries int `yaml:"max_entries"`
+}
+
+// APIVersionConfig selects the API versions clients may send
+type APIVersionConfig struct {
+	// Header carries the version; empty means "X-API-Version"
+	Header string `yaml:"header"`
+	// Supported lists the accepted versions; empty disables version checks
+	Supported []string `yaml:"supported"`
+	// Default applies to requests without the header; empty rejects them
+	Default string `yaml:"default"`
+}
+
+// ResponseCacheConfig controls the in-memory cache of upstream responses
+type ResponseCacheConfig struct {
+	// MaxEntries bounds how many responses are cached; zero uses the default of 1000
+	MaxEntries int `yaml:"max_entries"`
+	// Routes selects the cacheable paths; empty disables the cache
+	Routes []CacheRoute `yaml:"routes"`
+}
+
+// CacheRoute caches responses to requests under a path prefix
+type CacheRoute struct {
+	// PathPrefix matches request paths; the longest matching prefix wins
+	PathPrefix string `yaml:"path_prefix"`
+	// TTL is how long a response is served from the cache
+	TTL time.Duration `yaml:"ttl"`
+	// Methods lists the cacheable methods, GET or HEAD; empty means GET
+	Methods []string `yaml:"methods"`
+}
+
+// ProxyConfig controls how upstream responses are relayed
+type ProxyConfig struct {
+	// MaxResponseBytes caps the size of an upstream response body, streamed
+	// or not; zero is unlimited
+	MaxResponseBytes int64 `yaml:"max_response_bytes"`
+	// WarmUpConnections pre-dials this many connections to each upstream at
+	// startup so early requests skip the TLS handshake; zero disables warm-up
+	WarmUpConnections int `yaml:"warmup_connections"`
+	// WarmUpTimeout bounds the startup warm-up; zero means 5s
+	WarmUpTimeout time.Duration `yaml:"warmup_timeout"`
+	// BlockedMethods are rejected with 405 on every path
+	BlockedMethods []string `yaml:"blocked_methods"`
+	// BlockedMethodRoutes block further methods under path prefixes
+	BlockedMethodRoutes []BlockedMethodRoute `yaml:"blocked_method_routes"`
+	// ForwardTrailers passes HTTP trailers sent by the upstream, such as
+	// checksums after a chunked body, on to clients. Nil means true.
+	ForwardTrailers *bool `yaml:"forward_trailers"`
+	// NormalizePaths rewrites request paths to one form before they are
+	// routed, recorded or proxied
+	NormalizePaths PathNormalizationConfig `yaml:"normalize_paths"`
+	// WebSockets relays WebSocket upgrade requests to the upstream past the
+	// body-reading layers; when false they are refused with 501. Read at startup.
+	WebSockets bool `yaml:"websockets"`
+}
+
+// PathNormalizationConfig controls how request paths are normalized, so
+// that spellings of the same path share metrics and reach the upstream alike
+type PathNormalizationConfig struct {
+	// Enabled collapses repeated slashes, so //v1//models becomes /v1/models
+	Enabled bool `yaml:"enabled"`
+	// StripTrailingSlash also removes a trailing slash from paths other than /
+	StripTrailingSlash bool `yaml:"strip_trailing_slash"`
+}
+
+// ServerConfig bounds the request headers the gateway accepts, protecting it
+// from header bombs the way the body limit protects it from large bodies
+type ServerConfig struct {
+	// MaxHeaderBytes caps the size of the request line and headers, answered
+	// with 431 by the HTTP server; zero means 1MB
+	MaxHeaderBytes int `yaml:"max_header_bytes"`
+	// MaxHeaderCount caps the number of header values in a request, answered
+	// with 431; zero means 100
+	MaxHeaderCount int `yaml:"max_header_count"`
+	// PreShutdownDelay is how long Stop reports not-ready on /ready before
+	// it stops accepting connections, so load balancers can stop routing to
+	// the gateway first; zero shuts down immediately
+	PreShutdownDelay time.Duration `yaml:"pre_shutdown_delay"`
+}
+
+// RequestConfig bounds the handling of each request
+type RequestConfig struct {
+	// MaxTotalDuration is the time budget for a request, covering waits for
+	// limits and the upstream call; a request over it gets 504. Zero means no budget.
+	MaxTotalDuration time.Duration `yaml:"max_total_duration"`
+}
+
+// UpstreamIdentityConfig sends the upstream a stable, anonymized identifier
+// for each client key, for upstream-side per-user limits and abuse tracking
+type UpstreamIdentityConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Header carries the identifier; empty means X-Upstream-User
+	Header string `yaml:"header"`
+	// Salt keys the hash of the client key. Keep it secret: anyone holding
+	// it can test guessed keys against the identifiers.
+	Salt string `yaml:"salt"`
+}
+
+// MaintenanceConfig answers every proxied request with an error while the
+// upstream is under maintenance. Health, readiness, metrics and admin
+// endpoints are unaffected. Reloadable.
+type MaintenanceConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Status is the response status; zero means 503
+	Status int `yaml:"status"`
+	// Message is the error message shown to clients; empty means a default
+	Message string `yaml:"message"`
+	// RetryAfter is sent in the Retry-After header, in whole seconds rounded
+	// up; zero means one minute
+	RetryAfter time.Duration `yaml:"retry_after"`
+}
+
+// BlockedMethodRoute blocks methods for requests under a path prefix, in
+// addition to the globally blocked ones
+type BlockedMethodRoute struct {
+	// PathPrefix matches request paths; the longest matching prefix wins
+	PathPrefix string   `yaml:"path_prefix"`
+	Methods    []string `yaml:"methods"`
+}
+
+// UpstreamKeysConfig selects the provider that resolves upstream keys
+type UpstreamKeysConfig struct {
+	// Provider is "config" (default) to map keys with api_keys, "env" to read
+	// them from environment variables, or a name registered with the auth package
+	Provider string `yaml:"provider"`
+	// EnvPrefix prefixes the variables read by the env provider; empty means
+	// "NEXUS_UPSTREAM_KEY_"
+	EnvPrefix string `yaml:"env_prefix"`
+	// CacheTTL is how long keys from a provider other than config are cached;
+	// zero means 5m
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+}
+
+// HealthCheckConfig controls active probing of the upstream
+type HealthCheckConfig struct {
+	// Interval between probes of each upstream; zero disables health checks
+	Interval time.Duration `yaml:"interval"`
+	// Timeout bounds each probe; zero means 2s
+	Timeout time.Duration `yaml:"timeout"`
+	// Path is requested on the upstream; empty means "/". Any response below
+	// 500 counts as healthy.
+	Path string `yaml:"path"`
+	// UnhealthyThreshold is how many consecutive failed probes mark an
+	// upstream unhealthy; zero means 3
+	UnhealthyThreshold int `yaml:"unhealthy_threshold"`
+	// HealthyThreshold is how many consecutive successful probes mark an
+	// unhealthy upstream healthy again; zero means 2
+	HealthyThreshold int `yaml:"healthy_threshold"`
+}
+
+// TracingConfig controls request tracing
+type TracingConfig struct {
+	// OTLPEndpoint is the OTLP/HTTP collector spans are sent to, such as
+	// "http://localhost:4318"; empty disables tracing
+	OTLPEndpoint string `yaml:"otlp_endpoint"`
+}
+
+// AlertsConfig represents error-rate alerting configuration
+type AlertsConfig struct {
+	// ErrorRateThreshold is the failed/total ratio (0-1) that triggers an alert
+	ErrorRateThreshold float64 `yaml:"error_rate_threshold"`
+	// WebhookURL receives a JSON POST when the threshold is crossed
+	WebhookURL string `yaml:"webhook_url"`
+	// Cooldown is the minimum time between alerts for the same scope
+	Cooldown time.Duration `yaml:"cooldown"`
+	// Window is the rolling window over which the error rate is computed
+	Window time.Duration `yaml:"window"`
+	// MinRequests is the minimum number of requests in the window before alerting
+	MinRequests int `yaml:"min_requests"`
+	// PerKey evaluates the error rate per API key instead of globally
+	PerKey bool `yaml:"per_key"`
 }
 
 // Container holds application dependencies and provides dependency injection
@@ -0,0 +1,158 @@
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+const (
+	defaultMaxSizeMB  = 100
+	defaultMaxBackups = 5
+)
+
+// FileLogger implements interfaces.Logger by writing to a file that is rotated
+// once it grows past a size threshold.
+type FileLogger struct {
+	SlogLogger
+	level  *slog.LevelVar
+	writer *rotatingWriter
+}
+
+// NewFileLogger creates a logger that writes to path, rotating it to path.1,
+// path.2, ... once it exceeds maxSizeMB and keeping at most maxBackups rotated
+// files. Non-positive values fall back to 100 MB and 5 backups. The logger
+// starts at info level; use SetLevel to change it.
+func NewFileLogger(path string, maxSizeMB int, maxBackups int) (*FileLogger, error) {
+	if maxSizeMB <= 0 {
+		maxSizeMB = defaultMaxSizeMB
+	}
+	if maxBackups <= 0 {
+		maxBackups = defaultMaxBackups
+	}
+
+	w, err := newRotatingWriter(path, int64(maxSizeMB)*1024*1024, maxBackups)
+	if err != nil {
+		return nil, err
+	}
+
+	level := &slog.LevelVar{}
+	handler := slog.NewTextHandler(w, &slog.HandlerOptions{Level: level})
+
+	return &FileLogger{
+		SlogLogger: SlogLogger{logger: slog.New(handler)},
+		level:      level,
+		writer:     w,
+	}, nil
+}
+
+// SetLevel changes the minimum level written to the file.
+func (f *FileLogger) SetLevel(levelStr string) {
+	f.level.Set(parseLevel(levelStr))
+}
+
+// Close closes the underlying log file.
+func (f *FileLogger) Close() error {
+	return f.writer.Close()
+}
+
+// rotatingWriter is an io.Writer over a file that rotates by size. Writes are
+// serialized so concurrent log calls never interleave or race a rotation.
+type rotatingWriter struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func newRotatingWriter(path string, maxSize int64, maxBackups int) (*rotatingWriter, error) {
+	w := &rotatingWriter{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write implements io.Writer, rotating first if p would push the file past maxSize.
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, fmt.Errorf("log file %s is closed", w.path)
+	}
+
+	if w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close closes the current file; later writes fail.
+func (w *rotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// open opens the log file for appending and records its current size.
+func (w *rotatingWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	w.file = file
+	w.size = info.Size()
+	return nil
+}
+
+// rotate shifts path.N-1 to path.N down to path to path.1, dropping the oldest
+// backup, then reopens an empty file at path. Callers must hold w.mu.
+func (w *rotatingWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	w.file = nil
+
+	if err := os.Remove(w.backupName(w.maxBackups)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to prune log backup: %w", err)
+	}
+	for i := w.maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(w.backupName(i), w.backupName(i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate log backup: %w", err)
+		}
+	}
+	if err := os.Rename(w.path, w.backupName(1)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+
+	return w.open()
+}
+
+// backupName returns the path of the n-th most recent backup
+func (w *rotatingWriter) backupName(n int) string {
+	return fmt.Sprintf("%s.%d", w.path, n)
+}
@@ -0,0 +1,135 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFileLogger_WritesAndFiltersByLevel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nexus.log")
+
+	logger, err := NewFileLogger(path, 1, 1)
+	if err != nil {
+		t.Fatalf("NewFileLogger() error = %v", err)
+	}
+	defer logger.Close()
+	logger.SetLevel("warn")
+
+	logger.Info("hidden message", nil)
+	logger.Warn("visible message", map[string]any{"key": "value"})
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if strings.Contains(string(data), "hidden message") {
+		t.Error("Expected info message to be filtered at warn level")
+	}
+	if !strings.Contains(string(data), "visible message") || !strings.Contains(string(data), "key=value") {
+		t.Errorf("Expected warn message with fields in log file, got %q", data)
+	}
+}
+
+func TestFileLogger_InvalidPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "nexus.log")
+	if _, err := NewFileLogger(path, 1, 1); err == nil {
+		t.Error("Expected error for a log path in a missing directory")
+	}
+}
+
+func TestRotatingWriter_RotatesPastThreshold(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nexus.log")
+
+	w, err := newRotatingWriter(path, 10, 3)
+	if err != nil {
+		t.Fatalf("newRotatingWriter() error = %v", err)
+	}
+	defer w.Close()
+
+	w.Write([]byte("first-line\n"))
+	w.Write([]byte("second-line\n"))
+
+	current, _ := os.ReadFile(path)
+	if string(current) != "second-line\n" {
+		t.Errorf("Expected current file to hold the latest write, got %q", current)
+	}
+	backup, err := os.ReadFile(path + ".1")
+	if err != nil {
+		t.Fatalf("Expected backup %s.1 after rotation: %v", path, err)
+	}
+	if string(backup) != "first-line\n" {
+		t.Errorf("Expected backup to hold the earlier write, got %q", backup)
+	}
+}
+
+func TestRotatingWriter_PrunesOldBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nexus.log")
+
+	w, err := newRotatingWriter(path, 1, 2)
+	if err != nil {
+		t.Fatalf("newRotatingWriter() error = %v", err)
+	}
+	defer w.Close()
+
+	for i := 1; i <= 5; i++ {
+		w.Write([]byte(fmt.Sprintf("line-%d\n", i)))
+	}
+
+	expected := map[string]string{
+		path:        "line-5\n",
+		path + ".1": "line-4\n",
+		path + ".2": "line-3\n",
+	}
+	for name, want := range expected {
+		got, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected backups beyond max_backups to be pruned, stat error = %v", err)
+	}
+}
+
+func TestFileLogger_ConcurrentWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nexus.log")
+
+	logger, err := NewFileLogger(path, 1, 1)
+	if err != nil {
+		t.Fatalf("NewFileLogger() error = %v", err)
+	}
+	defer logger.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				logger.Info("concurrent", map[string]any{"worker": i})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 200 {
+		t.Fatalf("Expected 200 log lines, got %d", len(lines))
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, "msg=concurrent") {
+			t.Fatalf("Found interleaved log line: %q", line)
+		}
+	}
+}
@@ -14,22 +14,8 @@ type SlogLogger struct {
 
 // NewSlogLogger creates a new logger with the specified level.
 func NewSlogLogger(levelStr string) interfaces.Logger {
-	var level slog.Level
-	switch levelStr {
-	case "debug":
-		level = slog.LevelDebug
-	case "info":
-		level = slog.LevelInfo
-	case "warn":
-		level = slog.LevelWarn
-	case "error":
-		level = slog.LevelError
-	default:
-		level = slog.LevelInfo
-	}
-
 	handler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: level,
+		Level: parseLevel(levelStr),
 	})
 
 	return &SlogLogger{
@@ -37,6 +23,20 @@ func NewSlogLogger(levelStr string) interfaces.Logger {
 	}
 }
 
+// parseLevel maps a configured level name to a slog.Level, defaulting to info.
+func parseLevel(levelStr string) slog.Level {
+	switch levelStr {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
 // Debug logs debug messages.
 func (s *SlogLogger) Debug(msg string, fields map[string]any) {
 	s.logger.Debug(msg, fieldsToArgs(fields)...)
@@ -0,0 +1,61 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+// MultiLogger implements interfaces.Logger by fanning each call out to several loggers.
+type MultiLogger struct {
+	loggers []interfaces.Logger
+}
+
+// NewMultiLogger creates a logger that writes every message to all of the given
+// loggers, in order. Nil loggers are skipped. A logger that panics does not stop
+// the remaining loggers from receiving the message.
+func NewMultiLogger(loggers ...interfaces.Logger) interfaces.Logger {
+	m := &MultiLogger{loggers: make([]interfaces.Logger, 0, len(loggers))}
+	for _, l := range loggers {
+		if l != nil {
+			m.loggers = append(m.loggers, l)
+		}
+	}
+	return m
+}
+
+// Debug logs debug messages to every wrapped logger.
+func (m *MultiLogger) Debug(msg string, fields map[string]any) {
+	m.each(func(l interfaces.Logger) { l.Debug(msg, fields) })
+}
+
+// Info logs info messages to every wrapped logger.
+func (m *MultiLogger) Info(msg string, fields map[string]any) {
+	m.each(func(l interfaces.Logger) { l.Info(msg, fields) })
+}
+
+// Warn logs warning messages to every wrapped logger.
+func (m *MultiLogger) Warn(msg string, fields map[string]any) {
+	m.each(func(l interfaces.Logger) { l.Warn(msg, fields) })
+}
+
+// Error logs error messages to every wrapped logger.
+func (m *MultiLogger) Error(msg string, fields map[string]any) {
+	m.each(func(l interfaces.Logger) { l.Error(msg, fields) })
+}
+
+// each calls fn for every logger, isolating failures so one broken logger
+// cannot swallow the message for the others.
+func (m *MultiLogger) each(fn func(interfaces.Logger)) {
+	for _, l := range m.loggers {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					fmt.Fprintf(os.Stderr, "logging: logger %T panicked: %v\n", l, r)
+				}
+			}()
+			fn(l)
+		}()
+	}
+}
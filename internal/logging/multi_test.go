@@ -0,0 +1,67 @@
+package logging
+
+import (
+	"reflect"
+	"testing"
+)
+
+// recordingLogger records every call it receives
+type recordingLogger struct {
+	entries []string
+}
+
+func (r *recordingLogger) Debug(msg string, fields map[string]any) {
+	r.entries = append(r.entries, "debug:"+msg)
+}
+
+func (r *recordingLogger) Info(msg string, fields map[string]any) {
+	r.entries = append(r.entries, "info:"+msg)
+}
+
+func (r *recordingLogger) Warn(msg string, fields map[string]any) {
+	r.entries = append(r.entries, "warn:"+msg)
+}
+
+func (r *recordingLogger) Error(msg string, fields map[string]any) {
+	r.entries = append(r.entries, "error:"+msg)
+}
+
+// panickingLogger simulates a logger whose output has failed
+type panickingLogger struct{}
+
+func (p *panickingLogger) Debug(msg string, fields map[string]any) { panic("write failed") }
+func (p *panickingLogger) Info(msg string, fields map[string]any)  { panic("write failed") }
+func (p *panickingLogger) Warn(msg string, fields map[string]any)  { panic("write failed") }
+func (p *panickingLogger) Error(msg string, fields map[string]any) { panic("write failed") }
+
+func TestMultiLogger_FansOutToAllLoggers(t *testing.T) {
+	first := &recordingLogger{}
+	second := &recordingLogger{}
+	logger := NewMultiLogger(first, nil, second)
+
+	logger.Debug("d", nil)
+	logger.Info("i", map[string]any{"key": "value"})
+	logger.Warn("w", nil)
+	logger.Error("e", nil)
+
+	expected := []string{"debug:d", "info:i", "warn:w", "error:e"}
+	if !reflect.DeepEqual(first.entries, expected) {
+		t.Errorf("First logger got %v, want %v", first.entries, expected)
+	}
+	if !reflect.DeepEqual(second.entries, expected) {
+		t.Errorf("Second logger got %v, want %v", second.entries, expected)
+	}
+}
+
+func TestMultiLogger_FailingLoggerDoesNotBlockOthers(t *testing.T) {
+	healthy := &recordingLogger{}
+	logger := NewMultiLogger(&panickingLogger{}, healthy)
+
+	logger.Info("still delivered", nil)
+	logger.Error("also delivered", nil)
+
+	expected := []string{"info:still delivered", "error:also delivered"}
+	if !reflect.DeepEqual(healthy.entries, expected) {
+		t.Errorf("Healthy logger got %v, want %v", healthy.entries, expected)
+	}
+}
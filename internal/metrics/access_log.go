@@ -0,0 +1,91 @@
+// Package metrics provides comprehensive metrics collection and reporting for the Nexus API gateway.
+package metrics
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+	"github.com/jamesprial/nexus/internal/utils"
+)
+
+// RequestIDHeader is the header used to carry a request identifier through the gateway
+const RequestIDHeader = "X-Request-ID"
+
+// AccessLogMiddleware creates HTTP middleware that emits one structured Info line
+// per completed request, in the spirit of an nginx access log. It should be the
+// outermost middleware so the logged status reflects every rejection made further
+// down the chain.
+func AccessLogMiddleware(logger interfaces.Logger) func(http.Handler) http.Handler {
+	return RequestLogMiddleware(logger, true, 0)
+}
+
+// RequestLogMiddleware is AccessLogMiddleware with the per-request line made
+// optional and a slow request warning added: requests taking longer than
+// slowThreshold are logged at Warn as "slow request" whether or not
+// accessLog is set. A zero threshold disables the warning.
+func RequestLogMiddleware(logger interfaces.Logger, accessLog bool, slowThreshold time.Duration) func(http.Handler) http.Handler {
+	if logger == nil || (!accessLog && slowThreshold <= 0) {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			startTime := time.Now()
+
+			// Capture the client key before auth replaces the Authorization header
+			apiKey := extractAPIKey(r)
+			method := r.Method
+			path := r.URL.Path
+
+			requestID := r.Header.Get(RequestIDHeader)
+			if requestID == "" {
+				requestID = newRequestID()
+				r.Header.Set(RequestIDHeader, requestID)
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+
+			recorder := acquireStatusRecorder(w)
+			next.ServeHTTP(recorder, r)
+			duration := time.Since(startTime)
+			status, size := recorder.Status(), recorder.Size()
+			releaseStatusRecorder(recorder)
+
+			if accessLog {
+				logger.Info("access", map[string]any{
+					"method":      method,
+					"path":        path,
+					"status":      status,
+					"bytes":       size,
+					"duration_ms": duration.Milliseconds(),
+					"client_key":  utils.MaskAPIKey(apiKey),
+					"request_id":  requestID,
+				})
+			}
+			if slowThreshold > 0 && duration > slowThreshold {
+				logger.Warn("slow request", map[string]any{
+					"method":       method,
+					"path":         path,
+					"status":       status,
+					"duration_ms":  duration.Milliseconds(),
+					"threshold_ms": slowThreshold.Milliseconds(),
+					"client_key":   utils.MaskAPIKey(apiKey),
+					"request_id":   requestID,
+				})
+			}
+		})
+	}
+}
+
+// newRequestID generates a random 16-byte hex request identifier
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
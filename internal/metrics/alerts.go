@@ -0,0 +1,291 @@
+// Package metrics provides comprehensive metrics collection and reporting for the Nexus API gateway.
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+	"github.com/jamesprial/nexus/internal/utils"
+)
+
+const (
+	// defaultAlertWindow is the rolling window used when none is configured
+	defaultAlertWindow = time.Minute
+	// defaultAlertCooldown is the minimum time between alerts when none is configured
+	defaultAlertCooldown = 5 * time.Minute
+	// defaultAlertMinRequests avoids alerting on a handful of requests
+	defaultAlertMinRequests = 10
+	// maxAlertEvents bounds the per-scope event history
+	maxAlertEvents = 10000
+	// globalAlertScope is the scope name used when alerting across all keys
+	globalAlertScope = "global"
+)
+
+// ErrorRateAlert is the JSON payload POSTed to the configured webhook
+type ErrorRateAlert struct {
+	Alert         string  `json:"alert"`
+	Scope         string  `json:"scope"`
+	APIKey        string  `json:"api_key,omitempty"`
+	ErrorRate     float64 `json:"error_rate"`
+	Threshold     float64 `json:"threshold"`
+	Requests      int     `json:"requests"`
+	Failed        int     `json:"failed"`
+	WindowSeconds float64 `json:"window_seconds"`
+	Timestamp     string  `json:"timestamp"`
+}
+
+// alertEvent is a single observed request outcome
+type alertEvent struct {
+	at     time.Time
+	failed bool
+}
+
+// alertWindow tracks recent outcomes and the last alert time for one scope
+type alertWindow struct {
+	events []alertEvent
+	// failed counts the failures among events, kept as events come and go
+	failed    int
+	lastAlert time.Time
+}
+
+// trim drops events that fell out of the rolling window ending after cutoff,
+// and the oldest beyond the cap
+func (win *alertWindow) trim(cutoff time.Time) {
+	start := 0
+	for start < len(win.events) && win.events[start].at.Before(cutoff) {
+		start++
+	}
+	if len(win.events)-start > maxAlertEvents {
+		start = len(win.events) - maxAlertEvents
+	}
+	for _, ev := range win.events[:start] {
+		if ev.failed {
+			win.failed--
+		}
+	}
+	win.events = win.events[start:]
+}
+
+// ErrorRateWatcher computes a rolling error rate from recorded requests and
+// POSTs an alert to a webhook when it crosses the configured threshold.
+// Alerts for the same scope are debounced by a cooldown period.
+type ErrorRateWatcher struct {
+	threshold   float64
+	webhookURL  string
+	cooldown    time.Duration
+	window      time.Duration
+	minRequests int
+	perKey      bool
+	client      *http.Client
+	logger      interfaces.Logger
+
+	mu      sync.Mutex
+	windows map[string]*alertWindow
+	// lastSweep is when idle scopes were last removed from windows
+	lastSweep time.Time
+	// now is overridable for tests
+	now func() time.Time
+
+	// ctx is canceled by Close to abort webhook deliveries in flight;
+	// sends tracks them and closed stops new ones, both guarded by mu
+	ctx    context.Context
+	cancel context.CancelFunc
+	sends  sync.WaitGroup
+	closed bool
+}
+
+// NewErrorRateWatcher creates a watcher from alerts configuration.
+// Zero values for window, cooldown and minimum requests fall back to defaults.
+func NewErrorRateWatcher(cfg interfaces.AlertsConfig, logger interfaces.Logger) *ErrorRateWatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &ErrorRateWatcher{
+		threshold:   cfg.ErrorRateThreshold,
+		webhookURL:  cfg.WebhookURL,
+		cooldown:    cfg.Cooldown,
+		window:      cfg.Window,
+		minRequests: cfg.MinRequests,
+		perKey:      cfg.PerKey,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		logger:      logger,
+		windows:     make(map[string]*alertWindow),
+		now:         time.Now,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+	if w.window <= 0 {
+		w.window = defaultAlertWindow
+	}
+	if w.cooldown <= 0 {
+		w.cooldown = defaultAlertCooldown
+	}
+	if w.minRequests <= 0 {
+		w.minRequests = defaultAlertMinRequests
+	}
+	return w
+}
+
+// Observe records a request outcome and fires an alert if the rolling error
+// rate for its scope crosses the threshold outside the cooldown period.
+func (w *ErrorRateWatcher) Observe(apiKey string, failed bool) {
+	if w == nil || w.threshold <= 0 || w.webhookURL == "" {
+		return
+	}
+
+	scope := globalAlertScope
+	if w.perKey {
+		scope = apiKey
+	}
+
+	now := w.now()
+	alert, fire := w.evaluate(scope, now, failed)
+	if !fire {
+		return
+	}
+	if w.perKey {
+		alert.APIKey = utils.MaskAPIKey(apiKey)
+	}
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.sends.Add(1)
+	w.mu.Unlock()
+
+	go func() {
+		defer w.sends.Done()
+		w.send(alert)
+	}()
+}
+
+// Close aborts webhook deliveries in flight and waits for them to return.
+// Alerts raised after Close are dropped.
+func (w *ErrorRateWatcher) Close() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+
+	w.cancel()
+	w.sends.Wait()
+}
+
+// evaluate appends the event to the scope's window and decides whether to alert
+func (w *ErrorRateWatcher) evaluate(scope string, now time.Time, failed bool) (ErrorRateAlert, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.sweep(now)
+
+	win, ok := w.windows[scope]
+	if !ok {
+		win = &alertWindow{}
+		w.windows[scope] = win
+	}
+
+	win.events = append(win.events, alertEvent{at: now, failed: failed})
+	if failed {
+		win.failed++
+	}
+	win.trim(now.Add(-w.window))
+
+	total := len(win.events)
+	if total < w.minRequests {
+		return ErrorRateAlert{}, false
+	}
+
+	failedCount := win.failed
+	rate := float64(failedCount) / float64(total)
+	if rate < w.threshold {
+		return ErrorRateAlert{}, false
+	}
+	if !win.lastAlert.IsZero() && now.Sub(win.lastAlert) < w.cooldown {
+		return ErrorRateAlert{}, false
+	}
+	win.lastAlert = now
+
+	scopeName := globalAlertScope
+	if w.perKey {
+		scopeName = "api_key"
+	}
+	return ErrorRateAlert{
+		Alert:         "error_rate_exceeded",
+		Scope:         scopeName,
+		ErrorRate:     rate,
+		Threshold:     w.threshold,
+		Requests:      total,
+		Failed:        failedCount,
+		WindowSeconds: w.window.Seconds(),
+		Timestamp:     now.UTC().Format(time.RFC3339),
+	}, true
+}
+
+// sweep removes scopes with no events left in the window and no cooldown
+// running, so per-key scopes don't accumulate for keys that stopped sending.
+// It runs at most once per window. Callers must hold w.mu.
+func (w *ErrorRateWatcher) sweep(now time.Time) {
+	if now.Sub(w.lastSweep) < w.window {
+		return
+	}
+	w.lastSweep = now
+
+	cutoff := now.Add(-w.window)
+	for scope, win := range w.windows {
+		win.trim(cutoff)
+		if len(win.events) == 0 && (win.lastAlert.IsZero() || now.Sub(win.lastAlert) >= w.cooldown) {
+			delete(w.windows, scope)
+		}
+	}
+}
+
+// send POSTs the alert to the webhook, logging any delivery failure
+func (w *ErrorRateWatcher) send(alert ErrorRateAlert) {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		w.logError("Failed to marshal error rate alert", err)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(w.ctx, http.MethodPost, w.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		w.logError("Failed to create error rate alert request", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		w.logError("Failed to deliver error rate alert", err)
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 300 {
+		w.logError("Error rate alert webhook rejected alert", fmt.Errorf("status %d", resp.StatusCode))
+		return
+	}
+
+	if w.logger != nil {
+		w.logger.Warn("Error rate alert sent", map[string]any{
+			"scope":      alert.Scope,
+			"error_rate": alert.ErrorRate,
+			"threshold":  alert.Threshold,
+		})
+	}
+}
+
+// logError logs alert delivery problems when a logger is configured
+func (w *ErrorRateWatcher) logError(msg string, err error) {
+	if w.logger != nil {
+		w.logger.Error(msg, map[string]any{"error": err.Error()})
+	}
+}
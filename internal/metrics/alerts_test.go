@@ -0,0 +1,222 @@
+package metrics
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookReceiver collects alerts POSTed by the watcher
+type webhookReceiver struct {
+	mu     sync.Mutex
+	alerts []ErrorRateAlert
+}
+
+func (r *webhookReceiver) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var alert ErrorRateAlert
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&alert))
+		r.mu.Lock()
+		r.alerts = append(r.alerts, alert)
+		r.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (r *webhookReceiver) received() []ErrorRateAlert {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]ErrorRateAlert(nil), r.alerts...)
+}
+
+func TestErrorRateWatcher_SingleAlertForBurst(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver.handler(t))
+	defer server.Close()
+
+	collector := NewMetricsCollector()
+	collector.SetAlertWatcher(NewErrorRateWatcher(interfaces.AlertsConfig{
+		ErrorRateThreshold: 0.5,
+		WebhookURL:         server.URL,
+		Cooldown:           time.Minute,
+		MinRequests:        10,
+	}, nil))
+
+	// A few successes, then a burst of 500s
+	for i := 0; i < 5; i++ {
+		collector.RecordRequest("key1", "/v1/chat", "gpt-4", 10, 200, time.Millisecond)
+	}
+	for i := 0; i < 50; i++ {
+		collector.RecordRequest("key1", "/v1/chat", "gpt-4", 0, 500, time.Millisecond)
+	}
+
+	require.Eventually(t, func() bool { return len(receiver.received()) >= 1 }, 2*time.Second, 10*time.Millisecond)
+	// Give any duplicate alerts a chance to arrive before asserting there are none
+	time.Sleep(100 * time.Millisecond)
+
+	alerts := receiver.received()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "error_rate_exceeded", alerts[0].Alert)
+	assert.Equal(t, "global", alerts[0].Scope)
+	assert.GreaterOrEqual(t, alerts[0].ErrorRate, 0.5)
+	assert.Equal(t, 0.5, alerts[0].Threshold)
+}
+
+func TestErrorRateWatcher_BelowThreshold(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver.handler(t))
+	defer server.Close()
+
+	watcher := NewErrorRateWatcher(interfaces.AlertsConfig{
+		ErrorRateThreshold: 0.5,
+		WebhookURL:         server.URL,
+		MinRequests:        10,
+	}, nil)
+
+	for i := 0; i < 40; i++ {
+		watcher.Observe("key1", i%4 == 0) // 25% error rate
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, receiver.received())
+}
+
+func TestErrorRateWatcher_CooldownAndPerKey(t *testing.T) {
+	watcher := NewErrorRateWatcher(interfaces.AlertsConfig{
+		ErrorRateThreshold: 0.5,
+		WebhookURL:         "http://unused.invalid",
+		Cooldown:           time.Minute,
+		MinRequests:        2,
+		PerKey:             true,
+	}, nil)
+
+	base := time.Now()
+	_, fire := watcher.evaluate("key1", base, true)
+	assert.False(t, fire, "below minimum request count")
+	alert, fire := watcher.evaluate("key1", base, true)
+	require.True(t, fire)
+	assert.Equal(t, "api_key", alert.Scope)
+
+	_, fire = watcher.evaluate("key1", base.Add(30*time.Second), true)
+	assert.False(t, fire, "still within cooldown")
+
+	// A different key has its own window and cooldown
+	_, fire = watcher.evaluate("key2", base.Add(30*time.Second), true)
+	assert.False(t, fire)
+	_, fire = watcher.evaluate("key2", base.Add(30*time.Second), true)
+	assert.True(t, fire)
+
+	_, fire = watcher.evaluate("key1", base.Add(61*time.Second), true)
+	assert.True(t, fire, "cooldown elapsed")
+}
+
+func TestErrorRateWatcher_CountsFollowTheWindow(t *testing.T) {
+	watcher := NewErrorRateWatcher(interfaces.AlertsConfig{
+		ErrorRateThreshold: 0.5,
+		WebhookURL:         "http://unused.invalid",
+		Window:             time.Minute,
+		MinRequests:        4,
+	}, nil)
+
+	// Failures that have left the window no longer count
+	base := time.Now()
+	for i := 0; i < 3; i++ {
+		watcher.evaluate(globalAlertScope, base, true)
+	}
+	later := base.Add(2 * time.Minute)
+	for i := 0; i < 3; i++ {
+		_, fire := watcher.evaluate(globalAlertScope, later, false)
+		assert.False(t, fire)
+	}
+	alert, fire := watcher.evaluate(globalAlertScope, later, true)
+	assert.False(t, fire, "only 1 of 4 requests in the window failed")
+	assert.Zero(t, alert.Requests)
+
+	win := watcher.windows[globalAlertScope]
+	require.NotNil(t, win)
+	assert.Len(t, win.events, 4)
+	assert.Equal(t, 1, win.failed)
+}
+
+func TestErrorRateWatcher_RemovesIdleScopes(t *testing.T) {
+	watcher := NewErrorRateWatcher(interfaces.AlertsConfig{
+		ErrorRateThreshold: 0.5,
+		WebhookURL:         "http://unused.invalid",
+		Window:             time.Minute,
+		Cooldown:           5 * time.Minute,
+		MinRequests:        1,
+		PerKey:             true,
+	}, nil)
+
+	base := time.Now()
+	watcher.evaluate("quiet-key", base, false)
+	_, fire := watcher.evaluate("alerted-key", base, true)
+	require.True(t, fire)
+	assert.Len(t, watcher.windows, 2)
+
+	// Both windows have emptied, but the alerted key's cooldown still runs
+	watcher.evaluate("busy-key", base.Add(2*time.Minute), false)
+	assert.NotContains(t, watcher.windows, "quiet-key")
+	assert.Contains(t, watcher.windows, "alerted-key")
+	assert.Contains(t, watcher.windows, "busy-key")
+
+	_, fire = watcher.evaluate("alerted-key", base.Add(3*time.Minute), true)
+	assert.False(t, fire, "cooldown survives the sweep")
+
+	watcher.evaluate("busy-key", base.Add(10*time.Minute), false)
+	assert.NotContains(t, watcher.windows, "alerted-key")
+	assert.Len(t, watcher.windows, 1)
+}
+
+func TestErrorRateWatcher_CloseAbortsDeliveries(t *testing.T) {
+	var requests atomic.Int32
+	arrived := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+		arrived <- struct{}{}
+		// Hang until the watcher gives up on the delivery, which the server
+		// only notices once the body has been read
+		_, _ = io.Copy(io.Discard, req.Body)
+		<-req.Context().Done()
+	}))
+	defer server.Close()
+
+	watcher := NewErrorRateWatcher(interfaces.AlertsConfig{
+		ErrorRateThreshold: 0.5,
+		WebhookURL:         server.URL,
+		MinRequests:        1,
+		PerKey:             true,
+	}, nil)
+
+	watcher.Observe("key1", true)
+	select {
+	case <-arrived:
+	case <-time.After(2 * time.Second):
+		t.Fatal("alert was never delivered")
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		watcher.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close did not abort the delivery in flight")
+	}
+
+	// Alerts raised after Close are dropped
+	watcher.Observe("key2", true)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), requests.Load())
+}
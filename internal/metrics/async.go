@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+// DefaultRecordBufferSize is the number of pending records an AsyncRecorder
+// holds before it starts dropping them
+const DefaultRecordBufferSize = 4096
+
+// batchRecorder is implemented by collectors that can record several requests at once
+type batchRecorder interface {
+	RecordRequests(records []RequestRecord)
+}
+
+// AsyncRecorder hands request records to a collector from a background worker
+// so that a slow or failing collector never delays the request path. When the
+// buffer is full, records are dropped and counted instead of blocking.
+type AsyncRecorder struct {
+	collector interfaces.MetricsCollector
+	records   chan RequestRecord
+	dropped   atomic.Int64
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewAsyncRecorder creates a recorder buffering up to bufferSize records for
+// collector and starts its worker. A bufferSize of 0 or less uses
+// DefaultRecordBufferSize.
+func NewAsyncRecorder(collector interfaces.MetricsCollector, bufferSize int) *AsyncRecorder {
+	if bufferSize <= 0 {
+		bufferSize = DefaultRecordBufferSize
+	}
+
+	a := &AsyncRecorder{
+		collector: collector,
+		records:   make(chan RequestRecord, bufferSize),
+		done:      make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// Record queues rec for the collector without blocking. It reports false when
+// the buffer is full or the recorder is closed and the record was dropped.
+func (a *AsyncRecorder) Record(rec RequestRecord) (queued bool) {
+	defer func() {
+		// Sending on the closed channel after Close panics; count it as a drop
+		if recover() != nil {
+			a.dropped.Add(1)
+			queued = false
+		}
+	}()
+
+	select {
+	case a.records <- rec:
+		return true
+	default:
+		a.dropped.Add(1)
+		return false
+	}
+}
+
+// Dropped returns the number of records discarded because the buffer was full
+func (a *AsyncRecorder) Dropped() int64 {
+	return a.dropped.Load()
+}
+
+// Close stops accepting records and waits for the buffered ones to be recorded
+func (a *AsyncRecorder) Close() {
+	a.closeOnce.Do(func() {
+		close(a.records)
+	})
+	<-a.done
+}
+
+// run records queued requests until the channel is closed. Records already
+// waiting in the buffer are passed on as one batch when the collector supports it.
+func (a *AsyncRecorder) run() {
+	defer close(a.done)
+
+	batcher, canBatch := a.collector.(batchRecorder)
+	batch := make([]RequestRecord, 0, 64)
+	for rec := range a.records {
+		if !canBatch {
+			a.collector.RecordRequest(rec.APIKey, rec.Endpoint, rec.Model, rec.Tokens, rec.StatusCode, rec.Duration)
+			continue
+		}
+
+		batch = append(batch[:0], rec)
+	drain:
+		for len(batch) < cap(batch) {
+			select {
+			case next, ok := <-a.records:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, next)
+			default:
+				break drain
+			}
+		}
+		batcher.RecordRequests(batch)
+	}
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowCollector blocks every RecordRequest until release is closed
+type slowCollector struct {
+	interfaces.MetricsCollector
+	release  chan struct{}
+	mu       sync.Mutex
+	recorded int
+}
+
+func (s *slowCollector) RecordRequest(apiKey, endpoint, model string, tokens, statusCode int, duration time.Duration) {
+	<-s.release
+	s.mu.Lock()
+	s.recorded++
+	s.mu.Unlock()
+}
+
+func TestAsyncMetricsMiddleware_SlowCollectorDoesNotBlockRequests(t *testing.T) {
+	sink := &slowCollector{release: make(chan struct{})}
+	recorder := NewAsyncRecorder(sink, 1)
+
+	handler := AsyncMetricsMiddleware(recorder)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	const requests = 5
+	start := time.Now()
+	for i := 0; i < requests; i++ {
+		req := httptest.NewRequest("GET", "/v1/chat", nil)
+		req.Header.Set("Authorization", "Bearer key1")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	}
+	assert.Less(t, time.Since(start), time.Second, "requests must not wait for the collector")
+
+	// The worker holds at most one record and the buffer one more
+	assert.GreaterOrEqual(t, recorder.Dropped(), int64(requests-2))
+
+	close(sink.release)
+	recorder.Close()
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	assert.Equal(t, int64(requests), int64(sink.recorded)+recorder.Dropped())
+}
+
+func TestAsyncRecorder_BatchesIntoCollector(t *testing.T) {
+	collector := NewMetricsCollector()
+	recorder := NewAsyncRecorder(collector, 0)
+
+	for i := 0; i < 100; i++ {
+		assert.True(t, recorder.Record(RequestRecord{
+			APIKey:     "key1",
+			Endpoint:   "/v1/chat",
+			Model:      "gpt-4",
+			Tokens:     10,
+			StatusCode: http.StatusOK,
+			Duration:   time.Millisecond,
+		}))
+	}
+	recorder.Close()
+
+	km, ok := collector.GetMetricsForKey("key1")
+	require.True(t, ok)
+	assert.Equal(t, int64(100), km.TotalRequests)
+	assert.Equal(t, int64(1000), km.TotalTokensConsumed)
+	assert.Zero(t, recorder.Dropped())
+}
+
+func TestAsyncRecorder_RecordAfterCloseIsDropped(t *testing.T) {
+	recorder := NewAsyncRecorder(NewMetricsCollector(), 1)
+	recorder.Close()
+	recorder.Close()
+
+	assert.False(t, recorder.Record(RequestRecord{APIKey: "key1"}))
+	assert.Equal(t, int64(1), recorder.Dropped())
+}
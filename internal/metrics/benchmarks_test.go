@@ -30,6 +30,27 @@ func BenchmarkMetricsCollectorRecordRequest(b *testing.B) {
 	}
 }
 
+// BenchmarkMetricsCollectorLatencySampling measures the latency histogram's
+// share of per-request cost when observing every request and one in ten.
+// The rest of RecordRequest is unaffected by sampling.
+func BenchmarkMetricsCollectorLatencySampling(b *testing.B) {
+	for _, rate := range []int{1, 10} {
+		b.Run(fmt.Sprintf("1_in_%d", rate), func(b *testing.B) {
+			collector := NewMetricsCollector()
+			collector.SetLatencySampleRate(rate)
+
+			b.ResetTimer()
+			b.ReportAllocs()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					collector.recordLatency("bench-key", "/v1/test", "test-model", 100*time.Millisecond)
+				}
+			})
+		})
+	}
+}
+
 // BenchmarkMetricsCollectorRecordRequestParallel benchmarks concurrent RecordRequest calls
 func BenchmarkMetricsCollectorRecordRequestParallel(b *testing.B) {
 	collector := NewMetricsCollector()
@@ -99,6 +120,57 @@ func BenchmarkMetricsCollectorRecordRequestDifferentEndpoints(b *testing.B) {
 	}
 }
 
+// benchBatchSize is the number of sub-requests recorded per batch in the batch benchmarks
+const benchBatchSize = 64
+
+// benchRecords builds a batch of records spread over a few keys
+func benchRecords() []RequestRecord {
+	records := make([]RequestRecord, benchBatchSize)
+	for i := range records {
+		records[i] = RequestRecord{
+			APIKey:     fmt.Sprintf("batch-key-%d", i%4),
+			Endpoint:   "/v1/embeddings",
+			Model:      "embedding-model",
+			Tokens:     10,
+			StatusCode: 200,
+			Duration:   5 * time.Millisecond,
+		}
+	}
+	return records
+}
+
+// BenchmarkMetricsCollectorRecordIndividually records a batch one RecordRequest call at a time
+func BenchmarkMetricsCollectorRecordIndividually(b *testing.B) {
+	collector := NewMetricsCollector()
+	records := benchRecords()
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			for _, r := range records {
+				collector.RecordRequest(r.APIKey, r.Endpoint, r.Model, r.Tokens, r.StatusCode, r.Duration)
+			}
+		}
+	})
+}
+
+// BenchmarkMetricsCollectorRecordBatched records the same batch with one RecordRequests call
+func BenchmarkMetricsCollectorRecordBatched(b *testing.B) {
+	collector := NewMetricsCollector()
+	records := benchRecords()
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			collector.RecordRequests(records)
+		}
+	})
+}
+
 // BenchmarkMetricsCollectorGetMetrics benchmarks metrics retrieval
 func BenchmarkMetricsCollectorGetMetrics(b *testing.B) {
 	collector := NewMetricsCollector()
@@ -486,6 +558,36 @@ func BenchmarkStatusRecorderOverhead(b *testing.B) {
 	}
 }
 
+// benchRecorderSink keeps benchmarked recorders escaping to the heap, as they
+// do when passed down the handler chain
+var benchRecorderSink http.ResponseWriter
+
+// BenchmarkStatusRecorderPool compares taking the metrics middleware's status
+// recorder from its pool with allocating one per request
+func BenchmarkStatusRecorderPool(b *testing.B) {
+	w := httptest.NewRecorder()
+
+	b.Run("allocated", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			recorder := &statusRecorder{ResponseWriter: w}
+			benchRecorderSink = recorder
+			recorder.WriteHeader(http.StatusOK)
+		}
+	})
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			recorder := acquireStatusRecorder(w)
+			benchRecorderSink = recorder
+			recorder.WriteHeader(http.StatusOK)
+			releaseStatusRecorder(recorder)
+		}
+	})
+	benchRecorderSink = nil
+}
+
 // BenchmarkPrometheusCollectionOverhead benchmarks Prometheus collection overhead
 func BenchmarkPrometheusCollectionOverhead(b *testing.B) {
 	collector := NewMetricsCollector()
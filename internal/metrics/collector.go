@@ -5,7 +5,9 @@ package metrics
 
 import (
 	"fmt"
+	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -20,21 +22,149 @@ type (
 	EndpointMetrics = interfaces.EndpointMetrics
 	ModelMetrics    = interfaces.ModelMetrics
 	KeyMetrics      = interfaces.KeyMetrics
+	ApdexMetrics    = interfaces.ApdexMetrics
 )
 
+// tokensConsumedDesc describes the per-key, per-model token counter derived from KeyMetrics
+var tokensConsumedDesc = prometheus.NewDesc(
+	"nexus_tokens_consumed_total",
+	"Total tokens consumed, by API key and model",
+	[]string{"api_key", "model"},
+	nil,
+)
+
+// promptTokensDesc and completionTokensDesc describe the per-key input and
+// output token counters derived from KeyMetrics
+var (
+	promptTokensDesc = prometheus.NewDesc(
+		"nexus_prompt_tokens_total",
+		"Prompt (input) tokens consumed, by API key",
+		[]string{"api_key"},
+		nil,
+	)
+	completionTokensDesc = prometheus.NewDesc(
+		"nexus_completion_tokens_total",
+		"Completion (output) tokens consumed, by API key",
+		[]string{"api_key"},
+		nil,
+	)
+)
+
+// throttledDesc describes the per-key, per-limiter rejection counter derived from KeyMetrics
+var throttledDesc = prometheus.NewDesc(
+	"nexus_throttled_total",
+	"Total requests rejected by rate limiters, by API key and limiter type",
+	[]string{"api_key", "limiter_type"},
+	nil,
+)
+
+// successRatioDesc describes the per-key success ratio gauge derived from KeyMetrics
+var successRatioDesc = prometheus.NewDesc(
+	"nexus_success_ratio",
+	"Fraction of requests that succeeded, by API key",
+	[]string{"api_key"},
+	nil,
+)
+
+// latencyEWMADesc describes the per-key moving average latency gauge derived from KeyMetrics
+var latencyEWMADesc = prometheus.NewDesc(
+	"nexus_latency_ewma_seconds",
+	"Exponentially weighted moving average of request latency, by API key",
+	[]string{"api_key"},
+	nil,
+)
+
+// websocketSessionsDesc describes the per-key WebSocket session counter derived from KeyMetrics
+var websocketSessionsDesc = prometheus.NewDesc(
+	"nexus_websocket_sessions_total",
+	"WebSocket sessions relayed to the upstream, by API key",
+	[]string{"api_key"},
+	nil,
+)
+
+// responsesDesc describes the client-facing status code counter, summed over all keys
+var responsesDesc = prometheus.NewDesc(
+	"nexus_responses_total",
+	"Responses returned to clients, by status code",
+	[]string{"status"},
+	nil,
+)
+
+// inFlightDesc describes the gauge of requests currently being served
+var inFlightDesc = prometheus.NewDesc(
+	"nexus_requests_in_flight",
+	"Requests currently being served",
+	nil,
+	nil,
+)
+
+// defaultLatencyEWMADecay is the weight of each new request in the moving
+// average latency when none is configured
+const defaultLatencyEWMADecay = 0.1
+
+// apdexToleratedFactor is how many multiples of the SLO threshold a request may
+// take and still count as tolerated
+const apdexToleratedFactor = 4
+
 // MetricsCollector implements interfaces.MetricsCollector for collecting and aggregating
 // API request metrics. It provides thread-safe operations and Prometheus integration.
 type MetricsCollector struct {
 	// metrics holds per-API-key aggregated metrics
 	metrics map[string]*KeyMetrics
+	// statusCounts counts client-facing responses by status code across all keys
+	statusCounts map[int]int64
 	// mu protects the metrics map from concurrent access
 	mu sync.RWMutex // Use RWMutex for better read performance
+	// resetMu orders resets against recording: RecordRequests holds it shared
+	// while it updates both the maps and the latency histogram, so each batch
+	// lands entirely before or entirely after a reset
+	resetMu sync.RWMutex
+	// inFlight counts requests currently being served. It describes the
+	// present rather than history, so ResetMetrics leaves it alone.
+	inFlight atomic.Int64
 	// RequestLatency tracks request duration histograms for Prometheus export
 	RequestLatency *prometheus.HistogramVec
 	// histogramInit ensures histogram is properly initialized
 	histogramInit sync.Once
+	// registry is a private Prometheus registry so multiple collectors never
+	// collide in the global default registry
+	registry *prometheus.Registry
+	// registerOnce guards registration with the private registry
+	registerOnce sync.Once
+	// registerErr holds the result of the first registration attempt
+	registerErr error
+	// alertWatcher optionally raises webhook alerts on high error rates
+	alertWatcher *ErrorRateWatcher
+	// funcMetrics holds values sampled from other components at scrape time
+	funcMetrics []prometheus.Collector
+	// maxEndpoints and maxModels cap the per-key breakdown maps; zero is unlimited
+	maxEndpoints int
+	maxModels    int
+	// maxLabelValues caps the values tracked per label and key; zero is unlimited
+	maxLabelValues int
+	// latencySampleRate observes one request latency in every N; 0 or 1 observes all
+	latencySampleRate uint64
+	latencySeq        atomic.Uint64
+	// sloLatency is the apdex threshold; zero disables apdex tracking
+	sloLatency time.Duration
+	// successRanges are the status codes counted as successful; empty means 2xx
+	successRanges []StatusRange
+	// latencyEWMADecay is the weight of each new request in KeyMetrics.LatencyEWMAMs
+	latencyEWMADecay float64
+	// startedAt is when collection began, for average request rates
+	startedAt time.Time
 }
 
+// MetricsCollector is used through interfaces.MetricsCollector by the container
+var _ interfaces.MetricsCollector = (*MetricsCollector)(nil)
+
+// OtherBucket is the breakdown entry that absorbs endpoints, models and
+// label values beyond the per-key caps
+const OtherBucket = "__other__"
+
+// NoLabelValue is the label breakdown entry for requests without the header
+const NoLabelValue = "__none__"
+
 // Describe implements prometheus.Collector interface for metric registration
 func (c *MetricsCollector) Describe(ch chan<- *prometheus.Desc) {
 	c.mu.RLock()
@@ -42,6 +172,18 @@ func (c *MetricsCollector) Describe(ch chan<- *prometheus.Desc) {
 	if c.RequestLatency != nil {
 		c.RequestLatency.Describe(ch)
 	}
+	ch <- tokensConsumedDesc
+	ch <- promptTokensDesc
+	ch <- completionTokensDesc
+	ch <- throttledDesc
+	ch <- successRatioDesc
+	ch <- latencyEWMADesc
+	ch <- websocketSessionsDesc
+	ch <- responsesDesc
+	ch <- inFlightDesc
+	for _, m := range c.funcMetrics {
+		m.Describe(ch)
+	}
 }
 
 // Collect implements prometheus.Collector interface for metric collection
@@ -51,18 +193,226 @@ func (c *MetricsCollector) Collect(ch chan<- prometheus.Metric) {
 	if c.RequestLatency != nil {
 		c.RequestLatency.Collect(ch)
 	}
+	// Token counters are read from the per-model breakdown, so they share its label set
+	for apiKey, km := range c.metrics {
+		for model, mm := range km.PerModel {
+			ch <- prometheus.MustNewConstMetric(
+				tokensConsumedDesc,
+				prometheus.CounterValue,
+				float64(atomic.LoadInt64(&mm.TotalTokens)),
+				apiKey, model,
+			)
+		}
+		ch <- prometheus.MustNewConstMetric(
+			promptTokensDesc,
+			prometheus.CounterValue,
+			float64(atomic.LoadInt64(&km.TotalPromptTokens)),
+			apiKey,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			completionTokensDesc,
+			prometheus.CounterValue,
+			float64(atomic.LoadInt64(&km.TotalCompletionTokens)),
+			apiKey,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			websocketSessionsDesc,
+			prometheus.CounterValue,
+			float64(atomic.LoadInt64(&km.WebSocketSessions)),
+			apiKey,
+		)
+		for limiterType, count := range km.ThrottledRequests {
+			ch <- prometheus.MustNewConstMetric(
+				throttledDesc,
+				prometheus.CounterValue,
+				float64(count),
+				apiKey, limiterType,
+			)
+		}
+		// Keys seen only through throttles have no requests to take a ratio of
+		if total := atomic.LoadInt64(&km.TotalRequests); total > 0 {
+			ch <- prometheus.MustNewConstMetric(
+				successRatioDesc,
+				prometheus.GaugeValue,
+				float64(atomic.LoadInt64(&km.SuccessfulRequests))/float64(total),
+				apiKey,
+			)
+			ch <- prometheus.MustNewConstMetric(
+				latencyEWMADesc,
+				prometheus.GaugeValue,
+				km.LatencyEWMAMs/1000,
+				apiKey,
+			)
+		}
+	}
+	for status, count := range c.statusCounts {
+		ch <- prometheus.MustNewConstMetric(
+			responsesDesc,
+			prometheus.CounterValue,
+			float64(count),
+			strconv.Itoa(status),
+		)
+	}
+	ch <- prometheus.MustNewConstMetric(inFlightDesc, prometheus.GaugeValue, float64(c.inFlight.Load()))
+	for _, m := range c.funcMetrics {
+		m.Collect(ch)
+	}
 }
 
 // NewMetricsCollector creates a new MetricsCollector with proper initialization.
 // The collector is thread-safe and ready for concurrent use.
 func NewMetricsCollector() *MetricsCollector {
 	c := &MetricsCollector{
-		metrics: make(map[string]*KeyMetrics),
+		metrics:      make(map[string]*KeyMetrics),
+		statusCounts: make(map[int]int64),
+		registry:     prometheus.NewRegistry(),
+		startedAt:    time.Now(),
+
+		latencyEWMADecay: defaultLatencyEWMADecay,
 	}
 	c.initializeHistogram()
 	return c
 }
 
+// Register registers the collector with its private Prometheus registry.
+// It is safe to call multiple times; registration happens once and the
+// result of that attempt is returned on every call.
+func (c *MetricsCollector) Register() error {
+	c.registerOnce.Do(func() {
+		if c.registry == nil {
+			c.registry = prometheus.NewRegistry()
+		}
+		if err := c.registry.Register(c); err != nil {
+			c.registerErr = fmt.Errorf("failed to register metrics collector: %w", err)
+		}
+	})
+	return c.registerErr
+}
+
+// SetAlertWatcher attaches an error-rate watcher that observes every recorded request.
+// It must be called before the collector starts receiving requests.
+func (c *MetricsCollector) SetAlertWatcher(watcher *ErrorRateWatcher) {
+	c.alertWatcher = watcher
+}
+
+// AddGaugeFunc exports a gauge whose value is read from fn on every scrape.
+// It must be called before Register.
+func (c *MetricsCollector) AddGaugeFunc(name, help string, fn func() float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.funcMetrics = append(c.funcMetrics, prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: name,
+		Help: help,
+	}, fn))
+}
+
+// AddCounterFunc exports a counter whose value is read from fn on every scrape.
+// Counters sharing a name must be told apart by labels. It must be called before Register.
+func (c *MetricsCollector) AddCounterFunc(name, help string, labels map[string]string, fn func() float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.funcMetrics = append(c.funcMetrics, prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Name:        name,
+		Help:        help,
+		ConstLabels: labels,
+	}, fn))
+}
+
+// AddCounterVecFunc exports a counter family whose values are read from fn on
+// every scrape, keyed by the value of label. Unlike AddCounterFunc, the label
+// values need not be known up front. It must be called before Register.
+func (c *MetricsCollector) AddCounterVecFunc(name, help, label string, fn func() map[string]float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.funcMetrics = append(c.funcMetrics, &counterVecFunc{
+		desc: prometheus.NewDesc(name, help, []string{label}, nil),
+		fn:   fn,
+	})
+}
+
+// counterVecFunc is a prometheus.Collector reporting one counter per entry of fn's result
+type counterVecFunc struct {
+	desc *prometheus.Desc
+	fn   func() map[string]float64
+}
+
+// Describe implements prometheus.Collector
+func (f *counterVecFunc) Describe(ch chan<- *prometheus.Desc) {
+	ch <- f.desc
+}
+
+// Collect implements prometheus.Collector
+func (f *counterVecFunc) Collect(ch chan<- prometheus.Metric) {
+	for value, count := range f.fn() {
+		ch <- prometheus.MustNewConstMetric(f.desc, prometheus.CounterValue, count, value)
+	}
+}
+
+// SetBreakdownLimits caps how many distinct endpoints and models are tracked per
+// key. Once a key reaches a cap, new names are recorded under OtherBucket so the
+// breakdown totals still match the key's totals. Zero disables a cap.
+// It must be called before the collector starts receiving requests.
+func (c *MetricsCollector) SetBreakdownLimits(maxEndpoints, maxModels int) {
+	c.maxEndpoints = maxEndpoints
+	c.maxModels = maxModels
+}
+
+// SetLabelValueLimit caps how many distinct values of each header label are
+// tracked per key, like SetBreakdownLimits. Zero disables the cap.
+// It must be called before the collector starts receiving requests.
+func (c *MetricsCollector) SetLabelValueLimit(maxValues int) {
+	c.maxLabelValues = maxValues
+}
+
+// SetLatencyEWMADecay sets the weight, between 0 and 1, each new request gets
+// in a key's moving average latency. Higher values follow changes faster but
+// are noisier. Zero or out-of-range values use the default of 0.1.
+// It must be called before the collector starts receiving requests.
+func (c *MetricsCollector) SetLatencyEWMADecay(decay float64) {
+	if decay <= 0 || decay > 1 {
+		decay = defaultLatencyEWMADecay
+	}
+	c.latencyEWMADecay = decay
+}
+
+// SetLatencySampleRate makes the latency histogram observe only one request in
+// every n, reducing per-request overhead at very high request rates. Request,
+// token and error counters stay exact; only the histogram is sampled, so its
+// _count and _sum cover roughly 1/n of requests and should be scaled by n when
+// read as totals. Quantiles remain representative. Zero or one observes every
+// request. It must be called before the collector starts receiving requests.
+func (c *MetricsCollector) SetLatencySampleRate(n int) {
+	if n < 1 {
+		n = 1
+	}
+	c.latencySampleRate = uint64(n)
+}
+
+// SetSLOLatency sets the apdex threshold T. Each key then reports an apdex
+// score counting requests within T as satisfied, within 4T as tolerated, and
+// slower or failed requests as frustrated. Zero disables apdex tracking.
+// It must be called before the collector starts receiving requests.
+func (c *MetricsCollector) SetSLOLatency(threshold time.Duration) {
+	if threshold < 0 {
+		threshold = 0
+	}
+	c.sloLatency = threshold
+}
+
+// SetSuccessStatusRanges sets the status codes counted as successful requests,
+// in place of 2xx, for upstreams whose status codes don't match how their
+// outcome should be judged. Empty ranges restore the 2xx default.
+// It must be called before the collector starts receiving requests.
+func (c *MetricsCollector) SetSuccessStatusRanges(ranges []StatusRange) {
+	c.successRanges = ranges
+}
+
+// Registry returns the collector's private Prometheus registry.
+// Call Register before gathering from it.
+func (c *MetricsCollector) Registry() *prometheus.Registry {
+	return c.registry
+}
+
 // initializeHistogram creates and initializes the Prometheus histogram
 func (c *MetricsCollector) initializeHistogram() {
 	c.histogramInit.Do(func() {
@@ -77,46 +427,202 @@ func (c *MetricsCollector) initializeHistogram() {
 	})
 }
 
+// RequestRecord describes one completed request for RecordRequests
+type RequestRecord struct {
+	APIKey     string
+	Endpoint   string
+	Model      string
+	Tokens     int
+	StatusCode int
+	Duration   time.Duration
+	// PromptTokens and CompletionTokens split Tokens when the upstream
+	// reported them separately; both are zero otherwise
+	PromptTokens     int
+	CompletionTokens int
+	// Labels holds header values captured by the middleware, by label name
+	Labels map[string]string
+}
+
 // RecordRequest records metrics for a completed request.
 // This method is thread-safe and handles all metric aggregation including
 // per-key, per-endpoint, and per-model breakdowns.
 func (c *MetricsCollector) RecordRequest(apiKey string, endpoint string, model string, tokens int, statusCode int, duration time.Duration) {
-	// Sanitize and validate inputs
-	// For API keys, preserve empty strings but sanitize non-empty ones
-	if apiKey != "" {
-		apiKey = c.sanitizeInput(apiKey, "unknown")
+	c.RecordRequests([]RequestRecord{{
+		APIKey:     apiKey,
+		Endpoint:   endpoint,
+		Model:      model,
+		Tokens:     tokens,
+		StatusCode: statusCode,
+		Duration:   duration,
+	}})
+}
+
+// RecordRequestWithTokens records a completed request whose prompt and
+// completion tokens are known separately. The request's total is their sum.
+func (c *MetricsCollector) RecordRequestWithTokens(apiKey string, endpoint string, model string, promptTokens int, completionTokens int, statusCode int, duration time.Duration) {
+	c.RecordRequests([]RequestRecord{{
+		APIKey:           apiKey,
+		Endpoint:         endpoint,
+		Model:            model,
+		Tokens:           promptTokens + completionTokens,
+		StatusCode:       statusCode,
+		Duration:         duration,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+	}})
+}
+
+// RecordRequests records metrics for a batch of completed requests, taking the
+// collector lock once for the whole batch rather than once per request.
+func (c *MetricsCollector) RecordRequests(records []RequestRecord) {
+	if len(records) == 0 {
+		return
 	}
-	endpoint = c.sanitizeInput(endpoint, "unknown")
-	model = c.sanitizeInput(model, "unknown")
-	if tokens < 0 {
-		tokens = 0
+
+	// Sanitize and validate inputs before taking the lock
+	batch := make([]RequestRecord, len(records))
+	for i, rec := range records {
+		// For API keys, preserve empty strings but sanitize non-empty ones
+		if rec.APIKey != "" {
+			rec.APIKey = c.sanitizeInput(rec.APIKey, "unknown")
+		}
+		rec.Endpoint = c.sanitizeInput(rec.Endpoint, "unknown")
+		rec.Model = c.sanitizeInput(rec.Model, "unknown")
+		if rec.Tokens < 0 {
+			rec.Tokens = 0
+		}
+		if len(rec.Labels) > 0 {
+			labels := make(map[string]string, len(rec.Labels))
+			for name, value := range rec.Labels {
+				labels[c.sanitizeInput(name, "unknown")] = c.sanitizeInput(value, NoLabelValue)
+			}
+			rec.Labels = labels
+		}
+		batch[i] = rec
 	}
 
-	// Get or create key metrics
-	km := c.getOrCreateKeyMetrics(apiKey)
+	// Hold off resets until the batch is fully recorded, histogram included
+	c.resetMu.RLock()
+	defer c.resetMu.RUnlock()
+
+	now := time.Now()
+	c.mu.Lock()
+	for i := range batch {
+		c.applyRecord(&batch[i], now)
+	}
+	c.mu.Unlock()
+
+	for _, rec := range batch {
+		// Record latency histogram; a WebSocket session lasts as long as the
+		// client keeps it open, which says nothing about request latency
+		if rec.StatusCode != http.StatusSwitchingProtocols {
+			c.recordLatency(rec.APIKey, rec.Endpoint, rec.Model, rec.Duration)
+		}
+
+		// Feed the error-rate watcher if alerting is configured; client
+		// disconnects say nothing about upstream health
+		if rec.StatusCode != StatusClientClosedRequest {
+			c.alertWatcher.Observe(rec.APIKey, !c.isSuccessStatusCode(rec.StatusCode))
+		}
+	}
+}
+
+// applyRecord adds a sanitized record to the aggregates, rewriting its endpoint
+// and model to the possibly folded breakdown names. Callers must hold c.mu.
+func (c *MetricsCollector) applyRecord(rec *RequestRecord, now time.Time) {
+	km := c.getOrCreateKeyMetrics(rec.APIKey)
+	km.LastRequest = now
+	c.statusCounts[rec.StatusCode]++
 
 	// Update aggregate counters atomically
 	atomic.AddInt64(&km.TotalRequests, 1)
-	if c.isSuccessStatusCode(statusCode) {
+	if c.isSuccessStatusCode(rec.StatusCode) {
 		atomic.AddInt64(&km.SuccessfulRequests, 1)
 	} else {
 		atomic.AddInt64(&km.FailedRequests, 1)
+		km.LastErrorStatus = rec.StatusCode
+		km.LastErrorEndpoint = rec.Endpoint
+		km.LastErrorTime = now
+	}
+	if rec.StatusCode == StatusClientClosedRequest {
+		// Tracked separately so client disconnects aren't mistaken for upstream errors
+		atomic.AddInt64(&km.CanceledRequests, 1)
+	}
+	atomic.AddInt64(&km.TotalTokensConsumed, int64(rec.Tokens))
+	atomic.AddInt64(&km.TotalPromptTokens, int64(rec.PromptTokens))
+	atomic.AddInt64(&km.TotalCompletionTokens, int64(rec.CompletionTokens))
+	if rec.StatusCode == http.StatusSwitchingProtocols {
+		// Sessions are counted apart and kept out of the latency aggregates
+		atomic.AddInt64(&km.WebSocketSessions, 1)
+	} else {
+		c.updateLatencyEWMA(km, rec.Duration)
+		if c.sloLatency > 0 {
+			c.updateApdex(km, rec)
+		}
 	}
-	atomic.AddInt64(&km.TotalTokensConsumed, int64(tokens))
 
-	// Update breakdown metrics
-	c.updateEndpointMetrics(km, endpoint, tokens)
-	c.updateModelMetrics(km, model, tokens)
+	// Update breakdown metrics, using the possibly folded names from here on
+	rec.Endpoint = c.updateEndpointMetrics(km, rec.Endpoint, rec.Tokens)
+	rec.Model = c.updateModelMetrics(km, rec.Model, rec.Tokens)
+	c.updateLabelMetrics(km, rec.Labels)
+}
 
-	// Record latency histogram
-	c.recordLatency(apiKey, endpoint, model, duration)
+// updateLatencyEWMA folds a request's latency into the key's moving average.
+// The key's first request sets the average outright. Callers must hold c.mu.
+func (c *MetricsCollector) updateLatencyEWMA(km *KeyMetrics, duration time.Duration) {
+	ms := float64(duration) / float64(time.Millisecond)
+	if atomic.LoadInt64(&km.TotalRequests) <= 1 {
+		km.LatencyEWMAMs = ms
+		return
+	}
+	decay := c.latencyEWMADecay
+	if decay <= 0 {
+		decay = defaultLatencyEWMADecay
+	}
+	km.LatencyEWMAMs += decay * (ms - km.LatencyEWMAMs)
 }
 
-// getOrCreateKeyMetrics safely retrieves or creates KeyMetrics for an API key
-func (c *MetricsCollector) getOrCreateKeyMetrics(apiKey string) *KeyMetrics {
+// updateApdex grades a record against the SLO threshold. Callers must hold c.mu.
+func (c *MetricsCollector) updateApdex(km *KeyMetrics, rec *RequestRecord) {
+	if km.Apdex == nil {
+		km.Apdex = &ApdexMetrics{}
+	}
+	a := km.Apdex
+	switch {
+	case !c.isSuccessStatusCode(rec.StatusCode):
+		a.Frustrated++
+	case rec.Duration <= c.sloLatency:
+		a.Satisfied++
+	case rec.Duration <= apdexToleratedFactor*c.sloLatency:
+		a.Tolerated++
+	default:
+		a.Frustrated++
+	}
+	total := a.Satisfied + a.Tolerated + a.Frustrated
+	a.Score = (float64(a.Satisfied) + float64(a.Tolerated)/2) / float64(total)
+}
+
+// RecordThrottle records a request rejected by a rate limiter. limiterType
+// identifies the limiter, such as "rate" or "token".
+func (c *MetricsCollector) RecordThrottle(apiKey string, limiterType string) {
+	if apiKey != "" {
+		apiKey = c.sanitizeInput(apiKey, "unknown")
+	}
+	limiterType = c.sanitizeInput(limiterType, "unknown")
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	km := c.getOrCreateKeyMetrics(apiKey)
+	if km.ThrottledRequests == nil {
+		km.ThrottledRequests = make(map[string]int64)
+	}
+	km.ThrottledRequests[limiterType]++
+}
+
+// getOrCreateKeyMetrics retrieves or creates KeyMetrics for an API key.
+// Callers must hold c.mu.
+func (c *MetricsCollector) getOrCreateKeyMetrics(apiKey string) *KeyMetrics {
 	km, ok := c.metrics[apiKey]
 	if !ok {
 		km = &KeyMetrics{
@@ -130,35 +636,82 @@ func (c *MetricsCollector) getOrCreateKeyMetrics(apiKey string) *KeyMetrics {
 
 // isSuccessStatusCode determines if a status code represents success
 func (c *MetricsCollector) isSuccessStatusCode(statusCode int) bool {
-	return statusCode >= 200 && statusCode < 300
+	return isSuccessStatus(statusCode, c.successRanges)
 }
 
-// updateEndpointMetrics updates per-endpoint metrics breakdown
-func (c *MetricsCollector) updateEndpointMetrics(km *KeyMetrics, endpoint string, tokens int) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
+// updateEndpointMetrics updates per-endpoint metrics breakdown and returns the
+// entry name used, which is OtherBucket once the key's endpoint cap is reached.
+// Callers must hold c.mu.
+func (c *MetricsCollector) updateEndpointMetrics(km *KeyMetrics, endpoint string, tokens int) string {
 	if _, ok := km.PerEndpoint[endpoint]; !ok {
-		km.PerEndpoint[endpoint] = &EndpointMetrics{}
+		if atCap(len(km.PerEndpoint), km.PerEndpoint[OtherBucket] != nil, c.maxEndpoints) {
+			endpoint = OtherBucket
+		}
+		if _, ok := km.PerEndpoint[endpoint]; !ok {
+			km.PerEndpoint[endpoint] = &EndpointMetrics{}
+		}
 	}
 	atomic.AddInt64(&km.PerEndpoint[endpoint].TotalRequests, 1)
 	atomic.AddInt64(&km.PerEndpoint[endpoint].TotalTokens, int64(tokens))
+	return endpoint
 }
 
-// updateModelMetrics updates per-model metrics breakdown
-func (c *MetricsCollector) updateModelMetrics(km *KeyMetrics, model string, tokens int) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// atCap reports whether a breakdown map with size entries has no room for
+// another named entry. The overflow entry does not count toward the cap.
+func atCap(size int, hasOther bool, limit int) bool {
+	if limit <= 0 {
+		return false
+	}
+	if hasOther {
+		size--
+	}
+	return size >= limit
+}
 
+// updateModelMetrics updates per-model metrics breakdown and returns the
+// entry name used, which is OtherBucket once the key's model cap is reached.
+// Callers must hold c.mu.
+func (c *MetricsCollector) updateModelMetrics(km *KeyMetrics, model string, tokens int) string {
 	if _, ok := km.PerModel[model]; !ok {
-		km.PerModel[model] = &ModelMetrics{}
+		if atCap(len(km.PerModel), km.PerModel[OtherBucket] != nil, c.maxModels) {
+			model = OtherBucket
+		}
+		if _, ok := km.PerModel[model]; !ok {
+			km.PerModel[model] = &ModelMetrics{}
+		}
 	}
 	atomic.AddInt64(&km.PerModel[model].TotalRequests, 1)
 	atomic.AddInt64(&km.PerModel[model].TotalTokens, int64(tokens))
+	return model
+}
+
+// updateLabelMetrics counts a request under each of its header label values,
+// folding values beyond the per-key cap into OtherBucket. Callers must hold c.mu.
+func (c *MetricsCollector) updateLabelMetrics(km *KeyMetrics, labels map[string]string) {
+	for name, value := range labels {
+		if km.PerLabel == nil {
+			km.PerLabel = make(map[string]map[string]int64)
+		}
+		values := km.PerLabel[name]
+		if values == nil {
+			values = make(map[string]int64)
+			km.PerLabel[name] = values
+		}
+		if _, ok := values[value]; !ok {
+			_, hasOther := values[OtherBucket]
+			if atCap(len(values), hasOther, c.maxLabelValues) {
+				value = OtherBucket
+			}
+		}
+		values[value]++
+	}
 }
 
 // recordLatency records request latency in the Prometheus histogram
 func (c *MetricsCollector) recordLatency(apiKey, endpoint, model string, duration time.Duration) {
+	if n := c.latencySampleRate; n > 1 && (c.latencySeq.Add(1)-1)%n != 0 {
+		return
+	}
 	if c.RequestLatency != nil {
 		c.RequestLatency.WithLabelValues(apiKey, endpoint, model).Observe(duration.Seconds())
 	}
@@ -192,6 +745,34 @@ func (c *MetricsCollector) GetMetricsForKey(apiKey string) (*KeyMetrics, bool) {
 	return c.copyKeyMetrics(km), true
 }
 
+// GetStats returns statistics about the collector itself: how many keys and
+// breakdown entries it holds and the limits it was configured with
+func (c *MetricsCollector) GetStats() map[string]any {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var endpoints, models int
+	for _, km := range c.metrics {
+		endpoints += len(km.PerEndpoint)
+		models += len(km.PerModel)
+	}
+
+	sampleRate := c.latencySampleRate
+	if sampleRate == 0 {
+		sampleRate = 1
+	}
+	return map[string]any{
+		"tracked_keys":             len(c.metrics),
+		"tracked_endpoints":        endpoints,
+		"tracked_models":           models,
+		"max_endpoints_per_key":    c.maxEndpoints,
+		"max_models_per_key":       c.maxModels,
+		"max_label_values_per_key": c.maxLabelValues,
+		"latency_sample_rate":      int(sampleRate),
+		"func_metrics":             len(c.funcMetrics),
+	}
+}
+
 // copyKeyMetrics creates a deep copy of KeyMetrics to prevent race conditions
 func (c *MetricsCollector) copyKeyMetrics(km *KeyMetrics) *KeyMetrics {
 	if km == nil {
@@ -202,9 +783,19 @@ func (c *MetricsCollector) copyKeyMetrics(km *KeyMetrics) *KeyMetrics {
 		TotalRequests:       atomic.LoadInt64(&km.TotalRequests),
 		SuccessfulRequests:  atomic.LoadInt64(&km.SuccessfulRequests),
 		FailedRequests:      atomic.LoadInt64(&km.FailedRequests),
+		CanceledRequests:    atomic.LoadInt64(&km.CanceledRequests),
+		WebSocketSessions:   atomic.LoadInt64(&km.WebSocketSessions),
 		TotalTokensConsumed: atomic.LoadInt64(&km.TotalTokensConsumed),
 		PerEndpoint:         make(map[string]*EndpointMetrics, len(km.PerEndpoint)),
 		PerModel:            make(map[string]*ModelMetrics, len(km.PerModel)),
+		LastRequest:         km.LastRequest,
+		LatencyEWMAMs:       km.LatencyEWMAMs,
+		LastErrorStatus:     km.LastErrorStatus,
+		LastErrorEndpoint:   km.LastErrorEndpoint,
+		LastErrorTime:       km.LastErrorTime,
+
+		TotalPromptTokens:     atomic.LoadInt64(&km.TotalPromptTokens),
+		TotalCompletionTokens: atomic.LoadInt64(&km.TotalCompletionTokens),
 	}
 
 	// Copy endpoint metrics
@@ -223,16 +814,63 @@ func (c *MetricsCollector) copyKeyMetrics(km *KeyMetrics) *KeyMetrics {
 		}
 	}
 
+	// Copy apdex counts; like throttle counts they are only written under the collector lock
+	if km.Apdex != nil {
+		apdex := *km.Apdex
+		copy.Apdex = &apdex
+	}
+
+	// Copy throttle counts; they are only written under the collector lock
+	if km.ThrottledRequests != nil {
+		copy.ThrottledRequests = make(map[string]int64, len(km.ThrottledRequests))
+		for k, v := range km.ThrottledRequests {
+			copy.ThrottledRequests[k] = v
+		}
+	}
+
+	// Copy label counts, which are likewise only written under the collector lock
+	if km.PerLabel != nil {
+		copy.PerLabel = make(map[string]map[string]int64, len(km.PerLabel))
+		for name, values := range km.PerLabel {
+			copy.PerLabel[name] = make(map[string]int64, len(values))
+			for value, n := range values {
+				copy.PerLabel[name][value] = n
+			}
+		}
+	}
+
 	return copy
 }
 
+// RequestStarted counts a request as in flight until RequestFinished is called
+func (c *MetricsCollector) RequestStarted() {
+	c.inFlight.Add(1)
+}
+
+// RequestFinished ends a request counted by RequestStarted
+func (c *MetricsCollector) RequestFinished() {
+	c.inFlight.Add(-1)
+}
+
+// InFlight returns the number of requests currently being served
+func (c *MetricsCollector) InFlight() int64 {
+	return c.inFlight.Load()
+}
+
 // ResetMetrics clears all collected metrics and reinitializes the collector.
-// This is primarily used for testing and administrative purposes.
+// This is primarily used for testing and administrative purposes. It is safe
+// during traffic: it waits for batches being recorded to finish, requests
+// completing afterwards are recorded into the fresh metrics, and the
+// in-flight gauge is kept since those requests are still being served.
 func (c *MetricsCollector) ResetMetrics() {
+	c.resetMu.Lock()
+	defer c.resetMu.Unlock()
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	c.metrics = make(map[string]*KeyMetrics)
+	c.statusCounts = make(map[int]int64)
+	c.startedAt = time.Now()
 	// Reset histogram initialization flag and recreate
 	c.histogramInit = sync.Once{}
 	c.initializeHistogram()
@@ -1,11 +1,18 @@
 package metrics
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRecordRequestUpdatesCounters(t *testing.T) {
@@ -161,3 +168,580 @@ func BenchmarkRecordRequest(b *testing.B) {
 		c.RecordRequest("key1", "/v1/chat", "gpt-3.5-turbo", 100, 200, 500*time.Millisecond)
 	}
 }
+
+func TestRecordRequestCountsClientCancellations(t *testing.T) {
+	collector := NewMetricsCollector()
+	collector.RecordRequest("key1", "/v1/chat", "gpt-4", 0, StatusClientClosedRequest, time.Second)
+	collector.RecordRequest("key1", "/v1/chat", "gpt-4", 0, 502, time.Second)
+
+	km, ok := collector.GetMetricsForKey("key1")
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), km.TotalRequests)
+	assert.Equal(t, int64(1), km.CanceledRequests)
+	assert.Equal(t, int64(2), km.FailedRequests)
+	assert.Equal(t, int64(0), km.SuccessfulRequests)
+}
+
+func TestRecordRequestCapsBreakdowns(t *testing.T) {
+	collector := NewMetricsCollector()
+	collector.SetBreakdownLimits(5, 2)
+
+	for i := 0; i < 50; i++ {
+		endpoint := fmt.Sprintf("/v1/files/file-%d", i)
+		model := fmt.Sprintf("model-%d", i%4)
+		collector.RecordRequest("key1", endpoint, model, 10, 200, time.Millisecond)
+	}
+
+	km, ok := collector.GetMetricsForKey("key1")
+	assert.True(t, ok)
+
+	// Five named endpoints plus the overflow bucket
+	assert.Len(t, km.PerEndpoint, 6)
+	assert.Contains(t, km.PerEndpoint, OtherBucket)
+	assert.Equal(t, int64(45), km.PerEndpoint[OtherBucket].TotalRequests)
+	assert.Len(t, km.PerModel, 3)
+	assert.Contains(t, km.PerModel, OtherBucket)
+
+	// Breakdowns still reconcile with the key totals
+	var endpointRequests, endpointTokens, modelRequests, modelTokens int64
+	for _, em := range km.PerEndpoint {
+		endpointRequests += em.TotalRequests
+		endpointTokens += em.TotalTokens
+	}
+	for _, mm := range km.PerModel {
+		modelRequests += mm.TotalRequests
+		modelTokens += mm.TotalTokens
+	}
+	assert.Equal(t, km.TotalRequests, endpointRequests)
+	assert.Equal(t, km.TotalTokensConsumed, endpointTokens)
+	assert.Equal(t, km.TotalRequests, modelRequests)
+	assert.Equal(t, km.TotalTokensConsumed, modelTokens)
+
+	// Already-tracked names keep their own entries after the cap is reached
+	collector.RecordRequest("key1", "/v1/files/file-0", "model-0", 10, 200, time.Millisecond)
+	km, _ = collector.GetMetricsForKey("key1")
+	assert.Equal(t, int64(2), km.PerEndpoint["/v1/files/file-0"].TotalRequests)
+}
+
+func TestRecordRequestsMatchesIndividualRecording(t *testing.T) {
+	records := []RequestRecord{
+		{APIKey: "key1", Endpoint: "/v1/embeddings", Model: "embed", Tokens: 10, StatusCode: 200, Duration: time.Millisecond},
+		{APIKey: "key1", Endpoint: "/v1/embeddings", Model: "embed", Tokens: 20, StatusCode: 500, Duration: time.Millisecond},
+		{APIKey: "key2", Endpoint: "/v1/chat", Model: "gpt-4", Tokens: 30, StatusCode: StatusClientClosedRequest, Duration: time.Millisecond},
+		{APIKey: "key1", Endpoint: "/v1/chat", Model: "gpt-4", Tokens: -5, StatusCode: 200, Duration: time.Millisecond},
+	}
+
+	batched := NewMetricsCollector()
+	batched.RecordRequests(records)
+
+	individual := NewMetricsCollector()
+	for _, r := range records {
+		individual.RecordRequest(r.APIKey, r.Endpoint, r.Model, r.Tokens, r.StatusCode, r.Duration)
+	}
+
+	// Timestamps differ between the two collectors; compare everything else
+	withoutTimes := func(metrics map[string]any) map[string]any {
+		for _, v := range metrics {
+			km := v.(*KeyMetrics)
+			km.LastRequest, km.LastErrorTime = time.Time{}, time.Time{}
+		}
+		return metrics
+	}
+	assert.Equal(t, withoutTimes(individual.GetMetrics()), withoutTimes(batched.GetMetrics()))
+
+	km, ok := batched.GetMetricsForKey("key1")
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), km.TotalRequests)
+	assert.Equal(t, int64(2), km.SuccessfulRequests)
+	assert.Equal(t, int64(1), km.FailedRequests)
+	assert.Equal(t, int64(30), km.TotalTokensConsumed)
+	assert.Equal(t, int64(2), km.PerEndpoint["/v1/embeddings"].TotalRequests)
+
+	km, ok = batched.GetMetricsForKey("key2")
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), km.CanceledRequests)
+
+	// An empty batch is a no-op
+	batched.RecordRequests(nil)
+	assert.Len(t, batched.GetMetrics(), 2)
+}
+
+func TestLatencySamplingKeepsCountersExact(t *testing.T) {
+	tests := []struct {
+		name         string
+		rate         int
+		requests     int
+		expectSample uint64
+	}{
+		{name: "disabled", rate: 0, requests: 100, expectSample: 100},
+		{name: "every request", rate: 1, requests: 100, expectSample: 100},
+		{name: "one in ten", rate: 10, requests: 100, expectSample: 10},
+		{name: "one in three rounds up the first", rate: 3, requests: 10, expectSample: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := NewMetricsCollector()
+			collector.SetLatencySampleRate(tt.rate)
+
+			for i := 0; i < tt.requests; i++ {
+				collector.RecordRequest("key1", "/v1/chat", "gpt-4", 10, 200, 10*time.Millisecond)
+			}
+
+			km, ok := collector.GetMetricsForKey("key1")
+			require.True(t, ok)
+			assert.Equal(t, int64(tt.requests), km.TotalRequests)
+			assert.Equal(t, int64(tt.requests*10), km.TotalTokensConsumed)
+
+			observer, err := collector.RequestLatency.GetMetricWithLabelValues("key1", "/v1/chat", "gpt-4")
+			require.NoError(t, err)
+			var m dto.Metric
+			require.NoError(t, observer.(interface{ Write(*dto.Metric) error }).Write(&m))
+			assert.Equal(t, tt.expectSample, m.GetHistogram().GetSampleCount())
+		})
+	}
+}
+
+func TestApdexGradesLatencyAgainstSLO(t *testing.T) {
+	collector := NewMetricsCollector()
+	collector.SetSLOLatency(100 * time.Millisecond)
+
+	// 6 satisfied (<= T), 2 tolerated (<= 4T), 1 slow and 1 failed frustrated
+	latencies := []time.Duration{
+		10 * time.Millisecond, 50 * time.Millisecond, 80 * time.Millisecond,
+		90 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond,
+		150 * time.Millisecond, 400 * time.Millisecond,
+		401 * time.Millisecond,
+	}
+	for _, d := range latencies {
+		collector.RecordRequest("key1", "/v1/chat", "gpt-4", 0, 200, d)
+	}
+	collector.RecordRequest("key1", "/v1/chat", "gpt-4", 0, 502, time.Millisecond)
+
+	km, ok := collector.GetMetricsForKey("key1")
+	require.True(t, ok)
+	require.NotNil(t, km.Apdex)
+	assert.Equal(t, int64(6), km.Apdex.Satisfied)
+	assert.Equal(t, int64(2), km.Apdex.Tolerated)
+	assert.Equal(t, int64(2), km.Apdex.Frustrated)
+	assert.InDelta(t, 0.7, km.Apdex.Score, 1e-9)
+
+	data, err := NewMetricsExporter(collector).ExportJSON()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"apdex":{"satisfied":6,"tolerated":2,"frustrated":2,"score":0.7}`)
+}
+
+func TestApdexDisabledWithoutSLO(t *testing.T) {
+	collector := NewMetricsCollector()
+	collector.RecordRequest("key1", "/v1/chat", "gpt-4", 0, 200, time.Second)
+
+	km, ok := collector.GetMetricsForKey("key1")
+	require.True(t, ok)
+	assert.Nil(t, km.Apdex)
+
+	data, err := NewMetricsExporter(collector).ExportJSON()
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "apdex")
+}
+
+func TestSuccessRatioGauge(t *testing.T) {
+	collector := NewMetricsCollector()
+	for i := 0; i < 3; i++ {
+		collector.RecordRequest("key1", "/v1/chat", "gpt-4", 0, 200, time.Millisecond)
+	}
+	collector.RecordRequest("key1", "/v1/chat", "gpt-4", 0, 500, time.Millisecond)
+	collector.RecordRequest("key2", "/v1/chat", "gpt-4", 0, 503, time.Millisecond)
+	collector.RecordThrottle("key3", "rate")
+	require.NoError(t, collector.Register())
+
+	families, err := collector.Registry().Gather()
+	require.NoError(t, err)
+
+	ratios := map[string]float64{}
+	for _, mf := range families {
+		if mf.GetName() != "nexus_success_ratio" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			ratios[m.GetLabel()[0].GetValue()] = m.GetGauge().GetValue()
+		}
+	}
+	assert.Equal(t, map[string]float64{"key1": 0.75, "key2": 0}, ratios)
+}
+
+func TestResponsesCounterByStatus(t *testing.T) {
+	collector := NewMetricsCollector()
+	collector.RecordRequest("key1", "/v1/chat", "gpt-4", 0, 200, time.Millisecond)
+	collector.RecordRequest("key2", "/v1/chat", "gpt-4", 0, 200, time.Millisecond)
+	collector.RecordRequest("key2", "/v1/chat", "gpt-4", 0, 503, time.Millisecond)
+
+	upstream := map[string]float64{"502": 4}
+	collector.AddCounterVecFunc("nexus_test_upstream_total", "Test counter family", "status", func() map[string]float64 {
+		return upstream
+	})
+	require.NoError(t, collector.Register())
+
+	families, err := collector.Registry().Gather()
+	require.NoError(t, err)
+
+	counts := map[string]map[string]float64{}
+	for _, mf := range families {
+		if mf.GetName() != "nexus_responses_total" && mf.GetName() != "nexus_test_upstream_total" {
+			continue
+		}
+		counts[mf.GetName()] = map[string]float64{}
+		for _, m := range mf.GetMetric() {
+			counts[mf.GetName()][m.GetLabel()[0].GetValue()] = m.GetCounter().GetValue()
+		}
+	}
+	assert.Equal(t, map[string]float64{"200": 2, "503": 1}, counts["nexus_responses_total"])
+	assert.Equal(t, map[string]float64{"502": 4}, counts["nexus_test_upstream_total"])
+}
+
+func TestGetStatsDescribesTrackedState(t *testing.T) {
+	collector := NewMetricsCollector()
+	collector.SetBreakdownLimits(10, 5)
+	collector.SetLatencySampleRate(4)
+
+	stats := collector.GetStats()
+	assert.Equal(t, 0, stats["tracked_keys"])
+	assert.Equal(t, 1, NewMetricsCollector().GetStats()["latency_sample_rate"])
+
+	collector.RecordRequest("stats-key-1", "/v1/chat/completions", "gpt-4", 10, 200, time.Millisecond)
+	collector.RecordRequest("stats-key-1", "/v1/embeddings", "text-embedding-3-small", 10, 200, time.Millisecond)
+	collector.RecordRequest("stats-key-2", "/v1/chat/completions", "gpt-4", 10, 200, time.Millisecond)
+
+	stats = collector.GetStats()
+	assert.Equal(t, 2, stats["tracked_keys"])
+	assert.Equal(t, 3, stats["tracked_endpoints"])
+	assert.Equal(t, 3, stats["tracked_models"])
+	assert.Equal(t, 10, stats["max_endpoints_per_key"])
+	assert.Equal(t, 5, stats["max_models_per_key"])
+	assert.Equal(t, 4, stats["latency_sample_rate"])
+}
+
+// latencySampleCount sums the latency histogram's sample count over every series
+func latencySampleCount(t *testing.T, collector *MetricsCollector) uint64 {
+	t.Helper()
+	collector.mu.RLock()
+	histogram := collector.RequestLatency
+	collector.mu.RUnlock()
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		histogram.Collect(ch)
+		close(ch)
+	}()
+	var count uint64
+	for metric := range ch {
+		var m dto.Metric
+		require.NoError(t, metric.Write(&m))
+		count += m.GetHistogram().GetSampleCount()
+	}
+	return count
+}
+
+func TestResetMetricsPreservesInFlightRequests(t *testing.T) {
+	collector := NewMetricsCollector()
+	release := make(chan struct{})
+	handler := MetricsMiddleware(collector)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	const requests = 20
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+			req.Header.Set("Authorization", "Bearer key1")
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+		}()
+	}
+	require.Eventually(t, func() bool { return collector.InFlight() == requests }, 2*time.Second, time.Millisecond)
+
+	// Requests already being served stay counted across a reset...
+	collector.RecordRequest("key1", "/v1/models", "", 0, 200, time.Millisecond)
+	collector.ResetMetrics()
+	assert.Equal(t, int64(requests), collector.InFlight())
+	_, ok := collector.GetMetricsForKey("key1")
+	assert.False(t, ok, "the reset clears what was recorded before it")
+
+	// ...and are recorded into the fresh metrics when they complete
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int64(0), collector.InFlight())
+	km, ok := collector.GetMetricsForKey("key1")
+	require.True(t, ok)
+	assert.Equal(t, int64(requests), km.TotalRequests)
+	assert.Equal(t, uint64(requests), latencySampleCount(t, collector))
+}
+
+func TestResetMetricsDuringConcurrentTraffic(t *testing.T) {
+	collector := NewMetricsCollector()
+	handler := MetricsMiddleware(collector)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Microsecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	stop := make(chan struct{})
+	var negative atomic.Bool
+	var watchers sync.WaitGroup
+	watchers.Add(2)
+	go func() {
+		defer watchers.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				collector.ResetMetrics()
+				time.Sleep(50 * time.Microsecond)
+			}
+		}
+	}()
+	go func() {
+		defer watchers.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				if collector.InFlight() < 0 {
+					negative.Store(true)
+				}
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+				req.Header.Set("Authorization", fmt.Sprintf("Bearer key%d", worker))
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(stop)
+	watchers.Wait()
+
+	assert.False(t, negative.Load(), "the in-flight gauge must never go negative")
+	assert.Equal(t, int64(0), collector.InFlight())
+
+	// Each recorded request landed wholly on one side of every reset, so the
+	// per-key counts and the latency histogram agree
+	var total int64
+	for _, v := range collector.GetMetrics() {
+		total += v.(*KeyMetrics).TotalRequests
+	}
+	assert.Equal(t, uint64(total), latencySampleCount(t, collector))
+
+	// Traffic after the last reset is counted exactly
+	collector.ResetMetrics()
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+		req.Header.Set("Authorization", "Bearer key0")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	km, ok := collector.GetMetricsForKey("key0")
+	require.True(t, ok)
+	assert.Equal(t, int64(5), km.TotalRequests)
+	assert.Equal(t, uint64(5), latencySampleCount(t, collector))
+}
+
+func TestInFlightGaugeExported(t *testing.T) {
+	collector := NewMetricsCollector()
+	collector.RequestStarted()
+	collector.RequestStarted()
+	collector.RequestFinished()
+	require.NoError(t, collector.Register())
+
+	families, err := collector.Registry().Gather()
+	require.NoError(t, err)
+	for _, mf := range families {
+		if mf.GetName() == "nexus_requests_in_flight" {
+			assert.Equal(t, 1.0, mf.GetMetric()[0].GetGauge().GetValue())
+			return
+		}
+	}
+	t.Fatal("nexus_requests_in_flight not exported")
+}
+
+func TestLatencyEWMAConvergesAfterStepChange(t *testing.T) {
+	collector := NewMetricsCollector()
+	collector.SetLatencyEWMADecay(0.2)
+
+	// The first request sets the average outright
+	collector.RecordRequest("key", "/v1/chat/completions", "gpt-4", 0, 200, 100*time.Millisecond)
+	km, _ := collector.GetMetricsForKey("key")
+	assert.InDelta(t, 100, km.LatencyEWMAMs, 1e-9)
+
+	for i := 0; i < 20; i++ {
+		collector.RecordRequest("key", "/v1/chat/completions", "gpt-4", 0, 200, 100*time.Millisecond)
+	}
+	km, _ = collector.GetMetricsForKey("key")
+	assert.InDelta(t, 100, km.LatencyEWMAMs, 1e-9)
+
+	// After a step to 500ms the average rises monotonically toward it
+	previous := km.LatencyEWMAMs
+	for i := 0; i < 30; i++ {
+		collector.RecordRequest("key", "/v1/chat/completions", "gpt-4", 0, 200, 500*time.Millisecond)
+		km, _ = collector.GetMetricsForKey("key")
+		assert.Greater(t, km.LatencyEWMAMs, previous, "request %d", i)
+		assert.Less(t, km.LatencyEWMAMs, 500.0)
+		previous = km.LatencyEWMAMs
+		if i == 0 {
+			// One step moves a fifth of the way
+			assert.InDelta(t, 180, km.LatencyEWMAMs, 1e-9)
+		}
+	}
+	assert.InDelta(t, 500, km.LatencyEWMAMs, 1)
+
+	// Exported as a gauge in seconds
+	require.NoError(t, collector.Register())
+	families, err := collector.Registry().Gather()
+	require.NoError(t, err)
+	var gauge *dto.Metric
+	for _, f := range families {
+		if f.GetName() == "nexus_latency_ewma_seconds" {
+			require.Len(t, f.GetMetric(), 1)
+			gauge = f.GetMetric()[0]
+		}
+	}
+	require.NotNil(t, gauge, "nexus_latency_ewma_seconds not exported")
+	assert.InDelta(t, km.LatencyEWMAMs/1000, gauge.GetGauge().GetValue(), 1e-9)
+}
+
+func TestLatencyEWMADecayDefaults(t *testing.T) {
+	collector := NewMetricsCollector()
+	for _, decay := range []float64{0, -1, 2} {
+		collector.SetLatencyEWMADecay(decay)
+		assert.Equal(t, defaultLatencyEWMADecay, collector.latencyEWMADecay)
+	}
+}
+
+func TestLastErrorTracksMostRecentFailure(t *testing.T) {
+	collector := NewMetricsCollector()
+
+	collector.RecordRequest("key", "/v1/models", "", 0, 200, time.Millisecond)
+	km, _ := collector.GetMetricsForKey("key")
+	assert.Zero(t, km.LastErrorStatus)
+	assert.Empty(t, km.LastErrorEndpoint)
+	assert.True(t, km.LastErrorTime.IsZero())
+
+	before := time.Now()
+	collector.RecordRequest("key", "/v1/chat/completions", "gpt-4", 0, 502, time.Millisecond)
+	collector.RecordRequest("key", "/v1/embeddings", "", 0, 200, time.Millisecond)
+
+	km, _ = collector.GetMetricsForKey("key")
+	assert.Equal(t, 502, km.LastErrorStatus)
+	assert.Equal(t, "/v1/chat/completions", km.LastErrorEndpoint)
+	assert.False(t, km.LastErrorTime.Before(before))
+
+	data, err := NewMetricsExporter(collector).ExportJSON()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"last_error_status":502`)
+	assert.Contains(t, string(data), `"last_error_endpoint":"/v1/chat/completions"`)
+}
+
+func TestPromptAndCompletionTokenSplit(t *testing.T) {
+	collector := NewMetricsCollector()
+	collector.RecordRequestWithTokens("key", "/v1/chat/completions", "gpt-4", 120, 30, 200, time.Millisecond)
+	collector.RecordRequestWithTokens("key", "/v1/chat/completions", "gpt-4", 80, 70, 200, time.Millisecond)
+
+	km, ok := collector.GetMetricsForKey("key")
+	require.True(t, ok)
+	assert.Equal(t, int64(200), km.TotalPromptTokens)
+	assert.Equal(t, int64(100), km.TotalCompletionTokens)
+	assert.Equal(t, km.TotalPromptTokens+km.TotalCompletionTokens, km.TotalTokensConsumed)
+	assert.Equal(t, int64(300), km.PerModel["gpt-4"].TotalTokens)
+
+	// A total without a split still counts toward the total alone
+	collector.RecordRequest("key", "/v1/chat/completions", "gpt-4", 50, 200, time.Millisecond)
+	km, _ = collector.GetMetricsForKey("key")
+	assert.Equal(t, int64(350), km.TotalTokensConsumed)
+	assert.Equal(t, int64(200), km.TotalPromptTokens)
+	assert.Equal(t, int64(100), km.TotalCompletionTokens)
+
+	// Exported in JSON and as per-key Prometheus counters
+	data, err := NewMetricsExporter(collector).ExportJSON()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"total_prompt_tokens":200`)
+	assert.Contains(t, string(data), `"total_completion_tokens":100`)
+
+	require.NoError(t, collector.Register())
+	families, err := collector.Registry().Gather()
+	require.NoError(t, err)
+	counters := make(map[string]float64)
+	for _, f := range families {
+		switch f.GetName() {
+		case "nexus_prompt_tokens_total", "nexus_completion_tokens_total":
+			require.Len(t, f.GetMetric(), 1)
+			counters[f.GetName()] = f.GetMetric()[0].GetCounter().GetValue()
+		}
+	}
+	assert.Equal(t, map[string]float64{
+		"nexus_prompt_tokens_total":     200,
+		"nexus_completion_tokens_total": 100,
+	}, counters)
+}
+
+func TestSuccessStatusRanges(t *testing.T) {
+	ranges, err := ParseStatusRanges([]string{"200-299", "422"})
+	require.NoError(t, err)
+	assert.Equal(t, []StatusRange{{Min: 200, Max: 299}, {Min: 422, Max: 422}}, ranges)
+
+	collector := NewMetricsCollector()
+	collector.SetSuccessStatusRanges(ranges)
+	collector.RecordRequest("key", "/v1/chat/completions", "gpt-4", 0, 422, time.Millisecond)
+	collector.RecordRequest("key", "/v1/chat/completions", "gpt-4", 0, 400, time.Millisecond)
+
+	km, ok := collector.GetMetricsForKey("key")
+	require.True(t, ok)
+	assert.Equal(t, int64(1), km.SuccessfulRequests)
+	assert.Equal(t, int64(1), km.FailedRequests)
+	assert.Equal(t, 400, km.LastErrorStatus)
+
+	// Without ranges only 2xx counts as success
+	collector = NewMetricsCollector()
+	collector.RecordRequest("key", "/v1/chat/completions", "gpt-4", 0, 422, time.Millisecond)
+	km, _ = collector.GetMetricsForKey("key")
+	assert.Zero(t, km.SuccessfulRequests)
+	assert.Equal(t, int64(1), km.FailedRequests)
+}
+
+func TestWebSocketSessions(t *testing.T) {
+	collector := NewMetricsCollector()
+	collector.RecordRequest("key", "/v1/chat/completions", "gpt-4", 0, 200, 100*time.Millisecond)
+	// An hour-long session must not drag the latency average with it
+	collector.RecordRequest("key", "/v1/realtime", "", 0, 101, time.Hour)
+
+	km, ok := collector.GetMetricsForKey("key")
+	require.True(t, ok)
+	assert.Equal(t, int64(1), km.WebSocketSessions)
+	assert.Equal(t, int64(2), km.SuccessfulRequests)
+	assert.Zero(t, km.FailedRequests)
+	assert.InDelta(t, 100.0, km.LatencyEWMAMs, 0.001)
+
+	// Sessions count as success even when custom ranges leave out 1xx
+	collector = NewMetricsCollector()
+	collector.SetSuccessStatusRanges([]StatusRange{{Min: 200, Max: 299}})
+	collector.RecordRequest("key", "/v1/realtime", "", 0, 101, time.Minute)
+	km, _ = collector.GetMetricsForKey("key")
+	assert.Equal(t, int64(1), km.SuccessfulRequests)
+	assert.Equal(t, int64(1), km.WebSocketSessions)
+}
+
+func TestParseStatusRanges_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "abc", "99", "600", "300-200", "200-", "2xx"} {
+		_, err := ParseStatusRanges([]string{spec})
+		assert.Error(t, err, spec)
+	}
+}
@@ -0,0 +1,203 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+// EMF defaults used when the corresponding metrics.emf field is unset
+const (
+	defaultEMFNamespace = "Nexus"
+	defaultEMFInterval  = time.Minute
+)
+
+// maxEMFSeries caps the key and model pairs reported per interval; requests
+// for further models are reported under OtherBucket
+const maxEMFSeries = 1000
+
+// emfSeriesKey identifies the dimensions of one EMF log line
+type emfSeriesKey struct {
+	apiKey, model string
+}
+
+// emfSeries accumulates the requests of one key and model over an interval
+type emfSeries struct {
+	requests  int64
+	errors    int64
+	latencyMs float64
+	maxMs     float64
+}
+
+// EMFExporter implements interfaces.RequestSink by aggregating requests per
+// key and model, and periodically writes the aggregates as CloudWatch
+// Embedded Metric Format log lines, one per key and model. Each line carries
+// the counts for its interval only, so CloudWatch can sum them.
+type EMFExporter struct {
+	namespace string
+	interval  time.Duration
+	logger    interfaces.Logger
+	maskKeys  bool
+	// successRanges are the status codes not counted as errors; empty means 2xx
+	successRanges []StatusRange
+	// out receives the log lines and now stamps them; both are overridable for tests
+	out io.Writer
+	now func() time.Time
+
+	mu     sync.Mutex
+	series map[emfSeriesKey]*emfSeries
+}
+
+// NewEMFExporter creates an exporter from EMF configuration writing to
+// stdout. Zero namespace and interval settings fall back to defaults.
+func NewEMFExporter(cfg interfaces.EMFConfig, logger interfaces.Logger) *EMFExporter {
+	namespace := cfg.Namespace
+	if namespace == "" {
+		namespace = defaultEMFNamespace
+	}
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = defaultEMFInterval
+	}
+	return &EMFExporter{
+		namespace: namespace,
+		interval:  interval,
+		logger:    logger,
+		out:       os.Stdout,
+		now:       time.Now,
+		series:    make(map[emfSeriesKey]*emfSeries),
+	}
+}
+
+// SetAPIKeyMasking configures whether APIKey dimension values are masked
+// before they leave the gateway
+func (e *EMFExporter) SetAPIKeyMasking(enabled bool) {
+	e.maskKeys = enabled
+}
+
+// SetSuccessStatusRanges sets the status codes not counted as errors, as
+// MetricsCollector.SetSuccessStatusRanges does for the collector
+func (e *EMFExporter) SetSuccessStatusRanges(ranges []StatusRange) {
+	e.successRanges = ranges
+}
+
+// RecordRequest implements interfaces.RequestSink. Responses outside the
+// success status ranges, 2xx by default, count as errors.
+func (e *EMFExporter) RecordRequest(apiKey string, endpoint string, model string, tokens int, statusCode int, duration time.Duration) {
+	if model == "" {
+		model = "unknown"
+	}
+	key := emfSeriesKey{apiKey: apiKey, model: model}
+	latencyMs := float64(duration) / float64(time.Millisecond)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	s, ok := e.series[key]
+	if !ok {
+		if len(e.series) >= maxEMFSeries {
+			key.model = OtherBucket
+			s = e.series[key]
+		}
+		if s == nil {
+			s = &emfSeries{}
+			e.series[key] = s
+		}
+	}
+	s.requests++
+	if !isSuccessStatus(statusCode, e.successRanges) {
+		s.errors++
+	}
+	s.latencyMs += latencyMs
+	s.maxMs = max(s.maxMs, latencyMs)
+}
+
+// Run writes the aggregates every interval until ctx is cancelled, then
+// writes what was recorded since the last interval
+func (e *EMFExporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			e.flush()
+			return
+		case <-ticker.C:
+			e.flush()
+		}
+	}
+}
+
+// flush writes the current interval and logs a failure to do so
+func (e *EMFExporter) flush() {
+	if err := e.Flush(); err != nil && e.logger != nil {
+		e.logger.Warn("Failed to write EMF metrics", map[string]any{
+			"error": err.Error(),
+		})
+	}
+}
+
+// Flush writes one EMF log line per key and model recorded since the last
+// flush and starts a new interval. Nothing is written for an idle interval.
+func (e *EMFExporter) Flush() error {
+	e.mu.Lock()
+	series := e.series
+	e.series = make(map[emfSeriesKey]*emfSeries)
+	e.mu.Unlock()
+
+	keys := make([]emfSeriesKey, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].apiKey != keys[j].apiKey {
+			return keys[i].apiKey < keys[j].apiKey
+		}
+		return keys[i].model < keys[j].model
+	})
+
+	timestamp := e.now().UnixMilli()
+	enc := json.NewEncoder(e.out)
+	for _, key := range keys {
+		if err := enc.Encode(e.line(key, series[key], timestamp)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// line builds the EMF document for one key and model: the _aws metadata
+// declaring the metrics and dimensions, and the values as top-level members
+func (e *EMFExporter) line(key emfSeriesKey, s *emfSeries, timestamp int64) map[string]any {
+	apiKey := key.apiKey
+	if e.maskKeys {
+		apiKey = maskAPIKey(apiKey)
+	}
+	return map[string]any{
+		"_aws": map[string]any{
+			"Timestamp": timestamp,
+			"CloudWatchMetrics": []map[string]any{{
+				"Namespace":  e.namespace,
+				"Dimensions": [][]string{{"APIKey", "Model"}},
+				"Metrics": []map[string]string{
+					{"Name": "Requests", "Unit": "Count"},
+					{"Name": "Errors", "Unit": "Count"},
+					{"Name": "Latency", "Unit": "Milliseconds"},
+					{"Name": "LatencyMax", "Unit": "Milliseconds"},
+				},
+			}},
+		},
+		"APIKey":     apiKey,
+		"Model":      key.model,
+		"Requests":   s.requests,
+		"Errors":     s.errors,
+		"Latency":    s.latencyMs / float64(s.requests),
+		"LatencyMax": s.maxMs,
+	}
+}
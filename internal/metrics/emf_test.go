@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// emfLine is an EMF log line as CloudWatch parses it
+type emfLine struct {
+	AWS struct {
+		Timestamp         int64 `json:"Timestamp"`
+		CloudWatchMetrics []struct {
+			Namespace  string     `json:"Namespace"`
+			Dimensions [][]string `json:"Dimensions"`
+			Metrics    []struct {
+				Name string `json:"Name"`
+				Unit string `json:"Unit"`
+			} `json:"Metrics"`
+		} `json:"CloudWatchMetrics"`
+	} `json:"_aws"`
+	Values map[string]any `json:"-"`
+}
+
+// parseEMF decodes each line of out, keeping every member in Values
+func parseEMF(t *testing.T, out *bytes.Buffer) []emfLine {
+	t.Helper()
+	var lines []emfLine
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		var line emfLine
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line.Values))
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestEMFExporterFlush(t *testing.T) {
+	var out bytes.Buffer
+	e := NewEMFExporter(interfaces.EMFConfig{Enabled: true}, nil)
+	e.out = &out
+	e.now = func() time.Time { return time.UnixMilli(1700000000000) }
+
+	e.RecordRequest("key-a", "/v1/chat/completions", "gpt-4", 100, 200, 100*time.Millisecond)
+	e.RecordRequest("key-a", "/v1/chat/completions", "gpt-4", 50, 502, 300*time.Millisecond)
+	e.RecordRequest("key-b", "/v1/embeddings", "", 10, 200, 20*time.Millisecond)
+	require.NoError(t, e.Flush())
+
+	lines := parseEMF(t, &out)
+	require.Len(t, lines, 2)
+	for _, line := range lines {
+		assert.Equal(t, int64(1700000000000), line.AWS.Timestamp)
+		require.Len(t, line.AWS.CloudWatchMetrics, 1)
+		directive := line.AWS.CloudWatchMetrics[0]
+		assert.Equal(t, "Nexus", directive.Namespace)
+		assert.Equal(t, [][]string{{"APIKey", "Model"}}, directive.Dimensions)
+
+		// Every declared metric and dimension must be a member of the line
+		var names []string
+		for _, m := range directive.Metrics {
+			names = append(names, m.Name)
+			assert.Contains(t, line.Values, m.Name)
+		}
+		assert.Equal(t, []string{"Requests", "Errors", "Latency", "LatencyMax"}, names)
+		assert.Contains(t, line.Values, "APIKey")
+		assert.Contains(t, line.Values, "Model")
+	}
+
+	assert.Equal(t, "key-a", lines[0].Values["APIKey"])
+	assert.Equal(t, "gpt-4", lines[0].Values["Model"])
+	assert.Equal(t, 2.0, lines[0].Values["Requests"])
+	assert.Equal(t, 1.0, lines[0].Values["Errors"])
+	assert.Equal(t, 200.0, lines[0].Values["Latency"])
+	assert.Equal(t, 300.0, lines[0].Values["LatencyMax"])
+	assert.Equal(t, "key-b", lines[1].Values["APIKey"])
+	assert.Equal(t, "unknown", lines[1].Values["Model"])
+	assert.Equal(t, 0.0, lines[1].Values["Errors"])
+
+	// Each flush covers only its own interval; idle intervals write nothing
+	out.Reset()
+	require.NoError(t, e.Flush())
+	assert.Zero(t, out.Len())
+}
+
+func TestEMFExporterMasksAPIKeys(t *testing.T) {
+	var out bytes.Buffer
+	e := NewEMFExporter(interfaces.EMFConfig{Namespace: "Gateway"}, nil)
+	e.out = &out
+	e.SetAPIKeyMasking(true)
+
+	e.RecordRequest("sk-customer-secret-key", "/v1/models", "gpt-4", 0, 200, time.Millisecond)
+	require.NoError(t, e.Flush())
+
+	lines := parseEMF(t, &out)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "Gateway", lines[0].AWS.CloudWatchMetrics[0].Namespace)
+	assert.Equal(t, maskAPIKey("sk-customer-secret-key"), lines[0].Values["APIKey"])
+	assert.NotContains(t, out.String(), "secret")
+}
+
+func TestEMFExporterRunFlushesOnStop(t *testing.T) {
+	var out bytes.Buffer
+	e := NewEMFExporter(interfaces.EMFConfig{Interval: time.Hour}, nil)
+	e.out = &out
+	e.RecordRequest("key-a", "/v1/models", "gpt-4", 0, 200, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		e.Run(ctx)
+		close(done)
+	}()
+	cancel()
+	<-done
+
+	assert.Len(t, parseEMF(t, &out), 1)
+}
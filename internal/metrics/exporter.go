@@ -2,15 +2,17 @@
 package metrics
 
 import (
+	"compress/gzip"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/jamesprial/nexus/internal/interfaces"
-	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
@@ -44,12 +46,43 @@ func (e *MetricsExporter) SetSanitization(enabled bool) {
 	e.sanitizeData = enabled
 }
 
+// ExportFilter selects which keys a filtered export includes. Zero fields match everything.
+type ExportFilter struct {
+	// Prefix keeps only API keys starting with it, compared before masking
+	Prefix string
+	// Since keeps only keys whose last request was after it
+	Since time.Time
+}
+
+// matches reports whether the key and its metrics pass the filter
+func (f ExportFilter) matches(apiKey string, value any) bool {
+	if !strings.HasPrefix(apiKey, f.Prefix) {
+		return false
+	}
+	if f.Since.IsZero() {
+		return true
+	}
+	km, ok := value.(*interfaces.KeyMetrics)
+	return ok && km.LastRequest.After(f.Since)
+}
+
 // ExportJSON exports metrics as JSON with optional sanitization and masking.
 func (e *MetricsExporter) ExportJSON() ([]byte, error) {
+	return e.ExportJSONFiltered(ExportFilter{})
+}
+
+// ExportJSONFiltered exports metrics as JSON like ExportJSON, including only
+// the keys that match filter.
+func (e *MetricsExporter) ExportJSONFiltered(filter ExportFilter) ([]byte, error) {
 	metrics := e.collector.GetMetrics()
 	if metrics == nil {
 		return []byte("{}"), nil
 	}
+	for apiKey, value := range metrics {
+		if !filter.matches(apiKey, value) {
+			delete(metrics, apiKey)
+		}
+	}
 
 	sanitized := e.sanitizeMetrics(metrics)
 	data, err := json.Marshal(sanitized)
@@ -60,19 +93,38 @@ func (e *MetricsExporter) ExportJSON() ([]byte, error) {
 	return data, nil
 }
 
+// WriteFile writes a full export to path, as CSV when path ends in ".csv" and
+// as JSON otherwise. The export goes to a temporary file in the same directory
+// that is then renamed over path, so readers never see a partial file.
+func (e *MetricsExporter) WriteFile(path string) error {
+	var data []byte
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		data, err = e.ExportCSV()
+	} else {
+		data, err = e.ExportJSON()
+	}
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, data)
+}
+
 // ExportPrometheus returns an HTTP handler for Prometheus format.
 // The handler automatically applies Prometheus-compatible metric formatting.
 func (e *MetricsExporter) ExportPrometheus() http.Handler {
 	// Cast to concrete type for Prometheus registration
 	if concreteCollector, ok := e.collector.(*MetricsCollector); ok {
-		reg := prometheus.NewRegistry()
-		if err := reg.Register(concreteCollector); err != nil {
+		// Each collector owns a private registry, so repeated exports and
+		// multiple collectors never collide in the global registry
+		if err := concreteCollector.Register(); err != nil {
 			// If registration fails, return error handler
 			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				http.Error(w, fmt.Sprintf("Prometheus registration failed: %v", err), http.StatusInternalServerError)
 			})
 		}
-		return promhttp.HandlerFor(reg, promhttp.HandlerOpts{
+		return promhttp.HandlerFor(concreteCollector.Registry(), promhttp.HandlerOpts{
 			ErrorHandling: promhttp.ContinueOnError,
 			ErrorLog:      &prometheusErrorLogger{},
 		})
@@ -247,9 +299,25 @@ func PrometheusHandler(collector *MetricsCollector) http.Handler {
 	return exporter.ExportPrometheus()
 }
 
+// defaultMaxConcurrentExports is the number of exports served at once when
+// metrics.max_concurrent_exports is unset
+const defaultMaxConcurrentExports = 4
+
+// exportRetryAfter is the Retry-After, in seconds, sent with shed exports
+const exportRetryAfter = "1"
+
 // AuthenticatedExportHandler creates an HTTP handler that requires authentication
 // and supports multiple export formats based on query parameters.
+// Each export builds a full snapshot, so exports beyond
+// config.MaxConcurrentExports in progress are shed with 503 and Retry-After
+// rather than queued.
 func AuthenticatedExportHandler(exporter *MetricsExporter, config *interfaces.MetricsConfig, allowedKeys []string) http.Handler {
+	maxExports := config.MaxConcurrentExports
+	if maxExports <= 0 {
+		maxExports = defaultMaxConcurrentExports
+	}
+	exports := make(chan struct{}, maxExports)
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Check authentication if required
 		if config.AuthRequired {
@@ -260,15 +328,24 @@ func AuthenticatedExportHandler(exporter *MetricsExporter, config *interfaces.Me
 				return
 			}
 		}
+
+		select {
+		case exports <- struct{}{}:
+			defer func() { <-exports }()
+		default:
+			w.Header().Set("Retry-After", exportRetryAfter)
+			http.Error(w, "Too many concurrent metrics exports", http.StatusServiceUnavailable)
+			return
+		}
 		
 		// Determine export format from query parameter
 		format := strings.ToLower(r.URL.Query().Get("format"))
 		
 		switch format {
 		case "csv":
-			handleCSVExport(w, exporter, config)
+			handleCSVExport(w, r, exporter, config)
 		case "json":
-			handleJSONExport(w, exporter, config)
+			handleJSONExport(w, r, exporter, config)
 		case "prometheus", "":
 			// Default to Prometheus format
 			handlePrometheusExport(w, r, exporter, config)
@@ -287,7 +364,7 @@ func extractBearerToken(authHeader string) string {
 }
 
 // handleCSVExport handles CSV format export requests
-func handleCSVExport(w http.ResponseWriter, exporter *MetricsExporter, config *interfaces.MetricsConfig) {
+func handleCSVExport(w http.ResponseWriter, r *http.Request, exporter *MetricsExporter, config *interfaces.MetricsConfig) {
 	if !config.CSVExportEnabled {
 		http.Error(w, "CSV export not enabled", http.StatusForbidden)
 		return
@@ -301,27 +378,69 @@ func handleCSVExport(w http.ResponseWriter, exporter *MetricsExporter, config *i
 	
 	w.Header().Set("Content-Type", "text/csv")
 	w.Header().Set("Content-Disposition", "attachment; filename=metrics.csv")
-	_, _ = w.Write(data)
+	writeExport(w, r, data)
 }
 
-// handleJSONExport handles JSON format export requests
-func handleJSONExport(w http.ResponseWriter, exporter *MetricsExporter, config *interfaces.MetricsConfig) {
+// handleJSONExport handles JSON format export requests. The optional prefix and
+// since (RFC3339) query parameters narrow the export to matching keys.
+func handleJSONExport(w http.ResponseWriter, r *http.Request, exporter *MetricsExporter, config *interfaces.MetricsConfig) {
 	if !config.JSONExportEnabled {
 		http.Error(w, "JSON export not enabled", http.StatusForbidden)
 		return
 	}
+
+	query := r.URL.Query()
+	filter := ExportFilter{Prefix: query.Get("prefix")}
+	if since := query.Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			http.Error(w, "Invalid since parameter: must be RFC3339", http.StatusBadRequest)
+			return
+		}
+		filter.Since = t
+	}
 	
-	data, err := exporter.ExportJSON()
+	data, err := exporter.ExportJSONFiltered(filter)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to export JSON: %v", err), http.StatusInternalServerError)
 		return
 	}
 	
 	w.Header().Set("Content-Type", "application/json")
-	_, _ = w.Write(data)
+	writeExport(w, r, data)
+}
+
+// writeExport writes an export body, gzip-compressed when the request's
+// Accept-Encoding allows it
+func writeExport(w http.ResponseWriter, r *http.Request, data []byte) {
+	w.Header().Add("Vary", "Accept-Encoding")
+	if !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+		_, _ = w.Write(data)
+		return
+	}
+
+	w.Header().Set("Content-Encoding", "gzip")
+	gz := gzip.NewWriter(w)
+	_, _ = gz.Write(data)
+	_ = gz.Close()
+}
+
+// acceptsGzip reports whether an Accept-Encoding header value lists gzip
+// without refusing it with q=0
+func acceptsGzip(acceptEncoding string) bool {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q=")
+		return !ok || strings.Trim(q, "0.") != ""
+	}
+	return false
 }
 
-// handlePrometheusExport handles Prometheus format export requests
+// handlePrometheusExport handles Prometheus format export requests. The
+// Prometheus handler negotiates gzip itself.
 func handlePrometheusExport(w http.ResponseWriter, r *http.Request, exporter *MetricsExporter, config *interfaces.MetricsConfig) {
 	if !config.PrometheusEnabled {
 		http.Error(w, "Prometheus export not enabled", http.StatusForbidden)
@@ -1,13 +1,20 @@
 package metrics
 
 import (
+	"compress/gzip"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/jamesprial/nexus/internal/interfaces"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestExportJSON(t *testing.T) {
@@ -41,3 +48,312 @@ func TestPrometheusHandler(t *testing.T) {
 	assert.Contains(t, body, `api_key="key1"`)
 	// Check for specific metrics values
 }
+
+func TestPrometheusHandler_MultipleCollectors(t *testing.T) {
+	first := NewMetricsCollector()
+	second := NewMetricsCollector()
+	first.RecordRequest("first-key", "/v1/chat", "gpt-4", 10, 200, 100*time.Millisecond)
+	second.RecordRequest("second-key", "/v1/chat", "gpt-4", 10, 200, 100*time.Millisecond)
+
+	assert.NoError(t, first.Register())
+	assert.NoError(t, second.Register())
+	// Registering again must be a no-op rather than a duplicate-registration error
+	assert.NoError(t, first.Register())
+	assert.NotSame(t, first.Registry(), second.Registry())
+
+	for _, tc := range []struct {
+		collector *MetricsCollector
+		want      string
+		notWant   string
+	}{
+		{first, `api_key="first-key"`, `api_key="second-key"`},
+		{second, `api_key="second-key"`, `api_key="first-key"`},
+	} {
+		assert.NotPanics(t, func() {
+			// Building the handler repeatedly must not re-register the collector
+			for i := 0; i < 2; i++ {
+				rr := httptest.NewRecorder()
+				PrometheusHandler(tc.collector).ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+				assert.Equal(t, http.StatusOK, rr.Code)
+				assert.Contains(t, rr.Body.String(), tc.want)
+				assert.NotContains(t, rr.Body.String(), tc.notWant)
+			}
+		})
+	}
+}
+
+func TestPrometheusHandler_GaugeFunc(t *testing.T) {
+	collector := NewMetricsCollector()
+	depth := 3.0
+	collector.AddGaugeFunc("nexus_rate_limit_queue_depth", "Queued requests", func() float64 { return depth })
+
+	rr := httptest.NewRecorder()
+	PrometheusHandler(collector).ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "nexus_rate_limit_queue_depth 3")
+}
+
+func TestPrometheusHandler_Throttled(t *testing.T) {
+	collector := NewMetricsCollector()
+	collector.RecordThrottle("key1", "rate")
+	collector.RecordThrottle("key1", "rate")
+	collector.RecordThrottle("key1", "token")
+
+	rr := httptest.NewRecorder()
+	PrometheusHandler(collector).ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	body := rr.Body.String()
+	assert.Contains(t, body, "# TYPE nexus_throttled_total counter")
+	assert.Contains(t, body, `nexus_throttled_total{api_key="key1",limiter_type="rate"} 2`)
+	assert.Contains(t, body, `nexus_throttled_total{api_key="key1",limiter_type="token"} 1`)
+
+	data, err := NewMetricsExporter(collector).ExportJSON()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"throttled_requests":{"rate":2,"token":1}`)
+}
+
+func TestPrometheusHandler_TokensConsumed(t *testing.T) {
+	collector := NewMetricsCollector()
+	collector.RecordRequest("key1", "/v1/chat", "gpt-4", 100, 200, 10*time.Millisecond)
+	collector.RecordRequest("key1", "/v1/chat", "gpt-4", 50, 200, 10*time.Millisecond)
+	collector.RecordRequest("key1", "/v1/chat", "gpt-3.5-turbo", 30, 200, 10*time.Millisecond)
+	collector.RecordRequest("key2", "/v1/chat", "gpt-4", 7, 200, 10*time.Millisecond)
+
+	rr := httptest.NewRecorder()
+	PrometheusHandler(collector).ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	body := rr.Body.String()
+	assert.Contains(t, body, "# TYPE nexus_tokens_consumed_total counter")
+	assert.Contains(t, body, `nexus_tokens_consumed_total{api_key="key1",model="gpt-4"} 150`)
+	assert.Contains(t, body, `nexus_tokens_consumed_total{api_key="key1",model="gpt-3.5-turbo"} 30`)
+	assert.Contains(t, body, `nexus_tokens_consumed_total{api_key="key2",model="gpt-4"} 7`)
+}
+
+func TestAuthenticatedExportHandler_JSONFilters(t *testing.T) {
+	collector := NewMetricsCollector()
+	collector.RecordRequest("tenantA-key1", "/v1/chat", "gpt-4", 10, 200, time.Millisecond)
+	collector.RecordRequest("tenantA-key2", "/v1/chat", "gpt-4", 10, 200, time.Millisecond)
+	collector.RecordRequest("tenantB-key1", "/v1/chat", "gpt-4", 10, 200, time.Millisecond)
+
+	// Age one tenantA key so the since filter can tell them apart
+	cutoff := time.Now().Add(-time.Hour)
+	collector.mu.Lock()
+	collector.metrics["tenantA-key2"].LastRequest = cutoff.Add(-time.Hour)
+	collector.mu.Unlock()
+
+	// Masking is off so the assertions can name keys; filtering sees raw keys either way
+	exporter := NewMetricsExporter(collector)
+	exporter.SetAPIKeyMasking(false)
+	handler := AuthenticatedExportHandler(exporter, &interfaces.MetricsConfig{
+		JSONExportEnabled: true,
+	}, nil)
+
+	tests := []struct {
+		name         string
+		query        string
+		expectStatus int
+		expectKeys   []string
+	}{
+		{
+			name:         "no filter",
+			query:        "format=json",
+			expectStatus: http.StatusOK,
+			expectKeys:   []string{"tenantA-key1", "tenantA-key2", "tenantB-key1"},
+		},
+		{
+			name:         "prefix",
+			query:        "format=json&prefix=tenantA-",
+			expectStatus: http.StatusOK,
+			expectKeys:   []string{"tenantA-key1", "tenantA-key2"},
+		},
+		{
+			name:         "prefix and since",
+			query:        "format=json&prefix=tenantA-&since=" + url.QueryEscape(cutoff.Format(time.RFC3339Nano)),
+			expectStatus: http.StatusOK,
+			expectKeys:   []string{"tenantA-key1"},
+		},
+		{
+			name:         "since in the future",
+			query:        "format=json&since=" + url.QueryEscape(time.Now().Add(time.Hour).Format(time.RFC3339)),
+			expectStatus: http.StatusOK,
+			expectKeys:   []string{},
+		},
+		{
+			name:         "unmatched prefix",
+			query:        "format=json&prefix=tenantC-",
+			expectStatus: http.StatusOK,
+			expectKeys:   []string{},
+		},
+		{
+			name:         "invalid since",
+			query:        "format=json&since=yesterday",
+			expectStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("GET", "/metrics?"+tt.query, nil))
+
+			require.Equal(t, tt.expectStatus, rr.Code)
+			if tt.expectStatus != http.StatusOK {
+				return
+			}
+
+			var result map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+			keys := make([]string, 0, len(result))
+			for key := range result {
+				keys = append(keys, key)
+			}
+			assert.ElementsMatch(t, tt.expectKeys, keys)
+		})
+	}
+}
+
+func TestAuthenticatedExportHandler_Gzip(t *testing.T) {
+	collector := NewMetricsCollector()
+	// A single key keeps the CSV rows, written in map order, comparable
+	collector.RecordRequest("key1", "/v1/chat", "gpt-4", 100, 200, time.Millisecond)
+	collector.RecordRequest("key1", "/v1/embeddings", "", 20, 500, time.Millisecond)
+
+	handler := AuthenticatedExportHandler(NewMetricsExporter(collector), &interfaces.MetricsConfig{
+		PrometheusEnabled: true,
+		JSONExportEnabled: true,
+		CSVExportEnabled:  true,
+	}, nil)
+
+	for _, format := range []string{"json", "csv", "prometheus"} {
+		t.Run(format, func(t *testing.T) {
+			plain := httptest.NewRecorder()
+			handler.ServeHTTP(plain, httptest.NewRequest("GET", "/metrics?format="+format, nil))
+			require.Equal(t, http.StatusOK, plain.Code)
+			assert.Empty(t, plain.Header().Get("Content-Encoding"))
+
+			req := httptest.NewRequest("GET", "/metrics?format="+format, nil)
+			req.Header.Set("Accept-Encoding", "gzip, deflate")
+			compressed := httptest.NewRecorder()
+			handler.ServeHTTP(compressed, req)
+			require.Equal(t, http.StatusOK, compressed.Code)
+			assert.Equal(t, "gzip", compressed.Header().Get("Content-Encoding"))
+
+			gz, err := gzip.NewReader(compressed.Body)
+			require.NoError(t, err)
+			body, err := io.ReadAll(gz)
+			require.NoError(t, err)
+			assert.Equal(t, plain.Body.String(), string(body))
+		})
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{header: "", want: false},
+		{header: "gzip", want: true},
+		{header: "deflate, GZIP;q=0.5", want: true},
+		{header: "gzip;q=0", want: false},
+		{header: "gzip; q=0.000", want: false},
+		{header: "br, identity", want: false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, acceptsGzip(tt.header), "Accept-Encoding %q", tt.header)
+	}
+}
+
+func TestMetricsExporter_WriteFile(t *testing.T) {
+	collector := NewMetricsCollector()
+	collector.RecordRequest("key1-abcdefgh", "/v1/chat", "gpt-4", 10, 200, time.Millisecond)
+	exporter := NewMetricsExporter(collector)
+	exporter.SetAPIKeyMasking(false)
+
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "dump.json")
+	require.NoError(t, os.WriteFile(jsonPath, []byte("stale"), 0o644))
+	require.NoError(t, exporter.WriteFile(jsonPath))
+
+	data, err := os.ReadFile(jsonPath)
+	require.NoError(t, err)
+	var dump map[string]any
+	require.NoError(t, json.Unmarshal(data, &dump))
+	assert.Contains(t, dump, "key1-abcdefgh")
+
+	csvPath := filepath.Join(dir, "dump.CSV")
+	require.NoError(t, exporter.WriteFile(csvPath))
+	data, err = os.ReadFile(csvPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "key1-abcdefgh,1,1,0,10")
+
+	// Only the two dumps remain; temporary files are renamed or removed
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	assert.Error(t, exporter.WriteFile(filepath.Join(dir, "missing", "dump.json")))
+}
+
+// blockingExportWriter holds an export in progress by blocking the response
+// body until release is closed; rejections, written after a non-200
+// WriteHeader, pass straight through
+type blockingExportWriter struct {
+	*httptest.ResponseRecorder
+	started chan<- struct{}
+	release <-chan struct{}
+}
+
+func (w *blockingExportWriter) Write(data []byte) (int, error) {
+	if w.Code == 0 || w.Code == http.StatusOK {
+		w.started <- struct{}{}
+		<-w.release
+	}
+	return w.ResponseRecorder.Write(data)
+}
+
+func TestAuthenticatedExportHandler_ShedsConcurrentExports(t *testing.T) {
+	collector := NewMetricsCollector()
+	collector.RecordRequest("key1", "/v1/chat", "gpt-4", 100, 200, time.Millisecond)
+	handler := AuthenticatedExportHandler(NewMetricsExporter(collector), &interfaces.MetricsConfig{
+		JSONExportEnabled:    true,
+		MaxConcurrentExports: 2,
+	}, nil)
+
+	const scrapes = 5
+	started := make(chan struct{}, scrapes)
+	release := make(chan struct{})
+	codes := make(chan *blockingExportWriter, scrapes)
+	for range scrapes {
+		go func() {
+			w := &blockingExportWriter{ResponseRecorder: httptest.NewRecorder(), started: started, release: release}
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics?format=json", nil))
+			codes <- w
+		}()
+	}
+
+	// Two exports hold the slots while the other three are shed at once
+	for range 2 {
+		<-started
+	}
+	for range scrapes - 2 {
+		w := <-codes
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	}
+	assert.Empty(t, started, "no export beyond the limit may start")
+
+	close(release)
+	for range 2 {
+		assert.Equal(t, http.StatusOK, (<-codes).Code)
+	}
+
+	// Finished exports free their slots
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics?format=json", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
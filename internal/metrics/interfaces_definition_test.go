@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jamesprial/nexus/internal/interfaces"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -38,22 +39,31 @@ func TestMetricsCollectorInterfaceDefinition(t *testing.T) {
 	}
 	*/
 	
-	// Test implementation compliance
-	collector := NewMetricsCollector()
-	
+	// Test implementation compliance through the interface
+	var collector interfaces.MetricsCollector = NewMetricsCollector()
+
 	// Test RecordRequest
 	collector.RecordRequest("test-key", "/v1/test", "test-model", 100, 200, 100*time.Millisecond)
-	
+
 	// Test GetMetrics
 	metrics := collector.GetMetrics()
 	assert.NotNil(t, metrics)
 	assert.Contains(t, metrics, "test-key")
-	
-	// Note: The following methods would need to be implemented:
-	// - GetMetricsForKey
-	// - ResetMetrics  
-	// - ResetMetricsForKey
-	// - GetStats
+
+	// Test GetMetricsForKey
+	_, ok := collector.GetMetricsForKey("test-key")
+	assert.True(t, ok)
+
+	// Test GetStats
+	assert.Equal(t, 1, collector.GetStats()["tracked_keys"])
+
+	// Test ResetMetricsForKey and ResetMetrics
+	collector.ResetMetricsForKey("test-key")
+	_, ok = collector.GetMetricsForKey("test-key")
+	assert.False(t, ok)
+	collector.RecordRequest("test-key", "/v1/test", "test-model", 100, 200, 100*time.Millisecond)
+	collector.ResetMetrics()
+	assert.Empty(t, collector.GetMetrics())
 }
 
 // TestMetricsExporterInterfaceDefinition documents the MetricsExporter interface
@@ -71,11 +71,11 @@ func TestMetricsMiddlewareImplementsInterface(t *testing.T) {
 
 // MockMetricsCollector implements MetricsCollectorInterface for testing
 type MockMetricsCollector struct {
-	recordedRequests []RequestRecord
+	recordedRequests []mockRequestRecord
 	metrics          map[string]*KeyMetrics
 }
 
-type RequestRecord struct {
+type mockRequestRecord struct {
 	APIKey     string
 	Endpoint   string
 	Model      string
@@ -87,13 +87,13 @@ type RequestRecord struct {
 
 func NewMockMetricsCollector() *MockMetricsCollector {
 	return &MockMetricsCollector{
-		recordedRequests: make([]RequestRecord, 0),
+		recordedRequests: make([]mockRequestRecord, 0),
 		metrics:          make(map[string]*KeyMetrics),
 	}
 }
 
 func (m *MockMetricsCollector) RecordRequest(apiKey string, endpoint string, model string, tokens int, statusCode int, duration time.Duration) {
-	record := RequestRecord{
+	record := mockRequestRecord{
 		APIKey:     apiKey,
 		Endpoint:   endpoint,
 		Model:      model,
@@ -130,7 +130,7 @@ func (m *MockMetricsCollector) GetMetrics() map[string]any {
 	return result
 }
 
-func (m *MockMetricsCollector) GetRecordedRequests() []RequestRecord {
+func (m *MockMetricsCollector) GetRecordedRequests() []mockRequestRecord {
 	return m.recordedRequests
 }
 
@@ -2,9 +2,12 @@
 package metrics
 
 import (
+	"bufio"
 	"context"
+	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jamesprial/nexus/internal/interfaces"
@@ -14,14 +17,22 @@ import (
 type contextKey string
 
 const (
-	// ModelContextKey stores the AI model name in request context
+	// ModelContextKey stores the AI model name in request context. The model
+	// policy middleware sets it to the resolved model so later handlers need
+	// not re-parse the request body.
 	ModelContextKey contextKey = "metrics_model"
 	// TokensContextKey stores the token count in request context
 	TokensContextKey contextKey = "metrics_tokens"
-	// APIKeyContextKey stores the API key in request context
+	// APIKeyContextKey stores the API key in request context. The auth
+	// middleware sets it to the validated client key; the metrics middleware
+	// reads it before falling back to the Authorization header.
 	APIKeyContextKey contextKey = "metrics_api_key"
 )
 
+// StatusClientClosedRequest is the non-standard status (popularized by nginx)
+// recorded when the client disconnects before the upstream responds.
+const StatusClientClosedRequest = 499
+
 // statusRecorder wraps http.ResponseWriter to capture the HTTP status code
 // for metrics collection purposes.
 type statusRecorder struct {
@@ -30,6 +41,25 @@ type statusRecorder struct {
 	size   int
 }
 
+// statusRecorderPool reuses statusRecorders across requests
+var statusRecorderPool = sync.Pool{
+	New: func() any { return new(statusRecorder) },
+}
+
+// acquireStatusRecorder returns a reset statusRecorder wrapping w
+func acquireStatusRecorder(w http.ResponseWriter) *statusRecorder {
+	r := statusRecorderPool.Get().(*statusRecorder)
+	r.ResponseWriter = w
+	return r
+}
+
+// releaseStatusRecorder clears r, so it keeps no reference to the finished
+// response, and returns it to the pool. r must not be used afterwards.
+func releaseStatusRecorder(r *statusRecorder) {
+	*r = statusRecorder{}
+	statusRecorderPool.Put(r)
+}
+
 // WriteHeader captures the status code and forwards the call
 func (r *statusRecorder) WriteHeader(status int) {
 	r.status = status
@@ -47,6 +77,34 @@ func (r *statusRecorder) Write(data []byte) (int, error) {
 	return size, err
 }
 
+// Flush forwards to the underlying writer so streamed responses are not held
+// back. The reverse proxy also flushes to force chunked encoding for
+// responses with trailers, which the trailers need.
+func (r *statusRecorder) Flush() {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack takes over the connection for a protocol switch, such as a
+// WebSocket session relayed by the proxy, and records the response as 101
+// since the handler writes it to the connection itself
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 // Status returns the captured HTTP status code
 func (r *statusRecorder) Status() int {
 	if r.status == 0 {
@@ -62,20 +120,86 @@ func (r *statusRecorder) Size() int {
 
 // MetricsMiddleware creates HTTP middleware that collects request metrics.
 // It wraps handlers to automatically record request duration, status codes,
-// and other metrics data extracted from the request context.
-func MetricsMiddleware(collector interfaces.MetricsCollector) func(http.Handler) http.Handler {
-	if collector == nil {
+// and other metrics data extracted from the request context. Any sink,
+// such as a full interfaces.MetricsCollector or a MemorySink in tests, can
+// receive the records; WithSink adds further sinks. Sinks that accept whole
+// RequestRecords also receive the labels captured by WithLabelHeaders.
+func MetricsMiddleware(collector interfaces.RequestSink, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	o := newMiddlewareOptions(opts)
+	sinks := o.sinks
+	if collector != nil {
+		sinks = append([]interfaces.RequestSink{collector}, sinks...)
+	}
+	if len(sinks) == 0 {
 		// Return pass-through middleware if no collector provided
 		return func(next http.Handler) http.Handler {
 			return next
 		}
 	}
 
+	return metricsMiddleware(func(rec RequestRecord) {
+		for _, sink := range sinks {
+			recordTo(sink, rec)
+		}
+	}, inFlightTrackerFor(collector), o)
+}
+
+// AsyncMetricsMiddleware creates HTTP middleware like MetricsMiddleware that
+// hands records to recorder instead of recording them on the request path.
+// Sinks added with WithSink are still called on the request path.
+func AsyncMetricsMiddleware(recorder *AsyncRecorder, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	if recorder == nil {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+	o := newMiddlewareOptions(opts)
+
+	// In-flight requests are counted on the request path, not through the queue
+	return metricsMiddleware(func(rec RequestRecord) {
+		recorder.Record(rec)
+		for _, sink := range o.sinks {
+			recordTo(sink, rec)
+		}
+	}, inFlightTrackerFor(recorder.collector), o)
+}
+
+// recordTo passes rec to sink, as a whole record when the sink accepts one
+// so that its labels are kept
+func recordTo(sink interfaces.RequestSink, rec RequestRecord) {
+	if batcher, ok := sink.(batchRecorder); ok {
+		batcher.RecordRequests([]RequestRecord{rec})
+		return
+	}
+	sink.RecordRequest(rec.APIKey, rec.Endpoint, rec.Model, rec.Tokens, rec.StatusCode, rec.Duration)
+}
+
+// inFlightTracker is implemented by collectors that count requests being served
+type inFlightTracker interface {
+	RequestStarted()
+	RequestFinished()
+}
+
+// inFlightTrackerFor returns collector as an inFlightTracker, or nil if it isn't one
+func inFlightTrackerFor(collector interfaces.RequestSink) inFlightTracker {
+	tracker, _ := collector.(inFlightTracker)
+	return tracker
+}
+
+// metricsMiddleware builds the request metrics middleware around record,
+// counting requests in flight with tracker when it is not nil, and labelling
+// records and templating their endpoints as o says
+func metricsMiddleware(record func(RequestRecord), tracker inFlightTracker, o middlewareOptions) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Record start time for latency calculation
 			startTime := time.Now()
 
+			if tracker != nil {
+				tracker.RequestStarted()
+				defer tracker.RequestFinished()
+			}
+
 			// Extract API key from various sources
 			apiKey := extractAPIKey(r)
 			
@@ -85,35 +209,63 @@ func MetricsMiddleware(collector interfaces.MetricsCollector) func(http.Handler)
 				r = r.WithContext(ctx)
 			}
 
+			// Let downstream handlers report model and token usage
+			r = withUsage(r)
+
 			// Determine endpoint path for metrics
-			endpoint := sanitizeEndpoint(r.URL.Path)
+			endpoint := templateEndpoint(o.pathTemplates, sanitizeEndpoint(r.URL.Path))
+			labels := headerLabels(r, o.labelHeaders)
 			
-			// Wrap response writer to capture status and size
-			recorder := &statusRecorder{ResponseWriter: w, status: 0, size: 0}
+			// Wrap response writer to capture status and size; the recorder is
+			// pooled, so only its status outlives the request
+			recorder := acquireStatusRecorder(w)
 			
 			// Process request through the chain
 			next.ServeHTTP(recorder, r)
 
 			// Calculate request duration
 			duration := time.Since(startTime)
+			status := recorder.Status()
+			releaseStatusRecorder(recorder)
 
 			// Extract additional metrics data from context
 			model := extractModel(r)
 			tokens := extractTokens(r)
+			promptTokens, completionTokens := reportedTokenSplit(r)
 
 			// Record metrics for all requests (including empty API keys)
-			collector.RecordRequest(
-				apiKey,
-				endpoint,
-				model,
-				tokens,
-				recorder.Status(),
-				duration,
-			)
+			record(RequestRecord{
+				APIKey:           apiKey,
+				Endpoint:         endpoint,
+				Model:            model,
+				Tokens:           tokens,
+				StatusCode:       status,
+				Duration:         duration,
+				PromptTokens:     promptTokens,
+				CompletionTokens: completionTokens,
+				Labels:           labels,
+			})
 		})
 	}
 }
 
+// headerLabels returns the label values for r, by label name. Requests without
+// a header are labelled NoLabelValue.
+func headerLabels(r *http.Request, labelHeaders map[string]string) map[string]string {
+	if len(labelHeaders) == 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(labelHeaders))
+	for header, label := range labelHeaders {
+		value := r.Header.Get(header)
+		if value == "" {
+			value = NoLabelValue
+		}
+		labels[label] = value
+	}
+	return labels
+}
+
 // extractAPIKey extracts the API key from the request using multiple strategies.
 // It checks the request context first, then falls back to the Authorization header.
 func extractAPIKey(r *http.Request) string {
@@ -137,21 +289,29 @@ func extractAPIKey(r *http.Request) string {
 	return auth
 }
 
-// extractModel extracts the AI model name from the request context.
+// extractModel extracts the AI model name from the request context, falling
+// back to the model reported via ReportUsage.
 // Returns "unknown" if no model information is available.
 func extractModel(r *http.Request) string {
 	if model, ok := r.Context().Value(ModelContextKey).(string); ok && model != "" {
 		return model
 	}
+	if model, _, _ := reportedUsage(r); model != "" {
+		return model
+	}
 	return "unknown"
 }
 
-// extractTokens extracts the token count from the request context.
+// extractTokens extracts the token count from the request context, falling
+// back to the count reported via ReportUsage.
 // Returns 0 if no token information is available.
 func extractTokens(r *http.Request) int {
 	if tokens, ok := r.Context().Value(TokensContextKey).(int); ok && tokens >= 0 {
 		return tokens
 	}
+	if _, tokens, ok := reportedUsage(r); ok {
+		return tokens
+	}
 	return 0
 }
 
@@ -289,6 +449,8 @@ func ConfigurableMetricsMiddleware(collector interfaces.MetricsCollector, config
 				r = r.WithContext(ctx)
 			}
 
+			r = withUsage(r)
+
 			endpoint := r.URL.Path
 			if config.EnablePathNormalization {
 				endpoint = sanitizeEndpoint(endpoint)
@@ -297,15 +459,17 @@ func ConfigurableMetricsMiddleware(collector interfaces.MetricsCollector, config
 				}
 			}
 			
-			recorder := &statusRecorder{ResponseWriter: w, status: 0, size: 0}
+			recorder := acquireStatusRecorder(w)
 			next.ServeHTTP(recorder, r)
 
 			duration := time.Since(startTime)
+			status := recorder.Status()
+			releaseStatusRecorder(recorder)
 			model := extractModel(r)
 			tokens := extractTokens(r)
 
 			// Record metrics for all requests (including empty API keys)
-			collector.RecordRequest(apiKey, endpoint, model, tokens, recorder.Status(), duration)
+			collector.RecordRequest(apiKey, endpoint, model, tokens, status, duration)
 		})
 	}
 }
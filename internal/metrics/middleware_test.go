@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jamesprial/nexus/internal/interfaces"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
@@ -35,6 +36,37 @@ func TestMetricsMiddlewareRecordsMetrics(t *testing.T) {
 	// Assert tokens, endpoint, model - but need to set them in test
 }
 
+func TestMetricsMiddlewarePrefersContextAPIKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		contextKey string
+		expectKey  string
+	}{
+		{name: "context key set by auth", contextKey: "client-key", expectKey: "client-key"},
+		{name: "header fallback", contextKey: "", expectKey: "upstream-key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := NewMetricsCollector()
+			handler := MetricsMiddleware(collector)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, tt.expectKey, r.Context().Value(APIKeyContextKey))
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "/v1/chat", nil)
+			req.Header.Set("Authorization", "Bearer upstream-key")
+			req = SetAPIKey(req, tt.contextKey)
+
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			metrics := collector.GetMetrics()
+			assert.Len(t, metrics, 1)
+			assert.Contains(t, metrics, tt.expectKey)
+		})
+	}
+}
+
 func TestMetricsMiddlewareCapturesFailure(t *testing.T) {
 	collector := NewMetricsCollector()
 	mw := MetricsMiddleware(collector)
@@ -85,3 +117,220 @@ func TestMetricsMiddlewareConcurrentRequests(t *testing.T) {
 	assert.Equal(t, int64(50), keyMetrics.TotalRequests)
 	assert.Equal(t, int64(50), keyMetrics.SuccessfulRequests)
 }
+
+func TestMetricsMiddlewareRecordsIntoMockCollector(t *testing.T) {
+	mock := NewMockMetricsCollector()
+	mw := MetricsMiddleware(mock)
+
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ReportUsage(r, "gpt-4", 42, false)
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	req := SetAPIKey(httptest.NewRequest("POST", "/v1/chat/completions", nil), "mock-key")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	recorded := mock.GetRecordedRequests()
+	if assert.Len(t, recorded, 1) {
+		assert.Equal(t, "mock-key", recorded[0].APIKey)
+		assert.Equal(t, "/v1/chat/completions", recorded[0].Endpoint)
+		assert.Equal(t, "gpt-4", recorded[0].Model)
+		assert.Equal(t, 42, recorded[0].Tokens)
+		assert.Equal(t, http.StatusTeapot, recorded[0].StatusCode)
+	}
+}
+
+func TestMetricsMiddlewareWithSink(t *testing.T) {
+	collector := NewMetricsCollector()
+	sink := NewMemorySink()
+	handler := MetricsMiddleware(collector, WithSink(sink), WithSink(nil))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/v1/models", nil)
+	req.Header.Set("Authorization", "Bearer key1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Both the collector and the extra sink see the request
+	km, ok := collector.GetMetricsForKey("key1")
+	if assert.True(t, ok) {
+		assert.EqualValues(t, 1, km.TotalRequests)
+	}
+	records := sink.Records()
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, "key1", records[0].APIKey)
+		assert.Equal(t, http.StatusOK, records[0].StatusCode)
+	}
+
+	sink.Reset()
+	assert.Empty(t, sink.Records())
+
+	// A sink alone is enough to record
+	sinkOnly := NewMemorySink()
+	MetricsMiddleware(nil, WithSink(sinkOnly))(http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/missing", nil))
+	if assert.Len(t, sinkOnly.Records(), 1) {
+		assert.Equal(t, http.StatusNotFound, sinkOnly.Records()[0].StatusCode)
+	}
+}
+
+func TestMetricsMiddlewareLabelHeaders(t *testing.T) {
+	collector := NewMetricsCollector()
+	collector.SetLabelValueLimit(2)
+	handler := MetricsMiddleware(collector, WithLabelHeaders(map[string]string{"X-Tenant-ID": "tenant"}))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+
+	send := func(tenant string) {
+		req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+		req.Header.Set("Authorization", "Bearer key1")
+		if tenant != "" {
+			req.Header.Set("X-Tenant-ID", tenant)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	send("acme")
+	send("acme")
+	send("")
+	// The cap of two values is reached, so new tenants fold into OtherBucket
+	send("globex")
+	send("initech")
+	send("acme")
+
+	km, ok := collector.GetMetricsForKey("key1")
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, map[string]map[string]int64{
+		"tenant": {"acme": 3, NoLabelValue: 1, OtherBucket: 2},
+	}, km.PerLabel)
+	assert.EqualValues(t, 6, km.TotalRequests)
+}
+
+func TestMetricsMiddlewareWithoutLabelHeaders(t *testing.T) {
+	collector := NewMetricsCollector()
+	handler := MetricsMiddleware(collector)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/v1/models", nil)
+	req.Header.Set("Authorization", "Bearer key1")
+	req.Header.Set("X-Tenant-ID", "acme")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	km, ok := collector.GetMetricsForKey("key1")
+	if assert.True(t, ok) {
+		assert.Nil(t, km.PerLabel)
+	}
+}
+
+func TestMetricsMiddlewarePathTemplates(t *testing.T) {
+	templates, err := NewPathTemplates([]interfaces.PathTemplate{
+		{Pattern: `^/v1/files/[^/]+$`, Template: "/v1/files/:id"},
+		{Pattern: `^/v1/models/([^/]+)/versions/[^/]+$`, Template: "/v1/models/$1/versions/:version"},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	collector := NewMetricsCollector()
+	handler := MetricsMiddleware(collector, WithPathTemplates(templates))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+
+	for _, path := range []string{
+		"/v1/files/file-abc123",
+		"/v1/files/file-def456",
+		"/v1/models/gpt-4/versions/1",
+		"/v1/models/gpt-4/versions/2",
+		"/v1/files/file-abc123/content",
+		"/v1/files/file-def456/content",
+	} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer key1")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	km, ok := collector.GetMetricsForKey("key1")
+	if !assert.True(t, ok) {
+		return
+	}
+	endpoints := make(map[string]int64, len(km.PerEndpoint))
+	for endpoint, em := range km.PerEndpoint {
+		endpoints[endpoint] = em.TotalRequests
+	}
+	// Templated paths share an endpoint; unmatched paths stay distinct
+	assert.Equal(t, map[string]int64{
+		"/v1/files/:id":                      2,
+		"/v1/models/gpt-4/versions/:version": 2,
+		"/v1/files/file-abc123/content":      1,
+		"/v1/files/file-def456/content":      1,
+	}, endpoints)
+}
+
+func TestNewPathTemplates_Invalid(t *testing.T) {
+	for _, configured := range []interfaces.PathTemplate{
+		{Template: "/v1/files/:id"},
+		{Pattern: `^/v1/files/[^/]+$`},
+		{Pattern: `^/v1/files/(`, Template: "/v1/files/:id"},
+	} {
+		_, err := NewPathTemplates([]interfaces.PathTemplate{configured})
+		assert.Error(t, err, configured.Pattern)
+	}
+}
+
+func TestMetricsMiddlewareFlushes(t *testing.T) {
+	collector := NewMetricsCollector()
+	handler := MetricsMiddleware(collector)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if assert.True(t, ok, "the metrics wrapper must not hide Flush") {
+			flusher.Flush()
+		}
+	}))
+
+	req := httptest.NewRequest("GET", "/v1/chat/completions", nil)
+	req.Header.Set("Authorization", "Bearer key1")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.True(t, rr.Flushed)
+	km, ok := collector.GetMetricsForKey("key1")
+	if assert.True(t, ok) {
+		assert.EqualValues(t, 1, km.SuccessfulRequests)
+	}
+}
+
+func TestMetricsMiddlewarePooledRecorderKeepsNoState(t *testing.T) {
+	sink := NewMemorySink()
+	failFirst := true
+	handler := MetricsMiddleware(sink)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failFirst {
+			failFirst = false
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte("upstream failed"))
+		}
+		// The second request writes nothing, so its status must default to 200
+	}))
+
+	for range 2 {
+		req := httptest.NewRequest("GET", "/v1/models", nil)
+		req.Header.Set("Authorization", "Bearer key1")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	records := sink.Records()
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, http.StatusBadGateway, records[0].StatusCode)
+		assert.Equal(t, http.StatusOK, records[1].StatusCode)
+	}
+
+	// A released recorder holds no reference to the finished response
+	w := httptest.NewRecorder()
+	recorder := acquireStatusRecorder(w)
+	recorder.WriteHeader(http.StatusTeapot)
+	_, _ = recorder.Write([]byte("short and stout"))
+	releaseStatusRecorder(recorder)
+	assert.Equal(t, statusRecorder{}, *recorder)
+}
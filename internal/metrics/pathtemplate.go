@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+// PathTemplate records paths matching a pattern under one endpoint, such as
+// /v1/files/:id for every file, so that IDs embedded in paths don't each
+// become an endpoint of their own
+type PathTemplate struct {
+	pattern  *regexp.Regexp
+	template string
+}
+
+// NewPathTemplates compiles configured path templates, keeping their order
+func NewPathTemplates(configured []interfaces.PathTemplate) ([]PathTemplate, error) {
+	templates := make([]PathTemplate, 0, len(configured))
+	for i, c := range configured {
+		if c.Pattern == "" {
+			return nil, fmt.Errorf("path_templates[%d]: pattern is required", i)
+		}
+		if c.Template == "" {
+			return nil, fmt.Errorf("path_templates[%d]: template is required", i)
+		}
+		pattern, err := regexp.Compile(c.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("path_templates[%d]: invalid pattern: %w", i, err)
+		}
+		templates = append(templates, PathTemplate{pattern: pattern, template: c.Template})
+	}
+	return templates, nil
+}
+
+// templateEndpoint returns path with the first matching template applied, or
+// path itself when no template matches
+func templateEndpoint(templates []PathTemplate, path string) string {
+	for _, t := range templates {
+		if t.pattern.MatchString(path) {
+			return t.pattern.ReplaceAllString(path, t.template)
+		}
+	}
+	return path
+}
@@ -0,0 +1,269 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+	"github.com/jamesprial/nexus/internal/utils"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Remote write defaults used when the corresponding metrics.remote_write field is unset
+const (
+	defaultRemoteWriteInterval = time.Minute
+	defaultRemoteWriteTimeout  = 10 * time.Second
+)
+
+// remoteWriteLabel is a label pair of a remote-write time series
+type remoteWriteLabel struct {
+	name, value string
+}
+
+// remoteWriteSeries is one time series with a single sample, the unit of a
+// Prometheus remote-write request
+type remoteWriteSeries struct {
+	labels    []remoteWriteLabel
+	value     float64
+	timestamp int64
+}
+
+// RemoteWriter pushes the collector's metrics to a Prometheus remote-write
+// endpoint, for deployments where the gateway can't be scraped. Each push
+// sends the same series the Prometheus export would serve at that moment.
+type RemoteWriter struct {
+	collector *MetricsCollector
+	url       string
+	interval  time.Duration
+	client    *http.Client
+	logger    interfaces.Logger
+	maskKeys  bool
+	// now is overridable for tests
+	now func() time.Time
+}
+
+// NewRemoteWriter creates a writer from remote write configuration. Zero
+// interval and timeout settings fall back to defaults.
+func NewRemoteWriter(collector *MetricsCollector, cfg interfaces.RemoteWriteConfig, logger interfaces.Logger) *RemoteWriter {
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = defaultRemoteWriteInterval
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultRemoteWriteTimeout
+	}
+	return &RemoteWriter{
+		collector: collector,
+		url:       cfg.URL,
+		interval:  interval,
+		client:    &http.Client{Timeout: timeout},
+		logger:    logger,
+		now:       time.Now,
+	}
+}
+
+// SetAPIKeyMasking configures whether api_key label values are masked
+// before they leave the gateway
+func (w *RemoteWriter) SetAPIKeyMasking(enabled bool) {
+	w.maskKeys = enabled
+}
+
+// Run pushes metrics every interval until ctx is cancelled. Failed pushes
+// are logged and retried on the next tick with fresh values.
+func (w *RemoteWriter) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := w.Push(ctx); err != nil && ctx.Err() == nil && w.logger != nil {
+			w.logger.Warn("Failed to push metrics to remote write endpoint", map[string]any{
+				"url":   utils.MaskURL(w.url),
+				"error": err.Error(),
+			})
+		}
+	}
+}
+
+// Push sends the collector's current values to the remote write endpoint as
+// a snappy-compressed WriteRequest protobuf
+func (w *RemoteWriter) Push(ctx context.Context) error {
+	series, err := w.series()
+	if err != nil {
+		return err
+	}
+	body := encodeSnappy(encodeWriteRequest(series))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("remote write endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// series gathers a snapshot of the collector's registry and flattens it into
+// remote-write series stamped with the current time
+func (w *RemoteWriter) series() ([]remoteWriteSeries, error) {
+	if err := w.collector.Register(); err != nil {
+		return nil, err
+	}
+	families, err := w.collector.Registry().Gather()
+	if err != nil {
+		return nil, fmt.Errorf("failed to gather metrics: %w", err)
+	}
+
+	timestamp := w.now().UnixMilli()
+	var series []remoteWriteSeries
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			series = append(series, w.flatten(family.GetName(), family.GetType(), m, timestamp)...)
+		}
+	}
+	return series, nil
+}
+
+// flatten converts one metric into its samples. Histograms and summaries
+// expand into the same _bucket, _sum and _count series a scrape would see.
+func (w *RemoteWriter) flatten(name string, typ dto.MetricType, m *dto.Metric, timestamp int64) []remoteWriteSeries {
+	labels := make([]remoteWriteLabel, 0, len(m.GetLabel()))
+	for _, lp := range m.GetLabel() {
+		value := lp.GetValue()
+		if w.maskKeys && lp.GetName() == "api_key" {
+			value = maskAPIKey(value)
+		}
+		labels = append(labels, remoteWriteLabel{name: lp.GetName(), value: value})
+	}
+	sample := func(name string, value float64, extra ...remoteWriteLabel) remoteWriteSeries {
+		all := make([]remoteWriteLabel, 0, len(labels)+len(extra)+1)
+		all = append(all, remoteWriteLabel{name: "__name__", value: name})
+		all = append(all, labels...)
+		all = append(all, extra...)
+		// Remote write requires labels sorted by name
+		sort.Slice(all, func(i, j int) bool { return all[i].name < all[j].name })
+		return remoteWriteSeries{labels: all, value: value, timestamp: timestamp}
+	}
+
+	switch typ {
+	case dto.MetricType_COUNTER:
+		return []remoteWriteSeries{sample(name, m.GetCounter().GetValue())}
+	case dto.MetricType_GAUGE:
+		return []remoteWriteSeries{sample(name, m.GetGauge().GetValue())}
+	case dto.MetricType_UNTYPED:
+		return []remoteWriteSeries{sample(name, m.GetUntyped().GetValue())}
+	case dto.MetricType_HISTOGRAM:
+		h := m.GetHistogram()
+		var out []remoteWriteSeries
+		for _, b := range h.GetBucket() {
+			le := strconv.FormatFloat(b.GetUpperBound(), 'g', -1, 64)
+			out = append(out, sample(name+"_bucket", float64(b.GetCumulativeCount()), remoteWriteLabel{name: "le", value: le}))
+		}
+		return append(out,
+			sample(name+"_bucket", float64(h.GetSampleCount()), remoteWriteLabel{name: "le", value: "+Inf"}),
+			sample(name+"_sum", h.GetSampleSum()),
+			sample(name+"_count", float64(h.GetSampleCount())),
+		)
+	case dto.MetricType_SUMMARY:
+		s := m.GetSummary()
+		var out []remoteWriteSeries
+		for _, q := range s.GetQuantile() {
+			quantile := strconv.FormatFloat(q.GetQuantile(), 'g', -1, 64)
+			out = append(out, sample(name, q.GetValue(), remoteWriteLabel{name: "quantile", value: quantile}))
+		}
+		return append(out,
+			sample(name+"_sum", s.GetSampleSum()),
+			sample(name+"_count", float64(s.GetSampleCount())),
+		)
+	}
+	return nil
+}
+
+// encodeWriteRequest serializes series as a prometheus.WriteRequest message:
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(series []remoteWriteSeries) []byte {
+	var out, ts, msg []byte
+	for _, s := range series {
+		ts = ts[:0]
+		for _, l := range s.labels {
+			msg = msg[:0]
+			msg = appendProtoBytes(msg, 1, []byte(l.name))
+			msg = appendProtoBytes(msg, 2, []byte(l.value))
+			ts = appendProtoBytes(ts, 1, msg)
+		}
+		msg = msg[:0]
+		msg = binary.AppendUvarint(msg, 1<<3|1) // field 1, 64-bit
+		msg = binary.LittleEndian.AppendUint64(msg, math.Float64bits(s.value))
+		msg = binary.AppendUvarint(msg, 2<<3|0) // field 2, varint
+		msg = binary.AppendUvarint(msg, uint64(s.timestamp))
+		ts = appendProtoBytes(ts, 2, msg)
+
+		out = appendProtoBytes(out, 1, ts)
+	}
+	return out
+}
+
+// appendProtoBytes appends a length-delimited protobuf field
+func appendProtoBytes(b []byte, field uint64, data []byte) []byte {
+	b = binary.AppendUvarint(b, field<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+// maxSnappyLiteral is the longest literal encodeSnappy emits in one element
+const maxSnappyLiteral = 1 << 16
+
+// encodeSnappy wraps data in the snappy block format remote write requires.
+// It emits only literal elements, trading compression for not needing a
+// snappy dependency; any snappy decoder reads the result.
+func encodeSnappy(data []byte) []byte {
+	out := binary.AppendUvarint(make([]byte, 0, len(data)+len(data)/maxSnappyLiteral*3+16), uint64(len(data)))
+	for len(data) > 0 {
+		chunk := data
+		if len(chunk) > maxSnappyLiteral {
+			chunk = chunk[:maxSnappyLiteral]
+		}
+		n := len(chunk) - 1
+		switch {
+		case n < 60:
+			out = append(out, byte(n)<<2)
+		case n < 1<<8:
+			out = append(out, 60<<2, byte(n))
+		default:
+			out = append(out, 61<<2, byte(n), byte(n>>8))
+		}
+		out = append(out, chunk...)
+		data = data[len(chunk):]
+	}
+	return out
+}
@@ -0,0 +1,215 @@
+package metrics
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decodedSample is a remote-write series as received by the test server
+type decodedSample struct {
+	labels    map[string]string
+	value     float64
+	timestamp int64
+}
+
+// decodeSnappyLiterals reverses encodeSnappy; it rejects copy elements,
+// which the encoder never emits
+func decodeSnappyLiterals(t *testing.T, data []byte) []byte {
+	t.Helper()
+	size, n := binary.Uvarint(data)
+	require.Positive(t, n, "missing snappy length preamble")
+	data = data[n:]
+
+	out := make([]byte, 0, size)
+	for len(data) > 0 {
+		tag := data[0]
+		data = data[1:]
+		require.Zero(t, tag&3, "unexpected snappy copy element")
+		length := int(tag >> 2)
+		switch {
+		case length == 60:
+			length = int(data[0])
+			data = data[1:]
+		case length == 61:
+			length = int(data[0]) | int(data[1])<<8
+			data = data[2:]
+		case length > 61:
+			t.Fatalf("unexpected snappy literal tag %d", tag)
+		}
+		length++
+		out = append(out, data[:length]...)
+		data = data[length:]
+	}
+	require.Equal(t, int(size), len(out))
+	return out
+}
+
+// protoFields splits a protobuf message into its fields. Length-delimited
+// fields are returned as bytes, 64-bit fields as uint64 bits and varints as uint64.
+func protoFields(t *testing.T, data []byte) (fields []int, values []any) {
+	t.Helper()
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		require.Positive(t, n)
+		data = data[n:]
+		switch key & 7 {
+		case 0:
+			v, n := binary.Uvarint(data)
+			require.Positive(t, n)
+			data = data[n:]
+			values = append(values, v)
+		case 1:
+			values = append(values, binary.LittleEndian.Uint64(data))
+			data = data[8:]
+		case 2:
+			l, n := binary.Uvarint(data)
+			require.Positive(t, n)
+			data = data[n:]
+			values = append(values, data[:l])
+			data = data[l:]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+		fields = append(fields, int(key>>3))
+	}
+	return fields, values
+}
+
+func decodeWriteRequest(t *testing.T, data []byte) []decodedSample {
+	t.Helper()
+	var samples []decodedSample
+	fields, values := protoFields(t, data)
+	for i := range fields {
+		require.Equal(t, 1, fields[i], "WriteRequest holds only timeseries")
+		s := decodedSample{labels: map[string]string{}}
+		tsFields, tsValues := protoFields(t, values[i].([]byte))
+		for j := range tsFields {
+			f, v := protoFields(t, tsValues[j].([]byte))
+			switch tsFields[j] {
+			case 1:
+				s.labels[string(v[0].([]byte))] = string(v[1].([]byte))
+			case 2:
+				require.Equal(t, []int{1, 2}, f)
+				s.value = math.Float64frombits(v[0].(uint64))
+				s.timestamp = int64(v[1].(uint64))
+			}
+		}
+		samples = append(samples, s)
+	}
+	return samples
+}
+
+// findSample returns the value of the series with the given labels
+func findSample(samples []decodedSample, labels map[string]string) (decodedSample, bool) {
+	for _, s := range samples {
+		if fmt.Sprint(s.labels) == fmt.Sprint(labels) {
+			return s, true
+		}
+	}
+	return decodedSample{}, false
+}
+
+func TestRemoteWriterPush(t *testing.T) {
+	type push struct {
+		header http.Header
+		body   []byte
+	}
+	pushes := make(chan push, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		pushes <- push{header: r.Header, body: body}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	collector := NewMetricsCollector()
+	collector.RecordRequest("client-key-123456", "/v1/chat/completions", "gpt-4", 120, 200, 30*time.Millisecond)
+	collector.RecordRequest("client-key-123456", "/v1/chat/completions", "gpt-4", 0, 500, 10*time.Millisecond)
+
+	writer := NewRemoteWriter(collector, interfaces.RemoteWriteConfig{URL: server.URL}, nil)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	writer.now = func() time.Time { return now }
+	require.NoError(t, writer.Push(context.Background()))
+
+	got := <-pushes
+	assert.Equal(t, "snappy", got.header.Get("Content-Encoding"))
+	assert.Equal(t, "application/x-protobuf", got.header.Get("Content-Type"))
+	assert.Equal(t, "0.1.0", got.header.Get("X-Prometheus-Remote-Write-Version"))
+
+	samples := decodeWriteRequest(t, decodeSnappyLiterals(t, got.body))
+
+	ok200, found := findSample(samples, map[string]string{"__name__": "nexus_responses_total", "status": "200"})
+	require.True(t, found, "nexus_responses_total{status=200} missing")
+	assert.Equal(t, 1.0, ok200.value)
+	assert.Equal(t, now.UnixMilli(), ok200.timestamp)
+
+	tokens, found := findSample(samples, map[string]string{"__name__": "nexus_tokens_consumed_total", "api_key": "client-key-123456", "model": "gpt-4"})
+	require.True(t, found, "nexus_tokens_consumed_total missing")
+	assert.Equal(t, 120.0, tokens.value)
+
+	// Histograms expand into the series a scrape would return
+	var count *decodedSample
+	for i, s := range samples {
+		if s.labels["__name__"] == "nexus_request_latency_seconds_count" {
+			count = &samples[i]
+		}
+	}
+	require.NotNil(t, count, "latency histogram count missing")
+	assert.Equal(t, 2.0, count.value)
+}
+
+func TestRemoteWriterMasksAPIKeys(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+	}))
+	defer server.Close()
+
+	collector := NewMetricsCollector()
+	collector.RecordRequest("client-key-123456", "/v1/chat/completions", "gpt-4", 10, 200, time.Millisecond)
+
+	writer := NewRemoteWriter(collector, interfaces.RemoteWriteConfig{URL: server.URL}, nil)
+	writer.SetAPIKeyMasking(true)
+	require.NoError(t, writer.Push(context.Background()))
+
+	keyed := 0
+	for _, s := range decodeWriteRequest(t, decodeSnappyLiterals(t, <-bodies)) {
+		if key, ok := s.labels["api_key"]; ok {
+			keyed++
+			assert.Equal(t, maskAPIKey("client-key-123456"), key)
+		}
+	}
+	assert.Positive(t, keyed, "expected series labelled by api_key")
+}
+
+func TestRemoteWriterPushErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	writer := NewRemoteWriter(NewMetricsCollector(), interfaces.RemoteWriteConfig{URL: server.URL}, nil)
+	err := writer.Push(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "400")
+}
+
+func TestEncodeSnappyLongInput(t *testing.T) {
+	data := make([]byte, 3*maxSnappyLiteral+100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	assert.Equal(t, data, decodeSnappyLiterals(t, encodeSnappy(data)))
+}
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+// MiddlewareOption configures MetricsMiddleware
+type MiddlewareOption func(*middlewareOptions)
+
+// middlewareOptions holds the settings applied by MiddlewareOption
+type middlewareOptions struct {
+	sinks         []interfaces.RequestSink
+	labelHeaders  map[string]string
+	pathTemplates []PathTemplate
+}
+
+// newMiddlewareOptions applies opts to empty options
+func newMiddlewareOptions(opts []MiddlewareOption) middlewareOptions {
+	var o middlewareOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithSink sends every request record to sink as well as to the collector
+// passed to MetricsMiddleware. A nil sink is ignored.
+func WithSink(sink interfaces.RequestSink) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		if sink != nil {
+			o.sinks = append(o.sinks, sink)
+		}
+	}
+}
+
+// WithLabelHeaders labels each request record with the values of the given
+// request headers, keyed by header name with the label name as value
+func WithLabelHeaders(headers map[string]string) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.labelHeaders = headers
+	}
+}
+
+// WithPathTemplates records requests under the endpoint given by the first
+// of templates matching their path. Unmatched paths are recorded as they are.
+func WithPathTemplates(templates []PathTemplate) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.pathTemplates = templates
+	}
+}
+
+// MemorySink implements interfaces.RequestSink by keeping every record in
+// memory, for tests and ad hoc inspection. It is safe for concurrent use.
+type MemorySink struct {
+	mu      sync.Mutex
+	records []RequestRecord
+}
+
+// NewMemorySink creates an empty MemorySink
+func NewMemorySink() *MemorySink {
+	return &MemorySink{}
+}
+
+// RecordRequest implements interfaces.RequestSink
+func (s *MemorySink) RecordRequest(apiKey string, endpoint string, model string, tokens int, statusCode int, duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, RequestRecord{
+		APIKey:     apiKey,
+		Endpoint:   endpoint,
+		Model:      model,
+		Tokens:     tokens,
+		StatusCode: statusCode,
+		Duration:   duration,
+	})
+}
+
+// RecordRequests keeps whole records, labels included
+func (s *MemorySink) RecordRequests(records []RequestRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, records...)
+}
+
+// Records returns a copy of the records received so far, oldest first
+func (s *MemorySink) Records() []RequestRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]RequestRecord(nil), s.records...)
+}
+
+// Reset discards the records received so far
+func (s *MemorySink) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = nil
+}
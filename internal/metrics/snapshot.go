@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// snapshotVersion identifies the snapshot format written by SaveSnapshot
+const snapshotVersion = 1
+
+// snapshot is the serialized form of a collector's per-key counters
+type snapshot struct {
+	Version int                    `json:"version"`
+	SavedAt time.Time              `json:"saved_at"`
+	Keys    map[string]*KeyMetrics `json:"keys"`
+}
+
+// SaveSnapshot writes the collector's per-key counters to w as JSON. Latency
+// histograms are not included.
+func (c *MetricsCollector) SaveSnapshot(w io.Writer) error {
+	c.mu.RLock()
+	keys := make(map[string]*KeyMetrics, len(c.metrics))
+	for apiKey, km := range c.metrics {
+		keys[apiKey] = c.copyKeyMetrics(km)
+	}
+	c.mu.RUnlock()
+
+	if err := json.NewEncoder(w).Encode(snapshot{
+		Version: snapshotVersion,
+		SavedAt: time.Now().UTC(),
+		Keys:    keys,
+	}); err != nil {
+		return fmt.Errorf("failed to encode metrics snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot replaces the collector's per-key counters with those read from
+// r, as written by SaveSnapshot. Counters continue from the loaded values;
+// latency histograms start empty.
+func (c *MetricsCollector) LoadSnapshot(r io.Reader) error {
+	var snap snapshot
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return fmt.Errorf("failed to decode metrics snapshot: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("unsupported metrics snapshot version %d", snap.Version)
+	}
+
+	loaded := make(map[string]*KeyMetrics, len(snap.Keys))
+	for apiKey, km := range snap.Keys {
+		if km == nil {
+			continue
+		}
+		if km.PerEndpoint == nil {
+			km.PerEndpoint = make(map[string]*EndpointMetrics)
+		}
+		if km.PerModel == nil {
+			km.PerModel = make(map[string]*ModelMetrics)
+		}
+		loaded[apiKey] = km
+	}
+
+	c.mu.Lock()
+	c.metrics = loaded
+	c.mu.Unlock()
+	return nil
+}
+
+// SaveSnapshotFile writes a snapshot to path atomically
+func (c *MetricsCollector) SaveSnapshotFile(path string) error {
+	var buf bytes.Buffer
+	if err := c.SaveSnapshot(&buf); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+// LoadSnapshotFile loads a snapshot from path. A missing file is reported as
+// an error satisfying errors.Is(err, os.ErrNotExist).
+func (c *MetricsCollector) LoadSnapshotFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open metrics snapshot: %w", err)
+	}
+	defer f.Close()
+	return c.LoadSnapshot(f)
+}
+
+// writeFileAtomic writes data to a temporary file in path's directory and
+// renames it over path, so readers never see a partial file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to move %s into place: %w", path, err)
+	}
+	return nil
+}
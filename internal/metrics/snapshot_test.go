@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	original := NewMetricsCollector()
+	original.RecordRequest("key1", "/v1/chat/completions", "gpt-4", 100, 200, 50*time.Millisecond)
+	original.RecordRequest("key1", "/v1/chat/completions", "gpt-4", 40, 500, 10*time.Millisecond)
+	original.RecordRequest("key2", "/v1/embeddings", "text-embedding-3-small", 7, 200, time.Millisecond)
+	original.RecordThrottle("key2", "rate")
+
+	var buf bytes.Buffer
+	require.NoError(t, original.SaveSnapshot(&buf))
+
+	restored := NewMetricsCollector()
+	require.NoError(t, restored.LoadSnapshot(&buf))
+
+	for _, key := range []string{"key1", "key2"} {
+		want, ok := original.GetMetricsForKey(key)
+		require.True(t, ok)
+		got, ok := restored.GetMetricsForKey(key)
+		require.True(t, ok, "key %s not restored", key)
+		assert.True(t, want.LastRequest.Equal(got.LastRequest))
+		assert.True(t, want.LastErrorTime.Equal(got.LastErrorTime))
+		want.LastRequest, got.LastRequest = time.Time{}, time.Time{}
+		want.LastErrorTime, got.LastErrorTime = time.Time{}, time.Time{}
+		assert.Equal(t, want, got)
+	}
+
+	// Counters continue from the restored totals
+	restored.RecordRequest("key1", "/v1/chat/completions", "gpt-4", 10, 200, time.Millisecond)
+	km, _ := restored.GetMetricsForKey("key1")
+	assert.Equal(t, int64(3), km.TotalRequests)
+	assert.Equal(t, int64(150), km.TotalTokensConsumed)
+	assert.Equal(t, int64(3), km.PerEndpoint["/v1/chat/completions"].TotalRequests)
+
+	// Histograms start over
+	assert.Equal(t, 1, testutil.CollectAndCount(restored.RequestLatency))
+}
+
+func TestSnapshotFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.snapshot")
+
+	c := NewMetricsCollector()
+	err := c.LoadSnapshotFile(path)
+	assert.True(t, errors.Is(err, os.ErrNotExist), "expected not-exist error, got %v", err)
+
+	c.RecordRequest("key1", "/v1/models", "", 0, 200, time.Millisecond)
+	require.NoError(t, c.SaveSnapshotFile(path))
+
+	restored := NewMetricsCollector()
+	require.NoError(t, restored.LoadSnapshotFile(path))
+	km, ok := restored.GetMetricsForKey("key1")
+	require.True(t, ok)
+	assert.Equal(t, int64(1), km.TotalRequests)
+}
+
+func TestLoadSnapshotRejectsInvalidData(t *testing.T) {
+	c := NewMetricsCollector()
+	c.RecordRequest("key1", "/v1/models", "", 0, 200, time.Millisecond)
+
+	assert.Error(t, c.LoadSnapshot(strings.NewReader("not json")))
+	assert.Error(t, c.LoadSnapshot(strings.NewReader(`{"version":99,"keys":{}}`)))
+
+	// A failed load leaves existing metrics in place
+	_, ok := c.GetMetricsForKey("key1")
+	assert.True(t, ok)
+}
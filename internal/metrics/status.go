@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// StatusRange is an inclusive range of HTTP status codes
+type StatusRange struct {
+	Min, Max int
+}
+
+// ParseStatusRanges parses status codes ("422") and inclusive ranges
+// ("200-299") between 100 and 599
+func ParseStatusRanges(specs []string) ([]StatusRange, error) {
+	ranges := make([]StatusRange, 0, len(specs))
+	for _, spec := range specs {
+		lo, hi, isRange := strings.Cut(strings.TrimSpace(spec), "-")
+		first, err := parseStatusCode(lo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status %q: %w", spec, err)
+		}
+		last := first
+		if isRange {
+			if last, err = parseStatusCode(hi); err != nil {
+				return nil, fmt.Errorf("invalid status range %q: %w", spec, err)
+			}
+			if last < first {
+				return nil, fmt.Errorf("invalid status range %q: end is below start", spec)
+			}
+		}
+		ranges = append(ranges, StatusRange{Min: first, Max: last})
+	}
+	return ranges, nil
+}
+
+// parseStatusCode parses one status code between 100 and 599
+func parseStatusCode(s string) (int, error) {
+	code, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, errors.New("not a number")
+	}
+	if code < 100 || code > 599 {
+		return 0, fmt.Errorf("%d is outside 100-599", code)
+	}
+	return code, nil
+}
+
+// isSuccessStatus reports whether code falls in any of ranges, or is 2xx when
+// no ranges are configured. A 101 for a completed WebSocket upgrade is
+// always a success.
+func isSuccessStatus(code int, ranges []StatusRange) bool {
+	if code == http.StatusSwitchingProtocols {
+		return true
+	}
+	if len(ranges) == 0 {
+		return code >= 200 && code < 300
+	}
+	for _, r := range ranges {
+		if code >= r.Min && code <= r.Max {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// SummaryTopKeys is how many keys Summary ranks by request volume
+const SummaryTopKeys = 5
+
+// Summary is a compact overview of everything the collector has recorded
+type Summary struct {
+	TotalRequests int64 `json:"total_requests"`
+	TotalTokens   int64 `json:"total_tokens"`
+	// ErrorRate is the fraction (0-1) of requests that failed
+	ErrorRate float64 `json:"error_rate"`
+	// TopKeys lists the busiest keys, most requests first
+	TopKeys []KeySummary `json:"top_keys"`
+}
+
+// KeySummary is one key's share of the traffic in a Summary
+type KeySummary struct {
+	// APIKey is masked, since summaries are written to logs and health output
+	APIKey   string `json:"api_key"`
+	Requests int64  `json:"requests"`
+	Tokens   int64  `json:"tokens"`
+}
+
+// Summary aggregates the per-key counters into totals and ranks the
+// SummaryTopKeys busiest keys; ties are ordered by key
+func (c *MetricsCollector) Summary() Summary {
+	c.mu.RLock()
+	var summary Summary
+	var failed int64
+	keys := make([]KeySummary, 0, len(c.metrics))
+	for apiKey, km := range c.metrics {
+		summary.TotalRequests += km.TotalRequests
+		summary.TotalTokens += km.TotalTokensConsumed
+		failed += km.FailedRequests
+		keys = append(keys, KeySummary{
+			APIKey:   apiKey,
+			Requests: km.TotalRequests,
+			Tokens:   km.TotalTokensConsumed,
+		})
+	}
+	c.mu.RUnlock()
+
+	if summary.TotalRequests > 0 {
+		summary.ErrorRate = float64(failed) / float64(summary.TotalRequests)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Requests != keys[j].Requests {
+			return keys[i].Requests > keys[j].Requests
+		}
+		return keys[i].APIKey < keys[j].APIKey
+	})
+	if len(keys) > SummaryTopKeys {
+		keys = keys[:SummaryTopKeys]
+	}
+	for i := range keys {
+		keys[i].APIKey = utils.MaskAPIKey(keys[i].APIKey)
+	}
+	summary.TopKeys = keys
+
+	return summary
+}
+
+// LogFields returns the summary as flat log fields, with the top keys
+// rendered on one line
+func (s Summary) LogFields() map[string]any {
+	top := make([]string, len(s.TopKeys))
+	for i, k := range s.TopKeys {
+		top[i] = fmt.Sprintf("%s=%d req/%d tok", k.APIKey, k.Requests, k.Tokens)
+	}
+	return map[string]any{
+		"total_requests": s.TotalRequests,
+		"total_tokens":   s.TotalTokens,
+		"error_rate":     fmt.Sprintf("%.2f%%", s.ErrorRate*100),
+		"top_keys":       strings.Join(top, ", "),
+	}
+}
+
+// AggregateSummary is the subset of Summary that is safe to show to
+// lower-trust consumers: gateway-wide totals with no per-key breakdown
+type AggregateSummary struct {
+	TotalRequests int64 `json:"total_requests"`
+	TotalTokens   int64 `json:"total_tokens"`
+	// ErrorRate is the fraction (0-1) of requests that failed
+	ErrorRate float64 `json:"error_rate"`
+	// RequestsPerSecond is the average rate since collection began
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	// P95LatencySeconds is estimated from the latency histogram buckets
+	P95LatencySeconds float64 `json:"p95_latency_seconds"`
+}
+
+// AggregateSummary returns the totals from Summary together with the average
+// request rate and the p95 latency across all keys
+func (c *MetricsCollector) AggregateSummary() AggregateSummary {
+	summary := c.Summary()
+	aggregate := AggregateSummary{
+		TotalRequests: summary.TotalRequests,
+		TotalTokens:   summary.TotalTokens,
+		ErrorRate:     summary.ErrorRate,
+	}
+
+	c.mu.RLock()
+	elapsed := time.Since(c.startedAt).Seconds()
+	histogram := c.RequestLatency
+	c.mu.RUnlock()
+
+	if elapsed > 0 {
+		aggregate.RequestsPerSecond = float64(summary.TotalRequests) / elapsed
+	}
+	if histogram != nil {
+		aggregate.P95LatencySeconds = latencyQuantile(histogram, 0.95)
+	}
+	return aggregate
+}
+
+// latencyQuantile merges every series of the histogram and estimates the
+// quantile q by interpolating linearly within the bucket it falls in.
+// Observations beyond the largest bucket are reported as that bucket's bound.
+func latencyQuantile(histogram *prometheus.HistogramVec, q float64) float64 {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		histogram.Collect(ch)
+		close(ch)
+	}()
+
+	var total uint64
+	var bounds []float64
+	var counts []uint64
+	for metric := range ch {
+		var m dto.Metric
+		if err := metric.Write(&m); err != nil || m.Histogram == nil {
+			continue
+		}
+		total += m.Histogram.GetSampleCount()
+		for i, bucket := range m.Histogram.Bucket {
+			if i >= len(bounds) {
+				bounds = append(bounds, bucket.GetUpperBound())
+				counts = append(counts, 0)
+			}
+			counts[i] += bucket.GetCumulativeCount()
+		}
+	}
+	if total == 0 || len(bounds) == 0 {
+		return 0
+	}
+
+	rank := q * float64(total)
+	lower, below := 0.0, uint64(0)
+	for i, upper := range bounds {
+		if float64(counts[i]) >= rank {
+			inBucket := counts[i] - below
+			if inBucket == 0 || math.IsInf(upper, 1) {
+				return upper
+			}
+			return lower + (upper-lower)*(rank-float64(below))/float64(inBucket)
+		}
+		lower, below = upper, counts[i]
+	}
+	return bounds[len(bounds)-1]
+}
+
+// SummaryHandler serves AggregateSummary as JSON. When requireAuth is set,
+// requests must carry one of allowedKeys as a Bearer token; these keys are
+// meant to be separate from, and less privileged than, the full metrics keys.
+func SummaryHandler(collector *MetricsCollector, requireAuth bool, allowedKeys []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if requireAuth && !isAllowedKey(extractBearerToken(r.Header.Get("Authorization")), allowedKeys) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(collector.AggregateSummary()); err != nil {
+			http.Error(w, "Failed to encode summary", http.StatusInternalServerError)
+		}
+	})
+}
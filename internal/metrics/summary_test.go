@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummaryAggregatesAndRanksTopKeys(t *testing.T) {
+	collector := NewMetricsCollector()
+
+	// key-0 sends 7 requests, key-1 sends 6, ... key-6 sends 1
+	for i := 0; i < 7; i++ {
+		apiKey := fmt.Sprintf("summary-key-%d", i)
+		for n := 0; n < 7-i; n++ {
+			collector.RecordRequest(apiKey, "/v1/chat/completions", "gpt-4", 10, 200, time.Millisecond)
+		}
+	}
+	// Two failures out of 30 requests in total
+	collector.RecordRequest("summary-key-0", "/v1/chat/completions", "gpt-4", 0, 500, time.Millisecond)
+	collector.RecordRequest("summary-key-6", "/v1/chat/completions", "gpt-4", 0, 429, time.Millisecond)
+
+	summary := collector.Summary()
+
+	assert.Equal(t, int64(30), summary.TotalRequests)
+	assert.Equal(t, int64(280), summary.TotalTokens)
+	assert.InDelta(t, 2.0/30.0, summary.ErrorRate, 1e-9)
+
+	require.Len(t, summary.TopKeys, SummaryTopKeys)
+	wantRequests := []int64{8, 6, 5, 4, 3}
+	for i, k := range summary.TopKeys {
+		assert.Equal(t, utils.MaskAPIKey(fmt.Sprintf("summary-key-%d", i)), k.APIKey)
+		assert.Equal(t, wantRequests[i], k.Requests)
+	}
+	assert.Equal(t, int64(70), summary.TopKeys[0].Tokens)
+
+	fields := summary.LogFields()
+	assert.Equal(t, "6.67%", fields["error_rate"])
+	assert.Contains(t, fields["top_keys"], "=8 req/70 tok")
+}
+
+func TestSummaryEmptyCollector(t *testing.T) {
+	summary := NewMetricsCollector().Summary()
+
+	assert.Zero(t, summary.TotalRequests)
+	assert.Zero(t, summary.ErrorRate)
+	assert.Empty(t, summary.TopKeys)
+}
+
+func TestAggregateSummaryTotalsRateAndP95(t *testing.T) {
+	collector := NewMetricsCollector()
+	collector.startedAt = time.Now().Add(-10 * time.Second)
+
+	// 90 fast requests across two keys and 10 slow failures
+	for i := 0; i < 90; i++ {
+		collector.RecordRequest(fmt.Sprintf("aggregate-key-%d", i%2), "/v1/chat/completions", "gpt-4", 5, 200, 5*time.Millisecond)
+	}
+	for i := 0; i < 10; i++ {
+		collector.RecordRequest("aggregate-key-0", "/v1/chat/completions", "gpt-4", 0, 502, 2*time.Second)
+	}
+
+	aggregate := collector.AggregateSummary()
+
+	assert.Equal(t, int64(100), aggregate.TotalRequests)
+	assert.Equal(t, int64(450), aggregate.TotalTokens)
+	assert.InDelta(t, 0.1, aggregate.ErrorRate, 1e-9)
+	assert.InDelta(t, 10.0, aggregate.RequestsPerSecond, 0.5)
+	// The 95th of 100 observations is the 5th of 10 in the 1s-3s bucket
+	assert.InDelta(t, 2.0, aggregate.P95LatencySeconds, 1e-9)
+}
+
+func TestAggregateSummaryEmptyCollector(t *testing.T) {
+	aggregate := NewMetricsCollector().AggregateSummary()
+
+	assert.Zero(t, aggregate.TotalRequests)
+	assert.Zero(t, aggregate.ErrorRate)
+	assert.Zero(t, aggregate.P95LatencySeconds)
+}
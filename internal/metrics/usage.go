@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// usageContextKey stores the mutable usage record for a request
+const usageContextKey contextKey = "metrics_usage"
+
+// requestUsage collects the model and token count reported by handlers further
+// down the chain. Context values set downstream are not visible to the metrics
+// middleware, so it seeds this record before calling next and reads it afterwards.
+type requestUsage struct {
+	mu         sync.Mutex
+	model      string
+	tokens     int
+	reported   bool
+	estimated  bool
+	prompt     int
+	completion int
+}
+
+// withUsage attaches an empty usage record to the request if it has none
+func withUsage(r *http.Request) *http.Request {
+	if _, ok := r.Context().Value(usageContextKey).(*requestUsage); ok {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), usageContextKey, &requestUsage{}))
+}
+
+// ReportUsage records the model and token count for a request so the metrics
+// middleware can include them. Set estimated for counts derived from a
+// TokenEstimator rather than upstream-reported usage: an estimate never replaces
+// a real count, while a real count always replaces an estimate. An empty model
+// or a negative token count leaves the previously reported value unchanged.
+// It is a no-op when the request is not wrapped by the metrics middleware.
+func ReportUsage(r *http.Request, model string, tokens int, estimated bool) {
+	u, ok := r.Context().Value(usageContextKey).(*requestUsage)
+	if !ok {
+		return
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if model != "" {
+		u.model = model
+	}
+	if tokens < 0 || (estimated && u.reported && !u.estimated) {
+		return
+	}
+	u.tokens = tokens
+	u.reported = true
+	u.estimated = estimated
+}
+
+// ReportTokenSplit records how a request's tokens divide between prompt
+// (input) and completion (output), as reported by the upstream alongside the
+// total passed to ReportUsage. Negative counts are ignored. It is a no-op
+// when the request is not wrapped by the metrics middleware.
+func ReportTokenSplit(r *http.Request, promptTokens, completionTokens int) {
+	u, ok := r.Context().Value(usageContextKey).(*requestUsage)
+	if !ok || promptTokens < 0 || completionTokens < 0 {
+		return
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.prompt = promptTokens
+	u.completion = completionTokens
+}
+
+// reportedTokenSplit returns the split recorded via ReportTokenSplit, which
+// is zero when none was reported
+func reportedTokenSplit(r *http.Request) (promptTokens, completionTokens int) {
+	u, found := r.Context().Value(usageContextKey).(*requestUsage)
+	if !found {
+		return 0, 0
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.prompt, u.completion
+}
+
+// reportedUsage returns the usage recorded via ReportUsage, if any
+func reportedUsage(r *http.Request) (model string, tokens int, ok bool) {
+	u, found := r.Context().Value(usageContextKey).(*requestUsage)
+	if !found {
+		return "", 0, false
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.model, u.tokens, u.reported
+}
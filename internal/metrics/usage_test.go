@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportUsage_RecordedByMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		report     func(r *http.Request)
+		wantTokens int64
+		wantModel  string
+	}{
+		{
+			name:       "no usage reported",
+			report:     func(r *http.Request) {},
+			wantTokens: 0,
+			wantModel:  "unknown",
+		},
+		{
+			name:       "estimate used when real usage is absent",
+			report:     func(r *http.Request) { ReportUsage(r, "", 42, true) },
+			wantTokens: 42,
+			wantModel:  "unknown",
+		},
+		{
+			name: "real usage replaces estimate",
+			report: func(r *http.Request) {
+				ReportUsage(r, "", 42, true)
+				ReportUsage(r, "gpt-4", 57, false)
+			},
+			wantTokens: 57,
+			wantModel:  "gpt-4",
+		},
+		{
+			name: "estimate does not replace real usage",
+			report: func(r *http.Request) {
+				ReportUsage(r, "gpt-4", 57, false)
+				ReportUsage(r, "", 42, true)
+			},
+			wantTokens: 57,
+			wantModel:  "gpt-4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := NewMetricsCollector()
+			handler := MetricsMiddleware(collector)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tt.report(r)
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+			req.Header.Set("Authorization", "Bearer key1")
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			km, ok := collector.GetMetricsForKey("key1")
+			require.True(t, ok)
+			assert.Equal(t, tt.wantTokens, km.TotalTokensConsumed)
+			assert.Contains(t, km.PerModel, tt.wantModel)
+		})
+	}
+}
+
+func TestReportUsage_WithoutMiddleware(t *testing.T) {
+	req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	assert.NotPanics(t, func() { ReportUsage(req, "gpt-4", 10, false) })
+}
+
+func TestReportTokenSplit_RecordedByMiddleware(t *testing.T) {
+	collector := NewMetricsCollector()
+	handler := MetricsMiddleware(collector)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ReportUsage(r, "gpt-4", 57, false)
+		ReportTokenSplit(r, 12, 45)
+		// Negative counts are ignored
+		ReportTokenSplit(r, -1, 3)
+	}))
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	req.Header.Set("Authorization", "Bearer key1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	km, ok := collector.GetMetricsForKey("key1")
+	require.True(t, ok)
+	assert.Equal(t, int64(57), km.TotalTokensConsumed)
+	assert.Equal(t, int64(12), km.TotalPromptTokens)
+	assert.Equal(t, int64(45), km.TotalCompletionTokens)
+}
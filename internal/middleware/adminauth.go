@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+// NewAdminAuthMiddleware creates a middleware that requires a bearer token
+// matching one of the configured admin keys. With no keys configured every
+// request is rejected, so admin endpoints are closed by default.
+func NewAdminAuthMiddleware(keys []string, logger interfaces.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token == "" || !matchesAny(token, keys) {
+				if logger != nil {
+					logger.Warn("Admin request rejected", map[string]any{
+						"path":   r.URL.Path,
+						"method": r.Method,
+					})
+				}
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// matchesAny reports whether token equals one of keys, comparing in constant time
+func matchesAny(token string, keys []string) bool {
+	matched := 0
+	for _, key := range keys {
+		matched |= subtle.ConstantTimeCompare([]byte(token), []byte(key))
+	}
+	return matched == 1
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminAuthMiddleware(t *testing.T) {
+	tests := []struct {
+		name          string
+		keys          []string
+		authorization string
+		expectStatus  int
+	}{
+		{"valid key", []string{"admin-1", "admin-2"}, "Bearer admin-2", http.StatusOK},
+		{"invalid key", []string{"admin-1"}, "Bearer nope", http.StatusUnauthorized},
+		{"missing header", []string{"admin-1"}, "", http.StatusUnauthorized},
+		{"no keys configured", nil, "Bearer anything", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAdminAuthMiddleware(tt.keys, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "/admin/routes", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectStatus, rr.Code)
+			}
+		})
+	}
+}
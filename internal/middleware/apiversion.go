@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+// DefaultAPIVersionHeader carries the client's API version when no header is configured
+const DefaultAPIVersionHeader = "X-API-Version"
+
+// APIVersionPolicy rejects requests for API versions the gateway does not
+// support and counts accepted requests by version, so the remaining users of
+// a version can be found before it is retired
+type APIVersionPolicy struct {
+	header         string
+	defaultVersion string
+	counts         map[string]*atomic.Int64
+	rejections     *ValidationRejections
+}
+
+// NewAPIVersionPolicy creates a policy accepting cfg.Supported. Requests
+// without the header are treated as cfg.Default, or rejected when no default
+// is configured. Rejections are counted in rejections.
+func NewAPIVersionPolicy(cfg interfaces.APIVersionConfig, rejections *ValidationRejections) *APIVersionPolicy {
+	header := cfg.Header
+	if header == "" {
+		header = DefaultAPIVersionHeader
+	}
+	p := &APIVersionPolicy{
+		header:         header,
+		defaultVersion: cfg.Default,
+		counts:         make(map[string]*atomic.Int64, len(cfg.Supported)),
+		rejections:     rejections,
+	}
+	for _, version := range cfg.Supported {
+		p.counts[version] = &atomic.Int64{}
+	}
+	return p
+}
+
+// Middleware checks each request's version. A request that relied on the
+// default is forwarded with the default set in the header, so the upstream
+// sees the version the gateway accounted it under.
+func (p *APIVersionPolicy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version := r.Header.Get(p.header)
+		if version == "" {
+			if p.defaultVersion == "" {
+				p.rejections.reject(w, http.StatusBadRequest, ReasonMissingAPIVersion,
+					fmt.Sprintf("The %s header is required", p.header))
+				return
+			}
+			version = p.defaultVersion
+			r.Header.Set(p.header, version)
+		}
+
+		count, ok := p.counts[version]
+		if !ok {
+			p.rejections.reject(w, http.StatusBadRequest, ReasonUnsupportedAPIVersion,
+				fmt.Sprintf("API version %q is not supported", version))
+			return
+		}
+		count.Add(1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Counts returns the number of accepted requests for each supported version,
+// including versions with none
+func (p *APIVersionPolicy) Counts() map[string]int64 {
+	counts := make(map[string]int64, len(p.counts))
+	for version, n := range p.counts {
+		counts[version] = n.Load()
+	}
+	return counts
+}
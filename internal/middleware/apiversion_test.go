@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+func TestAPIVersionPolicy(t *testing.T) {
+	tests := []struct {
+		name          string
+		cfg           interfaces.APIVersionConfig
+		header        string
+		expectStatus  int
+		expectReason  string
+		expectVersion string
+	}{
+		{
+			name:          "supported version",
+			cfg:           interfaces.APIVersionConfig{Supported: []string{"2024-06-01", "2024-10-01"}},
+			header:        "2024-10-01",
+			expectStatus:  http.StatusOK,
+			expectVersion: "2024-10-01",
+		},
+		{
+			name:         "unsupported version",
+			cfg:          interfaces.APIVersionConfig{Supported: []string{"2024-06-01"}},
+			header:       "2023-01-01",
+			expectStatus: http.StatusBadRequest,
+			expectReason: ReasonUnsupportedAPIVersion,
+		},
+		{
+			name:          "missing version uses the default",
+			cfg:           interfaces.APIVersionConfig{Supported: []string{"2024-06-01"}, Default: "2024-06-01"},
+			expectStatus:  http.StatusOK,
+			expectVersion: "2024-06-01",
+		},
+		{
+			name:         "missing version without a default",
+			cfg:          interfaces.APIVersionConfig{Supported: []string{"2024-06-01"}},
+			expectStatus: http.StatusBadRequest,
+			expectReason: ReasonMissingAPIVersion,
+		},
+		{
+			name:          "custom header",
+			cfg:           interfaces.APIVersionConfig{Header: "OpenAI-Version", Supported: []string{"v2"}},
+			header:        "v2",
+			expectStatus:  http.StatusOK,
+			expectVersion: "v2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rejections := NewValidationRejections()
+			policy := NewAPIVersionPolicy(tt.cfg, rejections)
+			header := tt.cfg.Header
+			if header == "" {
+				header = DefaultAPIVersionHeader
+			}
+
+			var forwarded string
+			handler := policy.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				forwarded = r.Header.Get(header)
+			}))
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+			if tt.header != "" {
+				req.Header.Set(header, tt.header)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectStatus, rr.Code)
+			}
+			if tt.expectReason != "" {
+				if !strings.Contains(rr.Body.String(), `"reason":"`+tt.expectReason+`"`) {
+					t.Errorf("Expected reason %s, got %s", tt.expectReason, rr.Body.String())
+				}
+				if got := rejections.Counts()[tt.expectReason]; got != 1 {
+					t.Errorf("Expected one %s rejection, got %d", tt.expectReason, got)
+				}
+				for version, n := range policy.Counts() {
+					if n != 0 {
+						t.Errorf("Expected no accepted requests, got %d for %s", n, version)
+					}
+				}
+				return
+			}
+			if forwarded != tt.expectVersion {
+				t.Errorf("Expected %s forwarded as %q, got %q", header, tt.expectVersion, forwarded)
+			}
+			if got := policy.Counts()[tt.expectVersion]; got != 1 {
+				t.Errorf("Expected version %s counted once, got %d", tt.expectVersion, got)
+			}
+		})
+	}
+}
+
+func TestAPIVersionPolicy_CountsEveryVersion(t *testing.T) {
+	policy := NewAPIVersionPolicy(interfaces.APIVersionConfig{Supported: []string{"v1", "v2"}, Default: "v1"}, nil)
+	handler := policy.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, version := range []string{"v2", "", "v2", "v3"} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+		if version != "" {
+			req.Header.Set(DefaultAPIVersionHeader, version)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	counts := policy.Counts()
+	if len(counts) != 2 || counts["v1"] != 1 || counts["v2"] != 2 {
+		t.Errorf("Expected v1=1 and v2=2, got %v", counts)
+	}
+}
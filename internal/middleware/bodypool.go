@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// maxPooledBodyBuffer is the largest buffer kept for reuse, so one large
+// request body doesn't stay allocated for the life of the pool
+const maxPooledBodyBuffer = 64 * 1024
+
+// bodyBufferPool reuses the buffers request bodies are read into
+var bodyBufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// acquireBodyBuffer returns an empty buffer from the pool
+func acquireBodyBuffer() *bytes.Buffer {
+	buf := bodyBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// releaseBodyBuffer returns buf to the pool unless it has grown too large.
+// Nothing may use buf or its bytes afterwards.
+func releaseBodyBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBodyBuffer {
+		return
+	}
+	buf.Reset()
+	bodyBufferPool.Put(buf)
+}
+
+// pooledBody replays a request body read into a pooled buffer, returning the
+// buffer to the pool when closed. The transport closes the body once it has
+// sent it upstream, which may be after the handler has returned; a body that
+// is never closed leaves its buffer to the garbage collector.
+type pooledBody struct {
+	mu     sync.Mutex
+	reader *bytes.Reader
+	buf    *bytes.Buffer
+}
+
+// newPooledBody returns a body reading buf, which it takes ownership of
+func newPooledBody(buf *bytes.Buffer) *pooledBody {
+	return &pooledBody{reader: bytes.NewReader(buf.Bytes()), buf: buf}
+}
+
+// Read reads from the buffered body; a closed body reads as empty
+func (b *pooledBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.buf == nil {
+		return 0, io.EOF
+	}
+	return b.reader.Read(p)
+}
+
+// Close releases the buffer for reuse. It is safe to call more than once.
+func (b *pooledBody) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.buf != nil {
+		b.reader.Reset(nil)
+		releaseBodyBuffer(b.buf)
+		b.buf = nil
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidationPooledBodiesDoNotBleed(t *testing.T) {
+	var bodies []string
+	handler := NewRequestValidationMiddleware(1024, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("Failed to read body: %v", err)
+		}
+		// Closing hands the buffer back for the next request
+		r.Body.Close()
+		bodies = append(bodies, string(body))
+	}))
+
+	sent := []string{
+		`{"model":"gpt-4","messages":[{"role":"user","content":"a much longer first message"}]}`,
+		`{"model":"gpt-4","messages":[]}`,
+		`{"input":"x","model":"text-embedding-3-small"}`,
+	}
+	paths := []string{"/v1/chat/completions", "/v1/chat/completions", "/v1/embeddings"}
+	for i, body := range sent {
+		req := httptest.NewRequest(http.MethodPost, paths[i], strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected request %d to pass validation, got %d: %s", i, rr.Code, rr.Body.String())
+		}
+	}
+
+	for i := range sent {
+		if bodies[i] != sent[i] {
+			t.Errorf("Expected request %d to see its own body %q, got %q", i, sent[i], bodies[i])
+		}
+	}
+}
+
+func TestPooledBodyClose(t *testing.T) {
+	buf := acquireBodyBuffer()
+	buf.WriteString("hello")
+	body := newPooledBody(buf)
+
+	p := make([]byte, 2)
+	if n, _ := body.Read(p); string(p[:n]) != "he" {
+		t.Errorf("Expected to read %q, got %q", "he", p[:n])
+	}
+	if err := body.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	// The buffer may already serve another request, so nothing more is read
+	if n, err := body.Read(p); n != 0 || err != io.EOF {
+		t.Errorf("Expected EOF after Close, got %d bytes and %v", n, err)
+	}
+	if err := body.Close(); err != nil {
+		t.Errorf("Expected a second Close to succeed, got %v", err)
+	}
+}
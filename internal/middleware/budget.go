@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// NewTimeBudgetMiddleware creates a middleware that gives each request budget
+// to complete, as a deadline on its context, so queueing for limits and the
+// upstream call together cannot exceed it. A request whose budget runs out
+// before anything was written gets a 504; the proxy reports an upstream call
+// cut short the same way.
+func NewTimeBudgetMiddleware(budget time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), budget)
+			defer cancel()
+
+			bw := &budgetWriter{ResponseWriter: w}
+			next.ServeHTTP(bw, r.WithContext(ctx))
+
+			// Layers that gave up waiting for the deadline leave the response unwritten
+			if !bw.wrote && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusGatewayTimeout)
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"error": map[string]string{
+						"message": "Request exceeded its time budget",
+						"type":    "request_timeout",
+					},
+				})
+			}
+		})
+	}
+}
+
+// budgetWriter records whether a response was started
+type budgetWriter struct {
+	http.ResponseWriter
+	wrote bool
+}
+
+// WriteHeader records the response as started and forwards the call
+func (w *budgetWriter) WriteHeader(status int) {
+	w.wrote = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the response as started and forwards the call
+func (w *budgetWriter) Write(data []byte) (int, error) {
+	w.wrote = true
+	return w.ResponseWriter.Write(data)
+}
+
+// Flush forwards to the underlying writer so streamed responses are not held back
+func (w *budgetWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
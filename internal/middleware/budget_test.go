@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeBudgetMiddleware(t *testing.T) {
+	// A layer that waits on the context gives up without responding
+	waiting := NewTimeBudgetMiddleware(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	w := httptest.NewRecorder()
+	waiting.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected 504 once the budget ran out, got %d", w.Code)
+	}
+
+	// A response already started is left alone
+	started := NewTimeBudgetMiddleware(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		<-r.Context().Done()
+	}))
+	w = httptest.NewRecorder()
+	started.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil))
+	if w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Errorf("Expected the started response to be kept, got %d %q", w.Code, w.Body.String())
+	}
+
+	// Requests within the budget see a deadline but are otherwise untouched
+	fast := NewTimeBudgetMiddleware(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); !ok {
+			t.Error("Expected the request context to carry the budget's deadline")
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	w = httptest.NewRecorder()
+	fast.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil))
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected 201, got %d", w.Code)
+	}
+}
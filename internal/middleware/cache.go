@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+const (
+	// CacheStatusHeader reports whether a response was served from the response cache
+	CacheStatusHeader = "X-Nexus-Cache"
+
+	// DefaultResponseCacheMaxEntries bounds the response cache when no size is configured
+	DefaultResponseCacheMaxEntries = 1000
+)
+
+// responseLRU holds cached responses with per-entry expiry, evicting the least
+// recently used entry once maxEntries is exceeded. It is not safe for
+// concurrent use; callers hold their own lock.
+type responseLRU struct {
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List
+}
+
+func newResponseLRU(maxEntries int) *responseLRU {
+	return &responseLRU{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// get returns the unexpired response stored under key, dropping it if expired
+func (l *responseLRU) get(key string, now time.Time) *cachedResponse {
+	el, ok := l.entries[key]
+	if !ok {
+		return nil
+	}
+	resp := el.Value.(*cachedResponse)
+	if !now.Before(resp.expires) {
+		l.order.Remove(el)
+		delete(l.entries, key)
+		return nil
+	}
+	l.order.MoveToFront(el)
+	return resp
+}
+
+// add stores resp under key, replacing any earlier entry
+func (l *responseLRU) add(key string, resp *cachedResponse) {
+	if el, ok := l.entries[key]; ok {
+		l.order.Remove(el)
+	}
+	resp.key = key
+	l.entries[key] = l.order.PushFront(resp)
+	for l.order.Len() > l.maxEntries {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.entries, oldest.Value.(*cachedResponse).key)
+	}
+}
+
+// ResponseCache serves repeated requests to configured paths, such as the
+// model list, from memory instead of the upstream. Responses are keyed by
+// method, path, query and the Authorization, Accept and Accept-Encoding
+// headers, so callers mapped to different upstream keys never share entries.
+type ResponseCache struct {
+	routes []interfaces.CacheRoute
+	hits   atomic.Int64
+	misses atomic.Int64
+
+	mu    sync.Mutex
+	store *responseLRU
+	now   func() time.Time
+}
+
+// NewResponseCache creates a cache for routes holding at most maxEntries
+// responses (DefaultResponseCacheMaxEntries when zero or negative). Routes are
+// matched longest prefix first; a route without methods caches GET.
+func NewResponseCache(routes []interfaces.CacheRoute, maxEntries int) *ResponseCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultResponseCacheMaxEntries
+	}
+	sorted := append([]interfaces.CacheRoute(nil), routes...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i].PathPrefix) > len(sorted[j].PathPrefix)
+	})
+	return &ResponseCache{
+		routes: sorted,
+		store:  newResponseLRU(maxEntries),
+		now:    time.Now,
+	}
+}
+
+// Hits returns how many requests were served from the cache
+func (c *ResponseCache) Hits() int64 {
+	return c.hits.Load()
+}
+
+// Misses returns how many cacheable requests had to go to the upstream
+func (c *ResponseCache) Misses() int64 {
+	return c.misses.Load()
+}
+
+// route returns the route caching r, or nil when r is not cacheable
+func (c *ResponseCache) route(r *http.Request) *interfaces.CacheRoute {
+	for i := range c.routes {
+		route := &c.routes[i]
+		if !strings.HasPrefix(r.URL.Path, route.PathPrefix) {
+			continue
+		}
+		if len(route.Methods) == 0 {
+			if r.Method == http.MethodGet {
+				return route
+			}
+			return nil
+		}
+		for _, method := range route.Methods {
+			if strings.EqualFold(method, r.Method) {
+				return route
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// cacheKey identifies the response to r; the Authorization header is hashed
+// so upstream keys are not held in memory
+func cacheKey(r *http.Request) string {
+	auth := sha256.Sum256([]byte(r.Header.Get("Authorization")))
+	return strings.Join([]string{
+		r.Method,
+		r.URL.RequestURI(),
+		hex.EncodeToString(auth[:]),
+		r.Header.Get("Accept"),
+		r.Header.Get("Accept-Encoding"),
+	}, "\x00")
+}
+
+// Middleware serves cached responses for configured routes and stores
+// successful upstream responses for the route's TTL. Responses marked
+// Cache-Control: no-store are passed through without being stored. With no
+// routes the middleware is a no-op.
+func (c *ResponseCache) Middleware(next http.Handler) http.Handler {
+	if len(c.routes) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := c.route(r)
+		if route == nil || route.TTL <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := cacheKey(r)
+		c.mu.Lock()
+		cached := c.store.get(key, c.now())
+		c.mu.Unlock()
+		if cached != nil {
+			c.hits.Add(1)
+			writeCached(w, cached, CacheStatusHeader, "HIT")
+			return
+		}
+
+		c.misses.Add(1)
+		w.Header().Set(CacheStatusHeader, "MISS")
+		capture := &captureWriter{ResponseWriter: w}
+		next.ServeHTTP(capture, r)
+
+		resp := capture.cacheable()
+		if resp == nil || resp.status != http.StatusOK || hasNoStore(resp.header) {
+			return
+		}
+		resp.header.Del(CacheStatusHeader)
+		c.mu.Lock()
+		resp.expires = c.now().Add(route.TTL)
+		c.store.add(key, resp)
+		c.mu.Unlock()
+	})
+}
+
+// hasNoStore reports whether the response forbids caching
+func hasNoStore(header http.Header) bool {
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(directive), "no-store") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+// modelsUpstream answers with a body that changes on every call
+func modelsUpstream(calls *atomic.Int64, cacheControl string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		if cacheControl != "" {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+		fmt.Fprintf(w, `{"object":"list","version":%d}`, n)
+	})
+}
+
+func modelsRequest(auth string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	req.Header.Set("Authorization", auth)
+	return req
+}
+
+func TestResponseCache_HitAndExpiry(t *testing.T) {
+	var calls atomic.Int64
+	cache := NewResponseCache([]interfaces.CacheRoute{{PathPrefix: "/v1/models", TTL: time.Minute}}, 10)
+	now := time.Unix(1700000000, 0)
+	cache.now = func() time.Time { return now }
+	handler := cache.Middleware(modelsUpstream(&calls, ""))
+
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, modelsRequest("Bearer upstream-key"))
+	second := httptest.NewRecorder()
+	handler.ServeHTTP(second, modelsRequest("Bearer upstream-key"))
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Expected the upstream to be called once, got %d", got)
+	}
+	if first.Body.String() != second.Body.String() {
+		t.Errorf("Expected the cached body, got %q and %q", first.Body.String(), second.Body.String())
+	}
+	if first.Header().Get(CacheStatusHeader) != "MISS" || second.Header().Get(CacheStatusHeader) != "HIT" {
+		t.Errorf("Expected MISS then HIT, got %q then %q", first.Header().Get(CacheStatusHeader), second.Header().Get(CacheStatusHeader))
+	}
+	if second.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected cached headers to be replayed, got %v", second.Header())
+	}
+
+	// A different upstream key gets its own entry
+	handler.ServeHTTP(httptest.NewRecorder(), modelsRequest("Bearer other-key"))
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Expected a separate entry per Authorization header, got %d calls", got)
+	}
+
+	now = now.Add(2 * time.Minute)
+	expired := httptest.NewRecorder()
+	handler.ServeHTTP(expired, modelsRequest("Bearer upstream-key"))
+	if got := calls.Load(); got != 3 {
+		t.Errorf("Expected a miss after the TTL, got %d calls", got)
+	}
+	if expired.Body.String() == first.Body.String() {
+		t.Error("Expected a fresh body after the TTL")
+	}
+
+	if cache.Hits() != 1 || cache.Misses() != 3 {
+		t.Errorf("Expected 1 hit and 3 misses, got %d and %d", cache.Hits(), cache.Misses())
+	}
+}
+
+func TestResponseCache_Bypass(t *testing.T) {
+	tests := []struct {
+		name         string
+		cacheControl string
+		method       string
+		path         string
+	}{
+		{name: "upstream no-store", cacheControl: "private, no-store", method: http.MethodGet, path: "/v1/models"},
+		{name: "method not configured", method: http.MethodPost, path: "/v1/models"},
+		{name: "path not configured", method: http.MethodGet, path: "/v1/files"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int64
+			cache := NewResponseCache([]interfaces.CacheRoute{{PathPrefix: "/v1/models", TTL: time.Minute}}, 10)
+			handler := cache.Middleware(modelsUpstream(&calls, tt.cacheControl))
+
+			for i := 0; i < 2; i++ {
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.path, nil))
+				if rr.Header().Get(CacheStatusHeader) == "HIT" {
+					t.Error("Expected the response not to be served from the cache")
+				}
+			}
+			if got := calls.Load(); got != 2 {
+				t.Errorf("Expected both requests to reach the upstream, got %d", got)
+			}
+			if cache.Hits() != 0 {
+				t.Errorf("Expected no cache hits, got %d", cache.Hits())
+			}
+		})
+	}
+}
+
+func TestResponseCache_EvictsBeyondMaxEntries(t *testing.T) {
+	var calls atomic.Int64
+	cache := NewResponseCache([]interfaces.CacheRoute{{PathPrefix: "/v1/models", TTL: time.Minute}}, 1)
+	handler := cache.Middleware(modelsUpstream(&calls, ""))
+
+	handler.ServeHTTP(httptest.NewRecorder(), modelsRequest("Bearer a"))
+	handler.ServeHTTP(httptest.NewRecorder(), modelsRequest("Bearer b"))
+	handler.ServeHTTP(httptest.NewRecorder(), modelsRequest("Bearer a"))
+	if got := calls.Load(); got != 3 {
+		t.Errorf("Expected the first entry to be evicted, got %d calls", got)
+	}
+}
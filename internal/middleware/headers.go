@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// DefaultMaxHeaderCount is the number of header values a request may carry
+// when no limit is configured
+const DefaultMaxHeaderCount = 100
+
+// NewHeaderLimitMiddleware creates a middleware that rejects requests carrying
+// more than maxCount header values with 431, before any other middleware
+// walks them. A zero maxCount means DefaultMaxHeaderCount. Rejections are
+// counted in rejections under ReasonTooManyHeaders.
+func NewHeaderLimitMiddleware(maxCount int, rejections *ValidationRejections) func(http.Handler) http.Handler {
+	if maxCount <= 0 {
+		maxCount = DefaultMaxHeaderCount
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			count := 0
+			for _, values := range r.Header {
+				count += len(values)
+			}
+			if count > maxCount {
+				rejections.reject(w, http.StatusRequestHeaderFieldsTooLarge, ReasonTooManyHeaders,
+					fmt.Sprintf("Request has %d header values, more than the %d allowed", count, maxCount))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHeaderLimitMiddleware(t *testing.T) {
+	rejections := NewValidationRejections()
+	upstreamCalls := 0
+	handler := NewHeaderLimitMiddleware(10, rejections)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamCalls++
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set("Authorization", "Bearer key")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || upstreamCalls != 1 {
+		t.Fatalf("Expected a normal request to pass, got %d", w.Code)
+	}
+
+	// Repeated values count individually, so one name cannot smuggle many
+	req = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	for i := 0; i < 6; i++ {
+		req.Header.Add(fmt.Sprintf("X-Custom-%d", i), "a")
+		req.Header.Add("X-Repeated", "b")
+	}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestHeaderFieldsTooLarge {
+		t.Fatalf("Expected 431 for 12 header values, got %d", w.Code)
+	}
+	if upstreamCalls != 1 {
+		t.Error("Expected the rejected request not to reach the next handler")
+	}
+	if got := rejections.Counts()[ReasonTooManyHeaders]; got != 1 {
+		t.Errorf("Expected one too_many_headers rejection, got %d", got)
+	}
+}
+
+func TestHeaderLimitMiddleware_Default(t *testing.T) {
+	handler := NewHeaderLimitMiddleware(0, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	for i := 0; i < DefaultMaxHeaderCount; i++ {
+		req.Header.Add(fmt.Sprintf("X-Custom-%d", i), "a")
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected %d headers to pass by default, got %d", DefaultMaxHeaderCount, w.Code)
+	}
+
+	req.Header.Add("X-One-Too-Many", "a")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestHeaderFieldsTooLarge {
+		t.Errorf("Expected 431 past the default limit, got %d", w.Code)
+	}
+}
@@ -0,0 +1,200 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/metrics"
+)
+
+const (
+	// IdempotencyKeyHeader carries the client's idempotency key
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses served from the cache
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	// DefaultIdempotencyMaxEntries bounds the cache when no size is configured
+	DefaultIdempotencyMaxEntries = 1000
+	// maxCachedBodySize is the largest response body that is cached for replay
+	maxCachedBodySize = 1 << 20
+)
+
+// cachedResponse is a successful response stored for replay
+type cachedResponse struct {
+	key     string
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// idempotencyCache is a TTL cache of responses bounded to maxEntries. It also
+// tracks requests in flight so concurrent duplicates wait for the first
+// instead of calling the upstream.
+type idempotencyCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	store    *responseLRU
+	inFlight map[string]chan struct{}
+	now      func() time.Time
+}
+
+func newIdempotencyCache(ttl time.Duration, maxEntries int) *idempotencyCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultIdempotencyMaxEntries
+	}
+	return &idempotencyCache{
+		ttl:      ttl,
+		store:    newResponseLRU(maxEntries),
+		inFlight: make(map[string]chan struct{}),
+		now:      time.Now,
+	}
+}
+
+// acquire returns the cached response for key, or claims key for the caller
+// to serve. When another request holds the claim, the returned channel is
+// closed once it finishes and the caller should try again.
+func (c *idempotencyCache) acquire(key string) (*cachedResponse, chan struct{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if resp := c.store.get(key, c.now()); resp != nil {
+		return resp, nil, false
+	}
+
+	if wait, ok := c.inFlight[key]; ok {
+		return nil, wait, false
+	}
+	c.inFlight[key] = make(chan struct{})
+	return nil, nil, true
+}
+
+// release gives up the claim on key, caching resp if it is not nil
+func (c *idempotencyCache) release(key string, resp *cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if resp != nil {
+		resp.expires = c.now().Add(c.ttl)
+		c.store.add(key, resp)
+	}
+
+	close(c.inFlight[key])
+	delete(c.inFlight, key)
+}
+
+// captureWriter passes the response through to the client while keeping a
+// copy small enough to cache
+type captureWriter struct {
+	http.ResponseWriter
+	status   int
+	header   http.Header
+	body     bytes.Buffer
+	overflow bool
+}
+
+// WriteHeader snapshots the status and headers and forwards the call
+func (w *captureWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+		w.header = w.ResponseWriter.Header().Clone()
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write copies data into the capture buffer and forwards the call
+func (w *captureWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.overflow {
+		if w.body.Len()+len(data) > maxCachedBodySize {
+			w.overflow = true
+			w.body.Reset()
+		} else {
+			w.body.Write(data)
+		}
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Flush forwards to the underlying writer so streamed responses are not held back
+func (w *captureWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// cacheable returns the captured response if it succeeded and fit the buffer
+func (w *captureWriter) cacheable() *cachedResponse {
+	if w.status < 200 || w.status >= 300 || w.overflow {
+		return nil
+	}
+	return &cachedResponse{
+		status: w.status,
+		header: w.header,
+		body:   bytes.Clone(w.body.Bytes()),
+	}
+}
+
+// NewIdempotencyMiddleware creates a middleware that honours the
+// Idempotency-Key header on POST, PUT and PATCH requests. The first
+// successful response for a client key and idempotency key pair is cached
+// for ttl and replayed, marked with Idempotent-Replayed, to later requests
+// with the same pair instead of calling the upstream again. A duplicate that
+// arrives while the first is still in flight waits for it. Failed responses
+// and bodies over 1MB are not cached. At most maxEntries responses are kept
+// (DefaultIdempotencyMaxEntries when zero or negative). A ttl of zero
+// disables the middleware.
+func NewIdempotencyMiddleware(ttl time.Duration, maxEntries int) func(http.Handler) http.Handler {
+	cache := newIdempotencyCache(ttl, maxEntries)
+
+	return func(next http.Handler) http.Handler {
+		if ttl <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			idempotencyKey := r.Header.Get(IdempotencyKeyHeader)
+			if idempotencyKey == "" || !isWriteMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := metrics.GetAPIKey(r) + "\x00" + r.Method + " " + r.URL.Path + "\x00" + idempotencyKey
+			for {
+				cached, wait, claimed := cache.acquire(key)
+				if cached != nil {
+					writeCached(w, cached, IdempotentReplayedHeader, "true")
+					return
+				}
+				if claimed {
+					break
+				}
+				select {
+				case <-wait:
+				case <-r.Context().Done():
+					return
+				}
+			}
+
+			capture := &captureWriter{ResponseWriter: w}
+			defer func() {
+				cache.release(key, capture.cacheable())
+			}()
+			next.ServeHTTP(capture, r)
+		})
+	}
+}
+
+// writeCached writes a cached response to w, marking it with the given header
+func writeCached(w http.ResponseWriter, resp *cachedResponse, markHeader, markValue string) {
+	for name, values := range resp.header {
+		w.Header()[name] = append([]string(nil), values...)
+	}
+	w.Header().Set(markHeader, markValue)
+	w.WriteHeader(resp.status)
+	_, _ = w.Write(resp.body)
+}
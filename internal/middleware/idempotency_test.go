@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/metrics"
+)
+
+// countingUpstream answers with a body that changes on every call
+func countingUpstream(calls *atomic.Int64, status int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"id":"gen-%d"}`, n)
+	})
+}
+
+func idempotentRequest(clientKey, idempotencyKey string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{}`))
+	if idempotencyKey != "" {
+		req.Header.Set(IdempotencyKeyHeader, idempotencyKey)
+	}
+	return metrics.SetAPIKey(req, clientKey)
+}
+
+func TestIdempotencyMiddleware_ReplaysDuplicates(t *testing.T) {
+	var calls atomic.Int64
+	handler := NewIdempotencyMiddleware(time.Minute, 10)(countingUpstream(&calls, http.StatusOK))
+
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, idempotentRequest("client", "retry-1"))
+	second := httptest.NewRecorder()
+	handler.ServeHTTP(second, idempotentRequest("client", "retry-1"))
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Expected the upstream to be called once, got %d", got)
+	}
+	if first.Body.String() != second.Body.String() {
+		t.Errorf("Expected identical bodies, got %q and %q", first.Body.String(), second.Body.String())
+	}
+	if second.Code != http.StatusOK || second.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected the replay to keep status and headers, got %d %v", second.Code, second.Header())
+	}
+	if first.Header().Get(IdempotentReplayedHeader) != "" || second.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Error("Expected only the replayed response to be marked")
+	}
+}
+
+func TestIdempotencyMiddleware_ScopesAndSkips(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		second *http.Request
+		calls  int64
+	}{
+		{name: "different idempotency key", status: http.StatusOK, second: idempotentRequest("client", "retry-2"), calls: 2},
+		{name: "different client key", status: http.StatusOK, second: idempotentRequest("other-client", "retry-1"), calls: 2},
+		{name: "no idempotency key", status: http.StatusOK, second: idempotentRequest("client", ""), calls: 2},
+		{name: "failed responses are not cached", status: http.StatusInternalServerError, second: idempotentRequest("client", "retry-1"), calls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int64
+			handler := NewIdempotencyMiddleware(time.Minute, 10)(countingUpstream(&calls, tt.status))
+
+			handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("client", "retry-1"))
+			handler.ServeHTTP(httptest.NewRecorder(), tt.second)
+
+			if got := calls.Load(); got != tt.calls {
+				t.Errorf("Expected %d upstream calls, got %d", tt.calls, got)
+			}
+		})
+	}
+}
+
+func TestIdempotencyMiddleware_ConcurrentDuplicatesWait(t *testing.T) {
+	var calls atomic.Int64
+	release := make(chan struct{})
+	upstream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		fmt.Fprint(w, `{"id":"gen-1"}`)
+	})
+	handler := NewIdempotencyMiddleware(time.Minute, 10)(upstream)
+
+	var wg sync.WaitGroup
+	bodies := make([]string, 5)
+	for i := range bodies {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, idempotentRequest("client", "retry-1"))
+			bodies[i] = rr.Body.String()
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Expected the upstream to be called once, got %d", got)
+	}
+	for i, body := range bodies {
+		if body != `{"id":"gen-1"}` {
+			t.Errorf("Request %d: expected the shared response, got %q", i, body)
+		}
+	}
+}
+
+func TestIdempotencyCache_ExpiresAndEvicts(t *testing.T) {
+	cache := newIdempotencyCache(time.Minute, 2)
+	now := time.Unix(1700000000, 0)
+	cache.now = func() time.Time { return now }
+
+	store := func(key string) {
+		if _, _, claimed := cache.acquire(key); !claimed {
+			t.Fatalf("Expected to claim %q", key)
+		}
+		cache.release(key, &cachedResponse{status: http.StatusOK})
+	}
+	cached := func(key string) bool {
+		resp, _, claimed := cache.acquire(key)
+		if claimed {
+			cache.release(key, nil)
+		}
+		return resp != nil
+	}
+
+	store("a")
+	store("b")
+	store("c")
+	if cached("a") {
+		t.Error("Expected the least recently used entry to be evicted")
+	}
+	if !cached("b") || !cached("c") {
+		t.Error("Expected the newest entries to be kept")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if cached("b") {
+		t.Error("Expected entries to expire after the TTL")
+	}
+}
+
+func TestIdempotencyMiddleware_DisabledWithoutTTL(t *testing.T) {
+	var calls atomic.Int64
+	handler := NewIdempotencyMiddleware(0, 0)(countingUpstream(&calls, http.StatusOK))
+
+	handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("client", "retry-1"))
+	handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("client", "retry-1"))
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Expected every request to reach the upstream, got %d calls", got)
+	}
+}
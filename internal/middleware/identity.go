@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/jamesprial/nexus/internal/metrics"
+)
+
+// DefaultUpstreamIdentityHeader carries the client identifier when no header is configured
+const DefaultUpstreamIdentityHeader = "X-Upstream-User"
+
+// NewUpstreamIdentityMiddleware creates a middleware that sets header, or
+// DefaultUpstreamIdentityHeader when empty, to a salted hash of the validated
+// client key, so the upstream can tell users apart without seeing their keys.
+// The same key and salt always give the same identifier. It must run after
+// authentication; any value the client sent in header is removed.
+func NewUpstreamIdentityMiddleware(header, salt string) func(http.Handler) http.Handler {
+	if header == "" {
+		header = DefaultUpstreamIdentityHeader
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Header.Del(header)
+			if apiKey := metrics.GetAPIKey(r); apiKey != "" {
+				r.Header.Set(header, UpstreamIdentity(apiKey, salt))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// UpstreamIdentity returns the identifier sent upstream for apiKey: the
+// hex-encoded HMAC-SHA256 of the key under salt
+func UpstreamIdentity(apiKey, salt string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(apiKey))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jamesprial/nexus/internal/metrics"
+)
+
+func TestUpstreamIdentityMiddleware(t *testing.T) {
+	var seen []string
+	handler := NewUpstreamIdentityMiddleware("", "pepper")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get(DefaultUpstreamIdentityHeader))
+	}))
+	send := func(clientKey string) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		// A client can't choose its own identity
+		req.Header.Set(DefaultUpstreamIdentityHeader, "spoofed")
+		if clientKey != "" {
+			req = metrics.SetAPIKey(req, clientKey)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	send("sk-alice")
+	send("sk-alice")
+	send("sk-bob")
+	send("")
+
+	if len(seen[0]) != 64 || seen[0] != seen[1] {
+		t.Errorf("Expected the same hex identity for a key on every request, got %q and %q", seen[0], seen[1])
+	}
+	if seen[0] != UpstreamIdentity("sk-alice", "pepper") {
+		t.Errorf("Expected the salted hash of the key, got %q", seen[0])
+	}
+	if seen[2] == seen[0] {
+		t.Errorf("Expected different keys to get different identities, both got %q", seen[0])
+	}
+	if seen[3] != "" {
+		t.Errorf("Expected no identity without a validated key, got %q", seen[3])
+	}
+	for _, id := range seen[:3] {
+		if id == "sk-alice" || id == "sk-bob" || id == "spoofed" {
+			t.Errorf("Expected an anonymized identity, got %q", id)
+		}
+	}
+
+	// The salt changes every identity
+	if UpstreamIdentity("sk-alice", "other salt") == seen[0] {
+		t.Error("Expected a different salt to give a different identity")
+	}
+}
+
+func TestUpstreamIdentityMiddleware_CustomHeader(t *testing.T) {
+	var got string
+	handler := NewUpstreamIdentityMiddleware("X-End-User", "pepper")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("X-End-User")
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), metrics.SetAPIKey(httptest.NewRequest(http.MethodGet, "/v1/models", nil), "sk-alice"))
+
+	if got != UpstreamIdentity("sk-alice", "pepper") {
+		t.Errorf("Expected the identity in X-End-User, got %q", got)
+	}
+}
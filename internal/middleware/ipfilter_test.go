@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIPFilterMiddleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		allow          []string
+		deny           []string
+		trustedProxies int
+		remoteAddr     string
+		forwardedFor   string
+		expectStatus   int
+	}{
+		{
+			name:         "no lists allows everything",
+			remoteAddr:   "203.0.113.7:4321",
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "allowed single IP",
+			allow:        []string{"192.0.2.10"},
+			remoteAddr:   "192.0.2.10:4321",
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "IP not in allow list",
+			allow:        []string{"192.0.2.10"},
+			remoteAddr:   "192.0.2.11:4321",
+			expectStatus: http.StatusForbidden,
+		},
+		{
+			name:         "denied IP",
+			deny:         []string{"198.51.100.5"},
+			remoteAddr:   "198.51.100.5:4321",
+			expectStatus: http.StatusForbidden,
+		},
+		{
+			name:         "allowed CIDR range",
+			allow:        []string{"10.0.0.0/8"},
+			remoteAddr:   "10.20.30.40:4321",
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "deny takes precedence over allow",
+			allow:        []string{"10.0.0.0/8"},
+			deny:         []string{"10.1.0.0/16"},
+			remoteAddr:   "10.1.2.3:4321",
+			expectStatus: http.StatusForbidden,
+		},
+		{
+			name:         "IPv6 CIDR range",
+			allow:        []string{"2001:db8::/32"},
+			remoteAddr:   "[2001:db8::1]:4321",
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "spoofed X-Forwarded-For ignored at depth 0",
+			allow:        []string{"10.0.0.0/8"},
+			remoteAddr:   "203.0.113.7:4321",
+			forwardedFor: "10.0.0.1",
+			expectStatus: http.StatusForbidden,
+		},
+		{
+			name:           "X-Forwarded-For trusted at depth 1",
+			allow:          []string{"10.0.0.0/8"},
+			trustedProxies: 1,
+			remoteAddr:     "172.16.0.1:4321",
+			forwardedFor:   "10.0.0.1",
+			expectStatus:   http.StatusOK,
+		},
+		{
+			name:           "spoofed leading X-Forwarded-For entry ignored at depth 1",
+			allow:          []string{"10.0.0.0/8"},
+			trustedProxies: 1,
+			remoteAddr:     "172.16.0.1:4321",
+			forwardedFor:   "10.0.0.1, 203.0.113.7",
+			expectStatus:   http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewIPFilterMiddleware(tt.allow, tt.deny, tt.trustedProxies, nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			handler := filter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "/health", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestIPFilterMiddleware_InvalidEntries(t *testing.T) {
+	if _, err := NewIPFilterMiddleware([]string{"not-an-ip"}, nil, 0, nil); err == nil {
+		t.Error("Expected error for invalid allow entry")
+	}
+	if _, err := NewIPFilterMiddleware(nil, []string{"10.0.0.0/33"}, 0, nil); err == nil {
+		t.Error("Expected error for invalid deny entry")
+	}
+}
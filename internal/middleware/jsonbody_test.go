@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJSONBodyMiddleware(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		expectStatus int
+		expectBody   string
+	}{
+		{name: "valid JSON", method: http.MethodPost, path: "/v1/chat/completions", body: `{"model":"gpt-4"}`, expectStatus: http.StatusOK, expectBody: `{"model":"gpt-4"}`},
+		{name: "valid JSON array", method: http.MethodPut, path: "/v1/chat/completions", body: `[1,2]`, expectStatus: http.StatusOK, expectBody: `[1,2]`},
+		{name: "invalid JSON", method: http.MethodPost, path: "/v1/chat/completions", body: `{"model":`, expectStatus: http.StatusBadRequest},
+		{name: "empty body", method: http.MethodPost, path: "/v1/chat/completions", body: "", expectStatus: http.StatusBadRequest},
+		{name: "whitespace body", method: http.MethodPatch, path: "/v1/chat/completions", body: "  \n", expectStatus: http.StatusBadRequest},
+		{name: "body over the cap", method: http.MethodPost, path: "/v1/chat/completions", body: `{"input":"` + strings.Repeat("a", 64) + `"}`, expectStatus: http.StatusRequestEntityTooLarge},
+		{name: "unconfigured path", method: http.MethodPost, path: "/v1/embeddings", body: `not json`, expectStatus: http.StatusOK, expectBody: "not json"},
+		{name: "read method", method: http.MethodGet, path: "/v1/chat/completions", body: "", expectStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				received = string(body)
+				w.WriteHeader(http.StatusOK)
+			})
+
+			handler := NewJSONBodyMiddleware([]string{"/v1/chat"}, 32, nil)(next)
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectStatus, rr.Code, rr.Body.String())
+			}
+			if received != tt.expectBody {
+				t.Errorf("Expected next handler to read %q, got %q", tt.expectBody, received)
+			}
+		})
+	}
+}
+
+func TestJSONBodyMiddleware_NoPaths(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := NewJSONBodyMiddleware(nil, 0, nil)(next)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader("not json")))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected the middleware to be disabled without paths, got %d", rr.Code)
+	}
+}
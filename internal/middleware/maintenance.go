@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+// Defaults for the maintenance response when the corresponding
+// maintenance field is unset
+const (
+	DefaultMaintenanceMessage    = "The service is undergoing scheduled maintenance. Please try again later."
+	defaultMaintenanceRetryAfter = time.Minute
+)
+
+// Maintenance turns every request away with an error while maintenance mode
+// is enabled. Its configuration can be replaced while requests are being
+// served, so maintenance can be switched on and off by a reload.
+type Maintenance struct {
+	cfg atomic.Pointer[interfaces.MaintenanceConfig]
+}
+
+// NewMaintenance creates a maintenance switch with cfg
+func NewMaintenance(cfg interfaces.MaintenanceConfig) *Maintenance {
+	m := &Maintenance{}
+	m.SetConfig(cfg)
+	return m
+}
+
+// SetConfig replaces the maintenance configuration. It is safe to call while
+// requests are being served.
+func (m *Maintenance) SetConfig(cfg interfaces.MaintenanceConfig) {
+	m.cfg.Store(&cfg)
+}
+
+// Enabled reports whether requests are currently being turned away
+func (m *Maintenance) Enabled() bool {
+	return m.cfg.Load().Enabled
+}
+
+// Middleware answers requests with the configured status, 503 by default,
+// a Retry-After header and an OpenAI-style JSON error while maintenance is
+// enabled, and passes them to next otherwise
+func (m *Maintenance) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := m.cfg.Load()
+		if !cfg.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		status := cfg.Status
+		if status == 0 {
+			status = http.StatusServiceUnavailable
+		}
+		message := cfg.Message
+		if message == "" {
+			message = DefaultMaintenanceMessage
+		}
+		retryAfter := cfg.RetryAfter
+		if retryAfter <= 0 {
+			retryAfter = defaultMaintenanceRetryAfter
+		}
+
+		w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(retryAfter.Seconds())), 10))
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"error": map[string]string{
+				"message": message,
+				"type":    "maintenance",
+			},
+		})
+	})
+}
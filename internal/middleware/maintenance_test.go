@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+func TestMaintenanceMiddleware(t *testing.T) {
+	var calls int
+	m := NewMaintenance(interfaces.MaintenanceConfig{})
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	send := func() *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil))
+		return rr
+	}
+
+	if rr := send(); rr.Code != http.StatusOK || calls != 1 {
+		t.Fatalf("Expected requests passed through when disabled, got %d after %d calls", rr.Code, calls)
+	}
+
+	m.SetConfig(interfaces.MaintenanceConfig{Enabled: true})
+	if !m.Enabled() {
+		t.Error("Expected maintenance enabled after SetConfig")
+	}
+	rr := send()
+	if rr.Code != http.StatusServiceUnavailable || calls != 1 {
+		t.Fatalf("Expected 503 without reaching next, got %d after %d calls", rr.Code, calls)
+	}
+	if got := rr.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Expected the default Retry-After of 60, got %q", got)
+	}
+	var body struct {
+		Error struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode body %q: %v", rr.Body.String(), err)
+	}
+	if body.Error.Message != DefaultMaintenanceMessage || body.Error.Type != "maintenance" {
+		t.Errorf("Expected the default maintenance error, got %+v", body.Error)
+	}
+
+	m.SetConfig(interfaces.MaintenanceConfig{
+		Enabled:    true,
+		Status:     http.StatusBadGateway,
+		Message:    "Back at 02:00 UTC",
+		RetryAfter: 1500 * time.Millisecond,
+	})
+	rr = send()
+	if rr.Code != http.StatusBadGateway {
+		t.Errorf("Expected the configured status, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Expected Retry-After rounded up to 2, got %q", got)
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil || body.Error.Message != "Back at 02:00 UTC" {
+		t.Errorf("Expected the configured message, got %q", rr.Body.String())
+	}
+
+	m.SetConfig(interfaces.MaintenanceConfig{})
+	if rr := send(); rr.Code != http.StatusOK || calls != 2 {
+		t.Errorf("Expected requests passed through once disabled again, got %d after %d calls", rr.Code, calls)
+	}
+}
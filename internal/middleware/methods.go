@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+// gatewayMethods are the methods the gateway forwards, in the order the Allow
+// header lists them
+var gatewayMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+// ValidateMethods reports an error if any of methods is not an upper-case
+// HTTP method the gateway forwards
+func ValidateMethods(methods []string) error {
+	for _, m := range methods {
+		if !isGatewayMethod(m) {
+			return fmt.Errorf("unknown method %q, must be one of %s", m, strings.Join(gatewayMethods, ", "))
+		}
+	}
+	return nil
+}
+
+// isGatewayMethod reports whether method is one of gatewayMethods
+func isGatewayMethod(method string) bool {
+	for _, m := range gatewayMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// methodRoute blocks methods for requests under a path prefix
+type methodRoute struct {
+	prefix  string
+	blocked map[string]bool
+}
+
+// NewMethodFilterMiddleware creates a middleware that rejects blocked HTTP
+// methods with 405 before they reach the upstream. blocked applies to every
+// path; each route blocks further methods under its path prefix, the longest
+// matching prefix winning. The Allow header lists the methods still allowed
+// for the path. Rejections are counted in rejections under
+// ReasonMethodNotAllowed.
+func NewMethodFilterMiddleware(blocked []string, routes []interfaces.BlockedMethodRoute, rejections *ValidationRejections) func(http.Handler) http.Handler {
+	global := make(map[string]bool, len(blocked))
+	for _, m := range blocked {
+		global[m] = true
+	}
+	compiled := make([]methodRoute, 0, len(routes))
+	for _, route := range routes {
+		r := methodRoute{prefix: route.PathPrefix, blocked: make(map[string]bool, len(global)+len(route.Methods))}
+		for m := range global {
+			r.blocked[m] = true
+		}
+		for _, m := range route.Methods {
+			r.blocked[m] = true
+		}
+		compiled = append(compiled, r)
+	}
+	sort.SliceStable(compiled, func(i, j int) bool {
+		return len(compiled[i].prefix) > len(compiled[j].prefix)
+	})
+
+	return func(next http.Handler) http.Handler {
+		if len(global) == 0 && len(compiled) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			blockedHere := global
+			for _, route := range compiled {
+				if strings.HasPrefix(r.URL.Path, route.prefix) {
+					blockedHere = route.blocked
+					break
+				}
+			}
+			if !blockedHere[r.Method] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			allowed := make([]string, 0, len(gatewayMethods))
+			for _, m := range gatewayMethods {
+				if !blockedHere[m] {
+					allowed = append(allowed, m)
+				}
+			}
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			rejections.reject(w, http.StatusMethodNotAllowed, ReasonMethodNotAllowed,
+				fmt.Sprintf("Method %s is not allowed for %s", r.Method, r.URL.Path))
+		})
+	}
+}
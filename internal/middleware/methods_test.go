@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+func TestMethodFilterMiddleware(t *testing.T) {
+	rejections := NewValidationRejections()
+	mw := NewMethodFilterMiddleware(
+		[]string{http.MethodDelete},
+		[]interfaces.BlockedMethodRoute{
+			{PathPrefix: "/v1/files", Methods: []string{http.MethodPut, http.MethodPatch}},
+		},
+		rejections,
+	)
+	upstreamCalls := 0
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamCalls++
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		expectStatus int
+		expectAllow  string
+	}{
+		{name: "globally blocked", method: http.MethodDelete, path: "/v1/models/gpt-4", expectStatus: http.StatusMethodNotAllowed, expectAllow: "GET, HEAD, POST, PUT, PATCH, OPTIONS"},
+		{name: "blocked under route", method: http.MethodPut, path: "/v1/files/abc", expectStatus: http.StatusMethodNotAllowed, expectAllow: "GET, HEAD, POST, OPTIONS"},
+		{name: "global block applies under route", method: http.MethodDelete, path: "/v1/files/abc", expectStatus: http.StatusMethodNotAllowed, expectAllow: "GET, HEAD, POST, OPTIONS"},
+		{name: "route method allowed elsewhere", method: http.MethodPut, path: "/v1/assistants/abc", expectStatus: http.StatusOK},
+		{name: "allowed method", method: http.MethodPost, path: "/v1/files", expectStatus: http.StatusOK},
+	}
+
+	blocked := 0
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := upstreamCalls
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if w.Code != tt.expectStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectStatus, w.Code)
+			}
+			if tt.expectStatus == http.StatusOK {
+				if upstreamCalls != before+1 {
+					t.Error("Expected the allowed request to reach the next handler")
+				}
+				if allow := w.Header().Get("Allow"); allow != "" {
+					t.Errorf("Expected no Allow header, got %q", allow)
+				}
+				return
+			}
+
+			blocked++
+			if upstreamCalls != before {
+				t.Error("Expected the blocked request not to reach the next handler")
+			}
+			if allow := w.Header().Get("Allow"); allow != tt.expectAllow {
+				t.Errorf("Expected Allow %q, got %q", tt.expectAllow, allow)
+			}
+			var body struct {
+				Error struct {
+					Reason string `json:"reason"`
+				} `json:"error"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatalf("Failed to decode error body: %v", err)
+			}
+			if body.Error.Reason != ReasonMethodNotAllowed {
+				t.Errorf("Expected reason %q, got %q", ReasonMethodNotAllowed, body.Error.Reason)
+			}
+		})
+	}
+
+	if got := rejections.Counts()[ReasonMethodNotAllowed]; got != int64(blocked) {
+		t.Errorf("Expected %d method_not_allowed rejections, got %d", blocked, got)
+	}
+}
+
+func TestMethodFilterMiddleware_NothingBlocked(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := NewMethodFilterMiddleware(nil, nil, nil)(next)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/v1/files/abc", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected DELETE to pass with nothing blocked, got %d", w.Code)
+	}
+}
+
+func TestValidateMethods(t *testing.T) {
+	if err := ValidateMethods([]string{"GET", "DELETE"}); err != nil {
+		t.Errorf("Expected valid methods, got %v", err)
+	}
+	for _, m := range []string{"delete", "TRACE", ""} {
+		if err := ValidateMethods([]string{m}); err == nil {
+			t.Errorf("Expected an error for %q", m)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+	"github.com/jamesprial/nexus/internal/metrics"
+)
+
+// NewModelPolicyMiddleware creates a middleware that enforces which models clients
+// may request. The JSON body's "model" field is first rewritten through aliases,
+// then checked against allowed; requests for other models are rejected with 400.
+// An empty allowed list permits any model. Requests without a JSON body or a
+// model field pass through unchanged. The resolved model is reported to metrics
+// and stored in the request context for downstream handlers. Rejections are
+// counted in rejections, which may be nil.
+func NewModelPolicyMiddleware(allowed []string, aliases map[string]string, logger interfaces.Logger, rejections *ValidationRejections) func(http.Handler) http.Handler {
+	allowedSet := make(map[string]struct{}, len(allowed))
+	for _, model := range allowed {
+		allowedSet[model] = struct{}{}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				rejections.reject(w, http.StatusBadRequest, ReasonUnreadableBody, "Failed to read request body")
+				return
+			}
+			setBody(r, body)
+
+			var payload map[string]json.RawMessage
+			if err := json.Unmarshal(body, &payload); err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			rawModel, ok := payload["model"]
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			var model string
+			if err := json.Unmarshal(rawModel, &model); err != nil {
+				rejections.reject(w, http.StatusBadRequest, ReasonInvalidModel, "Field model must be a string")
+				return
+			}
+
+			if alias, ok := aliases[model]; ok {
+				if logger != nil {
+					logger.Debug("Rewrote model alias", map[string]any{
+						"alias": model,
+						"model": alias,
+					})
+				}
+				model = alias
+				payload["model"], _ = json.Marshal(model)
+				rewritten, err := json.Marshal(payload)
+				if err != nil {
+					http.Error(w, "Failed to rewrite request body", http.StatusInternalServerError)
+					return
+				}
+				setBody(r, rewritten)
+			}
+
+			if len(allowedSet) > 0 {
+				if _, ok := allowedSet[model]; !ok {
+					if logger != nil {
+						logger.Warn("Model not allowed", map[string]any{
+							"model": model,
+							"path":  r.URL.Path,
+						})
+					}
+					rejections.reject(w, http.StatusBadRequest, ReasonModelNotAllowed, fmt.Sprintf("Model not allowed: %s", model))
+					return
+				}
+			}
+
+			// A negative token count records the model without touching token usage
+			metrics.ReportUsage(r, model, -1, true)
+			// Share the resolved model with later handlers
+			r = metrics.SetModel(r, model)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// setBody replaces the request body so downstream handlers can read it again
+func setBody(r *http.Request, body []byte) {
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jamesprial/nexus/internal/metrics"
+)
+
+func TestModelPolicyMiddleware(t *testing.T) {
+	allowed := []string{"gpt-4", "gpt-4o-mini"}
+	aliases := map[string]string{"gpt4": "gpt-4"}
+
+	tests := []struct {
+		name         string
+		body         string
+		expectStatus int
+		expectModel  string
+	}{
+		{
+			name:         "allowed model",
+			body:         `{"model":"gpt-4","messages":[]}`,
+			expectStatus: http.StatusOK,
+			expectModel:  "gpt-4",
+		},
+		{
+			name:         "disallowed model",
+			body:         `{"model":"o1-pro","messages":[]}`,
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "aliased model rewritten",
+			body:         `{"model":"gpt4","messages":[{"role":"user","content":"hi"}]}`,
+			expectStatus: http.StatusOK,
+			expectModel:  "gpt-4",
+		},
+		{
+			name:         "no model field",
+			body:         `{"input":"hello"}`,
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "non-string model",
+			body:         `{"model":42}`,
+			expectStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var forwarded []byte
+			var contextModel string
+			handler := NewModelPolicyMiddleware(allowed, aliases, nil, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				forwarded, _ = io.ReadAll(r.Body)
+				contextModel = metrics.GetModel(r)
+				if r.ContentLength != int64(len(forwarded)) {
+					t.Errorf("ContentLength %d does not match forwarded body length %d", r.ContentLength, len(forwarded))
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectStatus, rr.Code)
+			}
+			if tt.expectStatus != http.StatusOK {
+				return
+			}
+
+			var payload map[string]any
+			if err := json.Unmarshal(forwarded, &payload); err != nil {
+				t.Fatalf("Forwarded body is not valid JSON: %v", err)
+			}
+			if tt.expectModel != "" && payload["model"] != tt.expectModel {
+				t.Errorf("Expected forwarded model %q, got %v", tt.expectModel, payload["model"])
+			}
+			if tt.expectModel == "" && payload["model"] != nil {
+				t.Errorf("Expected no model field, got %v", payload["model"])
+			}
+			if contextModel != tt.expectModel {
+				t.Errorf("Expected context model %q, got %q", tt.expectModel, contextModel)
+			}
+		})
+	}
+}
+
+func TestModelPolicyMiddleware_AliasesOnly(t *testing.T) {
+	handler := NewModelPolicyMiddleware(nil, map[string]string{"fast": "gpt-4o-mini"}, nil, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{"model":"any-model"}`))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected any model to pass without an allow-list, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Modes for handling OPTIONS requests, set with the options_mode config key
+const (
+	// OptionsAuthenticate sends OPTIONS through the full chain like any other request
+	OptionsAuthenticate = "authenticate"
+	// OptionsPassthrough forwards OPTIONS to the upstream without authentication or limits
+	OptionsPassthrough = "passthrough"
+	// OptionsRespond answers OPTIONS as a CORS preflight without contacting the upstream
+	OptionsRespond = "respond"
+)
+
+// preflightMethods and preflightHeaders are advertised when no specific ones are requested
+const (
+	preflightMethods = "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS"
+	preflightHeaders = "Authorization, Content-Type"
+)
+
+// ValidateOptionsMode reports an error if mode is not a known OPTIONS handling
+// mode. An empty mode is valid and means OptionsAuthenticate.
+func ValidateOptionsMode(mode string) error {
+	switch mode {
+	case "", OptionsAuthenticate, OptionsPassthrough, OptionsRespond:
+		return nil
+	default:
+		return fmt.Errorf("must be one of %s, %s, %s, got %q",
+			OptionsAuthenticate, OptionsPassthrough, OptionsRespond, mode)
+	}
+}
+
+// NewOptionsMiddleware creates a middleware that decides how OPTIONS requests
+// are handled before authentication. Browsers send CORS preflights without
+// credentials, so in passthrough mode they go straight to upstream, skipping
+// auth and rate limits, and in respond mode the gateway answers them itself
+// with 204. Other methods, and every request in authenticate mode, continue
+// down the chain unchanged.
+func NewOptionsMiddleware(mode string, upstream http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if mode == "" || mode == OptionsAuthenticate {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if mode == OptionsPassthrough {
+				upstream.ServeHTTP(w, r)
+				return
+			}
+
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				origin = "*"
+			}
+			methods := r.Header.Get("Access-Control-Request-Method")
+			if methods == "" {
+				methods = preflightMethods
+			}
+			headers := r.Header.Get("Access-Control-Request-Headers")
+			if headers == "" {
+				headers = preflightHeaders
+			}
+
+			h := w.Header()
+			h.Set("Allow", preflightMethods)
+			h.Set("Access-Control-Allow-Origin", origin)
+			h.Set("Access-Control-Allow-Methods", methods)
+			h.Set("Access-Control-Allow-Headers", headers)
+			h.Set("Access-Control-Max-Age", "600")
+			h.Add("Vary", "Origin")
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOptionsMiddleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		mode           string
+		method         string
+		expectStatus   int
+		expectHandler  string
+		expectCORSOrig string
+	}{
+		{name: "default authenticates", mode: "", method: http.MethodOptions, expectStatus: http.StatusUnauthorized, expectHandler: "chain"},
+		{name: "authenticate", mode: OptionsAuthenticate, method: http.MethodOptions, expectStatus: http.StatusUnauthorized, expectHandler: "chain"},
+		{name: "passthrough", mode: OptionsPassthrough, method: http.MethodOptions, expectStatus: http.StatusOK, expectHandler: "upstream"},
+		{name: "respond", mode: OptionsRespond, method: http.MethodOptions, expectStatus: http.StatusNoContent, expectCORSOrig: "https://app.example.com"},
+		{name: "passthrough leaves other methods", mode: OptionsPassthrough, method: http.MethodHead, expectStatus: http.StatusUnauthorized, expectHandler: "chain"},
+		{name: "respond leaves other methods", mode: OptionsRespond, method: http.MethodGet, expectStatus: http.StatusUnauthorized, expectHandler: "chain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reached string
+			upstream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				reached = "upstream"
+				w.WriteHeader(http.StatusOK)
+			})
+			chain := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				reached = "chain"
+				w.WriteHeader(http.StatusUnauthorized)
+			})
+
+			req := httptest.NewRequest(tt.method, "/v1/chat/completions", nil)
+			req.Header.Set("Origin", "https://app.example.com")
+			rr := httptest.NewRecorder()
+			NewOptionsMiddleware(tt.mode, upstream)(chain).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectStatus, rr.Code)
+			}
+			if reached != tt.expectHandler {
+				t.Errorf("Expected request to reach %q, reached %q", tt.expectHandler, reached)
+			}
+			if got := rr.Header().Get("Access-Control-Allow-Origin"); got != tt.expectCORSOrig {
+				t.Errorf("Expected Access-Control-Allow-Origin %q, got %q", tt.expectCORSOrig, got)
+			}
+		})
+	}
+}
+
+func TestValidateOptionsMode(t *testing.T) {
+	for _, mode := range []string{"", OptionsAuthenticate, OptionsPassthrough, OptionsRespond} {
+		if err := ValidateOptionsMode(mode); err != nil {
+			t.Errorf("Expected mode %q to be valid, got %v", mode, err)
+		}
+	}
+	if err := ValidateOptionsMode("cors"); err == nil {
+		t.Error("Expected unknown mode to be rejected")
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// NewPathNormalizationMiddleware creates a middleware that collapses repeated
+// slashes in request paths and, if stripTrailingSlash is set, removes a
+// trailing slash from paths other than "/", so that every spelling of a path
+// is routed, recorded in metrics and proxied alike. The query string is left
+// untouched. It must run before an http.ServeMux, which answers paths with
+// repeated slashes with a redirect.
+func NewPathNormalizationMiddleware(stripTrailingSlash bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path := normalizePath(r.URL.Path, stripTrailingSlash)
+			rawPath := normalizePath(r.URL.RawPath, stripTrailingSlash)
+			if path == r.URL.Path && rawPath == r.URL.RawPath {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r2 := new(http.Request)
+			*r2 = *r
+			r2.URL = new(url.URL)
+			*r2.URL = *r.URL
+			r2.URL.Path = path
+			r2.URL.RawPath = rawPath
+			next.ServeHTTP(w, r2)
+		})
+	}
+}
+
+// normalizePath collapses runs of slashes in path into one and, if
+// stripTrailingSlash is set, drops a final slash unless path is just "/"
+func normalizePath(path string, stripTrailingSlash bool) string {
+	if strings.Contains(path, "//") {
+		var b strings.Builder
+		b.Grow(len(path))
+		for i := 0; i < len(path); i++ {
+			if path[i] == '/' && i > 0 && path[i-1] == '/' {
+				continue
+			}
+			b.WriteByte(path[i])
+		}
+		path = b.String()
+	}
+	if stripTrailingSlash && len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path
+}
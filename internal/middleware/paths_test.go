@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPathNormalizationMiddleware(t *testing.T) {
+	tests := []struct {
+		name        string
+		strip       bool
+		target      string
+		wantPath    string
+		wantRawPath string
+		wantQuery   string
+	}{
+		{name: "unchanged", target: "/v1/chat/completions", wantPath: "/v1/chat/completions"},
+		{name: "repeated slashes", target: "//v1//chat///completions", wantPath: "/v1/chat/completions"},
+		{name: "trailing slash kept", target: "/v1/chat/completions/", wantPath: "/v1/chat/completions/"},
+		{name: "trailing slash stripped", strip: true, target: "/v1/chat/completions/", wantPath: "/v1/chat/completions"},
+		{name: "repeated trailing slashes stripped", strip: true, target: "/v1/models//", wantPath: "/v1/models"},
+		{name: "root kept", strip: true, target: "/", wantPath: "/"},
+		{name: "root collapsed", strip: true, target: "//", wantPath: "/"},
+		{
+			name:      "query untouched",
+			strip:     true,
+			target:    "/v1//files/?purpose=fine-tune&after=a//b/",
+			wantPath:  "/v1/files",
+			wantQuery: "purpose=fine-tune&after=a//b/",
+		},
+		{
+			name:        "escaped path",
+			strip:       true,
+			target:      "/v1//files/a%2Fb/",
+			wantPath:    "/v1/files/a/b",
+			wantRawPath: "/v1/files/a%2Fb",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path, rawPath, query string
+			handler := NewPathNormalizationMiddleware(tt.strip)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path, rawPath, query = r.URL.Path, r.URL.RawPath, r.URL.RawQuery
+			}))
+			req := httptest.NewRequest(http.MethodGet, "http://gateway"+tt.target, nil)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if path != tt.wantPath || rawPath != tt.wantRawPath || query != tt.wantQuery {
+				t.Errorf("Expected path %q, raw path %q and query %q, got %q, %q and %q",
+					tt.wantPath, tt.wantRawPath, tt.wantQuery, path, rawPath, query)
+			}
+		})
+	}
+}
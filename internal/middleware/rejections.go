@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+)
+
+// Reasons a request is rejected before it reaches the upstream, reported in
+// the error body and as the reason label of nexus_validation_rejections_total
+const (
+	ReasonInvalidHeader         = "invalid_header"
+	ReasonContentType           = "invalid_content_type"
+	ReasonBodyTooLarge          = "body_too_large"
+	ReasonUnreadableBody        = "unreadable_body"
+	ReasonMissingBody           = "missing_body"
+	ReasonInvalidJSON           = "invalid_json"
+	ReasonMissingField          = "missing_field"
+	ReasonInvalidModel          = "invalid_model"
+	ReasonModelNotAllowed       = "model_not_allowed"
+	ReasonMethodNotAllowed      = "method_not_allowed"
+	ReasonMissingAPIVersion     = "missing_api_version"
+	ReasonUnsupportedAPIVersion = "unsupported_api_version"
+	ReasonTooManyHeaders        = "too_many_headers"
+)
+
+// ValidationReasons lists every reason a validation middleware may reject with
+var ValidationReasons = []string{
+	ReasonInvalidHeader,
+	ReasonContentType,
+	ReasonBodyTooLarge,
+	ReasonUnreadableBody,
+	ReasonMissingBody,
+	ReasonInvalidJSON,
+	ReasonMissingField,
+	ReasonInvalidModel,
+	ReasonModelNotAllowed,
+	ReasonMethodNotAllowed,
+	ReasonMissingAPIVersion,
+	ReasonUnsupportedAPIVersion,
+	ReasonTooManyHeaders,
+}
+
+// ValidationRejections counts requests rejected by the validation, JSON body,
+// model policy, method filter, header limit and API version middlewares, by reason, so client misuse can be told apart
+// from upstream failures. A nil *ValidationRejections counts nothing.
+type ValidationRejections struct {
+	counts map[string]*atomic.Int64
+}
+
+// NewValidationRejections creates a counter for every reason in ValidationReasons
+func NewValidationRejections() *ValidationRejections {
+	v := &ValidationRejections{counts: make(map[string]*atomic.Int64, len(ValidationReasons))}
+	for _, reason := range ValidationReasons {
+		v.counts[reason] = &atomic.Int64{}
+	}
+	return v
+}
+
+// Counts returns the number of rejections for each reason, including those
+// that have not occurred
+func (v *ValidationRejections) Counts() map[string]int64 {
+	counts := make(map[string]int64, len(ValidationReasons))
+	if v == nil {
+		return counts
+	}
+	for reason, n := range v.counts {
+		counts[reason] = n.Load()
+	}
+	return counts
+}
+
+// reject counts a rejection and sends an OpenAI-style JSON error naming the reason
+func (v *ValidationRejections) reject(w http.ResponseWriter, status int, reason, message string) {
+	if v != nil {
+		if n, ok := v.counts[reason]; ok {
+			n.Add(1)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]string{
+			"message": message,
+			"type":    "invalid_request_error",
+			"reason":  reason,
+		},
+	})
+}
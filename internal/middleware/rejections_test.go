@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidationRejections(t *testing.T) {
+	type middlewareFunc func(*ValidationRejections) func(http.Handler) http.Handler
+	validation := func(v *ValidationRejections) func(http.Handler) http.Handler {
+		return NewRequestValidationMiddleware(64, v)
+	}
+	jsonBody := func(v *ValidationRejections) func(http.Handler) http.Handler {
+		return NewJSONBodyMiddleware([]string{"/v1/"}, 64, v)
+	}
+	modelPolicy := func(v *ValidationRejections) func(http.Handler) http.Handler {
+		return NewModelPolicyMiddleware([]string{"gpt-4"}, nil, nil, v)
+	}
+
+	tests := []struct {
+		name         string
+		middleware   middlewareFunc
+		path         string
+		contentType  string
+		header       string
+		body         string
+		expectStatus int
+		expectReason string
+	}{
+		{name: "suspicious header", middleware: validation, contentType: "application/json", header: "<script>", body: `{}`, expectStatus: http.StatusBadRequest, expectReason: ReasonInvalidHeader},
+		{name: "missing content type", middleware: validation, body: `{}`, expectStatus: http.StatusBadRequest, expectReason: ReasonContentType},
+		{name: "wrong content type", middleware: validation, contentType: "text/plain", body: `{}`, expectStatus: http.StatusBadRequest, expectReason: ReasonContentType},
+		{name: "body too large", middleware: validation, contentType: "application/json", body: `{"input":"` + strings.Repeat("a", 64) + `"}`, expectStatus: http.StatusRequestEntityTooLarge, expectReason: ReasonBodyTooLarge},
+		{name: "invalid JSON", middleware: validation, contentType: "application/json", body: `{"model":`, expectStatus: http.StatusBadRequest, expectReason: ReasonInvalidJSON},
+		{name: "missing required field", middleware: validation, path: "/v1/chat/completions", contentType: "application/json", body: `{"model":"gpt-4"}`, expectStatus: http.StatusBadRequest, expectReason: ReasonMissingField},
+		{name: "missing body", middleware: jsonBody, body: "", expectStatus: http.StatusBadRequest, expectReason: ReasonMissingBody},
+		{name: "malformed JSON body", middleware: jsonBody, body: `{"model":`, expectStatus: http.StatusBadRequest, expectReason: ReasonInvalidJSON},
+		{name: "JSON body over the cap", middleware: jsonBody, body: `{"input":"` + strings.Repeat("a", 64) + `"}`, expectStatus: http.StatusRequestEntityTooLarge, expectReason: ReasonBodyTooLarge},
+		{name: "non-string model", middleware: modelPolicy, body: `{"model":4}`, expectStatus: http.StatusBadRequest, expectReason: ReasonInvalidModel},
+		{name: "model not allowed", middleware: modelPolicy, body: `{"model":"gpt-3.5-turbo"}`, expectStatus: http.StatusBadRequest, expectReason: ReasonModelNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.path
+			if path == "" {
+				path = "/v1/embeddings"
+			}
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.header != "" {
+				req.Header.Set("X-Custom", tt.header)
+			}
+
+			rejections := NewValidationRejections()
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Error("Expected the request to be rejected before the next handler")
+			})
+			rr := httptest.NewRecorder()
+			tt.middleware(rejections)(next).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectStatus, rr.Code)
+			}
+			if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Expected a JSON error body, got Content-Type %q", ct)
+			}
+
+			var envelope struct {
+				Error struct {
+					Message string `json:"message"`
+					Type    string `json:"type"`
+					Reason  string `json:"reason"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &envelope); err != nil {
+				t.Fatalf("Failed to decode error body %q: %v", rr.Body.String(), err)
+			}
+			if envelope.Error.Reason != tt.expectReason || envelope.Error.Type != "invalid_request_error" || envelope.Error.Message == "" {
+				t.Errorf("Expected reason %q in the error envelope, got %+v", tt.expectReason, envelope.Error)
+			}
+
+			for reason, n := range rejections.Counts() {
+				want := int64(0)
+				if reason == tt.expectReason {
+					want = 1
+				}
+				if n != want {
+					t.Errorf("Expected %d rejections for %s, got %d", want, reason, n)
+				}
+			}
+		})
+	}
+}
+
+func TestValidationRejections_Counts(t *testing.T) {
+	var nilRejections *ValidationRejections
+	if len(nilRejections.Counts()) != 0 {
+		t.Error("Expected no counts from a nil counter")
+	}
+
+	counts := NewValidationRejections().Counts()
+	if len(counts) != len(ValidationReasons) {
+		t.Errorf("Expected a zero count for each of %d reasons, got %v", len(ValidationReasons), counts)
+	}
+}
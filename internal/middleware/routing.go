@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+// Actions for requests that match no route, set with routing.on_no_match
+const (
+	// OnNoMatchFallback sends unrouted requests to target_url
+	OnNoMatchFallback = "fallback"
+	// OnNoMatchReject answers unrouted requests with 404
+	OnNoMatchReject = "reject"
+)
+
+// reasonNoRoute is reported in the error body of rejected unrouted requests
+const reasonNoRoute = "no_route"
+
+// ValidateOnNoMatch reports an error if action is not a known action for
+// unrouted requests. An empty action is valid and means OnNoMatchFallback.
+func ValidateOnNoMatch(action string) error {
+	switch action {
+	case "", OnNoMatchFallback, OnNoMatchReject:
+		return nil
+	default:
+		return fmt.Errorf("must be one of %s, %s, got %q", OnNoMatchFallback, OnNoMatchReject, action)
+	}
+}
+
+// RouteCheck watches for requests whose path matches none of the configured
+// routes. It counts and logs each one, then passes it on to the fallback
+// upstream or rejects it with 404, depending on routing.on_no_match.
+type RouteCheck struct {
+	prefixes []string
+	reject   bool
+	logger   interfaces.Logger
+	unrouted atomic.Int64
+}
+
+// NewRouteCheck creates a check for the path prefixes of cfg.Routes
+func NewRouteCheck(cfg interfaces.RoutingConfig, logger interfaces.Logger) *RouteCheck {
+	prefixes := make([]string, 0, len(cfg.Routes))
+	for _, route := range cfg.Routes {
+		prefixes = append(prefixes, route.PathPrefix)
+	}
+	return &RouteCheck{
+		prefixes: prefixes,
+		reject:   cfg.OnNoMatch == OnNoMatchReject,
+		logger:   logger,
+	}
+}
+
+// Middleware passes routed requests to next and handles the rest according
+// to the configured action
+func (c *RouteCheck) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c.matches(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		c.unrouted.Add(1)
+		action := OnNoMatchFallback
+		if c.reject {
+			action = OnNoMatchReject
+		}
+		if c.logger != nil {
+			c.logger.Warn("Request matched no route", map[string]any{
+				"method": r.Method,
+				"path":   r.URL.Path,
+				"action": action,
+			})
+		}
+
+		if c.reject {
+			var rejections *ValidationRejections
+			rejections.reject(w, http.StatusNotFound, reasonNoRoute,
+				fmt.Sprintf("No route matches %s", r.URL.Path))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Unrouted returns the number of requests that matched no route
+func (c *RouteCheck) Unrouted() int64 {
+	return c.unrouted.Load()
+}
+
+// matches reports whether path falls under any route
+func (c *RouteCheck) matches(path string) bool {
+	for _, prefix := range c.prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+// warnLogger records the messages logged at warn level
+type warnLogger struct {
+	warnings []string
+}
+
+func (l *warnLogger) Debug(msg string, fields map[string]any) {}
+func (l *warnLogger) Info(msg string, fields map[string]any)  {}
+func (l *warnLogger) Warn(msg string, fields map[string]any)  { l.warnings = append(l.warnings, msg) }
+func (l *warnLogger) Error(msg string, fields map[string]any) {}
+
+func TestRouteCheck(t *testing.T) {
+	routes := []interfaces.TargetRoute{
+		{PathPrefix: "/v1/chat", Target: "https://chat.example.com"},
+		{PathPrefix: "/v1/embeddings", Target: "https://embeddings.example.com"},
+	}
+
+	tests := []struct {
+		name         string
+		onNoMatch    string
+		path         string
+		expectStatus int
+		expectNext   bool
+		expectCount  int64
+	}{
+		{name: "routed", onNoMatch: OnNoMatchReject, path: "/v1/chat/completions", expectStatus: http.StatusOK, expectNext: true},
+		{name: "unrouted fallback", path: "/v1/models", expectStatus: http.StatusOK, expectNext: true, expectCount: 1},
+		{name: "unrouted reject", onNoMatch: OnNoMatchReject, path: "/v1/models", expectStatus: http.StatusNotFound, expectCount: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &warnLogger{}
+			check := NewRouteCheck(interfaces.RoutingConfig{Routes: routes, OnNoMatch: tt.onNoMatch}, logger)
+			called := false
+			handler := check.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			}))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rr.Code != tt.expectStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectStatus, rr.Code)
+			}
+			if called != tt.expectNext {
+				t.Errorf("Expected next called %v, got %v", tt.expectNext, called)
+			}
+			if got := check.Unrouted(); got != tt.expectCount {
+				t.Errorf("Expected %d unrouted requests, got %d", tt.expectCount, got)
+			}
+			if tt.expectCount > 0 && len(logger.warnings) != 1 {
+				t.Errorf("Expected a warning for the unrouted request, got %v", logger.warnings)
+			}
+			if tt.expectStatus == http.StatusNotFound && !strings.Contains(rr.Body.String(), `"reason":"no_route"`) {
+				t.Errorf("Expected a no_route error body, got %s", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestValidateOnNoMatch(t *testing.T) {
+	for _, action := range []string{"", OnNoMatchFallback, OnNoMatchReject} {
+		if err := ValidateOnNoMatch(action); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", action, err)
+		}
+	}
+	if err := ValidateOnNoMatch("drop"); err == nil {
+		t.Error("Expected an error for an unknown action")
+	}
+}
@@ -1,7 +1,6 @@
 package middleware
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -17,8 +16,24 @@ const (
 	DefaultMaxBodySize = 10 * 1024 * 1024
 )
 
-// NewRequestValidationMiddleware creates a middleware that validates incoming requests
-func NewRequestValidationMiddleware(maxBodySize int64) func(http.Handler) http.Handler {
+// NewRequestValidationMiddleware creates a middleware that validates incoming
+// requests. Rejections are counted in rejections, which may be nil.
+func NewRequestValidationMiddleware(maxBodySize int64, rejections *ValidationRejections) func(http.Handler) http.Handler {
+	return newValidationMiddleware(maxBodySize, false, rejections)
+}
+
+// NewStreamingValidationMiddleware validates headers, Content-Type and the
+// declared Content-Length like NewRequestValidationMiddleware, but never
+// buffers the body: it is passed on as it arrives, failing the read once more
+// than maxBodySize bytes have been sent. Use it only when nothing later in
+// the chain needs to read the body.
+func NewStreamingValidationMiddleware(maxBodySize int64, rejections *ValidationRejections) func(http.Handler) http.Handler {
+	return newValidationMiddleware(maxBodySize, true, rejections)
+}
+
+// newValidationMiddleware builds the validation middleware, buffering and
+// checking the JSON body unless streaming is set
+func newValidationMiddleware(maxBodySize int64, streaming bool, rejections *ValidationRejections) func(http.Handler) http.Handler {
 	if maxBodySize <= 0 {
 		maxBodySize = DefaultMaxBodySize
 	}
@@ -33,7 +48,7 @@ func NewRequestValidationMiddleware(maxBodySize int64) func(http.Handler) http.H
 
 			// Validate headers
 			if err := validateHeaders(r); err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
+				rejections.reject(w, http.StatusBadRequest, ReasonInvalidHeader, err.Error())
 				return
 			}
 
@@ -41,36 +56,46 @@ func NewRequestValidationMiddleware(maxBodySize int64) func(http.Handler) http.H
 			if r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodPatch {
 				contentType := r.Header.Get("Content-Type")
 				if contentType == "" {
-					http.Error(w, "Content-Type header is required", http.StatusBadRequest)
+					rejections.reject(w, http.StatusBadRequest, ReasonContentType, "Content-Type header is required")
 					return
 				}
 				
 				// Check if content type is JSON (may include charset)
 				if !strings.HasPrefix(contentType, "application/json") {
-					http.Error(w, "Content-Type must be application/json", http.StatusBadRequest)
+					rejections.reject(w, http.StatusBadRequest, ReasonContentType, "Content-Type must be application/json")
 					return
 				}
 			}
 
 			// Check Content-Length if provided
 			if r.ContentLength > maxBodySize {
-				http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+				rejections.reject(w, http.StatusRequestEntityTooLarge, ReasonBodyTooLarge, "Request body too large")
+				return
+			}
+
+			if streaming && r.Body != nil && r.Body != http.NoBody {
+				r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+				next.ServeHTTP(w, r)
 				return
 			}
 
 			// Read and validate body
 			if r.Body != nil && r.Body != http.NoBody {
-				// Read body with size limit
+				// Read body with size limit into a pooled buffer, released
+				// here on rejection and by the body's Close otherwise
+				buf := acquireBodyBuffer()
 				bodyReader := io.LimitReader(r.Body, maxBodySize+1)
-				bodyBytes, err := io.ReadAll(bodyReader)
-				if err != nil {
-					http.Error(w, "Failed to read request body", http.StatusBadRequest)
+				if _, err := buf.ReadFrom(bodyReader); err != nil {
+					releaseBodyBuffer(buf)
+					rejections.reject(w, http.StatusBadRequest, ReasonUnreadableBody, "Failed to read request body")
 					return
 				}
+				bodyBytes := buf.Bytes()
 
 				// Check if body exceeded limit
 				if int64(len(bodyBytes)) > maxBodySize {
-					http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+					releaseBodyBuffer(buf)
+					rejections.reject(w, http.StatusRequestEntityTooLarge, ReasonBodyTooLarge, "Request body too large")
 					return
 				}
 
@@ -78,19 +103,21 @@ func NewRequestValidationMiddleware(maxBodySize int64) func(http.Handler) http.H
 				if len(bodyBytes) > 0 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
 					var jsonData map[string]interface{}
 					if err := json.Unmarshal(bodyBytes, &jsonData); err != nil {
-						http.Error(w, "Invalid JSON in request body", http.StatusBadRequest)
+						releaseBodyBuffer(buf)
+						rejections.reject(w, http.StatusBadRequest, ReasonInvalidJSON, "Invalid JSON in request body")
 						return
 					}
 
 					// Validate required fields for specific endpoints
 					if err := validateRequiredFields(r.URL.Path, jsonData); err != nil {
-						http.Error(w, err.Error(), http.StatusBadRequest)
+						releaseBodyBuffer(buf)
+						rejections.reject(w, http.StatusBadRequest, ReasonMissingField, err.Error())
 						return
 					}
 				}
 
 				// Replace body with new reader so it can be read again
-				r.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+				r.Body = newPooledBody(buf)
 			}
 
 			// Pass to next handler
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/utils"
+)
+
+// NewWebSocketMiddleware creates a middleware that hands WebSocket upgrade
+// requests to sessions instead of next, so they skip the layers that buffer
+// or parse request bodies. Sessions outlive any request timeout, so the
+// server's read and write deadlines are cleared for them first.
+func NewWebSocketMiddleware(sessions http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !utils.IsWebSocketUpgrade(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			rc := http.NewResponseController(w)
+			_ = rc.SetReadDeadline(time.Time{})
+			_ = rc.SetWriteDeadline(time.Time{})
+			sessions.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,313 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Values for the rate_limit_algorithm config key, selecting how each client's
+// request budget is counted
+const (
+	RateLimitAlgorithmTokenBucket   = "token_bucket"
+	RateLimitAlgorithmFixedWindow   = "fixed_window"
+	RateLimitAlgorithmSlidingWindow = "sliding_window_log"
+)
+
+// errNeverAllowed is returned by Wait for a budget that never refills
+var errNeverAllowed = errors.New("rate limit budget never refills")
+
+// RateLimiter is one client's request budget under a rate limiting algorithm.
+// Unlike interfaces.RateLimiter, which is middleware over every client, it
+// decides for a single bucket. Implementations are safe for concurrent use.
+type RateLimiter interface {
+	// Allow reports whether a request may proceed at now, spending from the
+	// budget if so
+	Allow(now time.Time) bool
+	// Wait blocks until a request may proceed and spends from the budget. It
+	// fails without waiting when ctx would expire first.
+	Wait(ctx context.Context) error
+	// Remaining returns how many requests may proceed at now; a partly
+	// refilled token bucket reports a fraction
+	Remaining(now time.Time) float64
+	// Reset restores the full budget
+	Reset()
+	// Limit returns the size of the budget: the burst, or requests per window
+	Limit() int
+	// NextAt returns when the next request may proceed: now if one may, or
+	// the zero time if the budget never refills
+	NextAt(now time.Time) time.Time
+}
+
+// NewRateLimiter creates a budget of b requests refilled at r per second
+// under algorithm, which defaults to the token bucket when empty. The window
+// algorithms count b requests per window of b/r, so all three allow the same
+// long-run rate:
+//
+//   - token_bucket refills continuously and allows bursts of up to b
+//   - fixed_window resets the count at clock-aligned window boundaries, so up
+//     to 2b requests can pass either side of a boundary
+//   - sliding_window_log keeps the time of each request and allows at most b
+//     in any window, at the cost of memory per request
+func NewRateLimiter(algorithm string, r rate.Limit, b int) (RateLimiter, error) {
+	newLimiter, err := rateLimiterFactory(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return newLimiter(r, b), nil
+}
+
+// rateLimiterFactory returns the constructor for algorithm
+func rateLimiterFactory(algorithm string) (func(rate.Limit, int) RateLimiter, error) {
+	switch algorithm {
+	case "", RateLimitAlgorithmTokenBucket:
+		return newTokenBucket, nil
+	case RateLimitAlgorithmFixedWindow:
+		return func(r rate.Limit, b int) RateLimiter {
+			if r == rate.Inf {
+				// There is no window to count in; the bucket allows everything
+				return newTokenBucket(r, b)
+			}
+			return &fixedWindow{limit: b, window: windowFor(r, b)}
+		}, nil
+	case RateLimitAlgorithmSlidingWindow:
+		return func(r rate.Limit, b int) RateLimiter {
+			if r == rate.Inf {
+				return newTokenBucket(r, b)
+			}
+			return &slidingWindowLog{limit: b, window: windowFor(r, b)}
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit algorithm %q", algorithm)
+	}
+}
+
+// windowFor returns the window in which b requests at r per second fit, or
+// zero for a rate that never refills
+func windowFor(r rate.Limit, b int) time.Duration {
+	if r <= 0 {
+		return 0
+	}
+	return time.Duration(float64(b) / float64(r) * float64(time.Second))
+}
+
+// tokenBucket is the token_bucket algorithm, backed by rate.Limiter
+type tokenBucket struct {
+	rate  rate.Limit
+	burst int
+
+	mu      sync.Mutex
+	limiter *rate.Limiter
+}
+
+func newTokenBucket(r rate.Limit, b int) RateLimiter {
+	return &tokenBucket{rate: r, burst: b, limiter: rate.NewLimiter(r, b)}
+}
+
+// current returns the bucket, which Reset replaces
+func (t *tokenBucket) current() *rate.Limiter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.limiter
+}
+
+func (t *tokenBucket) Allow(now time.Time) bool {
+	return t.current().AllowN(now, 1)
+}
+
+func (t *tokenBucket) Wait(ctx context.Context) error {
+	// Wait returns early without sleeping when the token can't arrive in time
+	return t.current().Wait(ctx)
+}
+
+func (t *tokenBucket) Remaining(now time.Time) float64 {
+	return max(t.current().TokensAt(now), 0)
+}
+
+func (t *tokenBucket) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.limiter = rate.NewLimiter(t.rate, t.burst)
+}
+
+func (t *tokenBucket) Limit() int {
+	return t.burst
+}
+
+func (t *tokenBucket) NextAt(now time.Time) time.Time {
+	return nextTokenAt(t.current(), now)
+}
+
+// fixedWindow is the fixed_window algorithm: up to limit requests in each
+// clock-aligned window. A zero window never ends.
+type fixedWindow struct {
+	limit  int
+	window time.Duration
+
+	mu    sync.Mutex
+	start time.Time
+	count int
+}
+
+// advance starts a new window if now is past the current one. Callers must hold f.mu.
+func (f *fixedWindow) advance(now time.Time) {
+	if f.start.IsZero() {
+		f.start = now
+		if f.window > 0 {
+			f.start = now.Truncate(f.window)
+		}
+		return
+	}
+	if f.window > 0 && !now.Before(f.start.Add(f.window)) {
+		f.start = now.Truncate(f.window)
+		f.count = 0
+	}
+}
+
+func (f *fixedWindow) Allow(now time.Time) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.advance(now)
+	if f.count >= f.limit {
+		return false
+	}
+	f.count++
+	return true
+}
+
+func (f *fixedWindow) Wait(ctx context.Context) error {
+	return waitUntilAllowed(ctx, f)
+}
+
+func (f *fixedWindow) Remaining(now time.Time) float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.advance(now)
+	return float64(f.limit - f.count)
+}
+
+func (f *fixedWindow) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.start = time.Time{}
+	f.count = 0
+}
+
+func (f *fixedWindow) Limit() int {
+	return f.limit
+}
+
+func (f *fixedWindow) NextAt(now time.Time) time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.advance(now)
+	switch {
+	case f.count < f.limit:
+		return now
+	case f.window <= 0 || f.limit <= 0:
+		return time.Time{}
+	default:
+		return f.start.Add(f.window)
+	}
+}
+
+// slidingWindowLog is the sliding_window_log algorithm: it logs the time of
+// each allowed request and allows at most limit within any window. A zero
+// window never forgets a request.
+type slidingWindowLog struct {
+	limit  int
+	window time.Duration
+
+	mu  sync.Mutex
+	log []time.Time
+}
+
+// prune drops the requests that have left the window ending at now. Callers
+// must hold s.mu.
+func (s *slidingWindowLog) prune(now time.Time) {
+	if s.window <= 0 {
+		return
+	}
+	cutoff := now.Add(-s.window)
+	i := 0
+	for i < len(s.log) && !s.log[i].After(cutoff) {
+		i++
+	}
+	s.log = s.log[i:]
+}
+
+func (s *slidingWindowLog) Allow(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(now)
+	if len(s.log) >= s.limit {
+		return false
+	}
+	s.log = append(s.log, now)
+	return true
+}
+
+func (s *slidingWindowLog) Wait(ctx context.Context) error {
+	return waitUntilAllowed(ctx, s)
+}
+
+func (s *slidingWindowLog) Remaining(now time.Time) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(now)
+	return float64(s.limit - len(s.log))
+}
+
+func (s *slidingWindowLog) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.log = nil
+}
+
+func (s *slidingWindowLog) Limit() int {
+	return s.limit
+}
+
+func (s *slidingWindowLog) NextAt(now time.Time) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(now)
+	switch {
+	case len(s.log) < s.limit:
+		return now
+	case s.window <= 0 || s.limit <= 0:
+		return time.Time{}
+	default:
+		// The oldest request leaves the window just after this
+		return s.log[0].Add(s.window + time.Nanosecond)
+	}
+}
+
+// waitUntilAllowed implements Wait for the window algorithms by sleeping
+// until NextAt and trying again, since other requests may take the slot first
+func waitUntilAllowed(ctx context.Context, l RateLimiter) error {
+	for {
+		now := time.Now()
+		if l.Allow(now) {
+			return nil
+		}
+		next := l.NextAt(now)
+		if next.IsZero() {
+			return errNeverAllowed
+		}
+		if deadline, ok := ctx.Deadline(); ok && deadline.Before(next) {
+			return context.DeadlineExceeded
+		}
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// allowAt sends n requests to l at now and returns how many were allowed
+func allowAt(l RateLimiter, now time.Time, n int) int {
+	allowed := 0
+	for range n {
+		if l.Allow(now) {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+func TestRateLimitAlgorithms_WindowBoundary(t *testing.T) {
+	// 5 requests per second: a burst of 5, or 5 per 1s window
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	beforeBoundary := base.Add(900 * time.Millisecond)
+	afterBoundary := base.Add(time.Second)
+
+	tests := []struct {
+		algorithm string
+		// allowed just after the boundary, having spent the budget just before it
+		wantAfter int
+		// allowed a full window after the first burst
+		wantLater int
+	}{
+		// Refills one token per 200ms, so half a token has accrued
+		{algorithm: RateLimitAlgorithmTokenBucket, wantAfter: 0, wantLater: 5},
+		// A new window starts at the boundary with a fresh count: 10 requests in 100ms
+		{algorithm: RateLimitAlgorithmFixedWindow, wantAfter: 5, wantLater: 0},
+		// The first burst is still inside the window ending at the boundary
+		{algorithm: RateLimitAlgorithmSlidingWindow, wantAfter: 0, wantLater: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			l, err := NewRateLimiter(tt.algorithm, 5, 5)
+			if err != nil {
+				t.Fatalf("NewRateLimiter() error = %v", err)
+			}
+			if got := allowAt(l, beforeBoundary, 6); got != 5 {
+				t.Fatalf("Expected the burst of 5 allowed, got %d", got)
+			}
+			if got := allowAt(l, afterBoundary, 5); got != tt.wantAfter {
+				t.Errorf("Expected %d allowed after the boundary, got %d", tt.wantAfter, got)
+			}
+			if got := allowAt(l, beforeBoundary.Add(time.Second+time.Millisecond), 5); got != tt.wantLater {
+				t.Errorf("Expected %d allowed a window later, got %d", tt.wantLater, got)
+			}
+		})
+	}
+}
+
+func TestSlidingWindowLog_NeverExceedsLimitInAnyWindow(t *testing.T) {
+	l, err := NewRateLimiter(RateLimitAlgorithmSlidingWindow, 5, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// One attempt every 50ms for 5s; record when each was allowed
+	var allowed []time.Time
+	for i := range 100 {
+		now := base.Add(time.Duration(i) * 50 * time.Millisecond)
+		if l.Allow(now) {
+			allowed = append(allowed, now)
+		}
+	}
+	for i := range allowed {
+		inWindow := 0
+		for _, at := range allowed[i:] {
+			if at.Sub(allowed[i]) < time.Second {
+				inWindow++
+			}
+		}
+		if inWindow > 5 {
+			t.Fatalf("Expected at most 5 requests in the second from %v, got %d", allowed[i], inWindow)
+		}
+	}
+	// The long-run rate still matches 5 per second
+	if len(allowed) < 20 {
+		t.Errorf("Expected at least 20 requests allowed over 5s, got %d", len(allowed))
+	}
+}
+
+func TestRateLimitAlgorithms_RemainingNextAtReset(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, algorithm := range []string{RateLimitAlgorithmTokenBucket, RateLimitAlgorithmFixedWindow, RateLimitAlgorithmSlidingWindow} {
+		t.Run(algorithm, func(t *testing.T) {
+			l, err := NewRateLimiter(algorithm, 2, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if l.Limit() != 2 {
+				t.Errorf("Expected limit 2, got %d", l.Limit())
+			}
+			if got := l.Remaining(base); got != 2 {
+				t.Errorf("Expected 2 remaining before any request, got %v", got)
+			}
+			if next := l.NextAt(base); !next.Equal(base) {
+				t.Errorf("Expected the next request allowed now, got %v", next)
+			}
+
+			allowAt(l, base, 2)
+			if got := l.Remaining(base); got != 0 {
+				t.Errorf("Expected nothing remaining after the burst, got %v", got)
+			}
+			next := l.NextAt(base)
+			if !next.After(base) || next.After(base.Add(time.Second+time.Millisecond)) {
+				t.Errorf("Expected the next request allowed within a window, got %v", next)
+			}
+			if !l.Allow(next) {
+				t.Errorf("Expected a request allowed at NextAt %v", next)
+			}
+
+			l.Reset()
+			if got := allowAt(l, base, 3); got != 2 {
+				t.Errorf("Expected the full budget after Reset, got %d allowed", got)
+			}
+		})
+	}
+}
+
+func TestRateLimitAlgorithms_NeverRefills(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, algorithm := range []string{RateLimitAlgorithmTokenBucket, RateLimitAlgorithmFixedWindow, RateLimitAlgorithmSlidingWindow} {
+		t.Run(algorithm, func(t *testing.T) {
+			l, err := NewRateLimiter(algorithm, 0, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := allowAt(l, base, 2); got != 1 {
+				t.Errorf("Expected only the burst allowed, got %d", got)
+			}
+			if got := allowAt(l, base.Add(time.Hour), 1); got != 0 {
+				t.Errorf("Expected nothing allowed later, got %d", got)
+			}
+			if next := l.NextAt(base.Add(time.Hour)); !next.IsZero() {
+				t.Errorf("Expected no next request time, got %v", next)
+			}
+		})
+	}
+}
+
+func TestRateLimitAlgorithms_Wait(t *testing.T) {
+	for _, algorithm := range []string{RateLimitAlgorithmTokenBucket, RateLimitAlgorithmFixedWindow, RateLimitAlgorithmSlidingWindow} {
+		t.Run(algorithm, func(t *testing.T) {
+			// One request per 10s, so the window can't end during the test
+			slow, err := NewRateLimiter(algorithm, 0.1, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slow.Allow(time.Now()) {
+				t.Fatal("Expected the first request allowed")
+			}
+			// A deadline shorter than the wait fails at once
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			if err := slow.Wait(ctx); err == nil {
+				t.Error("Expected Wait to fail when the deadline comes first")
+			}
+			if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+				t.Errorf("Expected Wait to fail without sleeping, took %v", elapsed)
+			}
+
+			// One request per 20ms
+			fast, err := NewRateLimiter(algorithm, 50, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !fast.Allow(time.Now()) {
+				t.Fatal("Expected the first request allowed")
+			}
+			ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			if err := fast.Wait(ctx); err != nil {
+				t.Errorf("Expected Wait to succeed once the budget refills, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNewRateLimiter_UnknownAlgorithm(t *testing.T) {
+	if _, err := NewRateLimiter("leaky_bucket", 1, 1); err == nil {
+		t.Error("Expected an error for an unknown algorithm")
+	}
+	if err := NewPerClientRateLimiter(1, 1).SetAlgorithm("leaky_bucket"); err == nil {
+		t.Error("Expected SetAlgorithm to reject an unknown algorithm")
+	}
+}
+
+func TestPerClientRateLimiter_FixedWindowHeaders(t *testing.T) {
+	limiter := NewPerClientRateLimiter(rate.Limit(1), 2)
+	if err := limiter.SetAlgorithm(RateLimitAlgorithmFixedWindow); err != nil {
+		t.Fatal(err)
+	}
+	limiter.SetHeaders(true)
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	var statuses []int
+	var last *httptest.ResponseRecorder
+	for range 3 {
+		req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+		req.Header.Set("Authorization", "Bearer client")
+		last = httptest.NewRecorder()
+		handler.ServeHTTP(last, req)
+		statuses = append(statuses, last.Code)
+	}
+
+	// Requests can straddle a window boundary, so allow either split
+	if statuses[0] != http.StatusOK || statuses[1] != http.StatusOK {
+		t.Fatalf("Expected the first two requests allowed, got %v", statuses)
+	}
+	if got := last.Header().Get("X-RateLimit-Limit"); got != "2" {
+		t.Errorf("Expected X-RateLimit-Limit 2, got %q", got)
+	}
+	if statuses[2] == http.StatusTooManyRequests {
+		if got := last.Header().Get("X-RateLimit-Remaining"); got != "0" {
+			t.Errorf("Expected X-RateLimit-Remaining 0, got %q", got)
+		}
+		if last.Header().Get("Retry-After") == "" {
+			t.Error("Expected Retry-After on the rejection")
+		}
+	}
+}
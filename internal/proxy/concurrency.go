@@ -0,0 +1,187 @@
+package proxy
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+// LimiterTypeConcurrency is reported with requests rejected for exceeding the
+// per-key in-flight cap
+const LimiterTypeConcurrency = "concurrency"
+
+// ConcurrencyLimiter caps how many requests a single key may have in flight at
+// once, so one key cannot hold every upstream connection. It implements
+// interfaces.RateLimiter; a cap of zero means unlimited. After
+// SetUpstreamCapacity it also shares a gateway-wide number of upstream slots
+// between keys, queuing requests while every slot is taken.
+type ConcurrencyLimiter struct {
+	mu sync.Mutex
+	// inFlight counts requests currently being served, by client key
+	inFlight  map[string]int
+	max       int
+	overrides map[string]int
+	// shadow counts would-be rejections without enforcing the cap
+	shadow      atomic.Bool
+	wouldReject atomic.Int64
+	collector   interfaces.MetricsCollector
+	logger      interfaces.Logger
+	// upstream schedules the gateway-wide slots; nil when uncapped
+	upstream *slotScheduler
+}
+
+// NewConcurrencyLimiter creates a limiter allowing max in-flight requests per
+// key. Rejections are recorded in collector when it is non-nil.
+func NewConcurrencyLimiter(max int, collector interfaces.MetricsCollector, logger interfaces.Logger) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		inFlight:  make(map[string]int),
+		max:       max,
+		collector: collector,
+		logger:    logger,
+	}
+}
+
+// SetKeyLimits configures per-key caps. Keys without an override, or with a
+// zero MaxConcurrent, use the global cap. Requests already in flight are unaffected.
+func (c *ConcurrencyLimiter) SetKeyLimits(limits map[string]interfaces.KeyLimits) {
+	overrides := make(map[string]int, len(limits))
+	for key, l := range limits {
+		if l.MaxConcurrent > 0 {
+			overrides[key] = l.MaxConcurrent
+		}
+	}
+
+	c.mu.Lock()
+	c.overrides = overrides
+	c.mu.Unlock()
+}
+
+// SetUpstreamCapacity caps the requests in flight across all keys. Requests
+// beyond it wait for a slot rather than being rejected, dispatched in arrival
+// order for SchedulingFIFO or round-robin between waiting keys for
+// SchedulingFair. A capacity of zero leaves the total uncapped.
+// It must be called before the limiter starts serving requests.
+func (c *ConcurrencyLimiter) SetUpstreamCapacity(capacity int, scheduling string) {
+	if capacity <= 0 {
+		c.upstream = nil
+		return
+	}
+	c.upstream = newSlotScheduler(capacity, scheduling)
+}
+
+// UpstreamQueueDepth returns the number of requests waiting for an upstream slot
+func (c *ConcurrencyLimiter) UpstreamQueueDepth() int {
+	if c.upstream == nil {
+		return 0
+	}
+	return c.upstream.queued()
+}
+
+// SetShadowMode enables or disables shadow mode. In shadow mode requests over
+// the cap are let through and only counted.
+func (c *ConcurrencyLimiter) SetShadowMode(enabled bool) {
+	c.shadow.Store(enabled)
+}
+
+// WouldRejectCount returns how many requests would have been rejected in shadow mode
+func (c *ConcurrencyLimiter) WouldRejectCount() int64 {
+	return c.wouldReject.Load()
+}
+
+// limitFor returns the cap for key; the caller must hold c.mu
+func (c *ConcurrencyLimiter) limitFor(key string) int {
+	if max, ok := c.overrides[key]; ok {
+		return max
+	}
+	return c.max
+}
+
+// acquire takes an in-flight slot for key, reporting false when the key is at its cap
+func (c *ConcurrencyLimiter) acquire(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if max := c.limitFor(key); max > 0 && c.inFlight[key] >= max {
+		return false
+	}
+	c.inFlight[key]++
+	return true
+}
+
+// release returns an in-flight slot for key
+func (c *ConcurrencyLimiter) release(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.inFlight[key] <= 1 {
+		delete(c.inFlight, key)
+		return
+	}
+	c.inFlight[key]--
+}
+
+// Middleware implements interfaces.RateLimiter. It holds a slot for the key
+// while the rest of the chain serves the request and rejects with 429 once
+// the key's cap is reached. With an upstream capacity set, the request then
+// waits for a gateway-wide slot too.
+func (c *ConcurrencyLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := clientIdentity(r)
+		if key != "" {
+			switch {
+			case c.acquire(key):
+				defer c.release(key)
+			case c.shadow.Load():
+				c.wouldReject.Add(1)
+			default:
+				if c.collector != nil {
+					c.collector.RecordThrottle(key, LimiterTypeConcurrency)
+				}
+				if c.logger != nil {
+					c.logger.Debug("Concurrent request limit reached", map[string]any{
+						"path": r.URL.Path,
+					})
+				}
+				http.Error(w, "Too many concurrent requests for this client", http.StatusTooManyRequests)
+				return
+			}
+		}
+
+		if c.upstream != nil {
+			if !c.upstream.acquire(r.Context(), key) {
+				// Client went away while queued; there is no one to respond to
+				return
+			}
+			defer c.upstream.release()
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GetLimit implements interfaces.RateLimiter, reporting whether key may start
+// another request and how many more it may start; remaining is -1 when uncapped
+func (c *ConcurrencyLimiter) GetLimit(apiKey string) (allowed bool, remaining int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	max := c.limitFor(apiKey)
+	if max <= 0 {
+		return true, -1
+	}
+	remaining = max - c.inFlight[apiKey]
+	return remaining > 0, remaining
+}
+
+// Reset implements interfaces.RateLimiter. In-flight requests release their
+// own slots, so there is no state to clear.
+func (c *ConcurrencyLimiter) Reset(apiKey string) {}
+
+// InFlight returns the number of requests key currently has in flight
+func (c *ConcurrencyLimiter) InFlight(apiKey string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.inFlight[apiKey]
+}
@@ -0,0 +1,129 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+	"github.com/jamesprial/nexus/internal/metrics"
+)
+
+// blockingHandler holds every request until release is closed
+type blockingHandler struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (h *blockingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.started <- struct{}{}
+	<-h.release
+	w.WriteHeader(http.StatusOK)
+}
+
+func concurrencyRequest(handler http.Handler, clientKey string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	req = metrics.SetAPIKey(req, clientKey)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestConcurrencyLimiter_RejectsOverCapUntilSlotFrees(t *testing.T) {
+	const maxInFlight = 3
+	collector := metrics.NewMetricsCollector()
+	limiter := NewConcurrencyLimiter(maxInFlight, collector, &mockLogger{})
+	upstream := &blockingHandler{started: make(chan struct{}), release: make(chan struct{})}
+	handler := limiter.Middleware(upstream)
+
+	var wg sync.WaitGroup
+	codes := make(chan int, maxInFlight)
+	for i := 0; i < maxInFlight; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- concurrencyRequest(handler, "client").Code
+		}()
+	}
+	for i := 0; i < maxInFlight; i++ {
+		<-upstream.started
+	}
+
+	if got := limiter.InFlight("client"); got != maxInFlight {
+		t.Fatalf("Expected %d requests in flight, got %d", maxInFlight, got)
+	}
+	if rr := concurrencyRequest(handler, "client"); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected request over the cap to be rejected, got %d", rr.Code)
+	}
+	if allowed, remaining := limiter.GetLimit("client"); allowed || remaining != 0 {
+		t.Errorf("Expected no remaining slots, got allowed=%v remaining=%d", allowed, remaining)
+	}
+
+	// Finishing one request frees a slot for the next
+	upstream.release <- struct{}{}
+	if code := <-codes; code != http.StatusOK {
+		t.Errorf("Expected in-flight request to succeed, got %d", code)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		codes <- concurrencyRequest(handler, "client").Code
+	}()
+	<-upstream.started
+
+	close(upstream.release)
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("Expected admitted request to succeed, got %d", code)
+		}
+	}
+	if got := limiter.InFlight("client"); got != 0 {
+		t.Errorf("Expected all slots released, got %d in flight", got)
+	}
+
+	km, ok := collector.GetMetricsForKey("client")
+	if !ok || km.ThrottledRequests[LimiterTypeConcurrency] != 1 {
+		t.Errorf("Expected one concurrency throttle recorded, got %+v", km)
+	}
+}
+
+func TestConcurrencyLimiter_KeyOverridesAndShadowMode(t *testing.T) {
+	limiter := NewConcurrencyLimiter(0, nil, nil)
+	limiter.SetKeyLimits(map[string]interfaces.KeyLimits{"capped": {MaxConcurrent: 1}})
+
+	if allowed, remaining := limiter.GetLimit("uncapped"); !allowed || remaining != -1 {
+		t.Errorf("Expected uncapped key to be unlimited, got allowed=%v remaining=%d", allowed, remaining)
+	}
+
+	upstream := &blockingHandler{started: make(chan struct{}), release: make(chan struct{})}
+	handler := limiter.Middleware(upstream)
+
+	done := make(chan struct{})
+	go func() {
+		concurrencyRequest(handler, "capped")
+		close(done)
+	}()
+	<-upstream.started
+
+	if rr := concurrencyRequest(handler, "capped"); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected per-key cap to apply, got %d", rr.Code)
+	}
+
+	limiter.SetShadowMode(true)
+	shadowCode := make(chan int, 1)
+	go func() { shadowCode <- concurrencyRequest(handler, "capped").Code }()
+	<-upstream.started
+	if got := limiter.WouldRejectCount(); got != 1 {
+		t.Errorf("Expected 1 would-reject decision, got %d", got)
+	}
+
+	close(upstream.release)
+	<-done
+	if code := <-shadowCode; code != http.StatusOK {
+		t.Errorf("Expected shadow mode to let the request through, got %d", code)
+	}
+}
@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+// Kinds of upstream failure reported by UpstreamError
+const (
+	UpstreamErrorTimeout = "timeout"
+	UpstreamErrorDNS     = "dns"
+	UpstreamErrorRefused = "connection_refused"
+	// UpstreamErrorTooLarge is a response body over the configured size limit
+	UpstreamErrorTooLarge = "response_too_large"
+	UpstreamErrorOther    = "other"
+)
+
+// UpstreamErrorKinds lists every kind an UpstreamError may have
+var UpstreamErrorKinds = []string{
+	UpstreamErrorTimeout,
+	UpstreamErrorDNS,
+	UpstreamErrorRefused,
+	UpstreamErrorTooLarge,
+	UpstreamErrorOther,
+}
+
+// UpstreamError is a failed round-trip to the upstream, classified by cause
+type UpstreamError struct {
+	Kind string
+	Err  error
+}
+
+// Error implements the error interface
+func (e *UpstreamError) Error() string {
+	return fmt.Sprintf("upstream %s: %v", e.Kind, e.Err)
+}
+
+// Unwrap returns the underlying transport error
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode returns the status reported to the client: 504 for timeouts and
+// 502 for every other failure
+func (e *UpstreamError) StatusCode() int {
+	if e.Kind == UpstreamErrorTimeout {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusBadGateway
+}
+
+// message returns the generic client-facing description and type of the failure
+func (e *UpstreamError) message() (string, string) {
+	switch e.Kind {
+	case UpstreamErrorTimeout:
+		return "Upstream service timed out", "upstream_timeout"
+	case UpstreamErrorTooLarge:
+		return "Upstream response exceeded the size limit", "upstream_response_too_large"
+	}
+	return "Upstream service unavailable", "upstream_error"
+}
+
+// classifyUpstreamError wraps a transport error in an UpstreamError
+func classifyUpstreamError(err error) *UpstreamError {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	kind := UpstreamErrorOther
+	switch {
+	case errors.Is(err, ErrResponseTooLarge):
+		kind = UpstreamErrorTooLarge
+	case errors.As(err, &dnsErr) && !dnsErr.IsTimeout:
+		kind = UpstreamErrorDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		kind = UpstreamErrorRefused
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		kind = UpstreamErrorTimeout
+	}
+	return &UpstreamError{Kind: kind, Err: err}
+}
+
+// writeUpstreamError sends a JSON error body that names the failure without
+// exposing the underlying error
+func writeUpstreamError(w http.ResponseWriter, upstreamErr *UpstreamError) {
+	message, errType := upstreamErr.message()
+	writeJSONError(w, upstreamErr.StatusCode(), message, errType)
+}
+
+// writeJSONError sends an OpenAI-style JSON error body with status
+func writeJSONError(w http.ResponseWriter, status int, message, errType string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]string{
+			"message": message,
+			"type":    errType,
+		},
+	})
+}
@@ -0,0 +1,170 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// refusingURL returns a URL on which nothing listens
+func refusingURL(t *testing.T) *url.URL {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to reserve a port: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	u, _ := url.Parse("http://user:secret@" + addr)
+	return u
+}
+
+func TestClassifyUpstreamError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		expectKind string
+		expectCode int
+	}{
+		{
+			name:       "connection refused",
+			err:        &net.OpError{Op: "dial", Net: "tcp", Err: fmt.Errorf("connect: %w", syscall.ECONNREFUSED)},
+			expectKind: UpstreamErrorRefused,
+			expectCode: http.StatusBadGateway,
+		},
+		{
+			name:       "unknown host",
+			err:        &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "upstream.invalid", IsNotFound: true}},
+			expectKind: UpstreamErrorDNS,
+			expectCode: http.StatusBadGateway,
+		},
+		{
+			name:       "deadline exceeded",
+			err:        fmt.Errorf("round trip: %w", context.DeadlineExceeded),
+			expectKind: UpstreamErrorTimeout,
+			expectCode: http.StatusGatewayTimeout,
+		},
+		{
+			name:       "dns timeout",
+			err:        &net.DNSError{Err: "i/o timeout", Name: "upstream.example", IsTimeout: true},
+			expectKind: UpstreamErrorTimeout,
+			expectCode: http.StatusGatewayTimeout,
+		},
+		{
+			name:       "other failure",
+			err:        errors.New("unexpected EOF"),
+			expectKind: UpstreamErrorOther,
+			expectCode: http.StatusBadGateway,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstreamErr := classifyUpstreamError(tt.err)
+			if upstreamErr.Kind != tt.expectKind {
+				t.Errorf("Expected kind %q, got %q", tt.expectKind, upstreamErr.Kind)
+			}
+			if upstreamErr.StatusCode() != tt.expectCode {
+				t.Errorf("Expected status %d, got %d", tt.expectCode, upstreamErr.StatusCode())
+			}
+			if !errors.Is(upstreamErr, tt.err) {
+				t.Error("Expected UpstreamError to unwrap to the transport error")
+			}
+		})
+	}
+}
+
+func TestHTTPProxy_RefusedUpstreamReturnsSanitizedError(t *testing.T) {
+	target := refusingURL(t)
+	logger := &mockLogger{}
+	proxy := NewHTTPProxy(target, logger)
+
+	rr := httptest.NewRecorder()
+	proxy.ServeHTTP(rr, httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{}`)))
+
+	if rr.Code != http.StatusBadGateway {
+		t.Fatalf("Expected 502, got %d", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected JSON content type, got %q", ct)
+	}
+
+	var body struct {
+		Error struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Expected JSON error body, got %q: %v", rr.Body.String(), err)
+	}
+	if body.Error.Message != "Upstream service unavailable" {
+		t.Errorf("Expected generic message, got %q", body.Error.Message)
+	}
+	if body.Error.Type != "upstream_error" {
+		t.Errorf("Expected type upstream_error, got %q", body.Error.Type)
+	}
+	for _, leak := range []string{target.Host, "refused", "dial", "secret"} {
+		if strings.Contains(rr.Body.String(), leak) {
+			t.Errorf("Response body leaks %q: %s", leak, rr.Body.String())
+		}
+	}
+
+	if got := proxy.UpstreamErrors()[UpstreamErrorRefused]; got != 1 {
+		t.Errorf("Expected 1 refused error counted, got %d", got)
+	}
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	var logged bool
+	for _, entry := range logger.logs {
+		if entry.level != "error" {
+			continue
+		}
+		logged = true
+		if strings.Contains(fmt.Sprint(entry.fields["target"]), "secret") {
+			t.Errorf("Logged target leaks credentials: %v", entry.fields["target"])
+		}
+		if !strings.Contains(fmt.Sprint(entry.fields["error"]), "refused") {
+			t.Errorf("Expected the real error to be logged, got %v", entry.fields["error"])
+		}
+	}
+	if !logged {
+		t.Error("Expected the upstream failure to be logged")
+	}
+}
+
+func TestHTTPProxy_UpstreamTimeoutIsGatewayTimeout(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer upstream.Close()
+	defer close(release)
+
+	target, _ := url.Parse(upstream.URL)
+	proxy := NewHTTPProxy(target, &mockLogger{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest("GET", "/v1/models", nil).WithContext(ctx)
+	rr := httptest.NewRecorder()
+	proxy.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected 504, got %d", rr.Code)
+	}
+	if got := proxy.UpstreamErrors()[UpstreamErrorTimeout]; got != 1 {
+		t.Errorf("Expected 1 timeout counted, got %d", got)
+	}
+}
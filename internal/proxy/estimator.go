@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"fmt"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+	"github.com/tiktoken-go/tokenizer"
+)
+
+// defaultCharsPerToken is the rule-of-thumb ratio for English text
+const defaultCharsPerToken = 4
+
+// HeuristicEstimator approximates tokens as a fixed number of characters per token.
+// It is cheap and model-agnostic, at the cost of accuracy.
+type HeuristicEstimator struct {
+	// CharsPerToken is the character count per token; zero uses 4
+	CharsPerToken int
+}
+
+// EstimateTokens implements interfaces.TokenEstimator
+func (h *HeuristicEstimator) EstimateTokens(model, text string) int {
+	charsPerToken := h.CharsPerToken
+	if charsPerToken <= 0 {
+		charsPerToken = defaultCharsPerToken
+	}
+	return len(text) / charsPerToken
+}
+
+// TiktokenEstimator counts tokens with the tiktoken encoding for the model.
+// It falls back to the heuristic if the encoding cannot be loaded.
+type TiktokenEstimator struct{}
+
+// EstimateTokens implements interfaces.TokenEstimator
+func (t *TiktokenEstimator) EstimateTokens(model, text string) int {
+	enc, err := tokenizer.Get(getEncodingForModel(model))
+	if err != nil {
+		return (&HeuristicEstimator{}).EstimateTokens(model, text)
+	}
+	ids, _, _ := enc.Encode(text)
+	return len(ids)
+}
+
+// NewTokenEstimator returns the estimator with the given name: "heuristic"
+// (or empty) for the chars-per-token heuristic, or "tiktoken".
+func NewTokenEstimator(name string) (interfaces.TokenEstimator, error) {
+	switch name {
+	case "", "heuristic":
+		return &HeuristicEstimator{}, nil
+	case "tiktoken":
+		return &TiktokenEstimator{}, nil
+	default:
+		return nil, fmt.Errorf("unknown token estimator %q", name)
+	}
+}
@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHeuristicEstimator(t *testing.T) {
+	tests := []struct {
+		name          string
+		charsPerToken int
+		text          string
+		expected      int
+	}{
+		{"empty text", 0, "", 0},
+		{"default four chars per token", 0, strings.Repeat("a", 40), 10},
+		{"rounds down", 0, "abcdefg", 1},
+		{"custom ratio", 2, strings.Repeat("a", 40), 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			estimator := &HeuristicEstimator{CharsPerToken: tt.charsPerToken}
+			if got := estimator.EstimateTokens("gpt-4", tt.text); got != tt.expected {
+				t.Errorf("Expected %d tokens, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTiktokenEstimator(t *testing.T) {
+	estimator := &TiktokenEstimator{}
+	// "Hello, world!" is 4 tokens in cl100k_base
+	if got := estimator.EstimateTokens("gpt-4", "Hello, world!"); got != 4 {
+		t.Errorf("Expected 4 tokens, got %d", got)
+	}
+}
+
+func TestNewTokenEstimator(t *testing.T) {
+	tests := []struct {
+		name      string
+		estimator string
+		expectErr bool
+		check     func(any) bool
+	}{
+		{"default", "", false, func(e any) bool { _, ok := e.(*HeuristicEstimator); return ok }},
+		{"heuristic", "heuristic", false, func(e any) bool { _, ok := e.(*HeuristicEstimator); return ok }},
+		{"tiktoken", "tiktoken", false, func(e any) bool { _, ok := e.(*TiktokenEstimator); return ok }},
+		{"unknown", "sentencepiece", true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			estimator, err := NewTokenEstimator(tt.estimator)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("Expected error for unknown estimator")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !tt.check(estimator) {
+				t.Errorf("Unexpected estimator type %T", estimator)
+			}
+		})
+	}
+}
+
+// fixedEstimator returns the same count for any non-empty text
+type fixedEstimator struct{ tokens int }
+
+func (f *fixedEstimator) EstimateTokens(model, text string) int {
+	if text == "" {
+		return 0
+	}
+	return f.tokens
+}
+
+func TestDefaultTokenCounter_UsesEstimator(t *testing.T) {
+	counter := &DefaultTokenCounter{Estimator: &fixedEstimator{tokens: 50}}
+	body := `{"model":"gpt-4","messages":[{"role":"user","content":"hi"},{"role":"user","content":"there"}]}`
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(body))
+
+	got, err := counter.CountTokens(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got != 100 {
+		t.Errorf("Expected 100 tokens from the plugged estimator, got %d", got)
+	}
+}
@@ -0,0 +1,159 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+	"github.com/jamesprial/nexus/internal/utils"
+)
+
+// Health check defaults used when the corresponding health_check field is unset
+const (
+	defaultHealthCheckTimeout = 2 * time.Second
+	defaultHealthCheckPath    = "/"
+	defaultUnhealthyThreshold = 3
+	defaultHealthyThreshold   = 2
+)
+
+// HealthChecker periodically probes an upstream and tracks whether it is
+// healthy. A single result never flips the state: the upstream is marked
+// unhealthy only after UnhealthyThreshold consecutive failed probes, and
+// healthy again only after HealthyThreshold consecutive successes, so an
+// occasional blip doesn't make the reported health flap.
+type HealthChecker struct {
+	target             string
+	client             *http.Client
+	interval           time.Duration
+	unhealthyThreshold int
+	healthyThreshold   int
+	logger             interfaces.Logger
+
+	mu        sync.RWMutex
+	healthy   bool
+	failures  int
+	successes int
+}
+
+// NewHealthChecker creates a checker for the upstream at targetURL. It starts
+// out healthy. Zero timeout, path and threshold settings fall back to defaults.
+func NewHealthChecker(targetURL string, cfg interfaces.HealthCheckConfig, logger interfaces.Logger) *HealthChecker {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	path := cfg.Path
+	if path == "" {
+		path = defaultHealthCheckPath
+	}
+	unhealthy := cfg.UnhealthyThreshold
+	if unhealthy <= 0 {
+		unhealthy = defaultUnhealthyThreshold
+	}
+	healthy := cfg.HealthyThreshold
+	if healthy <= 0 {
+		healthy = defaultHealthyThreshold
+	}
+
+	return &HealthChecker{
+		target:             strings.TrimRight(targetURL, "/") + "/" + strings.TrimLeft(path, "/"),
+		client:             &http.Client{Timeout: timeout},
+		interval:           cfg.Interval,
+		unhealthyThreshold: unhealthy,
+		healthyThreshold:   healthy,
+		logger:             logger,
+		healthy:            true,
+	}
+}
+
+// Healthy reports the stable health state
+func (h *HealthChecker) Healthy() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.healthy
+}
+
+// Record applies one probe result and reports whether it changed the stable state
+func (h *HealthChecker) Record(ok bool) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if ok {
+		h.failures = 0
+		h.successes++
+		if h.healthy || h.successes < h.healthyThreshold {
+			return false
+		}
+	} else {
+		h.successes = 0
+		h.failures++
+		if !h.healthy || h.failures < h.unhealthyThreshold {
+			return false
+		}
+	}
+
+	h.healthy = ok
+	if h.logger != nil {
+		fields := map[string]any{"target": utils.MaskURL(h.target)}
+		if ok {
+			fields["consecutive_successes"] = h.successes
+			h.logger.Info("Upstream health check recovered", fields)
+		} else {
+			fields["consecutive_failures"] = h.failures
+			h.logger.Warn("Upstream health check failing, marking unhealthy", fields)
+		}
+	}
+	return true
+}
+
+// Probe sends one GET to the health check URL. Any response below 500 counts
+// as healthy.
+func (h *HealthChecker) Probe(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("health check returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Run probes the upstream every interval, recording each result, until ctx is
+// done. The first probe is sent immediately.
+func (h *HealthChecker) Run(ctx context.Context) {
+	if h.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		err := h.Probe(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil && h.logger != nil {
+			h.logger.Debug("Upstream health probe failed", map[string]any{
+				"target": utils.MaskURL(h.target),
+				"error":  err.Error(),
+			})
+		}
+		h.Record(err == nil)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
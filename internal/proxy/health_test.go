@@ -0,0 +1,157 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+func TestHealthChecker_Hysteresis(t *testing.T) {
+	checker := NewHealthChecker("http://upstream.example.com", interfaces.HealthCheckConfig{
+		Interval:           time.Second,
+		UnhealthyThreshold: 3,
+		HealthyThreshold:   2,
+	}, &mockLogger{})
+
+	steps := []struct {
+		ok          bool
+		wantHealthy bool
+		wantChanged bool
+	}{
+		// Failures short of the threshold, interrupted by a success, don't count
+		{ok: false, wantHealthy: true},
+		{ok: false, wantHealthy: true},
+		{ok: true, wantHealthy: true},
+		{ok: false, wantHealthy: true},
+		{ok: false, wantHealthy: true},
+		// The third consecutive failure flips the state
+		{ok: false, wantHealthy: false, wantChanged: true},
+		{ok: false, wantHealthy: false},
+		// One success isn't enough to recover, and a failure resets the count
+		{ok: true, wantHealthy: false},
+		{ok: false, wantHealthy: false},
+		{ok: true, wantHealthy: false},
+		// The second consecutive success recovers
+		{ok: true, wantHealthy: true, wantChanged: true},
+		{ok: true, wantHealthy: true},
+	}
+
+	for i, step := range steps {
+		changed := checker.Record(step.ok)
+		if changed != step.wantChanged {
+			t.Errorf("step %d: Record(%v) changed = %v, want %v", i, step.ok, changed, step.wantChanged)
+		}
+		if got := checker.Healthy(); got != step.wantHealthy {
+			t.Errorf("step %d: Healthy() = %v, want %v", i, got, step.wantHealthy)
+		}
+	}
+}
+
+func TestHealthChecker_DefaultThresholds(t *testing.T) {
+	checker := NewHealthChecker("http://upstream.example.com", interfaces.HealthCheckConfig{}, nil)
+
+	for i := 0; i < defaultUnhealthyThreshold-1; i++ {
+		checker.Record(false)
+	}
+	if !checker.Healthy() {
+		t.Fatalf("Expected healthy before %d failures", defaultUnhealthyThreshold)
+	}
+	checker.Record(false)
+	if checker.Healthy() {
+		t.Fatalf("Expected unhealthy after %d failures", defaultUnhealthyThreshold)
+	}
+
+	for i := 0; i < defaultHealthyThreshold-1; i++ {
+		checker.Record(true)
+	}
+	if checker.Healthy() {
+		t.Fatalf("Expected unhealthy before %d successes", defaultHealthyThreshold)
+	}
+	checker.Record(true)
+	if !checker.Healthy() {
+		t.Fatalf("Expected healthy after %d successes", defaultHealthyThreshold)
+	}
+}
+
+func TestHealthChecker_Probe(t *testing.T) {
+	var status atomic.Int64
+	status.Store(http.StatusOK)
+	var lastPath atomic.Value
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastPath.Store(r.URL.Path)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer upstream.Close()
+
+	checker := NewHealthChecker(upstream.URL+"/", interfaces.HealthCheckConfig{Path: "/healthz"}, nil)
+	ctx := context.Background()
+
+	if err := checker.Probe(ctx); err != nil {
+		t.Errorf("Expected a 200 probe to succeed, got %v", err)
+	}
+	if lastPath.Load() != "/healthz" {
+		t.Errorf("Expected the probe at /healthz, got %v", lastPath.Load())
+	}
+
+	// Client errors still show the upstream is up
+	status.Store(http.StatusNotFound)
+	if err := checker.Probe(ctx); err != nil {
+		t.Errorf("Expected a 404 probe to succeed, got %v", err)
+	}
+
+	status.Store(http.StatusServiceUnavailable)
+	if err := checker.Probe(ctx); err == nil {
+		t.Error("Expected a 503 probe to fail")
+	}
+
+	upstream.Close()
+	if err := checker.Probe(ctx); err == nil {
+		t.Error("Expected a probe of a closed upstream to fail")
+	}
+}
+
+func TestHealthChecker_Run(t *testing.T) {
+	var status atomic.Int64
+	status.Store(http.StatusServiceUnavailable)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer upstream.Close()
+
+	checker := NewHealthChecker(upstream.URL, interfaces.HealthCheckConfig{
+		Interval:           5 * time.Millisecond,
+		UnhealthyThreshold: 2,
+		HealthyThreshold:   2,
+	}, &mockLogger{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		checker.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	waitFor := func(want bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for checker.Healthy() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for Healthy() = %v", want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	waitFor(false)
+	status.Store(http.StatusOK)
+	waitFor(true)
+}
@@ -2,7 +2,9 @@ package proxy
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,15 +15,41 @@ import (
 	"time"
 
 	"github.com/jamesprial/nexus/internal/interfaces"
+	"github.com/jamesprial/nexus/internal/metrics"
 	"github.com/jamesprial/nexus/internal/utils"
 	"golang.org/x/time/rate"
 )
 
 // DefaultTokenCounter implements interfaces.TokenCounter
-type DefaultTokenCounter struct{}
+type DefaultTokenCounter struct {
+	// Estimator counts tokens in message text; nil uses the chars/4 heuristic
+	Estimator interfaces.TokenEstimator
+}
+
+// estimator returns the configured estimator or the default heuristic
+func (d *DefaultTokenCounter) estimator() interfaces.TokenEstimator {
+	if d.Estimator != nil {
+		return d.Estimator
+	}
+	return &HeuristicEstimator{}
+}
+
+// hasBody reports whether r may carry a body worth reading. HEAD and OPTIONS
+// requests are never buffered, so a client that leaves their body open cannot
+// stall the request.
+func hasBody(r *http.Request) bool {
+	if r.Method == http.MethodHead || r.Method == http.MethodOptions {
+		return false
+	}
+	return r.Body != nil && r.Body != http.NoBody
+}
 
 // CountTokens implements the token counting logic
 func (d *DefaultTokenCounter) CountTokens(r *http.Request) (int, error) {
+	if !hasBody(r) {
+		return 1, nil
+	}
+
 	// Read request body without consuming it
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -44,25 +72,26 @@ func (d *DefaultTokenCounter) CountTokens(r *http.Request) (int, error) {
 		Prompt string `json:"prompt"`
 	}
 
-	// If JSON parsing fails, count the raw body size
+	estimator := d.estimator()
+
+	// If JSON parsing fails, estimate from the raw body
 	if err := json.Unmarshal(body, &payload); err != nil {
-		tokenCount := len(body) / 4
+		tokenCount := estimator.EstimateTokens("", string(body))
 		if tokenCount < 1 {
 			tokenCount = 1
 		}
 		return tokenCount, nil
 	}
 
-	// Calculate token count: 4 characters ≈ 1 token
 	tokenCount := 0
 
 	// Count tokens from messages if present
 	for _, msg := range payload.Messages {
-		tokenCount += len(msg.Content) / 4
+		tokenCount += estimator.EstimateTokens(payload.Model, msg.Content)
 	}
 
 	// Also count tokens from prompt if present
-	tokenCount += len(payload.Prompt) / 4
+	tokenCount += estimator.EstimateTokens(payload.Model, payload.Prompt)
 
 	// Add minimum token count for system messages/metadata
 	if tokenCount < 5 {
@@ -77,7 +106,277 @@ type HTTPProxy struct {
 	ReverseProxy *httputil.ReverseProxy
 	Logger       interfaces.Logger
 	target       *url.URL
-	mu           sync.RWMutex
+	billing      interfaces.BillingConfig
+	// usagePaths locate the model and token count in upstream responses
+	usagePaths UsagePaths
+	// transforms rewrite bodies for matching paths, longest prefix first
+	transforms []TransformRoute
+	// pathRewrites change the upstream path; the first matching rewrite applies
+	pathRewrites []PathRewrite
+	// targetRoutes send requests under their path prefix to their own
+	// upstream instead of target, longest prefix first
+	targetRoutes []TargetRoute
+	// keyDirectors send the requests of specific client keys to their own
+	// upstream instead of target or a route's
+	keyDirectors map[string]func(*http.Request)
+	// maxResponseBytes caps upstream response bodies; zero is unlimited
+	maxResponseBytes int64
+	// dropTrailers discards upstream response trailers instead of forwarding them
+	dropTrailers bool
+	// websockets relays WebSocket upgrades to the upstream; without it they
+	// are refused with 501
+	websockets bool
+	// transport replaces http.DefaultTransport once WarmUp has sized an idle pool
+	transport *http.Transport
+	// upstreamErrors counts failed round-trips by UpstreamError kind
+	upstreamErrors map[string]int64
+	// upstreamStatuses counts upstream responses by the status the upstream sent
+	upstreamStatuses map[int]int64
+	mu             sync.RWMutex
+}
+
+// NewHTTPProxy creates an HTTPProxy forwarding to target. The upstream call
+// uses the client request's context, so it is aborted when the client disconnects.
+func NewHTTPProxy(target *url.URL, logger interfaces.Logger) *HTTPProxy {
+	h := &HTTPProxy{
+		Logger: logger,
+		target: target,
+	}
+	h.ReverseProxy = h.newReverseProxy(target)
+	return h
+}
+
+// newReverseProxy builds a reverse proxy for target using this proxy's error handling
+func (h *HTTPProxy) newReverseProxy(target *url.URL) *httputil.ReverseProxy {
+	reverseProxy := httputil.NewSingleHostReverseProxy(target)
+	director := reverseProxy.Director
+	reverseProxy.Director = func(req *http.Request) {
+		h.mu.RLock()
+		rewrites := h.pathRewrites
+		// Routes match the client's path, before any rewrite
+		route := matchTargetRoute(h.targetRoutes, req.URL.Path)
+		keyDirector := h.keyDirectors[metrics.GetAPIKey(req)]
+		h.mu.RUnlock()
+		rewritePath(rewrites, req)
+		if keyDirector != nil {
+			keyDirector(req)
+			return
+		}
+		if route != nil {
+			route.director(req)
+			return
+		}
+		director(req)
+	}
+	reverseProxy.ErrorHandler = h.handleError
+	reverseProxy.ModifyResponse = h.modifyResponse
+	if h.transport != nil {
+		reverseProxy.Transport = h.transport
+	}
+	return reverseProxy
+}
+
+// modifyResponse counts the upstream's status, enforces the response size limit, translates the response
+// for the client, then records upstream-reported token usage and drops
+// trailers if configured before the response is returned. A 101 is left as
+// is, since its body is the upgraded connection the reverse proxy relays.
+func (h *HTTPProxy) modifyResponse(resp *http.Response) error {
+	h.mu.Lock()
+	if h.upstreamStatuses == nil {
+		h.upstreamStatuses = make(map[int]int64)
+	}
+	h.upstreamStatuses[resp.StatusCode]++
+	billing := h.billing
+	usagePaths := h.usagePaths
+	maxResponseBytes := h.maxResponseBytes
+	dropTrailers := h.dropTrailers
+	h.mu.Unlock()
+
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		return nil
+	}
+
+	if err := limitResponse(resp, maxResponseBytes, func() { h.recordResponseTooLarge(resp.Request, maxResponseBytes) }); err != nil {
+		return err
+	}
+	if err := transformResponse(resp); err != nil {
+		return err
+	}
+	if err := accountUsage(resp, billing, usagePaths); err != nil {
+		return err
+	}
+	if dropTrailers {
+		dropResponseTrailers(resp)
+	}
+	return nil
+}
+
+// SetKeyTargets sends the requests of each client key in targets to its own
+// upstream, overriding the proxy's target. Other keys are unaffected.
+func (h *HTTPProxy) SetKeyTargets(targets map[string]*url.URL) {
+	directors := keyDirectors(targets)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.keyDirectors = directors
+}
+
+// SetBilling configures the usage headers added to proxied responses
+func (h *HTTPProxy) SetBilling(billing interfaces.BillingConfig) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.billing = billing
+}
+
+// SetMaxResponseBytes caps the size of upstream response bodies. Larger
+// responses fail with 502 if nothing has been sent yet, and are cut off
+// mid-stream otherwise. Zero removes the limit.
+func (h *HTTPProxy) SetMaxResponseBytes(n int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.maxResponseBytes = n
+}
+
+// SetForwardTrailers configures whether trailers sent by the upstream after
+// the response body are passed on to clients. They are forwarded by default.
+func (h *HTTPProxy) SetForwardTrailers(enabled bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.dropTrailers = !enabled
+}
+
+// SetWebSockets configures whether WebSocket upgrade requests are relayed to
+// the upstream. They are refused with 501 by default.
+func (h *HTTPProxy) SetWebSockets(enabled bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.websockets = enabled
+}
+
+// recordResponseTooLarge counts and logs a response that crossed the size
+// limit. It runs when the limit is hit, since a streamed response is already
+// under way and never reaches handleError.
+func (h *HTTPProxy) recordResponseTooLarge(r *http.Request, limit int64) {
+	h.mu.Lock()
+	if h.upstreamErrors == nil {
+		h.upstreamErrors = make(map[string]int64)
+	}
+	h.upstreamErrors[UpstreamErrorTooLarge]++
+	target := h.target
+	h.mu.Unlock()
+
+	if h.Logger != nil {
+		fields := map[string]any{
+			"limit_bytes": limit,
+		}
+		if r != nil {
+			fields["method"] = r.Method
+			fields["path"] = r.URL.Path
+		}
+		if target != nil {
+			fields["target"] = utils.MaskURL(target.String())
+		}
+		h.Logger.Error("Upstream response exceeded size limit", fields)
+	}
+}
+
+// SetTransforms configures the body transformers applied by request path
+func (h *HTTPProxy) SetTransforms(routes []TransformRoute) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.transforms = routes
+}
+
+// SetTargetRoutes sends requests under each route's path prefix to the
+// route's upstream, overriding the proxy's target. Other paths are unaffected.
+func (h *HTTPProxy) SetTargetRoutes(routes []TargetRoute) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.targetRoutes = routes
+}
+
+// SetPathRewrites configures how request paths are rewritten for the upstream
+func (h *HTTPProxy) SetPathRewrites(rewrites []PathRewrite) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pathRewrites = rewrites
+}
+
+// SetUsagePaths configures where the model and token count are read from in
+// upstream responses
+func (h *HTTPProxy) SetUsagePaths(paths UsagePaths) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.usagePaths = paths
+}
+
+// handleError responds to a failed upstream round-trip with a JSON 502, or 504
+// for timeouts, that does not expose the underlying error. A client that went
+// away is reported with StatusClientClosedRequest rather than blamed on the upstream.
+func (h *HTTPProxy) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(r.Context().Err(), context.Canceled) {
+		if h.Logger != nil {
+			h.Logger.Debug("Client canceled request", map[string]any{
+				"method": r.Method,
+				"path":   r.URL.Path,
+			})
+		}
+		w.WriteHeader(metrics.StatusClientClosedRequest)
+		return
+	}
+
+	upstreamErr := classifyUpstreamError(err)
+	if upstreamErr.Kind == UpstreamErrorTooLarge {
+		// Already counted and logged when the limit was hit
+		writeUpstreamError(w, upstreamErr)
+		return
+	}
+
+	h.mu.Lock()
+	if h.upstreamErrors == nil {
+		h.upstreamErrors = make(map[string]int64)
+	}
+	h.upstreamErrors[upstreamErr.Kind]++
+	target := h.target
+	h.mu.Unlock()
+
+	if h.Logger != nil {
+		fields := map[string]any{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"kind":   upstreamErr.Kind,
+			"error":  err.Error(),
+		}
+		if target != nil {
+			fields["target"] = utils.MaskURL(target.String())
+		}
+		h.Logger.Error("Upstream request failed", fields)
+	}
+	writeUpstreamError(w, upstreamErr)
+}
+
+// UpstreamErrors returns the number of failed upstream round-trips by kind
+func (h *HTTPProxy) UpstreamErrors() map[string]int64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	counts := make(map[string]int64, len(h.upstreamErrors))
+	for kind, n := range h.upstreamErrors {
+		counts[kind] = n
+	}
+	return counts
+}
+
+// UpstreamStatuses returns the number of upstream responses by the status
+// code the upstream returned, before anything the gateway does to the response
+func (h *HTTPProxy) UpstreamStatuses() map[int]int64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	counts := make(map[int]int64, len(h.upstreamStatuses))
+	for status, n := range h.upstreamStatuses {
+		counts[status] = n
+	}
+	return counts
 }
 
 // ServeHTTP implements the http.Handler interface
@@ -90,7 +389,37 @@ func (h *HTTPProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
-	h.ReverseProxy.ServeHTTP(w, r)
+	// Read under lock since SetTarget may swap the proxy on reload
+	h.mu.RLock()
+	reverseProxy := h.ReverseProxy
+	transformer := matchTransformRoute(h.transforms, r.URL.Path)
+	websockets := h.websockets
+	h.mu.RUnlock()
+
+	if utils.IsWebSocketUpgrade(r) {
+		if !websockets {
+			writeJSONError(w, http.StatusNotImplemented, "WebSocket upgrades are not enabled", "invalid_request")
+			return
+		}
+		// The session is relayed byte for byte, so there is no body to transform
+		transformer = nil
+	}
+
+	if transformer != nil {
+		var err error
+		if r, err = transformRequest(r, transformer); err != nil {
+			if h.Logger != nil {
+				h.Logger.Warn("Failed to transform request", map[string]any{
+					"path":  r.URL.Path,
+					"error": err.Error(),
+				})
+			}
+			writeJSONError(w, http.StatusBadRequest, "Request could not be translated for the upstream", "invalid_request")
+			return
+		}
+	}
+
+	reverseProxy.ServeHTTP(w, r)
 }
 
 // SetTarget changes the upstream target URL
@@ -117,7 +446,7 @@ func (h *HTTPProxy) SetTarget(targetURL string) error {
 	defer h.mu.Unlock()
 
 	h.target = target
-	h.ReverseProxy = httputil.NewSingleHostReverseProxy(target)
+	h.ReverseProxy = h.newReverseProxy(target)
 
 	if h.Logger != nil {
 		h.Logger.Info("Updated proxy target", map[string]any{
@@ -159,8 +488,7 @@ func (r *perClientRateLimiterWithLogger) Middleware(next http.Handler) http.Hand
 
 // GetLimit returns remaining requests for the API key.
 func (r *perClientRateLimiterWithLogger) GetLimit(apiKey string) (allowed bool, remaining int) {
-	limiter := r.limiter.getClient(apiKey)
-	tokens := limiter.Tokens()
+	tokens := r.limiter.getClient(apiKey).Remaining(time.Now())
 	return tokens > 0, int(tokens)
 }
 
@@ -1,6 +1,7 @@
 package proxy
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -10,6 +11,9 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/metrics"
 )
 
 // mockLogger for testing
@@ -199,6 +203,61 @@ func TestHTTPProxy_ServeHTTP(t *testing.T) {
 	}
 }
 
+func TestHTTPProxy_ClientCancelAbortsUpstream(t *testing.T) {
+	upstreamStarted := make(chan struct{})
+	upstreamCanceled := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(upstreamStarted)
+		select {
+		case <-r.Context().Done():
+			close(upstreamCanceled)
+		case <-time.After(5 * time.Second):
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer backend.Close()
+
+	backendURL, _ := url.Parse(backend.URL)
+	proxy := NewHTTPProxy(backendURL, &mockLogger{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("POST", "/v1/chat/completions", nil).WithContext(ctx)
+	rr := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		proxy.ServeHTTP(rr, req)
+		close(done)
+	}()
+
+	<-upstreamStarted
+	cancel()
+
+	select {
+	case <-upstreamCanceled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected upstream request to be canceled when the client disconnects")
+	}
+	<-done
+
+	if rr.Code != metrics.StatusClientClosedRequest {
+		t.Errorf("Expected status %d for client cancellation, got %d", metrics.StatusClientClosedRequest, rr.Code)
+	}
+}
+
+func TestHTTPProxy_UpstreamErrorIsBadGateway(t *testing.T) {
+	// Nothing listens on this address, so the round-trip fails
+	target, _ := url.Parse("http://127.0.0.1:1")
+	proxy := NewHTTPProxy(target, &mockLogger{})
+
+	rr := httptest.NewRecorder()
+	proxy.ServeHTTP(rr, httptest.NewRequest("GET", "/v1/models", nil))
+
+	if rr.Code != http.StatusBadGateway {
+		t.Errorf("Expected 502 for upstream failure, got %d", rr.Code)
+	}
+}
+
 func TestHTTPProxy_SetTarget(t *testing.T) {
 	logger := &mockLogger{}
 	proxy := &HTTPProxy{
@@ -517,4 +576,24 @@ func BenchmarkPerClientRateLimiter(b *testing.B) {
 		rr := httptest.NewRecorder()
 		handler.ServeHTTP(rr, req)
 	}
-}
\ No newline at end of file
+}
+func TestHTTPProxy_UpstreamStatuses(t *testing.T) {
+	statuses := []int{http.StatusOK, http.StatusBadGateway, http.StatusBadGateway}
+	var i int
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statuses[i])
+		i++
+	}))
+	defer upstream.Close()
+
+	target, _ := url.Parse(upstream.URL)
+	p := NewHTTPProxy(target, &mockLogger{})
+	for range statuses {
+		p.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/models", nil))
+	}
+
+	got := p.UpstreamStatuses()
+	if got[http.StatusOK] != 1 || got[http.StatusBadGateway] != 2 || len(got) != 2 {
+		t.Errorf("Expected one 200 and two 502s, got %v", got)
+	}
+}
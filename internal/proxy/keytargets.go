@@ -0,0 +1,40 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"github.com/jamesprial/nexus/internal/utils"
+)
+
+// NewKeyTargets parses per-key upstream URLs, by client key. Each URL must
+// have a scheme and host.
+func NewKeyTargets(targets map[string]string) (map[string]*url.URL, error) {
+	parsed := make(map[string]*url.URL, len(targets))
+	for key, raw := range targets {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse target URL for key %s: %w", utils.MaskAPIKey(key), err)
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("target URL for key %s must have a scheme and host", utils.MaskAPIKey(key))
+		}
+		parsed[key] = u
+	}
+	return parsed, nil
+}
+
+// keyDirectors builds a director per client key sending its requests to the
+// key's own target instead of the proxy's
+func keyDirectors(targets map[string]*url.URL) map[string]func(*http.Request) {
+	if len(targets) == 0 {
+		return nil
+	}
+	directors := make(map[string]func(*http.Request), len(targets))
+	for key, target := range targets {
+		directors[key] = httputil.NewSingleHostReverseProxy(target).Director
+	}
+	return directors
+}
@@ -0,0 +1,52 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/jamesprial/nexus/internal/metrics"
+)
+
+func TestHTTPProxy_KeyTargets(t *testing.T) {
+	var defaultHits, keyHits int
+	defaultUpstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { defaultHits++ }))
+	defer defaultUpstream.Close()
+	keyUpstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { keyHits++ }))
+	defer keyUpstream.Close()
+
+	target, _ := url.Parse(defaultUpstream.URL)
+	p := NewHTTPProxy(target, nil)
+	targets, err := NewKeyTargets(map[string]string{"routed": keyUpstream.URL})
+	if err != nil {
+		t.Fatalf("NewKeyTargets() error = %v", err)
+	}
+	p.SetKeyTargets(targets)
+
+	for _, key := range []string{"routed", "other", ""} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+		if key != "" {
+			req = metrics.SetAPIKey(req, key)
+		}
+		p.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if keyHits != 1 || defaultHits != 2 {
+		t.Errorf("Expected 1 request at the key's upstream and 2 at the default, got %d and %d", keyHits, defaultHits)
+	}
+
+	// Clearing the targets sends every key to the default again
+	p.SetKeyTargets(nil)
+	p.ServeHTTP(httptest.NewRecorder(), metrics.SetAPIKey(httptest.NewRequest(http.MethodGet, "/v1/models", nil), "routed"))
+	if keyHits != 1 || defaultHits != 3 {
+		t.Errorf("Expected cleared targets to use the default upstream, got %d and %d", keyHits, defaultHits)
+	}
+}
+
+func TestNewKeyTargets_Invalid(t *testing.T) {
+	for _, raw := range []string{"eu.example.com", "://bad", "https://"} {
+		if _, err := NewKeyTargets(map[string]string{"client-key": raw}); err == nil {
+			t.Errorf("Expected an error for %q", raw)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// ErrResponseTooLarge is returned when an upstream response body exceeds the
+// configured max_response_bytes
+var ErrResponseTooLarge = errors.New("upstream response exceeded max_response_bytes")
+
+// limitedBody fails reads once more than limit bytes have been read, counted
+// across every chunk of a streamed body. onExceed runs once, on the first
+// failing read.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	onExceed  func()
+	once      sync.Once
+}
+
+// Read implements io.Reader. A read that crosses the limit returns the bytes
+// up to the limit followed by ErrResponseTooLarge.
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, ErrResponseTooLarge
+	}
+	// Read one byte past the limit so an exact-size body still succeeds
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		b.once.Do(b.onExceed)
+		return n + int(b.remaining), ErrResponseTooLarge
+	}
+	return n, err
+}
+
+// limitResponse enforces limit on resp, rejecting it outright when the
+// declared Content-Length is already too large and otherwise failing the body
+// mid-stream once the limit is crossed. A limit of zero or less disables the check.
+func limitResponse(resp *http.Response, limit int64, onExceed func()) error {
+	if limit <= 0 {
+		return nil
+	}
+	if resp.ContentLength > limit {
+		onExceed()
+		resp.Body.Close()
+		return ErrResponseTooLarge
+	}
+	resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: limit, onExceed: onExceed}
+	return nil
+}
@@ -0,0 +1,105 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestHTTPProxy_MaxResponseBytes(t *testing.T) {
+	const limit = 64
+
+	tests := []struct {
+		name          string
+		handler       http.HandlerFunc
+		expectStatus  int
+		expectErrors  int64
+		expectBodyLen int
+	}{
+		{
+			name: "declared length over the limit",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				body := fmt.Sprintf(`{"data":"%s"}`, strings.Repeat("a", limit))
+				w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+				_, _ = io.WriteString(w, body)
+			},
+			expectStatus: http.StatusBadGateway,
+			expectErrors: 1,
+		},
+		{
+			name: "chunked JSON over the limit",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = io.WriteString(w, `{"data":"`)
+				w.(http.Flusher).Flush()
+				_, _ = io.WriteString(w, strings.Repeat("a", limit)+`"}`)
+			},
+			expectStatus: http.StatusBadGateway,
+			expectErrors: 1,
+		},
+		{
+			name: "stream over the limit is cut off",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				for i := 0; i < 10; i++ {
+					_, _ = io.WriteString(w, "data: "+strings.Repeat("x", 20)+"\n\n")
+					w.(http.Flusher).Flush()
+				}
+			},
+			expectStatus:  http.StatusOK,
+			expectErrors:  1,
+			expectBodyLen: limit,
+		},
+		{
+			name: "body exactly at the limit",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				_, _ = io.WriteString(w, strings.Repeat("a", limit))
+			},
+			expectStatus:  http.StatusOK,
+			expectBodyLen: limit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := httptest.NewServer(tt.handler)
+			defer upstream.Close()
+
+			target, _ := url.Parse(upstream.URL)
+			logger := &mockLogger{}
+			p := NewHTTPProxy(target, logger)
+			p.SetMaxResponseBytes(limit)
+
+			rr := httptest.NewRecorder()
+			p.ServeHTTP(rr, httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{}`)))
+
+			if rr.Code != tt.expectStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectStatus, rr.Code)
+			}
+			if tt.expectStatus == http.StatusBadGateway && !strings.Contains(rr.Body.String(), "upstream_response_too_large") {
+				t.Errorf("Expected a response size error, got %s", rr.Body.String())
+			}
+			if tt.expectBodyLen > 0 && rr.Body.Len() > tt.expectBodyLen {
+				t.Errorf("Expected at most %d bytes relayed, got %d", tt.expectBodyLen, rr.Body.Len())
+			}
+			if got := p.UpstreamErrors()[UpstreamErrorTooLarge]; got != tt.expectErrors {
+				t.Errorf("Expected %d recorded size failures, got %d", tt.expectErrors, got)
+			}
+			logged := false
+			logger.mu.Lock()
+			for _, entry := range logger.logs {
+				logged = logged || (entry.level == "error" && strings.Contains(entry.message, "size limit"))
+			}
+			logger.mu.Unlock()
+			if logged != (tt.expectErrors > 0) {
+				t.Errorf("Expected size failure logged: %v, got %v", tt.expectErrors > 0, logged)
+			}
+		})
+	}
+}
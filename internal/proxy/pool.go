@@ -0,0 +1,259 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+	"github.com/jamesprial/nexus/internal/utils"
+)
+
+// unhealthyCooldown is how long a target that failed a round-trip is skipped
+const unhealthyCooldown = 30 * time.Second
+
+// poolTarget is one upstream in a TargetPool
+type poolTarget struct {
+	url      *url.URL
+	weight   int
+	proxy    *HTTPProxy
+	requests atomic.Int64
+	// unhealthyUntil is the UnixNano time until which the target is skipped
+	unhealthyUntil atomic.Int64
+}
+
+// healthy reports whether the target is outside its unhealthy cooldown
+func (t *poolTarget) healthy(now time.Time) bool {
+	return now.UnixNano() >= t.unhealthyUntil.Load()
+}
+
+// TargetPool implements interfaces.Proxy by spreading requests across several
+// equivalent upstreams with weighted random selection. A target whose
+// round-trip fails is skipped for a cooldown period; if every target is
+// unhealthy, selection falls back to the full pool rather than failing outright.
+type TargetPool struct {
+	targets     []*poolTarget
+	totalWeight int
+	logger      interfaces.Logger
+	// intN returns a random int in [0, n); replaceable in tests
+	intN func(n int) int
+}
+
+// NewTargetPool creates a pool over targets. A zero weight counts as 1.
+func NewTargetPool(targets []interfaces.PoolTarget, logger interfaces.Logger) (*TargetPool, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("target pool must contain at least one target")
+	}
+
+	p := &TargetPool{
+		targets: make([]*poolTarget, 0, len(targets)),
+		logger:  logger,
+		intN:    rand.IntN,
+	}
+	for i, t := range targets {
+		u, err := url.Parse(t.URL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse target_pool[%d] URL: %w", i, err)
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("target_pool[%d] URL must have a scheme and host", i)
+		}
+		if t.Weight < 0 {
+			return nil, fmt.Errorf("target_pool[%d] weight must not be negative", i)
+		}
+
+		weight := t.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		target := &poolTarget{
+			url:    u,
+			weight: weight,
+			proxy:  NewHTTPProxy(u, logger),
+		}
+		target.proxy.ReverseProxy.ErrorHandler = p.errorHandler(target)
+		p.targets = append(p.targets, target)
+		p.totalWeight += weight
+	}
+	return p, nil
+}
+
+// ServeHTTP implements the http.Handler interface
+func (p *TargetPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target := p.pick(time.Now())
+	target.requests.Add(1)
+	target.proxy.ServeHTTP(w, r)
+}
+
+// SetTarget implements interfaces.Proxy. A pool's membership is fixed at
+// startup, so changing to a single target is rejected.
+func (p *TargetPool) SetTarget(targetURL string) error {
+	return fmt.Errorf("target_pool is configured; change it and restart instead of setting a target")
+}
+
+// SetBilling configures the usage headers added to responses from every target
+func (p *TargetPool) SetBilling(billing interfaces.BillingConfig) {
+	for _, t := range p.targets {
+		t.proxy.SetBilling(billing)
+	}
+}
+
+// SetMaxResponseBytes caps the size of response bodies from every target
+func (p *TargetPool) SetMaxResponseBytes(n int64) {
+	for _, t := range p.targets {
+		t.proxy.SetMaxResponseBytes(n)
+	}
+}
+
+// SetForwardTrailers configures whether every target forwards response trailers
+func (p *TargetPool) SetForwardTrailers(enabled bool) {
+	for _, t := range p.targets {
+		t.proxy.SetForwardTrailers(enabled)
+	}
+}
+
+// SetWebSockets configures whether every target relays WebSocket upgrades
+func (p *TargetPool) SetWebSockets(enabled bool) {
+	for _, t := range p.targets {
+		t.proxy.SetWebSockets(enabled)
+	}
+}
+
+// SetTransforms configures the body transformers used for every target
+func (p *TargetPool) SetTransforms(routes []TransformRoute) {
+	for _, t := range p.targets {
+		t.proxy.SetTransforms(routes)
+	}
+}
+
+// SetPathRewrites configures the path rewrites used for every target
+func (p *TargetPool) SetPathRewrites(rewrites []PathRewrite) {
+	for _, t := range p.targets {
+		t.proxy.SetPathRewrites(rewrites)
+	}
+}
+
+// SetUsagePaths configures where every target reads the model and token
+// count from in responses
+func (p *TargetPool) SetUsagePaths(paths UsagePaths) {
+	for _, t := range p.targets {
+		t.proxy.SetUsagePaths(paths)
+	}
+}
+
+// RequestCounts returns the number of requests sent to each target, keyed by
+// the target URL with credentials masked.
+func (p *TargetPool) RequestCounts() map[string]int64 {
+	counts := make(map[string]int64, len(p.targets))
+	for _, t := range p.targets {
+		counts[utils.MaskURL(t.url.String())] += t.requests.Load()
+	}
+	return counts
+}
+
+// UpstreamStatuses returns the number of upstream responses by status, summed over all targets
+func (p *TargetPool) UpstreamStatuses() map[int]int64 {
+	counts := make(map[int]int64)
+	for _, t := range p.targets {
+		for status, n := range t.proxy.UpstreamStatuses() {
+			counts[status] += n
+		}
+	}
+	return counts
+}
+
+// UpstreamErrors returns the number of failed round-trips by kind, summed over all targets
+func (p *TargetPool) UpstreamErrors() map[string]int64 {
+	counts := make(map[string]int64)
+	for _, t := range p.targets {
+		for kind, n := range t.proxy.UpstreamErrors() {
+			counts[kind] += n
+		}
+	}
+	return counts
+}
+
+// Targets returns the pool's target URLs with credentials masked, in configured order
+func (p *TargetPool) Targets() []string {
+	urls := make([]string, len(p.targets))
+	for i, t := range p.targets {
+		urls[i] = utils.MaskURL(t.url.String())
+	}
+	return urls
+}
+
+// TargetRequests returns a function reporting the request count of the target
+// at index i, suitable for exporting as a metric.
+func (p *TargetPool) TargetRequests(i int) func() float64 {
+	target := p.targets[i]
+	return func() float64 { return float64(target.requests.Load()) }
+}
+
+// SetHealthy marks the target with the given URL healthy or unhealthy. An
+// unhealthy target is skipped until the cooldown passes or it is marked healthy.
+func (p *TargetPool) SetHealthy(targetURL string, healthy bool) error {
+	for _, t := range p.targets {
+		if t.url.String() != targetURL {
+			continue
+		}
+		if healthy {
+			t.unhealthyUntil.Store(0)
+		} else {
+			t.unhealthyUntil.Store(time.Now().Add(unhealthyCooldown).UnixNano())
+		}
+		return nil
+	}
+	return fmt.Errorf("target %s is not in the pool", utils.MaskURL(targetURL))
+}
+
+// pick selects a target by weight among the healthy ones, or among all
+// targets when none are healthy.
+func (p *TargetPool) pick(now time.Time) *poolTarget {
+	healthyWeight := 0
+	for _, t := range p.targets {
+		if t.healthy(now) {
+			healthyWeight += t.weight
+		}
+	}
+
+	onlyHealthy := healthyWeight > 0
+	total := p.totalWeight
+	if onlyHealthy {
+		total = healthyWeight
+	}
+
+	n := p.intN(total)
+	for _, t := range p.targets {
+		if onlyHealthy && !t.healthy(now) {
+			continue
+		}
+		if n < t.weight {
+			return t
+		}
+		n -= t.weight
+	}
+	return p.targets[len(p.targets)-1]
+}
+
+// errorHandler marks target unhealthy when its round-trip fails, then responds
+// as a single-target proxy would. Client cancellations say nothing about the
+// target's health and leave it in rotation.
+func (p *TargetPool) errorHandler(target *poolTarget) func(http.ResponseWriter, *http.Request, error) {
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		if !errors.Is(r.Context().Err(), context.Canceled) {
+			target.unhealthyUntil.Store(time.Now().Add(unhealthyCooldown).UnixNano())
+			if p.logger != nil {
+				p.logger.Warn("Marking pool target unhealthy", map[string]any{
+					"target":   utils.MaskURL(target.url.String()),
+					"cooldown": unhealthyCooldown.String(),
+				})
+			}
+		}
+		target.proxy.handleError(w, r, err)
+	}
+}
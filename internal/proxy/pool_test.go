@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+func TestNewTargetPool_InvalidTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets []interfaces.PoolTarget
+	}{
+		{name: "empty pool"},
+		{name: "missing scheme", targets: []interfaces.PoolTarget{{URL: "example.com"}}},
+		{name: "negative weight", targets: []interfaces.PoolTarget{{URL: "http://example.com", Weight: -1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewTargetPool(tt.targets, nil); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func TestTargetPool_WeightedDistribution(t *testing.T) {
+	pool, err := NewTargetPool([]interfaces.PoolTarget{
+		{URL: "http://a.example.com", Weight: 3},
+		{URL: "http://b.example.com", Weight: 1},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewTargetPool() error = %v", err)
+	}
+
+	const picks = 20000
+	counts := make(map[string]int)
+	now := time.Now()
+	for i := 0; i < picks; i++ {
+		counts[pool.pick(now).url.Host]++
+	}
+
+	share := float64(counts["a.example.com"]) / picks
+	if math.Abs(share-0.75) > 0.03 {
+		t.Errorf("Expected about 75%% of picks for weight 3 of 4, got %.1f%%", share*100)
+	}
+}
+
+func TestTargetPool_SkipsUnhealthyTarget(t *testing.T) {
+	var healthyHits atomic.Int64
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		healthyHits.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer healthy.Close()
+
+	// A closed server refuses connections, failing the round-trip
+	dead := httptest.NewServer(http.NotFoundHandler())
+	deadURL := dead.URL
+	dead.Close()
+
+	pool, err := NewTargetPool([]interfaces.PoolTarget{
+		{URL: deadURL, Weight: 1},
+		{URL: healthy.URL, Weight: 1},
+	}, &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewTargetPool() error = %v", err)
+	}
+	// Always pick the first eligible target, so the dead one is tried while healthy
+	pool.intN = func(n int) int { return 0 }
+
+	rr := httptest.NewRecorder()
+	pool.ServeHTTP(rr, httptest.NewRequest("GET", "/v1/chat", nil))
+	if rr.Code != http.StatusBadGateway {
+		t.Fatalf("Expected 502 from the dead target, got %d", rr.Code)
+	}
+
+	for i := 0; i < 10; i++ {
+		rr := httptest.NewRecorder()
+		pool.ServeHTTP(rr, httptest.NewRequest("GET", "/v1/chat", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200 from the healthy target, got %d", i, rr.Code)
+		}
+	}
+	if got := healthyHits.Load(); got != 10 {
+		t.Errorf("Expected 10 requests at the healthy target, got %d", got)
+	}
+
+	counts := pool.RequestCounts()
+	if counts[deadURL] != 1 || counts[healthy.URL] != 10 {
+		t.Errorf("Unexpected per-target request counts: %v", counts)
+	}
+
+	// Marking the target healthy puts it back in rotation
+	if err := pool.SetHealthy(deadURL, true); err != nil {
+		t.Fatalf("SetHealthy() error = %v", err)
+	}
+	if got := pool.pick(time.Now()).url.String(); got != deadURL {
+		t.Errorf("Expected recovered target to be picked, got %s", got)
+	}
+}
+
+func TestTargetPool_AllUnhealthyFallsBackToFullPool(t *testing.T) {
+	pool, err := NewTargetPool([]interfaces.PoolTarget{
+		{URL: "http://a.example.com"},
+		{URL: "http://b.example.com"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewTargetPool() error = %v", err)
+	}
+	_ = pool.SetHealthy("http://a.example.com", false)
+	_ = pool.SetHealthy("http://b.example.com", false)
+
+	if target := pool.pick(time.Now()); target == nil {
+		t.Fatal("Expected a target even when all are unhealthy")
+	}
+	if err := pool.SetHealthy("http://c.example.com", false); err == nil {
+		t.Error("Expected error for a target outside the pool")
+	}
+}
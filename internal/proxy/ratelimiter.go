@@ -1,12 +1,36 @@
 package proxy
 
 import (
+	"context"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/jamesprial/nexus/internal/interfaces"
+	"github.com/jamesprial/nexus/internal/metrics"
+	"github.com/jamesprial/nexus/internal/utils"
 	"golang.org/x/time/rate"
 )
 
+// clientIdentity returns the key used to bucket a request: the validated client
+// key stored by the auth middleware when present, otherwise the raw Authorization header.
+func clientIdentity(r *http.Request) string {
+	if key := metrics.GetAPIKey(r); key != "" {
+		return key
+	}
+	return r.Header.Get("Authorization")
+}
+
+// Values for the rate_limit_by config key, selecting how requests are bucketed
+const (
+	RateLimitByAPIKey = "api_key"
+	RateLimitByIP     = "ip"
+)
+
 // GlobalRateLimiter applies a single rate limit to all incoming requests.
 type GlobalRateLimiter struct {
 	limiter *rate.Limiter
@@ -33,31 +57,122 @@ func (rl *GlobalRateLimiter) Middleware(next http.Handler) http.Handler {
 	})
 }
 
-// PerClientRateLimiter applies rate limits on a per-API-key basis.
+// PerClientRateLimiter applies rate limits on a per-API-key basis, or per
+// client IP after SetLimitByIP.
 // This was the old behavior of RateLimiter, preserved here for clarity.
 type PerClientRateLimiter struct {
-	clients map[string]*rate.Limiter
+	clients map[string]RateLimiter
 	mu      sync.Mutex
 	rate    rate.Limit
 	burst   int
+	// newLimiter creates client budgets under the configured algorithm
+	newLimiter func(rate.Limit, int) RateLimiter
+	// overrides holds per-key rate and burst, keyed by client key
+	overrides map[string]keyRate
+	// shadow evaluates limits without rejecting, counting would-be rejections
+	shadow      atomic.Bool
+	wouldReject atomic.Int64
+	// maxWait and maxQueue bound how long and how many requests may queue for a token
+	maxWait  time.Duration
+	maxQueue int64
+	queued   atomic.Int64
+	// collector, when set, records rejections
+	collector interfaces.MetricsCollector
+	// byIP buckets requests by client IP instead of API key
+	byIP              bool
+	trustedProxyCount int
+	// headers reports the bucket state to clients in X-RateLimit-* headers
+	headers bool
+	// soft is the warning threshold for served requests
+	soft softLimit
 }
 
+// Limiter types reported with throttled requests
+const (
+	LimiterTypeRate  = "rate"
+	LimiterTypeToken = "token"
+)
+
+// defaultMaxQueue caps waiting requests when queuing is enabled without an explicit cap
+const defaultMaxQueue = 100
+
 // NewPerClientRateLimiter creates a new per-client rate limiter.
 func NewPerClientRateLimiter(r rate.Limit, b int) *PerClientRateLimiter {
 	return &PerClientRateLimiter{
-		clients: make(map[string]*rate.Limiter),
-		rate:    r,
-		burst:   b,
+		clients:    make(map[string]RateLimiter),
+		rate:       r,
+		burst:      b,
+		newLimiter: newTokenBucket,
+	}
+}
+
+// SetAlgorithm selects how client budgets are counted, as described for
+// NewRateLimiter; token_bucket is the default.
+// It must be called before the limiter starts serving requests.
+func (rl *PerClientRateLimiter) SetAlgorithm(algorithm string) error {
+	newLimiter, err := rateLimiterFactory(algorithm)
+	if err != nil {
+		return err
+	}
+	rl.newLimiter = newLimiter
+	return nil
+}
+
+// keyRate is a resolved per-key request rate and burst
+type keyRate struct {
+	rate  rate.Limit
+	burst int
+}
+
+// SetKeyLimits configures per-key request limits. Keys without an override,
+// and zero fields within an override, use the limiter's global rate and burst.
+// Existing buckets keep their capacity until they are reset or expire.
+func (rl *PerClientRateLimiter) SetKeyLimits(limits map[string]interfaces.KeyLimits) {
+	overrides := make(map[string]keyRate, len(limits))
+	for key, l := range limits {
+		kr := keyRate{rate: rl.rate, burst: rl.burst}
+		if l.RequestsPerSecond > 0 {
+			kr.rate = rate.Limit(l.RequestsPerSecond)
+		}
+		if l.Burst > 0 {
+			kr.burst = l.Burst
+		}
+		overrides[key] = kr
 	}
+
+	rl.mu.Lock()
+	rl.overrides = overrides
+	rl.mu.Unlock()
+}
+
+// SetLimitByIP buckets requests by client IP rather than API key, deriving the
+// IP from X-Forwarded-For through trustedProxyCount trusted proxies. Per-key
+// overrides do not apply to IP buckets.
+// It must be called before the limiter starts serving requests.
+func (rl *PerClientRateLimiter) SetLimitByIP(trustedProxyCount int) {
+	rl.byIP = true
+	rl.trustedProxyCount = trustedProxyCount
 }
 
-func (rl *PerClientRateLimiter) getClient(apiKey string) *rate.Limiter {
+// bucketKey returns the key whose bucket a request draws from
+func (rl *PerClientRateLimiter) bucketKey(r *http.Request) string {
+	if rl.byIP {
+		return utils.ClientIP(r, rl.trustedProxyCount)
+	}
+	return clientIdentity(r)
+}
+
+func (rl *PerClientRateLimiter) getClient(apiKey string) RateLimiter {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
 	limiter, exists := rl.clients[apiKey]
 	if !exists {
-		limiter = rate.NewLimiter(rl.rate, rl.burst)
+		r, b := rl.rate, rl.burst
+		if kr, ok := rl.overrides[apiKey]; ok {
+			r, b = kr.rate, kr.burst
+		}
+		limiter = rl.newLimiter(r, b)
 		rl.clients[apiKey] = limiter
 	}
 	return limiter
@@ -65,19 +180,163 @@ func (rl *PerClientRateLimiter) getClient(apiKey string) *rate.Limiter {
 
 func (rl *PerClientRateLimiter) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		apiKey := r.Header.Get("Authorization")
-		if apiKey == "" {
+		bucket := rl.bucketKey(r)
+		if bucket == "" {
 			// For per-client limiting, an API key is essential.
 			http.Error(w, "Authorization header is required for rate limiting", http.StatusUnauthorized)
 			return
 		}
 
-		limiter := rl.getClient(apiKey)
-		if !limiter.Allow() {
+		limiter := rl.getClient(bucket)
+		allowed := limiter.Allow(time.Now()) || rl.wait(r.Context(), limiter)
+		var resetAt time.Time
+		if rl.headers {
+			resetAt = setRateLimitHeaders(w, limiter, time.Now())
+		}
+		if !allowed {
+			if r.Context().Err() != nil {
+				// Client went away while queued; there is no one to respond to
+				return
+			}
+			if rl.shadow.Load() {
+				// Shadow mode: record the decision but let the request through
+				rl.wouldReject.Add(1)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if rl.collector != nil {
+				// Throttles are reported per key even when bucketing by IP
+				rl.collector.RecordThrottle(clientIdentity(r), LimiterTypeRate)
+			}
+			if !resetAt.IsZero() {
+				retryAfter := max(int64(math.Ceil(time.Until(resetAt).Seconds())), 1)
+				w.Header().Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+			}
 			http.Error(w, "Too many requests for this client", http.StatusTooManyRequests)
 			return
 		}
 
+		rl.soft.check(w, limiter.Limit(), limiter.Remaining(time.Now()), LimiterTypeRate)
 		next.ServeHTTP(w, r)
 	})
 }
+
+// SetHeaders enables the X-RateLimit-Limit, X-RateLimit-Remaining and
+// X-RateLimit-Reset response headers, and Retry-After on rejections.
+// It must be called before the limiter starts serving requests.
+func (rl *PerClientRateLimiter) SetHeaders(enabled bool) {
+	rl.headers = enabled
+}
+
+// setRateLimitHeaders describes limiter's budget at now: its size, the whole
+// requests left, and the unix second by which the next request is allowed. It
+// returns the reset time, which is zero when the budget never refills.
+func setRateLimitHeaders(w http.ResponseWriter, limiter RateLimiter, now time.Time) time.Time {
+	h := w.Header()
+	h.Set("X-RateLimit-Limit", strconv.Itoa(limiter.Limit()))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(int(limiter.Remaining(now))))
+	resetAt := limiter.NextAt(now)
+	if !resetAt.IsZero() {
+		// Round up so the advertised time is never before the token arrives
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(resetAt.Add(time.Second-1).Unix(), 10))
+	}
+	return resetAt
+}
+
+// nextTokenAt returns when limiter next has a whole token: now if it has one,
+// otherwise once the bucket has refilled the shortfall. It returns the zero
+// time for a bucket that never refills.
+func nextTokenAt(limiter *rate.Limiter, now time.Time) time.Time {
+	tokens := limiter.TokensAt(now)
+	if tokens >= 1 || limiter.Limit() == rate.Inf {
+		return now
+	}
+	if limiter.Limit() <= 0 {
+		return time.Time{}
+	}
+	wait := time.Duration((1 - tokens) / float64(limiter.Limit()) * float64(time.Second))
+	return now.Add(wait)
+}
+
+// SetSoftLimit warns clients whose served request leaves less than
+// 1-threshold of their burst: the response carries an X-RateLimit-Warning
+// header. A zero threshold disables the warning.
+// It must be called before the limiter starts serving requests.
+func (rl *PerClientRateLimiter) SetSoftLimit(threshold float64) {
+	rl.soft.threshold = threshold
+}
+
+// SoftWarningCount returns how many served requests crossed the soft limit
+func (rl *PerClientRateLimiter) SoftWarningCount() int64 {
+	return rl.soft.warnings.Load()
+}
+
+// SetShadowMode enables or disables shadow mode. In shadow mode the limiter
+// evaluates every request but never rejects; rejections are only counted.
+func (rl *PerClientRateLimiter) SetShadowMode(enabled bool) {
+	rl.shadow.Store(enabled)
+}
+
+// WouldRejectCount returns how many requests would have been rejected in shadow mode
+func (rl *PerClientRateLimiter) WouldRejectCount() int64 {
+	return rl.wouldReject.Load()
+}
+
+// SetQueue enables bounded waiting: a request that finds its bucket empty waits up
+// to maxWait for a token instead of being rejected immediately. At most maxQueue
+// requests wait at once (a default cap applies when maxQueue is zero); beyond that
+// requests are rejected without waiting. A zero maxWait disables queuing.
+// It must be called before the limiter starts serving requests.
+func (rl *PerClientRateLimiter) SetQueue(maxWait time.Duration, maxQueue int) {
+	if maxQueue <= 0 {
+		maxQueue = defaultMaxQueue
+	}
+	rl.maxWait = maxWait
+	rl.maxQueue = int64(maxQueue)
+}
+
+// QueueDepth returns the number of requests currently waiting for a token
+func (rl *PerClientRateLimiter) QueueDepth() int64 {
+	return rl.queued.Load()
+}
+
+// wait blocks until the limiter grants a token, the wait would exceed maxWait,
+// or ctx is canceled. It reports whether a token was granted.
+func (rl *PerClientRateLimiter) wait(ctx context.Context, limiter RateLimiter) bool {
+	if rl.maxWait <= 0 || rl.shadow.Load() {
+		return false
+	}
+	if rl.queued.Add(1) > rl.maxQueue {
+		rl.queued.Add(-1)
+		return false
+	}
+	defer rl.queued.Add(-1)
+
+	ctx, cancel := context.WithTimeout(ctx, rl.maxWait)
+	defer cancel()
+	return limiter.Wait(ctx) == nil
+}
+
+// softLimit warns when a served request brings a bucket past a fraction of
+// its burst, ahead of the hard limit
+type softLimit struct {
+	threshold float64
+	warnings  atomic.Int64
+}
+
+// check sets X-RateLimit-Warning and counts the warning when a budget of
+// limit with remaining left is at least threshold used. kind names the limit
+// in the warning.
+func (s *softLimit) check(w http.ResponseWriter, limit int, remaining float64, kind string) {
+	if s.threshold <= 0 || limit <= 0 {
+		return
+	}
+	// Compare whole percentages so refill since the request doesn't hide it
+	burst := float64(limit)
+	usedPct := math.Round((burst - remaining) / burst * 100)
+	if usedPct < s.threshold*100 {
+		return
+	}
+	s.warnings.Add(1)
+	w.Header().Add("X-RateLimit-Warning", fmt.Sprintf("%d%% of %s limit used", int(usedPct), kind))
+}
@@ -3,9 +3,11 @@ package proxy
 import (
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jamesprial/nexus/internal/interfaces"
+	"github.com/jamesprial/nexus/internal/metrics"
 	"github.com/jamesprial/nexus/internal/utils"
 	"golang.org/x/time/rate"
 )
@@ -19,10 +21,13 @@ type PerClientRateLimiterWithTTL struct {
 	mu         sync.RWMutex
 }
 
-// NewPerClientRateLimiterWithTTL creates a new per-client rate limiter with TTL
-func NewPerClientRateLimiterWithTTL(r rate.Limit, b int, ttl time.Duration, logger interfaces.Logger) *PerClientRateLimiterWithTTL {
+// NewPerClientRateLimiterWithTTL creates a new per-client rate limiter with TTL.
+// Rejections are recorded in collector when it is non-nil.
+func NewPerClientRateLimiterWithTTL(r rate.Limit, b int, ttl time.Duration, collector interfaces.MetricsCollector, logger interfaces.Logger) *PerClientRateLimiterWithTTL {
+	base := NewPerClientRateLimiter(r, b)
+	base.collector = collector
 	return &PerClientRateLimiterWithTTL{
-		PerClientRateLimiter: NewPerClientRateLimiter(r, b),
+		PerClientRateLimiter: base,
 		lastAccess:           make(map[string]time.Time),
 		ttl:                  ttl,
 		logger:               logger,
@@ -34,15 +39,15 @@ func (r *PerClientRateLimiterWithTTL) Middleware(next http.Handler) http.Handler
 	originalMiddleware := r.PerClientRateLimiter.Middleware(next)
 
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		apiKey := req.Header.Get("Authorization")
-		if apiKey != "" {
-			r.updateLastAccess(apiKey)
+		bucket := r.bucketKey(req)
+		if bucket != "" {
+			r.updateLastAccess(bucket)
 		}
 
 		if r.logger != nil {
 			r.logger.Debug("Per-client rate limit check", map[string]any{
 				"path":    req.URL.Path,
-				"api_key": utils.MaskAPIKey(apiKey),
+				"api_key": utils.MaskAPIKey(clientIdentity(req)),
 			})
 		}
 
@@ -58,7 +63,7 @@ func (r *PerClientRateLimiterWithTTL) updateLastAccess(apiKey string) {
 }
 
 // getOrCreateLimiter gets or creates a limiter for the given key
-func (r *PerClientRateLimiterWithTTL) getOrCreateLimiter(apiKey string) *rate.Limiter {
+func (r *PerClientRateLimiterWithTTL) getOrCreateLimiter(apiKey string) RateLimiter {
 	r.updateLastAccess(apiKey)
 	return r.getClient(apiKey)
 }
@@ -129,8 +134,7 @@ func (r *PerClientRateLimiterWithTTL) cleanup() {
 
 // GetLimit returns remaining requests for the API key
 func (r *PerClientRateLimiterWithTTL) GetLimit(apiKey string) (allowed bool, remaining int) {
-	limiter := r.getClient(apiKey)
-	tokens := limiter.Tokens()
+	tokens := r.getClient(apiKey).Remaining(time.Now())
 	return tokens > 0, int(tokens)
 }
 
@@ -158,11 +162,20 @@ type TokenLimiterWithTTL struct {
 	burst        int
 	ttl          time.Duration
 	tokenCounter interfaces.TokenCounter
+	collector    interfaces.MetricsCollector
 	logger       interfaces.Logger
+	// overrides holds per-key token rate and burst, keyed by client key
+	overrides map[string]tokenRate
+	// shadow evaluates limits without rejecting, counting would-be rejections
+	shadow      atomic.Bool
+	wouldReject atomic.Int64
+	// soft is the warning threshold for served requests
+	soft softLimit
 }
 
-// NewTokenLimiterWithTTL creates a token limiter with TTL cleanup
-func NewTokenLimiterWithTTL(tpm, burst int, counter interfaces.TokenCounter, ttl time.Duration, logger interfaces.Logger) *TokenLimiterWithTTL {
+// NewTokenLimiterWithTTL creates a token limiter with TTL cleanup.
+// Rejections are recorded in collector when it is non-nil.
+func NewTokenLimiterWithTTL(tpm, burst int, counter interfaces.TokenCounter, ttl time.Duration, collector interfaces.MetricsCollector, logger interfaces.Logger) *TokenLimiterWithTTL {
 	return &TokenLimiterWithTTL{
 		tpm:          tpm,
 		tps:          float64(tpm) / 60.0,
@@ -171,6 +184,7 @@ func NewTokenLimiterWithTTL(tpm, burst int, counter interfaces.TokenCounter, ttl
 		lastAccess:   make(map[string]time.Time),
 		ttl:          ttl,
 		tokenCounter: counter,
+		collector:    collector,
 		logger:       logger,
 	}
 }
@@ -178,7 +192,7 @@ func NewTokenLimiterWithTTL(tpm, burst int, counter interfaces.TokenCounter, ttl
 // Middleware implements the rate limiting middleware
 func (t *TokenLimiterWithTTL) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		apiKey := r.Header.Get("Authorization")
+		apiKey := clientIdentity(r)
 		if apiKey == "" {
 			http.Error(w, "Missing API key", http.StatusUnauthorized)
 			return
@@ -187,7 +201,11 @@ func (t *TokenLimiterWithTTL) Middleware(next http.Handler) http.Handler {
 		t.mu.Lock()
 		limiter, exists := t.clients[apiKey]
 		if !exists {
-			limiter = rate.NewLimiter(rate.Limit(t.tps), t.burst)
+			tps, burst := t.tps, t.burst
+			if tr, ok := t.overrides[apiKey]; ok {
+				tps, burst = tr.tps, tr.burst
+			}
+			limiter = rate.NewLimiter(rate.Limit(tps), burst)
 			t.clients[apiKey] = limiter
 		}
 		t.lastAccess[apiKey] = time.Now()
@@ -207,6 +225,20 @@ func (t *TokenLimiterWithTTL) Middleware(next http.Handler) http.Handler {
 		}
 
 		if !limiter.AllowN(time.Now(), tokenCount) {
+			if t.shadow.Load() {
+				// Shadow mode: record the decision but let the request through
+				t.wouldReject.Add(1)
+				if t.logger != nil {
+					t.logger.Info("Token limit would be exceeded (shadow mode)", map[string]any{
+						"api_key":          utils.MaskAPIKey(apiKey),
+						"tokens_needed":    tokenCount,
+						"tokens_available": limiter.Tokens(),
+					})
+				}
+				metrics.ReportUsage(r, "", tokenCount, true)
+				next.ServeHTTP(w, r)
+				return
+			}
 			if t.logger != nil {
 				t.logger.Warn("Token limit exceeded", map[string]any{
 					"api_key":          utils.MaskAPIKey(apiKey),
@@ -214,6 +246,9 @@ func (t *TokenLimiterWithTTL) Middleware(next http.Handler) http.Handler {
 					"tokens_available": limiter.Tokens(),
 				})
 			}
+			if t.collector != nil {
+				t.collector.RecordThrottle(apiKey, LimiterTypeToken)
+			}
 			http.Error(w, "Token limit exceeded", http.StatusTooManyRequests)
 			return
 		}
@@ -226,10 +261,70 @@ func (t *TokenLimiterWithTTL) Middleware(next http.Handler) http.Handler {
 			})
 		}
 
+		t.soft.check(w, limiter.Burst(), limiter.TokensAt(time.Now()), LimiterTypeToken)
+
+		// Report the estimate so metrics have a count when upstream usage is absent
+		metrics.ReportUsage(r, "", tokenCount, true)
+
 		next.ServeHTTP(w, r)
 	})
 }
 
+// tokenRate is a resolved per-key token rate and burst
+type tokenRate struct {
+	tps   float64
+	burst int
+}
+
+// DefaultTokenBurst returns the token burst used for a tokens-per-minute limit:
+// ten seconds' worth of tokens, but never less than 100.
+func DefaultTokenBurst(tpm int) int {
+	return max(tpm/6, 100)
+}
+
+// SetKeyLimits configures per-key token limits. Keys without a token override
+// use the limiter's global rate and burst. Existing buckets keep their capacity
+// until they are reset or expire.
+func (t *TokenLimiterWithTTL) SetKeyLimits(limits map[string]interfaces.KeyLimits) {
+	overrides := make(map[string]tokenRate, len(limits))
+	for key, l := range limits {
+		if l.ModelTokensPerMinute <= 0 {
+			continue
+		}
+		overrides[key] = tokenRate{
+			tps:   float64(l.ModelTokensPerMinute) / 60.0,
+			burst: DefaultTokenBurst(l.ModelTokensPerMinute),
+		}
+	}
+
+	t.mu.Lock()
+	t.overrides = overrides
+	t.mu.Unlock()
+}
+
+// SetSoftLimit warns clients whose served request leaves less than
+// 1-threshold of their token burst, like PerClientRateLimiter.SetSoftLimit.
+// It must be called before the limiter starts serving requests.
+func (t *TokenLimiterWithTTL) SetSoftLimit(threshold float64) {
+	t.soft.threshold = threshold
+}
+
+// SoftWarningCount returns how many served requests crossed the soft limit
+func (t *TokenLimiterWithTTL) SoftWarningCount() int64 {
+	return t.soft.warnings.Load()
+}
+
+// SetShadowMode enables or disables shadow mode. In shadow mode the limiter
+// evaluates every request but never rejects; rejections are only counted.
+func (t *TokenLimiterWithTTL) SetShadowMode(enabled bool) {
+	t.shadow.Store(enabled)
+}
+
+// WouldRejectCount returns how many requests would have been rejected in shadow mode
+func (t *TokenLimiterWithTTL) WouldRejectCount() int64 {
+	return t.wouldReject.Load()
+}
+
 // HasClient checks if a client is currently tracked
 func (t *TokenLimiterWithTTL) HasClient(apiKey string) bool {
 	t.mu.RLock()
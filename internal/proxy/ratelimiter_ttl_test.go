@@ -1,20 +1,27 @@
 package proxy
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+	"github.com/jamesprial/nexus/internal/metrics"
+	"golang.org/x/time/rate"
 )
 
 // Test TTL cleanup for per-client rate limiter
 func TestPerClientRateLimiterWithTTL(t *testing.T) {
 	logger := &mockLogger{}
 	// Create limiter with 200ms TTL for fast testing
-	limiter := NewPerClientRateLimiterWithTTL(1, 1, 200*time.Millisecond, logger)
+	limiter := NewPerClientRateLimiterWithTTL(1, 1, 200*time.Millisecond, nil, logger)
 
 	// Start cleanup routine
 	stopChan := make(chan struct{})
@@ -50,7 +57,7 @@ func TestPerClientRateLimiterWithTTL(t *testing.T) {
 func TestPerClientRateLimiterWithTTL_ActiveClients(t *testing.T) {
 	logger := &mockLogger{}
 	// Create limiter with 300ms TTL
-	limiter := NewPerClientRateLimiterWithTTL(10, 10, 300*time.Millisecond, logger)
+	limiter := NewPerClientRateLimiterWithTTL(10, 10, 300*time.Millisecond, nil, logger)
 
 	// Start cleanup routine
 	stopChan := make(chan struct{})
@@ -95,7 +102,7 @@ func TestPerClientRateLimiterWithTTL_ActiveClients(t *testing.T) {
 // Test concurrent access during cleanup
 func TestPerClientRateLimiterWithTTL_Concurrent(t *testing.T) {
 	logger := &mockLogger{}
-	limiter := NewPerClientRateLimiterWithTTL(100, 100, 100*time.Millisecond, logger)
+	limiter := NewPerClientRateLimiterWithTTL(100, 100, 100*time.Millisecond, nil, logger)
 
 	// Start cleanup routine
 	stopChan := make(chan struct{})
@@ -131,7 +138,7 @@ func TestPerClientRateLimiterWithTTL_Concurrent(t *testing.T) {
 // Test memory cleanup effectiveness
 func TestPerClientRateLimiterWithTTL_MemoryCleanup(t *testing.T) {
 	logger := &mockLogger{}
-	limiter := NewPerClientRateLimiterWithTTL(1000, 1000, 100*time.Millisecond, logger)
+	limiter := NewPerClientRateLimiterWithTTL(1000, 1000, 100*time.Millisecond, nil, logger)
 
 	// Start cleanup routine
 	stopChan := make(chan struct{})
@@ -189,7 +196,7 @@ func TestPerClientRateLimiterWithTTL_MemoryCleanup(t *testing.T) {
 // Test stopping cleanup routine
 func TestPerClientRateLimiterWithTTL_StopCleanup(t *testing.T) {
 	logger := &mockLogger{}
-	limiter := NewPerClientRateLimiterWithTTL(1, 1, 100*time.Millisecond, logger)
+	limiter := NewPerClientRateLimiterWithTTL(1, 1, 100*time.Millisecond, nil, logger)
 
 	// Start cleanup routine
 	stopChan := make(chan struct{})
@@ -219,7 +226,7 @@ func TestTokenLimiterWithTTL(t *testing.T) {
 	logger := &mockLogger{}
 	tokenCounter := &DefaultTokenCounter{}
 	// Create limiter with 200ms TTL
-	limiter := NewTokenLimiterWithTTL(60, 10, tokenCounter, 200*time.Millisecond, logger)
+	limiter := NewTokenLimiterWithTTL(60, 10, tokenCounter, 200*time.Millisecond, nil, logger)
 
 	// Start cleanup routine
 	stopChan := make(chan struct{})
@@ -252,10 +259,235 @@ func TestTokenLimiterWithTTL(t *testing.T) {
 	}
 }
 
+// Test that shadow mode lets requests through while counting would-be rejections
+func TestPerClientRateLimiterWithTTL_ShadowMode(t *testing.T) {
+	limiter := NewPerClientRateLimiterWithTTL(1, 1, time.Hour, nil, &mockLogger{})
+	limiter.SetShadowMode(true)
+
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer shadow-client")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Errorf("Request %d: expected status 200 in shadow mode, got %d", i, rr.Code)
+		}
+	}
+
+	// Burst of 1 allows the first request; the remaining 4 would have been rejected
+	if got := limiter.WouldRejectCount(); got != 4 {
+		t.Errorf("Expected 4 would-reject decisions, got %d", got)
+	}
+
+	// Disabling shadow mode enforces the limit again
+	limiter.SetShadowMode(false)
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer shadow-client")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 with shadow mode disabled, got %d", rr.Code)
+	}
+}
+
+func TestPerClientRateLimiterWithTTL_LimitByIP(t *testing.T) {
+	limiter := NewPerClientRateLimiterWithTTL(1, 1, time.Hour, nil, &mockLogger{})
+	limiter.SetLimitByIP(1)
+
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	status := func(clientKey, forwardedFor string) int {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = "10.0.0.1:443"
+		req.Header.Set("Authorization", "Bearer "+clientKey)
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := status("key-a", "198.51.100.1"); code != http.StatusOK {
+		t.Fatalf("Expected first request from IP to pass, got %d", code)
+	}
+	// A different key from the same IP shares the IP's bucket
+	if code := status("key-b", "198.51.100.1"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected second request from same IP to be limited, got %d", code)
+	}
+	// The same key from another IP gets its own bucket
+	if code := status("key-a", "198.51.100.2"); code != http.StatusOK {
+		t.Errorf("Expected request from another IP to pass, got %d", code)
+	}
+	if !limiter.HasClient("198.51.100.1") || limiter.HasClient("key-a") {
+		t.Error("Expected buckets to be keyed by client IP")
+	}
+}
+
+// Test that rejections are recorded in the metrics collector with the limiter type
+func TestLimitersRecordThrottles(t *testing.T) {
+	collector := metrics.NewMetricsCollector()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	rateLimiter := NewPerClientRateLimiterWithTTL(1, 1, time.Hour, collector, &mockLogger{})
+	rateHandler := rateLimiter.Middleware(ok)
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req = metrics.SetAPIKey(req, "throttled-client")
+		rateHandler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	tokenLimiter := NewTokenLimiterWithTTL(60, 10, &DefaultTokenCounter{}, time.Hour, collector, &mockLogger{})
+	tokenHandler := tokenLimiter.Middleware(ok)
+	body := `{"messages": [{"role": "user", "content": "` + strings.Repeat("word ", 40) + `"}]}`
+	req := httptest.NewRequest("POST", "/test", strings.NewReader(body))
+	req = metrics.SetAPIKey(req, "throttled-client")
+	rr := httptest.NewRecorder()
+	tokenHandler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected token limiter to reject with 429, got %d", rr.Code)
+	}
+
+	km, found := collector.GetMetricsForKey("throttled-client")
+	if !found {
+		t.Fatal("Expected metrics for the throttled client")
+	}
+	// Burst of 1 allows the first request; the remaining 2 are rejected
+	if got := km.ThrottledRequests[LimiterTypeRate]; got != 2 {
+		t.Errorf("Expected 2 rate limiter rejections, got %d", got)
+	}
+	if got := km.ThrottledRequests[LimiterTypeToken]; got != 1 {
+		t.Errorf("Expected 1 token limiter rejection, got %d", got)
+	}
+}
+
+// Test that shadow-mode decisions are not recorded as throttles
+func TestLimitersShadowModeDoesNotRecordThrottles(t *testing.T) {
+	collector := metrics.NewMetricsCollector()
+	limiter := NewPerClientRateLimiterWithTTL(1, 1, time.Hour, collector, &mockLogger{})
+	limiter.SetShadowMode(true)
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req = metrics.SetAPIKey(req, "shadow-client")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if _, found := collector.GetMetricsForKey("shadow-client"); found {
+		t.Error("Expected no throttle metrics in shadow mode")
+	}
+}
+
+// Test that token limiter shadow mode lets requests through while counting would-be rejections
+func TestTokenLimiterWithTTL_ShadowMode(t *testing.T) {
+	logger := &mockLogger{}
+	limiter := NewTokenLimiterWithTTL(60, 10, &DefaultTokenCounter{}, time.Hour, nil, logger)
+	limiter.SetShadowMode(true)
+
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Each request costs well over the burst of 10 tokens
+	body := `{"messages": [{"role": "user", "content": "` + strings.Repeat("word ", 40) + `"}]}`
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("POST", "/test", strings.NewReader(body))
+		req.Header.Set("Authorization", "shadow-key")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Errorf("Request %d: expected status 200 in shadow mode, got %d", i, rr.Code)
+		}
+	}
+
+	if got := limiter.WouldRejectCount(); got != 3 {
+		t.Errorf("Expected 3 would-reject decisions, got %d", got)
+	}
+}
+
+// Test that a key with a per-key override sustains more throughput than a default key
+func TestPerClientRateLimiterWithTTL_PerKeyLimits(t *testing.T) {
+	limiter := NewPerClientRateLimiterWithTTL(1, 2, time.Hour, nil, &mockLogger{})
+	limiter.SetKeyLimits(map[string]interfaces.KeyLimits{
+		"premium-key": {RequestsPerSecond: 100, Burst: 10},
+	})
+
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	countAllowed := func(clientKey string) int {
+		allowed := 0
+		for i := 0; i < 10; i++ {
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "Bearer shared-upstream-key")
+			req = metrics.SetAPIKey(req, clientKey)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code == http.StatusOK {
+				allowed++
+			}
+		}
+		return allowed
+	}
+
+	if got := countAllowed("premium-key"); got != 10 {
+		t.Errorf("Expected premium key to get 10 requests through, got %d", got)
+	}
+	if got := countAllowed("default-key"); got != 2 {
+		t.Errorf("Expected default key to be limited to its burst of 2, got %d", got)
+	}
+}
+
+// Test that token limiter per-key overrides size buckets per client key
+func TestTokenLimiterWithTTL_PerKeyLimits(t *testing.T) {
+	limiter := NewTokenLimiterWithTTL(600, DefaultTokenBurst(600), &DefaultTokenCounter{}, time.Hour, nil, &mockLogger{})
+	limiter.SetKeyLimits(map[string]interfaces.KeyLimits{
+		"premium-key": {ModelTokensPerMinute: 60000},
+	})
+
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Roughly 50 tokens per request: the default 100-token burst admits two
+	body := `{"messages": [{"role": "user", "content": "` + strings.Repeat("abcd", 50) + `"}]}`
+	countAllowed := func(clientKey string) int {
+		allowed := 0
+		for i := 0; i < 10; i++ {
+			req := httptest.NewRequest("POST", "/test", strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer shared-upstream-key")
+			req = metrics.SetAPIKey(req, clientKey)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code == http.StatusOK {
+				allowed++
+			}
+		}
+		return allowed
+	}
+
+	if got := countAllowed("premium-key"); got != 10 {
+		t.Errorf("Expected premium key to get 10 requests through, got %d", got)
+	}
+	if got := countAllowed("default-key"); got != 2 {
+		t.Errorf("Expected default key to get 2 requests through, got %d", got)
+	}
+}
+
 // Benchmark cleanup performance
 func BenchmarkPerClientRateLimiterWithTTL_Cleanup(b *testing.B) {
 	logger := &mockLogger{}
-	limiter := NewPerClientRateLimiterWithTTL(1000, 1000, 100*time.Millisecond, logger)
+	limiter := NewPerClientRateLimiterWithTTL(1000, 1000, 100*time.Millisecond, nil, logger)
 
 	// Pre-populate with clients
 	for i := 0; i < 1000; i++ {
@@ -274,4 +506,320 @@ func BenchmarkPerClientRateLimiterWithTTL_Cleanup(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		limiter.cleanup()
 	}
-}
\ No newline at end of file
+}
+// Test that a queued request waits for a token and then succeeds
+func TestPerClientRateLimiterWithTTL_QueueWaitsAndSucceeds(t *testing.T) {
+	// 20 req/s refills a token every 50ms
+	limiter := NewPerClientRateLimiterWithTTL(20, 1, time.Hour, nil, &mockLogger{})
+	limiter.SetQueue(time.Second, 10)
+
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer queued-client")
+		rr := httptest.NewRecorder()
+		start := time.Now()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Errorf("Request %d: expected status 200 after queuing, got %d", i, rr.Code)
+		}
+		if i > 0 && time.Since(start) < 20*time.Millisecond {
+			t.Errorf("Request %d: expected to wait for a token, returned after %v", i, time.Since(start))
+		}
+	}
+
+	if depth := limiter.QueueDepth(); depth != 0 {
+		t.Errorf("Expected empty queue after requests complete, got %d", depth)
+	}
+}
+
+// Test that a request is rejected when no token frees up within max wait
+func TestPerClientRateLimiterWithTTL_QueueTimeout(t *testing.T) {
+	// 1 req/s means the next token is a full second away
+	limiter := NewPerClientRateLimiterWithTTL(1, 1, time.Hour, nil, &mockLogger{})
+	limiter.SetQueue(50*time.Millisecond, 10)
+
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func() int {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer slow-client")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := send(); code != http.StatusOK {
+		t.Fatalf("Expected first request to use the burst, got %d", code)
+	}
+
+	start := time.Now()
+	if code := send(); code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 after queue timeout, got %d", code)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected rejection within max wait, took %v", elapsed)
+	}
+}
+
+// Test that requests beyond the queue cap are rejected without waiting
+func TestPerClientRateLimiterWithTTL_QueueFull(t *testing.T) {
+	limiter := NewPerClientRateLimiterWithTTL(5, 1, time.Hour, nil, &mockLogger{})
+	limiter.SetQueue(time.Second, 1)
+
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer busy-client")
+		return req
+	}
+
+	// Drain the burst
+	handler.ServeHTTP(httptest.NewRecorder(), newRequest())
+
+	// Occupy the single queue slot
+	done := make(chan int)
+	go func() {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, newRequest())
+		done <- rr.Code
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for limiter.QueueDepth() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if limiter.QueueDepth() != 1 {
+		t.Fatalf("Expected one queued request, got %d", limiter.QueueDepth())
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, newRequest())
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 with full queue, got %d", rr.Code)
+	}
+
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("Expected queued request to succeed, got %d", code)
+	}
+}
+
+// Test that a queued request stops waiting when the client cancels
+func TestPerClientRateLimiterWithTTL_QueueCanceled(t *testing.T) {
+	limiter := NewPerClientRateLimiterWithTTL(1, 1, time.Hour, nil, &mockLogger{})
+	limiter.SetQueue(5*time.Second, 10)
+
+	called := 0
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called++
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer leaving-client")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected canceled request to stop waiting promptly, took %v", elapsed)
+	}
+	if called != 1 {
+		t.Errorf("Expected only the first request to reach the handler, got %d", called)
+	}
+}
+
+// Test that the rate limit headers describe the caller's bucket
+func TestPerClientRateLimiterWithTTL_Headers(t *testing.T) {
+	// One token every 10 seconds with a burst of 2
+	limiter := NewPerClientRateLimiterWithTTL(0.1, 2, time.Hour, nil, &mockLogger{})
+	limiter.SetHeaders(true)
+
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer header-client")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := send()
+	if got := rr.Header().Get("X-RateLimit-Limit"); got != "2" {
+		t.Errorf("Expected X-RateLimit-Limit 2, got %q", got)
+	}
+	if got := rr.Header().Get("X-RateLimit-Remaining"); got != "1" {
+		t.Errorf("Expected X-RateLimit-Remaining 1, got %q", got)
+	}
+	if reset, _ := strconv.ParseInt(rr.Header().Get("X-RateLimit-Reset"), 10, 64); reset > time.Now().Unix()+1 {
+		t.Errorf("Expected a token to be available now, got reset %d", reset)
+	}
+
+	send()
+	rr = send()
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 once the burst is spent, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("X-RateLimit-Remaining"); got != "0" {
+		t.Errorf("Expected X-RateLimit-Remaining 0, got %q", got)
+	}
+	reset, err := strconv.ParseInt(rr.Header().Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		t.Fatalf("Expected a unix X-RateLimit-Reset, got %q", rr.Header().Get("X-RateLimit-Reset"))
+	}
+	if now := time.Now().Unix(); reset <= now || reset > now+11 {
+		t.Errorf("Expected reset about 10s in the future, got %d (now %d)", reset, now)
+	}
+	if got := rr.Header().Get("Retry-After"); got != "10" {
+		t.Errorf("Expected Retry-After 10, got %q", got)
+	}
+}
+
+func TestPerClientRateLimiterWithTTL_HeadersDisabled(t *testing.T) {
+	limiter := NewPerClientRateLimiterWithTTL(1, 1, time.Hour, nil, &mockLogger{})
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer quiet-client")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		for _, h := range []string{"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Retry-After"} {
+			if got := rr.Header().Get(h); got != "" {
+				t.Errorf("Request %d: expected no %s header by default, got %q", i, h, got)
+			}
+		}
+	}
+}
+
+// Test that the wait for the next token shrinks to zero as the bucket refills
+func TestNextTokenAt(t *testing.T) {
+	limiter := rate.NewLimiter(2, 1)
+	start := time.Now()
+	if !limiter.AllowN(start, 1) {
+		t.Fatal("Expected the first token to be granted")
+	}
+
+	previous := time.Duration(math.MaxInt64)
+	for _, elapsed := range []time.Duration{0, 100 * time.Millisecond, 250 * time.Millisecond, 400 * time.Millisecond} {
+		now := start.Add(elapsed)
+		reset := nextTokenAt(limiter, now)
+		wait := reset.Sub(now)
+		if wait <= 0 {
+			t.Errorf("At %v: expected the next token in the future, got %v", elapsed, wait)
+		}
+		if wait >= previous {
+			t.Errorf("At %v: expected the wait to shrink below %v, got %v", elapsed, previous, wait)
+		}
+		previous = wait
+	}
+
+	// Half a second after the token was spent the bucket has refilled
+	now := start.Add(500 * time.Millisecond)
+	if reset := nextTokenAt(limiter, now); !reset.Equal(now) {
+		t.Errorf("Expected the next token to be available now, got %v later", reset.Sub(now))
+	}
+
+	if reset := nextTokenAt(rate.NewLimiter(0, 0), now); !reset.IsZero() {
+		t.Errorf("Expected no reset for a bucket that never refills, got %v", reset)
+	}
+}
+
+// Test that requests past the soft threshold are warned about but still served
+func TestPerClientRateLimiterWithTTL_SoftLimit(t *testing.T) {
+	// A burst of 10 that effectively never refills during the test
+	limiter := NewPerClientRateLimiterWithTTL(0.001, 10, time.Hour, nil, &mockLogger{})
+	limiter.SetSoftLimit(0.8)
+
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i := 1; i <= 11; i++ {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer soft-client")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		warning := rr.Header().Get("X-RateLimit-Warning")
+
+		switch {
+		case i < 8:
+			if rr.Code != http.StatusOK || warning != "" {
+				t.Errorf("Request %d: expected 200 without a warning, got %d %q", i, rr.Code, warning)
+			}
+		case i <= 10:
+			if rr.Code != http.StatusOK {
+				t.Errorf("Request %d: expected the soft zone to be served, got %d", i, rr.Code)
+			}
+			want := fmt.Sprintf("%d%% of rate limit used", i*10)
+			if warning != want {
+				t.Errorf("Request %d: expected warning %q, got %q", i, want, warning)
+			}
+		default:
+			if rr.Code != http.StatusTooManyRequests {
+				t.Errorf("Request %d: expected 429 at capacity, got %d", i, rr.Code)
+			}
+		}
+	}
+
+	if got := limiter.SoftWarningCount(); got != 3 {
+		t.Errorf("Expected 3 soft limit warnings, got %d", got)
+	}
+}
+
+// fixedTokenCounter charges every request the same number of tokens
+type fixedTokenCounter int
+
+func (c fixedTokenCounter) CountTokens(r *http.Request) (int, error) {
+	return int(c), nil
+}
+
+func TestTokenLimiterWithTTL_SoftLimit(t *testing.T) {
+	limiter := NewTokenLimiterWithTTL(1, 100, fixedTokenCounter(30), time.Hour, nil, &mockLogger{})
+	limiter.SetSoftLimit(0.5)
+
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	wantWarnings := []string{"", "60% of token limit used", "90% of token limit used"}
+	for i, want := range wantWarnings {
+		req := httptest.NewRequest("POST", "/test", nil)
+		req.Header.Set("Authorization", "soft-key")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200, got %d", i, rr.Code)
+		}
+		if got := rr.Header().Get("X-RateLimit-Warning"); got != want {
+			t.Errorf("Request %d: expected warning %q, got %q", i, want, got)
+		}
+	}
+
+	req := httptest.NewRequest("POST", "/test", nil)
+	req.Header.Set("Authorization", "soft-key")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 past the token burst, got %d", rr.Code)
+	}
+	if got := limiter.SoftWarningCount(); got != 2 {
+		t.Errorf("Expected 2 soft limit warnings, got %d", got)
+	}
+}
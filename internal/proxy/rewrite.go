@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+// PathRewrite changes the path a request is sent upstream with. It either
+// replaces a path prefix or, when pattern is set, every match of a regular
+// expression, whose replacement may refer to groups as $1 or ${name}.
+type PathRewrite struct {
+	prefix      string
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// NewPathRewrite compiles one configured rewrite. Exactly one of path_prefix
+// and pattern must be set.
+func NewPathRewrite(c interfaces.PathRewrite) (PathRewrite, error) {
+	switch {
+	case c.PathPrefix != "" && c.Pattern != "":
+		return PathRewrite{}, fmt.Errorf("set either path_prefix or pattern, not both")
+	case c.PathPrefix != "":
+		if !strings.HasPrefix(c.PathPrefix, "/") {
+			return PathRewrite{}, fmt.Errorf("path_prefix must start with /, got %q", c.PathPrefix)
+		}
+		return PathRewrite{prefix: c.PathPrefix, replacement: c.Replacement}, nil
+	case c.Pattern != "":
+		pattern, err := regexp.Compile(c.Pattern)
+		if err != nil {
+			return PathRewrite{}, fmt.Errorf("invalid pattern: %w", err)
+		}
+		return PathRewrite{pattern: pattern, replacement: c.Replacement}, nil
+	}
+	return PathRewrite{}, fmt.Errorf("path_prefix or pattern is required")
+}
+
+// NewPathRewrites compiles configured rewrites, keeping their order
+func NewPathRewrites(configured []interfaces.PathRewrite) ([]PathRewrite, error) {
+	rewrites := make([]PathRewrite, 0, len(configured))
+	for i, c := range configured {
+		rw, err := NewPathRewrite(c)
+		if err != nil {
+			return nil, fmt.Errorf("path_rewrites[%d]: %w", i, err)
+		}
+		rewrites = append(rewrites, rw)
+	}
+	return rewrites, nil
+}
+
+// apply returns the rewritten path and whether the rewrite matched path
+func (rw PathRewrite) apply(path string) (string, bool) {
+	if rw.pattern != nil {
+		if !rw.pattern.MatchString(path) {
+			return path, false
+		}
+		return rw.pattern.ReplaceAllString(path, rw.replacement), true
+	}
+	if !strings.HasPrefix(path, rw.prefix) {
+		return path, false
+	}
+	return rw.replacement + strings.TrimPrefix(path, rw.prefix), true
+}
+
+// rewritePath applies the first rewrite matching req's path. It runs on the
+// outgoing request, so the client-facing path seen by the rest of the chain,
+// metrics included, is unchanged.
+func rewritePath(rewrites []PathRewrite, req *http.Request) {
+	for _, rw := range rewrites {
+		if path, ok := rw.apply(req.URL.Path); ok {
+			req.URL.Path = path
+			req.URL.RawPath = ""
+			return
+		}
+	}
+}
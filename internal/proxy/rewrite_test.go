@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+	"github.com/jamesprial/nexus/internal/metrics"
+)
+
+func TestHTTPProxy_PathRewrites(t *testing.T) {
+	tests := []struct {
+		name     string
+		rewrites []interfaces.PathRewrite
+		path     string
+		want     string
+	}{
+		{
+			name:     "prefix",
+			rewrites: []interfaces.PathRewrite{{PathPrefix: "/v1", Replacement: "/openai/v1"}},
+			path:     "/v1/chat/completions",
+			want:     "/openai/v1/chat/completions",
+		},
+		{
+			name:     "pattern with group",
+			rewrites: []interfaces.PathRewrite{{Pattern: `^/v1/engines/([^/]+)/completions$`, Replacement: "/deployments/$1/completions"}},
+			path:     "/v1/engines/gpt-4/completions",
+			want:     "/deployments/gpt-4/completions",
+		},
+		{
+			name: "first match wins",
+			rewrites: []interfaces.PathRewrite{
+				{PathPrefix: "/v1/embeddings", Replacement: "/embed"},
+				{PathPrefix: "/v1", Replacement: "/openai/v1"},
+			},
+			path: "/v1/embeddings",
+			want: "/embed",
+		},
+		{
+			name:     "no match",
+			rewrites: []interfaces.PathRewrite{{PathPrefix: "/v2", Replacement: "/openai/v2"}},
+			path:     "/v1/models",
+			want:     "/v1/models",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath string
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+			}))
+			defer upstream.Close()
+
+			target, _ := url.Parse(upstream.URL)
+			p := NewHTTPProxy(target, nil)
+			rewrites, err := NewPathRewrites(tt.rewrites)
+			if err != nil {
+				t.Fatalf("NewPathRewrites() error = %v", err)
+			}
+			p.SetPathRewrites(rewrites)
+
+			p.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if gotPath != tt.want {
+				t.Errorf("Expected upstream path %q, got %q", tt.want, gotPath)
+			}
+		})
+	}
+}
+
+func TestHTTPProxy_PathRewriteKeepsClientPathInMetrics(t *testing.T) {
+	var gotPath string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+	}))
+	defer upstream.Close()
+
+	target, _ := url.Parse(upstream.URL)
+	p := NewHTTPProxy(target, nil)
+	rewrites, err := NewPathRewrites([]interfaces.PathRewrite{{PathPrefix: "/v1", Replacement: "/openai/v1"}})
+	if err != nil {
+		t.Fatalf("NewPathRewrites() error = %v", err)
+	}
+	p.SetPathRewrites(rewrites)
+
+	collector := metrics.NewMetricsCollector()
+	handler := metrics.MetricsMiddleware(collector)(p)
+	req := metrics.SetAPIKey(httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil), "client")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if gotPath != "/openai/v1/chat/completions" {
+		t.Errorf("Expected the rewritten path upstream, got %q", gotPath)
+	}
+	km, ok := collector.GetMetricsForKey("client")
+	if !ok {
+		t.Fatal("Expected metrics for the client key")
+	}
+	if _, ok := km.PerEndpoint["/v1/chat/completions"]; !ok {
+		t.Errorf("Expected metrics under the client-facing path, got %v", km.PerEndpoint)
+	}
+	if _, ok := km.PerEndpoint["/openai/v1/chat/completions"]; ok {
+		t.Error("Expected no metrics under the upstream path")
+	}
+}
+
+func TestNewPathRewrite_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		rewrite interfaces.PathRewrite
+	}{
+		{name: "empty", rewrite: interfaces.PathRewrite{Replacement: "/x"}},
+		{name: "both set", rewrite: interfaces.PathRewrite{PathPrefix: "/v1", Pattern: "^/v1", Replacement: "/x"}},
+		{name: "relative prefix", rewrite: interfaces.PathRewrite{PathPrefix: "v1", Replacement: "/x"}},
+		{name: "bad pattern", rewrite: interfaces.PathRewrite{Pattern: "([", Replacement: "/x"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPathRewrite(tt.rewrite); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+// TargetRoute sends requests whose path starts with PathPrefix to Target
+type TargetRoute struct {
+	PathPrefix string
+	Target     *url.URL
+	director   func(*http.Request)
+}
+
+// NewTargetRoutes parses configured routes, ordered so the longest prefix is
+// matched first. Each target must have a scheme and host.
+func NewTargetRoutes(configured []interfaces.TargetRoute) ([]TargetRoute, error) {
+	routes := make([]TargetRoute, 0, len(configured))
+	for _, c := range configured {
+		target, err := url.Parse(c.Target)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse target URL for route %s: %w", c.PathPrefix, err)
+		}
+		if target.Scheme == "" || target.Host == "" {
+			return nil, fmt.Errorf("target URL for route %s must have a scheme and host", c.PathPrefix)
+		}
+		routes = append(routes, TargetRoute{
+			PathPrefix: c.PathPrefix,
+			Target:     target,
+			director:   httputil.NewSingleHostReverseProxy(target).Director,
+		})
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		return len(routes[i].PathPrefix) > len(routes[j].PathPrefix)
+	})
+	return routes, nil
+}
+
+// matchTargetRoute returns the route for path, or nil when no route matches
+func matchTargetRoute(routes []TargetRoute, path string) *TargetRoute {
+	for i := range routes {
+		if strings.HasPrefix(path, routes[i].PathPrefix) {
+			return &routes[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+func TestHTTPProxy_TargetRoutes(t *testing.T) {
+	var defaultHits, chatHits, completionsHits int
+	defaultUpstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { defaultHits++ }))
+	defer defaultUpstream.Close()
+	chatUpstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { chatHits++ }))
+	defer chatUpstream.Close()
+	completionsUpstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { completionsHits++ }))
+	defer completionsUpstream.Close()
+
+	target, _ := url.Parse(defaultUpstream.URL)
+	p := NewHTTPProxy(target, nil)
+	routes, err := NewTargetRoutes([]interfaces.TargetRoute{
+		{PathPrefix: "/v1/chat", Target: chatUpstream.URL},
+		{PathPrefix: "/v1/chat/completions", Target: completionsUpstream.URL},
+	})
+	if err != nil {
+		t.Fatalf("NewTargetRoutes() error = %v", err)
+	}
+	p.SetTargetRoutes(routes)
+
+	for _, path := range []string{"/v1/chat/completions", "/v1/chat/other", "/v1/models"} {
+		p.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	// The longest matching prefix wins
+	if completionsHits != 1 || chatHits != 1 || defaultHits != 1 {
+		t.Errorf("Expected one request at each upstream, got completions %d, chat %d, default %d",
+			completionsHits, chatHits, defaultHits)
+	}
+}
+
+func TestNewTargetRoutes_Invalid(t *testing.T) {
+	for _, raw := range []string{"chat.example.com", "://bad", "https://"} {
+		if _, err := NewTargetRoutes([]interfaces.TargetRoute{{PathPrefix: "/v1", Target: raw}}); err == nil {
+			t.Errorf("Expected an error for %q", raw)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package proxy
+
+import (
+	"context"
+	"sync"
+)
+
+// Values for the scheduling config key, selecting the order in which requests
+// waiting for an upstream slot are dispatched
+const (
+	SchedulingFIFO = "fifo"
+	SchedulingFair = "fair"
+)
+
+// slotWaiter is a request waiting for an upstream slot
+type slotWaiter struct {
+	key   string
+	ready chan struct{}
+}
+
+// slotScheduler shares a fixed number of upstream slots between keys. When
+// every slot is taken requests wait; FIFO dispatches them in arrival order,
+// fair round-robins between the keys with requests waiting so a busy key
+// cannot starve quiet ones.
+type slotScheduler struct {
+	mu       sync.Mutex
+	capacity int
+	inFlight int
+	fair     bool
+	// queues holds the waiters per key, and order the keys with waiters in
+	// dispatch order. In FIFO mode every waiter shares the "" queue.
+	queues  map[string][]*slotWaiter
+	order   []string
+	waiting int
+}
+
+// newSlotScheduler creates a scheduler for capacity slots dispatched by scheduling
+func newSlotScheduler(capacity int, scheduling string) *slotScheduler {
+	return &slotScheduler{
+		capacity: capacity,
+		fair:     scheduling == SchedulingFair,
+		queues:   make(map[string][]*slotWaiter),
+	}
+}
+
+// acquire takes a slot for key, waiting for one to be released if none is
+// free. It reports false, without a slot, if ctx ends first.
+func (s *slotScheduler) acquire(ctx context.Context, key string) bool {
+	s.mu.Lock()
+	if s.inFlight < s.capacity && s.waiting == 0 {
+		s.inFlight++
+		s.mu.Unlock()
+		return true
+	}
+	w := &slotWaiter{key: s.queueKey(key), ready: make(chan struct{})}
+	s.enqueue(w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return true
+	case <-ctx.Done():
+		s.mu.Lock()
+		removed := s.remove(w)
+		s.mu.Unlock()
+		if !removed {
+			// The slot was handed over as ctx ended; pass it on
+			s.release()
+		}
+		return false
+	}
+}
+
+// release returns a slot, handing it straight to the next waiter if any
+func (s *slotScheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if w := s.dequeue(); w != nil {
+		close(w.ready)
+		return
+	}
+	s.inFlight--
+}
+
+// queued returns the number of requests waiting for a slot
+func (s *slotScheduler) queued() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.waiting
+}
+
+// queueKey returns the queue a request for key waits in
+func (s *slotScheduler) queueKey(key string) string {
+	if s.fair {
+		return key
+	}
+	return ""
+}
+
+// enqueue adds w behind the other waiters for its key; the caller must hold s.mu
+func (s *slotScheduler) enqueue(w *slotWaiter) {
+	if len(s.queues[w.key]) == 0 {
+		s.order = append(s.order, w.key)
+	}
+	s.queues[w.key] = append(s.queues[w.key], w)
+	s.waiting++
+}
+
+// dequeue removes the next waiter, taking the first from the key at the
+// front of order and moving that key to the back. The caller must hold s.mu.
+func (s *slotScheduler) dequeue() *slotWaiter {
+	if len(s.order) == 0 {
+		return nil
+	}
+	key := s.order[0]
+	queue := s.queues[key]
+	w := queue[0]
+	s.order = s.order[1:]
+	if len(queue) == 1 {
+		delete(s.queues, key)
+	} else {
+		s.queues[key] = queue[1:]
+		s.order = append(s.order, key)
+	}
+	s.waiting--
+	return w
+}
+
+// remove drops w from its queue, reporting false if it was already
+// dispatched. The caller must hold s.mu.
+func (s *slotScheduler) remove(w *slotWaiter) bool {
+	queue := s.queues[w.key]
+	for i, queued := range queue {
+		if queued != w {
+			continue
+		}
+		if len(queue) == 1 {
+			delete(s.queues, w.key)
+			for j, key := range s.order {
+				if key == w.key {
+					s.order = append(s.order[:j], s.order[j+1:]...)
+					break
+				}
+			}
+		} else {
+			s.queues[w.key] = append(queue[:i:i], queue[i+1:]...)
+		}
+		s.waiting--
+		return true
+	}
+	return false
+}
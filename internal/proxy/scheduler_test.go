@@ -0,0 +1,140 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// dispatchOrder holds the scheduler's only slot, queues noisy requests and
+// then one request from each quiet key, and returns the keys in the order
+// releasing the slot dispatches them
+func dispatchOrder(t *testing.T, scheduling string, noisy int, quiet []string) []string {
+	t.Helper()
+	s := newSlotScheduler(1, scheduling)
+	if !s.acquire(context.Background(), "noisy") {
+		t.Fatal("Expected the free slot to be granted")
+	}
+
+	granted := make(chan string)
+	queue := func(key string) {
+		want := s.queued() + 1
+		go func() {
+			s.acquire(context.Background(), key)
+			granted <- key
+		}()
+		for s.queued() != want {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	for i := 0; i < noisy; i++ {
+		queue("noisy")
+	}
+	for _, key := range quiet {
+		queue(key)
+	}
+
+	var order []string
+	for i := 0; i < noisy+len(quiet); i++ {
+		s.release()
+		order = append(order, <-granted)
+	}
+	return order
+}
+
+func TestSlotScheduler_FairDoesNotStarveQuietKeys(t *testing.T) {
+	quiet := []string{"quiet-a", "quiet-b", "quiet-c"}
+	order := dispatchOrder(t, SchedulingFair, 20, quiet)
+
+	// Every waiting key gets a turn before any key gets a second one, so the
+	// quiet keys are all served within the first round
+	bound := len(quiet) + 1
+	for _, key := range quiet {
+		position := -1
+		for i, k := range order {
+			if k == key {
+				position = i
+				break
+			}
+		}
+		if position < 0 || position >= bound {
+			t.Errorf("Expected %s within the first %d dispatches, got position %d in %v", key, bound, position, order)
+		}
+	}
+}
+
+func TestSlotScheduler_FIFOServesInArrivalOrder(t *testing.T) {
+	order := dispatchOrder(t, SchedulingFIFO, 5, []string{"quiet-a", "quiet-b"})
+
+	want := []string{"noisy", "noisy", "noisy", "noisy", "noisy", "quiet-a", "quiet-b"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("Expected arrival order %v, got %v", want, order)
+		}
+	}
+}
+
+func TestSlotScheduler_CanceledWaiterLeavesQueue(t *testing.T) {
+	s := newSlotScheduler(1, SchedulingFair)
+	s.acquire(context.Background(), "a")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan bool)
+	go func() { done <- s.acquire(ctx, "b") }()
+	for s.queued() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if <-done {
+		t.Error("Expected a canceled waiter not to get a slot")
+	}
+	if got := s.queued(); got != 0 {
+		t.Errorf("Expected the canceled waiter to leave the queue, got %d waiting", got)
+	}
+
+	// The slot is free again once its holder releases it
+	s.release()
+	if !s.acquire(context.Background(), "c") {
+		t.Error("Expected the released slot to be granted")
+	}
+}
+
+func TestConcurrencyLimiter_UpstreamCapacity(t *testing.T) {
+	limiter := NewConcurrencyLimiter(0, nil, nil)
+	limiter.SetUpstreamCapacity(2, SchedulingFair)
+
+	handler := &blockingHandler{started: make(chan struct{}, 3), release: make(chan struct{})}
+	mw := limiter.Middleware(handler)
+
+	var wg sync.WaitGroup
+	codes := make(chan int, 3)
+	for _, key := range []string{"a", "a", "b"} {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			codes <- concurrencyRequest(mw, key).Code
+		}(key)
+	}
+
+	<-handler.started
+	<-handler.started
+	for limiter.UpstreamQueueDepth() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-handler.started:
+		t.Fatal("Expected the third request to wait for a slot")
+	default:
+	}
+
+	close(handler.release)
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("Expected queued requests to be served, got %d", code)
+		}
+	}
+}
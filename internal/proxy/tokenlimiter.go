@@ -60,6 +60,10 @@ func getEncodingForModel(model string) tokenizer.Encoding {
 
 // countTokens calculates the accurate token count for a request using tiktoken
 func countTokens(r *http.Request) (int, error) {
+	if !hasBody(r) {
+		return 1, nil // Minimal cost for non-body requests
+	}
+
 	// Read request body without consuming it
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -0,0 +1,28 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+)
+
+// trailerDroppingBody clears its response's trailers when closed. The
+// transport fills in resp.Trailer as the body reaches EOF, and the reverse
+// proxy copies whatever is there after closing the body.
+type trailerDroppingBody struct {
+	io.ReadCloser
+	resp *http.Response
+}
+
+// Close closes the body, then discards the trailers read with it
+func (b *trailerDroppingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.resp.Trailer = nil
+	return err
+}
+
+// dropResponseTrailers keeps resp's trailers from reaching the client: none
+// are announced in its headers, and none are copied once the body is read
+func dropResponseTrailers(resp *http.Response) {
+	resp.Trailer = nil
+	resp.Body = &trailerDroppingBody{ReadCloser: resp.Body, resp: resp}
+}
@@ -0,0 +1,159 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+// Transformer translates between the client's API dialect and the upstream's.
+// Either half may be nil to leave that direction unchanged.
+type Transformer struct {
+	Request  interfaces.RequestTransformer
+	Response interfaces.ResponseTransformer
+}
+
+// NoopTransformer implements interfaces.RequestTransformer and
+// interfaces.ResponseTransformer, returning every body unchanged
+type NoopTransformer struct{}
+
+// TransformRequest implements interfaces.RequestTransformer
+func (NoopTransformer) TransformRequest(r *http.Request, body []byte) ([]byte, error) {
+	return body, nil
+}
+
+// TransformResponse implements interfaces.ResponseTransformer
+func (NoopTransformer) TransformResponse(resp *http.Response, body []byte) ([]byte, error) {
+	return body, nil
+}
+
+// transformers maps the names used in the transforms config to transformers
+var (
+	transformersMu sync.RWMutex
+	transformers   = map[string]Transformer{
+		"none": {Request: NoopTransformer{}, Response: NoopTransformer{}},
+	}
+)
+
+// RegisterTransformer makes t selectable by name in the transforms config.
+// Registering a name again replaces the earlier transformer.
+func RegisterTransformer(name string, t Transformer) {
+	transformersMu.Lock()
+	defer transformersMu.Unlock()
+	transformers[name] = t
+}
+
+// LookupTransformer returns the transformer registered under name
+func LookupTransformer(name string) (Transformer, error) {
+	transformersMu.RLock()
+	defer transformersMu.RUnlock()
+
+	t, ok := transformers[name]
+	if !ok {
+		return Transformer{}, fmt.Errorf("unknown transformer %q", name)
+	}
+	return t, nil
+}
+
+// TransformRoute applies a transformer to requests whose path starts with PathPrefix
+type TransformRoute struct {
+	PathPrefix  string
+	Transformer Transformer
+}
+
+// NewTransformRoutes resolves configured routes against the registered
+// transformers, ordered so the longest prefix is matched first
+func NewTransformRoutes(configured []interfaces.TransformRoute) ([]TransformRoute, error) {
+	routes := make([]TransformRoute, 0, len(configured))
+	for _, c := range configured {
+		t, err := LookupTransformer(c.Transformer)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, TransformRoute{PathPrefix: c.PathPrefix, Transformer: t})
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		return len(routes[i].PathPrefix) > len(routes[j].PathPrefix)
+	})
+	return routes, nil
+}
+
+// matchTransformRoute returns the transformer for path, or nil when no route matches
+func matchTransformRoute(routes []TransformRoute, path string) *Transformer {
+	for i := range routes {
+		if strings.HasPrefix(path, routes[i].PathPrefix) {
+			return &routes[i].Transformer
+		}
+	}
+	return nil
+}
+
+// transformerContextKey carries the matched transformer from the request to
+// the response it produces
+type transformerContextKey struct{}
+
+// transformRequest rewrites r's body with t and remembers t for the response.
+// Requests without a body are passed through untouched.
+func transformRequest(r *http.Request, t *Transformer) (*http.Request, error) {
+	r = r.WithContext(context.WithValue(r.Context(), transformerContextKey{}, t))
+	if t.Request == nil || !hasBody(r) {
+		return r, nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return r, err
+	}
+	body, err = t.Request.TransformRequest(r, body)
+	if err != nil {
+		return r, err
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return r, nil
+}
+
+// transformResponse rewrites a JSON upstream response with the transformer
+// matched for its request. Compressed, streamed and non-JSON responses pass
+// through untouched.
+func transformResponse(resp *http.Response) error {
+	if resp.Request == nil {
+		return nil
+	}
+	t, _ := resp.Request.Context().Value(transformerContextKey{}).(*Transformer)
+	if t == nil || t.Response == nil {
+		return nil
+	}
+	if !strings.Contains(resp.Header.Get("Content-Type"), "application/json") {
+		return nil
+	}
+	if enc := resp.Header.Get("Content-Encoding"); enc != "" && enc != "identity" {
+		return nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	body, err = t.Response.TransformResponse(resp, body)
+	if err != nil {
+		return fmt.Errorf("failed to transform response: %w", err)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return nil
+}
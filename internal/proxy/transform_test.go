@@ -0,0 +1,138 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+// renameField rewrites a top-level JSON field from one name to another
+type renameField struct {
+	from, to string
+}
+
+func (f renameField) rename(body []byte) ([]byte, error) {
+	var payload map[string]any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	if v, ok := payload[f.from]; ok {
+		payload[f.to] = v
+		delete(payload, f.from)
+	}
+	return json.Marshal(payload)
+}
+
+func (f renameField) TransformRequest(r *http.Request, body []byte) ([]byte, error) {
+	return f.rename(body)
+}
+
+func (f renameField) TransformResponse(resp *http.Response, body []byte) ([]byte, error) {
+	return f.rename(body)
+}
+
+func TestHTTPProxy_TransformsRequestAndResponse(t *testing.T) {
+	RegisterTransformer("test-rename", Transformer{
+		Request:  renameField{from: "prompt", to: "input"},
+		Response: renameField{from: "output", to: "text"},
+	})
+
+	var upstreamBody map[string]any
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&upstreamBody); err != nil {
+			t.Errorf("Upstream failed to decode body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"output":"hello there","usage":{"total_tokens":7}}`)
+	}))
+	defer upstream.Close()
+
+	target, _ := url.Parse(upstream.URL)
+	p := NewHTTPProxy(target, nil)
+	routes, err := NewTransformRoutes([]interfaces.TransformRoute{
+		{PathPrefix: "/v1", Transformer: "none"},
+		{PathPrefix: "/v1/completions", Transformer: "test-rename"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to resolve routes: %v", err)
+	}
+	p.SetTransforms(routes)
+
+	req := httptest.NewRequest("POST", "/v1/completions", strings.NewReader(`{"model":"gpt-4","prompt":"hi"}`))
+	rr := httptest.NewRecorder()
+	p.ServeHTTP(rr, req)
+
+	if upstreamBody["input"] != "hi" || upstreamBody["prompt"] != nil {
+		t.Errorf("Expected upstream to receive the renamed field, got %v", upstreamBody)
+	}
+
+	var clientBody map[string]any
+	if err := json.Unmarshal(rr.Body.Bytes(), &clientBody); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if clientBody["text"] != "hello there" || clientBody["output"] != nil {
+		t.Errorf("Expected client to receive the renamed field, got %v", clientBody)
+	}
+	if got := rr.Header().Get("Content-Length"); got != "" && got != strconv.Itoa(rr.Body.Len()) {
+		t.Errorf("Expected Content-Length to match the rewritten body, got %s for %d bytes", got, rr.Body.Len())
+	}
+
+	// The longer prefix wins; other paths use the no-op transformer
+	req = httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{"prompt":"hi"}`))
+	rr = httptest.NewRecorder()
+	p.ServeHTTP(rr, req)
+	if upstreamBody["prompt"] != "hi" {
+		t.Errorf("Expected unmatched path to pass through, got %v", upstreamBody)
+	}
+	if !strings.Contains(rr.Body.String(), `"output"`) {
+		t.Errorf("Expected unmatched response to pass through, got %s", rr.Body.String())
+	}
+}
+
+// failingTransformer rejects every body
+type failingTransformer struct{}
+
+func (failingTransformer) TransformRequest(r *http.Request, body []byte) ([]byte, error) {
+	return nil, errors.New("unsupported field")
+}
+
+func TestHTTPProxy_RequestTransformFailureReturns400(t *testing.T) {
+	RegisterTransformer("test-failing", Transformer{Request: failingTransformer{}})
+
+	called := false
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer upstream.Close()
+
+	target, _ := url.Parse(upstream.URL)
+	p := NewHTTPProxy(target, &mockLogger{})
+	routes, err := NewTransformRoutes([]interfaces.TransformRoute{{PathPrefix: "/", Transformer: "test-failing"}})
+	if err != nil {
+		t.Fatalf("Failed to resolve routes: %v", err)
+	}
+	p.SetTransforms(routes)
+
+	rr := httptest.NewRecorder()
+	p.ServeHTTP(rr, httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{}`)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", rr.Code)
+	}
+	if called {
+		t.Error("Expected the upstream not to be called")
+	}
+}
+
+func TestNewTransformRoutes_UnknownTransformer(t *testing.T) {
+	if _, err := NewTransformRoutes([]interfaces.TransformRoute{{PathPrefix: "/", Transformer: "missing"}}); err == nil {
+		t.Error("Expected an error for an unknown transformer")
+	}
+}
@@ -0,0 +1,285 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+	"github.com/jamesprial/nexus/internal/metrics"
+)
+
+// Response headers carrying per-request usage for billing systems
+const (
+	HeaderTokens  = "X-Nexus-Tokens"
+	HeaderCostUSD = "X-Nexus-Cost-USD"
+)
+
+// upstreamUsage is the token usage an upstream reported for one response
+type upstreamUsage struct {
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+}
+
+// report passes the usage to the metrics middleware handling r, with the
+// prompt and completion split when the upstream reported one
+func (u upstreamUsage) report(r *http.Request) {
+	metrics.ReportUsage(r, u.Model, u.TotalTokens, false)
+	if u.PromptTokens > 0 || u.CompletionTokens > 0 {
+		metrics.ReportTokenSplit(r, u.PromptTokens, u.CompletionTokens)
+	}
+}
+
+// Default locations of the model and total token count in upstream responses,
+// matching OpenAI-style bodies
+const (
+	DefaultModelJSONPath  = "model"
+	DefaultTokensJSONPath = "usage.total_tokens"
+)
+
+// UsagePaths locates the model and total token count in upstream JSON
+// responses. Paths are dot-separated object keys, with numeric segments
+// indexing arrays, as in "data.0.model".
+type UsagePaths struct {
+	Model  string
+	Tokens string
+}
+
+// NewUsagePaths validates configured paths, using the defaults for empty ones
+func NewUsagePaths(model, tokens string) (UsagePaths, error) {
+	paths := UsagePaths{Model: model, Tokens: tokens}.withDefaults()
+	if err := validateJSONPath(paths.Model); err != nil {
+		return UsagePaths{}, fmt.Errorf("model_json_path %w", err)
+	}
+	if err := validateJSONPath(paths.Tokens); err != nil {
+		return UsagePaths{}, fmt.Errorf("tokens_json_path %w", err)
+	}
+	return paths, nil
+}
+
+// withDefaults fills empty paths with the OpenAI-style defaults
+func (p UsagePaths) withDefaults() UsagePaths {
+	if p.Model == "" {
+		p.Model = DefaultModelJSONPath
+	}
+	if p.Tokens == "" {
+		p.Tokens = DefaultTokensJSONPath
+	}
+	return p
+}
+
+// validateJSONPath reports an error for a path with an empty segment
+func validateJSONPath(path string) error {
+	for _, segment := range strings.Split(path, ".") {
+		if segment == "" {
+			return fmt.Errorf("%q has an empty segment", path)
+		}
+	}
+	return nil
+}
+
+// lookupJSONPath follows path through a decoded JSON value, reporting false
+// when a segment is missing or indexes a value that isn't an object or array
+func lookupJSONPath(value any, path string) (any, bool) {
+	for _, segment := range strings.Split(path, ".") {
+		switch node := value.(type) {
+		case map[string]any:
+			next, ok := node[segment]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []any:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			value = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// jsonInt returns the number at path, reporting false when it is missing or
+// not a number
+func jsonInt(value any, path string) (int, bool) {
+	v, ok := lookupJSONPath(value, path)
+	if !ok {
+		return 0, false
+	}
+	n, ok := v.(float64)
+	return int(n), ok
+}
+
+// parseUsage extracts token usage from a JSON response body, reading the model
+// and total at paths. Prompt and completion counts come from the "usage"
+// block of OpenAI-style and Anthropic-style responses, and stand in for a
+// missing total. It reports false when the body is not JSON or carries
+// neither a total nor a usage block.
+func parseUsage(body []byte, paths UsagePaths) (upstreamUsage, bool) {
+	var payload any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return upstreamUsage{}, false
+	}
+	paths = paths.withDefaults()
+
+	var usage upstreamUsage
+	if v, ok := lookupJSONPath(payload, paths.Model); ok {
+		usage.Model, _ = v.(string)
+	}
+	block, _ := lookupJSONPath(payload, "usage")
+	_, hasBlock := block.(map[string]any)
+	if hasBlock {
+		prompt, _ := jsonInt(block, "prompt_tokens")
+		input, _ := jsonInt(block, "input_tokens")
+		completion, _ := jsonInt(block, "completion_tokens")
+		output, _ := jsonInt(block, "output_tokens")
+		usage.PromptTokens = prompt + input
+		usage.CompletionTokens = completion + output
+	}
+
+	total, hasTotal := jsonInt(payload, paths.Tokens)
+	if !hasTotal && !hasBlock {
+		return upstreamUsage{}, false
+	}
+	usage.TotalTokens = total
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	return usage, true
+}
+
+// cost returns the USD cost of usage under pricing
+func (u upstreamUsage) cost(pricing interfaces.ModelPricing) float64 {
+	return float64(u.PromptTokens)/1000*pricing.InputPer1K +
+		float64(u.CompletionTokens)/1000*pricing.OutputPer1K
+}
+
+// accountUsage reads the usage reported in a JSON upstream response, records
+// it for metrics as a real (non-estimated) count and, when billing headers are
+// enabled, exposes it in the response headers. Server-sent event streams are
+// scanned for usage as they are relayed; their headers are sent before the
+// usage arrives, so they never carry billing headers. Compressed and other
+// responses pass through untouched.
+func accountUsage(resp *http.Response, billing interfaces.BillingConfig, paths UsagePaths) error {
+	if enc := resp.Header.Get("Content-Encoding"); enc != "" && enc != "identity" {
+		return nil
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if strings.Contains(contentType, "text/event-stream") {
+		if resp.Request != nil {
+			resp.Body = &sseUsageBody{ReadCloser: resp.Body, req: resp.Request, paths: paths}
+		}
+		return nil
+	}
+	if !strings.Contains(contentType, "application/json") {
+		return nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	usage, ok := parseUsage(body, paths)
+	if !ok {
+		return nil
+	}
+	if resp.Request != nil {
+		usage.report(resp.Request)
+	}
+
+	if !billing.Headers {
+		return nil
+	}
+	resp.Header.Set(HeaderTokens, strconv.Itoa(usage.TotalTokens))
+	if pricing, ok := billing.Pricing[usage.Model]; ok {
+		resp.Header.Set(HeaderCostUSD, strconv.FormatFloat(usage.cost(pricing), 'f', 6, 64))
+	}
+	return nil
+}
+
+// maxEventLineBytes bounds the partial line kept while scanning a stream for
+// usage; longer lines are relayed but not parsed
+const maxEventLineBytes = 1 << 20
+
+// sseUsageBody relays a server-sent event stream unchanged while parsing its
+// data lines, reporting the usage of every chunk that carries some so the
+// final usage chunk of an OpenAI stream with stream_options.include_usage
+// wins. The [DONE] sentinel and malformed chunks are skipped.
+type sseUsageBody struct {
+	io.ReadCloser
+	req   *http.Request
+	paths UsagePaths
+	// line holds the current line up to the bytes read so far; overflow marks
+	// a line that grew past maxEventLineBytes
+	line     []byte
+	overflow bool
+}
+
+// Read implements io.Reader, scanning the bytes it returns
+func (b *sseUsageBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	data := p[:n]
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			b.appendLine(data)
+			break
+		}
+		b.appendLine(data[:i])
+		b.endLine()
+		data = data[i+1:]
+	}
+	if err == io.EOF {
+		// A stream may end without a newline after its last line
+		b.endLine()
+	}
+	return n, err
+}
+
+// appendLine adds part to the current line unless it has overflowed
+func (b *sseUsageBody) appendLine(part []byte) {
+	if b.overflow {
+		return
+	}
+	if len(b.line)+len(part) > maxEventLineBytes {
+		b.overflow = true
+		b.line = b.line[:0]
+		return
+	}
+	b.line = append(b.line, part...)
+}
+
+// endLine reports the usage in the current line, if any, and starts a new one
+func (b *sseUsageBody) endLine() {
+	line := bytes.TrimSuffix(b.line, []byte("\r"))
+	overflow := b.overflow
+	b.line = b.line[:0]
+	b.overflow = false
+	if overflow {
+		return
+	}
+
+	data, ok := bytes.CutPrefix(line, []byte("data:"))
+	if !ok {
+		return
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '{' {
+		return
+	}
+	if usage, ok := parseUsage(data, b.paths); ok {
+		usage.report(b.req)
+	}
+}
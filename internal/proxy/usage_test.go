@@ -0,0 +1,279 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+	"github.com/jamesprial/nexus/internal/metrics"
+)
+
+func TestParseUsage(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		paths  UsagePaths
+		want   upstreamUsage
+		wantOK bool
+	}{
+		{
+			name:   "OpenAI style",
+			body:   `{"model":"gpt-4","usage":{"prompt_tokens":100,"completion_tokens":50,"total_tokens":150}}`,
+			want:   upstreamUsage{Model: "gpt-4", PromptTokens: 100, CompletionTokens: 50, TotalTokens: 150},
+			wantOK: true,
+		},
+		{
+			name:   "Anthropic style",
+			body:   `{"model":"claude-3","usage":{"input_tokens":30,"output_tokens":20}}`,
+			want:   upstreamUsage{Model: "claude-3", PromptTokens: 30, CompletionTokens: 20, TotalTokens: 50},
+			wantOK: true,
+		},
+		{name: "no usage block", body: `{"model":"gpt-4"}`},
+		{name: "not JSON", body: `plain text`},
+		{
+			name:   "nested paths",
+			body:   `{"request":{"model":"llama-3"},"meta":{"billing":[{"tokens":42}]}}`,
+			paths:  UsagePaths{Model: "request.model", Tokens: "meta.billing.0.tokens"},
+			want:   upstreamUsage{Model: "llama-3", TotalTokens: 42},
+			wantOK: true,
+		},
+		{
+			name:   "missing total falls back to the usage block",
+			body:   `{"model":"gpt-4","usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`,
+			paths:  UsagePaths{Tokens: "meta.tokens"},
+			want:   upstreamUsage{Model: "gpt-4", PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+			wantOK: true,
+		},
+		{
+			name:   "missing model",
+			body:   `{"model":"gpt-4","meta":{"tokens":7}}`,
+			paths:  UsagePaths{Model: "request.model", Tokens: "meta.tokens"},
+			want:   upstreamUsage{TotalTokens: 7},
+			wantOK: true,
+		},
+		{
+			name:  "missing total without a usage block",
+			body:  `{"request":{"model":"llama-3"}}`,
+			paths: UsagePaths{Model: "request.model", Tokens: "meta.tokens"},
+		},
+		{
+			name:  "path through a non-object",
+			body:  `{"model":"gpt-4","meta":"none"}`,
+			paths: UsagePaths{Tokens: "meta.tokens.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseUsage([]byte(tt.body), tt.paths)
+			if ok != tt.wantOK {
+				t.Fatalf("parseUsage() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("parseUsage() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewUsagePaths(t *testing.T) {
+	paths, err := NewUsagePaths("", "")
+	if err != nil {
+		t.Fatalf("NewUsagePaths() error = %v", err)
+	}
+	if paths.Model != DefaultModelJSONPath || paths.Tokens != DefaultTokensJSONPath {
+		t.Errorf("Expected the default paths, got %+v", paths)
+	}
+
+	for _, tt := range []struct{ model, tokens string }{
+		{model: "request..model"},
+		{tokens: "usage."},
+		{tokens: ".total"},
+	} {
+		if _, err := NewUsagePaths(tt.model, tt.tokens); err == nil {
+			t.Errorf("Expected an error for model %q, tokens %q", tt.model, tt.tokens)
+		}
+	}
+}
+
+func TestHTTPProxy_BillingHeaders(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"model":"gpt-4","usage":{"prompt_tokens":1000,"completion_tokens":500,"total_tokens":1500}}`))
+	}))
+	defer upstream.Close()
+
+	target, _ := url.Parse(upstream.URL)
+	pricing := map[string]interfaces.ModelPricing{
+		"gpt-4": {InputPer1K: 0.03, OutputPer1K: 0.06},
+	}
+
+	tests := []struct {
+		name      string
+		billing   interfaces.BillingConfig
+		wantToken string
+		wantCost  string
+	}{
+		{
+			name:      "headers with pricing",
+			billing:   interfaces.BillingConfig{Headers: true, Pricing: pricing},
+			wantToken: "1500",
+			// 1000/1000*0.03 + 500/1000*0.06
+			wantCost: "0.060000",
+		},
+		{
+			name:      "headers without pricing",
+			billing:   interfaces.BillingConfig{Headers: true},
+			wantToken: "1500",
+		},
+		{
+			name:    "headers disabled",
+			billing: interfaces.BillingConfig{Pricing: pricing},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewHTTPProxy(target, nil)
+			p.SetBilling(tt.billing)
+
+			rr := httptest.NewRecorder()
+			p.ServeHTTP(rr, httptest.NewRequest("POST", "/v1/chat/completions", nil))
+
+			if got := rr.Header().Get(HeaderTokens); got != tt.wantToken {
+				t.Errorf("%s = %q, want %q", HeaderTokens, got, tt.wantToken)
+			}
+			if got := rr.Header().Get(HeaderCostUSD); got != tt.wantCost {
+				t.Errorf("%s = %q, want %q", HeaderCostUSD, got, tt.wantCost)
+			}
+			if rr.Body.Len() == 0 {
+				t.Error("Expected the upstream body to reach the client")
+			}
+		})
+	}
+}
+
+func TestHTTPProxy_UsagePathsFeedMetrics(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/no-model" {
+			_, _ = w.Write([]byte(`{"meta":{"tokens":9}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"request":{"model":"llama-3"},"meta":{"tokens":42}}`))
+	}))
+	defer upstream.Close()
+
+	target, _ := url.Parse(upstream.URL)
+	p := NewHTTPProxy(target, nil)
+	paths, err := NewUsagePaths("request.model", "meta.tokens")
+	if err != nil {
+		t.Fatalf("NewUsagePaths() error = %v", err)
+	}
+	p.SetUsagePaths(paths)
+
+	collector := metrics.NewMetricsCollector()
+	handler := metrics.MetricsMiddleware(collector)(p)
+	for _, path := range []string{"/v1/completions", "/no-model"} {
+		req := metrics.SetAPIKey(httptest.NewRequest(http.MethodPost, path, nil), "client")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	km, ok := collector.GetMetricsForKey("client")
+	if !ok {
+		t.Fatal("Expected metrics for the client key")
+	}
+	if m := km.PerModel["llama-3"]; m == nil || m.TotalTokens != 42 {
+		t.Errorf("Expected 42 tokens for llama-3, got %+v", km.PerModel)
+	}
+	if m := km.PerModel["unknown"]; m == nil || m.TotalTokens != 9 {
+		t.Errorf("Expected 9 tokens for the unknown model, got %+v", km.PerModel)
+	}
+}
+
+// usageStream is an OpenAI chat completion stream with
+// stream_options.include_usage, preceded by a malformed chunk
+const usageStream = "data: {\"model\":\"gpt-4\",\"choices\":[{\"delta\":{\"content\":\"Hi\"}}],\"usage\":null}\n\n" +
+	"data: {not json\n\n" +
+	": keep-alive comment\n\n" +
+	"data: {\"model\":\"gpt-4\",\"choices\":[{\"delta\":{\"content\":\"!\"}}],\"usage\":null}\n\n" +
+	"data: {\"model\":\"gpt-4\",\"choices\":[],\"usage\":{\"prompt_tokens\":12,\"completion_tokens\":45,\"total_tokens\":57}}\r\n\r\n" +
+	"data: [DONE]\n\n"
+
+func TestHTTPProxy_StreamingUsageFeedsMetrics(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		// Send the stream in uneven pieces so chunks straddle writes
+		for rest := usageStream; rest != ""; {
+			n := min(len(rest), 37)
+			_, _ = io.WriteString(w, rest[:n])
+			w.(http.Flusher).Flush()
+			rest = rest[n:]
+		}
+	}))
+	defer upstream.Close()
+
+	target, _ := url.Parse(upstream.URL)
+	collector := metrics.NewMetricsCollector()
+	handler := metrics.MetricsMiddleware(collector)(NewHTTPProxy(target, nil))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, metrics.SetAPIKey(httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil), "client"))
+	if rr.Body.String() != usageStream {
+		t.Errorf("Expected the stream to reach the client unchanged, got %q", rr.Body.String())
+	}
+
+	km, ok := collector.GetMetricsForKey("client")
+	if !ok {
+		t.Fatal("Expected metrics for the client key")
+	}
+	if km.TotalTokensConsumed != 57 {
+		t.Errorf("Expected 57 tokens from the usage chunk, got %d", km.TotalTokensConsumed)
+	}
+	if m := km.PerModel["gpt-4"]; m == nil || m.TotalTokens != 57 {
+		t.Errorf("Expected 57 tokens for gpt-4, got %+v", km.PerModel)
+	}
+	if km.TotalPromptTokens != 12 || km.TotalCompletionTokens != 45 {
+		t.Errorf("Expected a 12/45 prompt/completion split, got %d/%d", km.TotalPromptTokens, km.TotalCompletionTokens)
+	}
+}
+
+func TestSSEUsageBody(t *testing.T) {
+	tests := []struct {
+		name       string
+		stream     string
+		wantTokens int64
+	}{
+		{name: "usage chunk", stream: usageStream, wantTokens: 57},
+		{name: "no trailing newline", stream: `data: {"usage":{"total_tokens":9}}`, wantTokens: 9},
+		{name: "without usage", stream: "data: {\"usage\":null}\n\ndata: [DONE]\n\n"},
+		{name: "only malformed chunks", stream: "data: {\"usage\":\n\ndata: }\n\n"},
+		{name: "overlong line", stream: "data: {\"usage\":{\"total_tokens\":3},\"pad\":\"" + strings.Repeat("x", maxEventLineBytes) + "\"}\n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := metrics.NewMetricsCollector()
+			handler := metrics.MetricsMiddleware(collector)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body := &sseUsageBody{ReadCloser: io.NopCloser(iotest.OneByteReader(strings.NewReader(tt.stream))), req: r}
+				if _, err := io.Copy(io.Discard, body); err != nil {
+					t.Fatalf("Failed to read the stream: %v", err)
+				}
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), metrics.SetAPIKey(httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil), "client"))
+
+			km, ok := collector.GetMetricsForKey("client")
+			if !ok {
+				t.Fatal("Expected metrics for the client key")
+			}
+			if km.TotalTokensConsumed != tt.wantTokens {
+				t.Errorf("Expected %d tokens, got %d", tt.wantTokens, km.TotalTokensConsumed)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// warmUpTransport returns the round tripper the reverse proxy uses. When it
+// would otherwise use http.DefaultTransport, the proxy switches to its own
+// copy whose idle pool can hold at least n connections to the target.
+func (h *HTTPProxy) warmUpTransport(n int) http.RoundTripper {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.ReverseProxy.Transport != nil && h.ReverseProxy.Transport != h.transport {
+		return h.ReverseProxy.Transport
+	}
+	if h.transport == nil {
+		h.transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	if h.transport.MaxIdleConnsPerHost < n {
+		h.transport.MaxIdleConnsPerHost = n
+	}
+	h.ReverseProxy.Transport = h.transport
+	return h.transport
+}
+
+// WarmUp opens n connections to the upstream target in parallel, completing
+// any TLS handshake, and leaves them in the transport's idle pool so the
+// first requests after startup don't pay for dialing. Each connection is
+// opened with a HEAD request to the target URL; any HTTP response counts as
+// success. It returns the number of connections warmed and, if any failed,
+// an error wrapping the first failure.
+func (h *HTTPProxy) WarmUp(ctx context.Context, n int) (int, error) {
+	if n <= 0 {
+		return 0, nil
+	}
+	transport := h.warmUpTransport(n)
+
+	h.mu.RLock()
+	target := h.target.String()
+	h.mu.RUnlock()
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		warmed int
+		errs   []error
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := warmUpConn(ctx, transport, target)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			warmed++
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return warmed, fmt.Errorf("%d of %d warm-up connections failed: %w", len(errs), n, errs[0])
+	}
+	return warmed, nil
+}
+
+// warmUpConn sends one HEAD request and drains the response so its connection
+// returns to the idle pool
+func warmUpConn(ctx context.Context, transport http.RoundTripper, target string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+// WarmUp opens n connections to every target in the pool. It returns the
+// total number of connections warmed and the errors of those that failed.
+func (p *TargetPool) WarmUp(ctx context.Context, n int) (int, error) {
+	var warmed int
+	var errs []error
+	for _, t := range p.targets {
+		count, err := t.proxy.WarmUp(ctx, n)
+		warmed += count
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return warmed, errors.Join(errs...)
+}
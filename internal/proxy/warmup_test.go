@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jamesprial/nexus/internal/interfaces"
+)
+
+// countingUpstream starts a server that counts the connections dialed to it
+func countingUpstream(t *testing.T, dials *atomic.Int64) *httptest.Server {
+	t.Helper()
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Hold each request briefly so parallel warm-ups can't share a connection
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	upstream.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			dials.Add(1)
+		}
+	}
+	upstream.Start()
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+func TestHTTPProxy_WarmUp(t *testing.T) {
+	var dials atomic.Int64
+	upstream := countingUpstream(t, &dials)
+
+	target, _ := url.Parse(upstream.URL)
+	p := NewHTTPProxy(target, &mockLogger{})
+
+	warmed, err := p.WarmUp(context.Background(), 4)
+	if err != nil {
+		t.Fatalf("Unexpected warm-up error: %v", err)
+	}
+	if warmed != 4 {
+		t.Errorf("Expected 4 connections warmed, got %d", warmed)
+	}
+	if got := dials.Load(); got != 4 {
+		t.Errorf("Expected the upstream to be dialed 4 times, got %d", got)
+	}
+
+	// Proxied requests reuse the warmed connections instead of dialing
+	for i := 0; i < 3; i++ {
+		rr := httptest.NewRecorder()
+		p.ServeHTTP(rr, httptest.NewRequest("GET", "/v1/models", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", rr.Code)
+		}
+	}
+	if got := dials.Load(); got != 4 {
+		t.Errorf("Expected requests to use the idle pool, got %d dials", got)
+	}
+}
+
+func TestHTTPProxy_WarmUpFailure(t *testing.T) {
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	target, _ := url.Parse(upstream.URL)
+	upstream.Close()
+
+	p := NewHTTPProxy(target, &mockLogger{})
+	warmed, err := p.WarmUp(context.Background(), 2)
+	if err == nil {
+		t.Error("Expected an error for an unreachable upstream")
+	}
+	if warmed != 0 {
+		t.Errorf("Expected no connections warmed, got %d", warmed)
+	}
+
+	if warmed, err := p.WarmUp(context.Background(), 0); warmed != 0 || err != nil {
+		t.Errorf("Expected zero connections to be a no-op, got %d, %v", warmed, err)
+	}
+}
+
+func TestTargetPool_WarmUp(t *testing.T) {
+	var dialsA, dialsB atomic.Int64
+	a := countingUpstream(t, &dialsA)
+	b := countingUpstream(t, &dialsB)
+
+	pool, err := NewTargetPool([]interfaces.PoolTarget{{URL: a.URL}, {URL: b.URL}}, &mockLogger{})
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+
+	warmed, err := pool.WarmUp(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("Unexpected warm-up error: %v", err)
+	}
+	if warmed != 6 {
+		t.Errorf("Expected 6 connections warmed, got %d", warmed)
+	}
+	if dialsA.Load() != 3 || dialsB.Load() != 3 {
+		t.Errorf("Expected 3 dials per target, got %d and %d", dialsA.Load(), dialsB.Load())
+	}
+}
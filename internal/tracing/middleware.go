@@ -0,0 +1,127 @@
+package tracing
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/jamesprial/nexus/internal/metrics"
+	"github.com/jamesprial/nexus/internal/utils"
+)
+
+// statusRecorder captures the response status for the span
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader captures the status code and forwards the call
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write defaults the status to 200 and forwards the call
+func (r *statusRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(data)
+}
+
+// Flush forwards to the underlying writer so streamed responses are not held back
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the wrapped writer, so http.ResponseController can reach
+// the connection to hijack it for a protocol switch
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Status returns the captured status code, defaulting to 200
+func (r *statusRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+// endWithStatus records the response status on span and ends it
+func endWithStatus(span *Span, status int) {
+	span.SetAttribute("http.response.status_code", status)
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(StatusError, strconv.Itoa(status))
+	}
+	span.End()
+}
+
+// Middleware creates HTTP middleware that starts a server span per request,
+// continuing the caller's trace when a valid traceparent header is present.
+// It should wrap the whole chain so the span covers every rejection.
+func Middleware(tracer *Tracer) func(http.Handler) http.Handler {
+	if tracer == nil {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if parent, ok := Extract(r.Header); ok {
+				ctx = ContextWithRemoteSpanContext(ctx, parent)
+			}
+
+			ctx, span := tracer.Start(ctx, r.Method+" "+r.URL.Path, SpanKindServer)
+			span.SetAttribute("http.request.method", r.Method)
+			span.SetAttribute("url.path", r.URL.Path)
+			span.SetAttribute("server.address", r.Host)
+			if ua := r.UserAgent(); ua != "" {
+				span.SetAttribute("user_agent.original", ua)
+			}
+
+			recorder := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+			endWithStatus(span, recorder.Status())
+		})
+	}
+}
+
+// UpstreamMiddleware creates HTTP middleware that times the upstream call as a
+// client span, child of the request's server span, and forwards the trace to
+// the upstream in the traceparent header. It should wrap the proxy directly,
+// where the client key and resolved model are in the request context, and
+// records both on the span with the key masked.
+func UpstreamMiddleware(tracer *Tracer) func(http.Handler) http.Handler {
+	if tracer == nil {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, span := tracer.Start(r.Context(), "upstream "+r.Method, SpanKindClient)
+			span.SetAttribute("http.request.method", r.Method)
+			span.SetAttribute("url.path", r.URL.Path)
+			if key := metrics.GetAPIKey(r); key != "" {
+				span.SetAttribute("nexus.client_key", utils.MaskAPIKey(key))
+			}
+			if model := metrics.GetModel(r); model != "" {
+				span.SetAttribute("gen_ai.request.model", model)
+			}
+
+			r = r.WithContext(ctx)
+			Inject(r.Header, span.SpanContext())
+
+			recorder := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(recorder, r)
+			endWithStatus(span, recorder.Status())
+		})
+	}
+}
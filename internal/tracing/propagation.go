@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TraceparentHeader carries the W3C trace context between services
+const TraceparentHeader = "traceparent"
+
+// Extract parses the traceparent header in h, reporting false when it is
+// missing or malformed so the caller starts a new trace instead
+func Extract(h http.Header) (SpanContext, bool) {
+	value := strings.TrimSpace(h.Get(TraceparentHeader))
+	parts := strings.Split(value, "-")
+	if len(parts) < 4 {
+		return SpanContext{}, false
+	}
+
+	// Version ff is forbidden; unknown later versions may append fields
+	version, flags := parts[0], parts[3]
+	if len(version) != 2 || version == "ff" || (version == "00" && len(parts) != 4) {
+		return SpanContext{}, false
+	}
+	if _, err := hex.DecodeString(version); err != nil {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+	if !decodeHex(sc.TraceID[:], parts[1]) || !decodeHex(sc.SpanID[:], parts[2]) {
+		return SpanContext{}, false
+	}
+	var flagBytes [1]byte
+	if !decodeHex(flagBytes[:], flags) {
+		return SpanContext{}, false
+	}
+	sc.Flags = flagBytes[0]
+	sc.Remote = true
+
+	if !sc.IsValid() {
+		return SpanContext{}, false
+	}
+	return sc, true
+}
+
+// Inject sets the traceparent header in h to continue sc downstream
+func Inject(h http.Header, sc SpanContext) {
+	if !sc.IsValid() {
+		return
+	}
+	h.Set(TraceparentHeader, fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, sc.Flags))
+}
+
+// decodeHex decodes lowercase hex s into dst, requiring an exact length match
+func decodeHex(dst []byte, s string) bool {
+	if len(s) != hex.EncodedLen(len(dst)) || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
@@ -0,0 +1,222 @@
+// Package tracing provides lightweight request tracing for the Nexus API
+// gateway. Spans follow the OpenTelemetry data model, are propagated with W3C
+// traceparent headers and can be exported to any OTLP/HTTP collector.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// TraceID identifies a trace across services
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+// String returns the lowercase hex form used in traceparent headers and OTLP
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// IsValid reports whether t is non-zero, as required by the W3C spec
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+// String returns the lowercase hex form used in traceparent headers and OTLP
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// IsValid reports whether s is non-zero, as required by the W3C spec
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+// flagSampled is the W3C trace-flags bit marking a trace as sampled
+const flagSampled byte = 0x01
+
+// SpanContext is the part of a span that crosses process boundaries
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Flags   byte
+	// Remote is true when the context was extracted from an incoming request
+	Remote bool
+}
+
+// IsValid reports whether both IDs are set
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// SpanKind describes a span's role, using the OTLP enum values
+type SpanKind int
+
+const (
+	// SpanKindInternal is an operation inside the gateway
+	SpanKindInternal SpanKind = 1
+	// SpanKindServer handles a request received by the gateway
+	SpanKindServer SpanKind = 2
+	// SpanKindClient covers a request the gateway makes to the upstream
+	SpanKindClient SpanKind = 3
+)
+
+// StatusCode is a span's outcome, using the OTLP enum values
+type StatusCode int
+
+const (
+	// StatusUnset is the default status of a span
+	StatusUnset StatusCode = 0
+	// StatusOK marks a span as explicitly successful
+	StatusOK StatusCode = 1
+	// StatusError marks a span as failed
+	StatusError StatusCode = 2
+)
+
+// SpanData is the immutable record of a finished span handed to an Exporter
+type SpanData struct {
+	Name          string
+	Kind          SpanKind
+	SpanContext   SpanContext
+	Parent        SpanID
+	Start         time.Time
+	End           time.Time
+	Attributes    map[string]any
+	Status        StatusCode
+	StatusMessage string
+}
+
+// Exporter receives finished spans. ExportSpan is called on the request path
+// and must not block; Shutdown flushes anything still buffered.
+type Exporter interface {
+	ExportSpan(span SpanData)
+	Shutdown(ctx context.Context) error
+}
+
+// Tracer starts spans and hands them to an Exporter when they end
+type Tracer struct {
+	exporter Exporter
+}
+
+// NewTracer creates a tracer exporting finished spans to exporter
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{exporter: exporter}
+}
+
+// Start begins a span named name as a child of the span in ctx, or of the
+// remote parent stored with ContextWithRemoteSpanContext. Without a parent the
+// span starts a new trace. The returned context carries the new span.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+
+	sc := SpanContext{Flags: flagSampled}
+	var parentID SpanID
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.Flags = parent.Flags
+		parentID = parent.SpanID
+	} else {
+		_, _ = rand.Read(sc.TraceID[:])
+	}
+	_, _ = rand.Read(sc.SpanID[:])
+
+	span := &Span{
+		tracer: t,
+		data: SpanData{
+			Name:        name,
+			Kind:        kind,
+			SpanContext: sc,
+			Parent:      parentID,
+			Start:       time.Now(),
+			Attributes:  make(map[string]any),
+		},
+	}
+	return context.WithValue(ctx, spanKey, span), span
+}
+
+// Shutdown flushes the exporter
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	return t.exporter.Shutdown(ctx)
+}
+
+// Span is an operation being timed. It is safe for concurrent use.
+type Span struct {
+	tracer *Tracer
+	mu     sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+// SpanContext returns the span's identifiers
+func (s *Span) SpanContext() SpanContext {
+	return s.data.SpanContext
+}
+
+// SetAttribute records a string, bool, integer or float value on the span
+func (s *Span) SetAttribute(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Attributes[key] = value
+	}
+}
+
+// SetStatus records the span's outcome
+func (s *Span) SetStatus(code StatusCode, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Status = code
+		s.data.StatusMessage = message
+	}
+}
+
+// End finishes the span and exports it. Calls after the first have no effect.
+func (s *Span) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	s.tracer.exporter.ExportSpan(data)
+}
+
+type contextKey int
+
+const (
+	spanKey contextKey = iota
+	remoteSpanContextKey
+)
+
+// SpanFromContext returns the span stored in ctx, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey).(*Span)
+	return span
+}
+
+// ContextWithRemoteSpanContext stores a parent extracted from an incoming
+// request so the next span started from ctx continues its trace
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	sc.Remote = true
+	return context.WithValue(ctx, remoteSpanContextKey, sc)
+}
+
+// SpanContextFromContext returns the context of the current span in ctx,
+// falling back to a remote parent; the result is invalid when there is neither
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext()
+	}
+	sc, _ := ctx.Value(remoteSpanContextKey).(SpanContext)
+	return sc
+}
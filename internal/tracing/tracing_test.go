@@ -0,0 +1,214 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jamesprial/nexus/internal/metrics"
+	"github.com/jamesprial/nexus/internal/utils"
+)
+
+const incomingTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestMiddleware_RecordsServerAndUpstreamSpans(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	tracer := NewTracer(exporter)
+
+	var forwarded string
+	upstream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Get(TraceparentHeader)
+		w.WriteHeader(http.StatusCreated)
+	})
+	// Stands in for the auth and model middleware between the two spans
+	const clientKey = "nexus-client-secret-key"
+	resolve := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = metrics.SetAPIKey(r, clientKey)
+			r = metrics.SetModel(r, "gpt-4o")
+			next.ServeHTTP(w, r)
+		})
+	}
+	handler := Middleware(tracer)(resolve(UpstreamMiddleware(tracer)(upstream)))
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	req.Header.Set(TraceparentHeader, incomingTraceparent)
+	req.Header.Set("User-Agent", "nexus-test")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+	client, server := spans[0], spans[1]
+
+	parent, _ := Extract(http.Header{"Traceparent": {incomingTraceparent}})
+	if server.SpanContext.TraceID != parent.TraceID {
+		t.Errorf("Expected server span to continue trace %s, got %s", parent.TraceID, server.SpanContext.TraceID)
+	}
+	if server.Parent != parent.SpanID {
+		t.Errorf("Expected server span parent %s, got %s", parent.SpanID, server.Parent)
+	}
+	if server.Kind != SpanKindServer || server.Name != "POST /v1/chat/completions" {
+		t.Errorf("Unexpected server span %q of kind %d", server.Name, server.Kind)
+	}
+	wantAttrs := map[string]any{
+		"http.request.method":       "POST",
+		"url.path":                  "/v1/chat/completions",
+		"http.response.status_code": http.StatusCreated,
+		"user_agent.original":       "nexus-test",
+	}
+	for key, want := range wantAttrs {
+		if got := server.Attributes[key]; got != want {
+			t.Errorf("Expected server attribute %s=%v, got %v", key, want, got)
+		}
+	}
+
+	if client.Kind != SpanKindClient {
+		t.Errorf("Expected upstream span to be a client span, got kind %d", client.Kind)
+	}
+	if client.SpanContext.TraceID != parent.TraceID || client.Parent != server.SpanContext.SpanID {
+		t.Errorf("Expected upstream span to be a child of the server span")
+	}
+	if got, want := client.Attributes["nexus.client_key"], utils.MaskAPIKey(clientKey); got != want {
+		t.Errorf("Expected upstream span client key %v, got %v", want, got)
+	}
+	if got := client.Attributes["gen_ai.request.model"]; got != "gpt-4o" {
+		t.Errorf("Expected upstream span model gpt-4o, got %v", got)
+	}
+	if client.End.Before(client.Start) || server.Start.After(client.Start) || server.End.Before(client.End) {
+		t.Errorf("Expected upstream span to be timed within the server span")
+	}
+
+	if sc, ok := Extract(http.Header{"Traceparent": {forwarded}}); !ok || sc.SpanID != client.SpanContext.SpanID {
+		t.Errorf("Expected upstream to receive the upstream span in traceparent, got %q", forwarded)
+	}
+}
+
+func TestMiddleware_StartsNewTraceAndMarksServerErrors(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	handler := Middleware(NewTracer(exporter))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusBadGateway)
+	}))
+
+	req := httptest.NewRequest("GET", "/v1/models", nil)
+	req.Header.Set(TraceparentHeader, "not-a-traceparent")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	if !spans[0].SpanContext.IsValid() || spans[0].Parent.IsValid() {
+		t.Errorf("Expected a new root span, got %+v", spans[0].SpanContext)
+	}
+	if spans[0].Status != StatusError {
+		t.Errorf("Expected 502 to mark the span as an error, got status %d", spans[0].Status)
+	}
+}
+
+func TestMiddleware_NilTracerPassesThrough(t *testing.T) {
+	called := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true })
+	Middleware(nil)(UpstreamMiddleware(nil)(next)).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if !called {
+		t.Error("Expected request to reach the next handler")
+	}
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		valid bool
+	}{
+		{"valid", incomingTraceparent, true},
+		{"unsampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true},
+		{"future version with extra field", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true},
+		{"missing", "", false},
+		{"forbidden version", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"extra field in version 00", incomingTraceparent + "-extra", false},
+		{"zero trace id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"zero span id", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"uppercase", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"short trace id", "00-4bf92f3577b34da6-00f067aa0ba902b7-01", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			if tt.value != "" {
+				h.Set(TraceparentHeader, tt.value)
+			}
+			sc, ok := Extract(h)
+			if ok != tt.valid {
+				t.Fatalf("Expected valid=%v, got %v", tt.valid, ok)
+			}
+			if !ok {
+				return
+			}
+
+			out := http.Header{}
+			Inject(out, sc)
+			if tt.name == "valid" && out.Get(TraceparentHeader) != tt.value {
+				t.Errorf("Expected round trip to %q, got %q", tt.value, out.Get(TraceparentHeader))
+			}
+		})
+	}
+}
+
+func TestOTLPExporter_SendsSpansOnShutdown(t *testing.T) {
+	received := make(chan otlpRequest, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("Expected spans posted to /v1/traces, got %s", r.URL.Path)
+		}
+		var req otlpRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode export request: %v", err)
+		}
+		received <- req
+	}))
+	defer collector.Close()
+
+	exporter, err := NewOTLPExporter(collector.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to create exporter: %v", err)
+	}
+	tracer := NewTracer(exporter)
+
+	ctx, parent := tracer.Start(context.Background(), "parent", SpanKindServer)
+	_, child := tracer.Start(ctx, "child", SpanKindClient)
+	child.SetAttribute("http.response.status_code", 200)
+	child.End()
+	parent.End()
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	req := <-received
+	if len(req.ResourceSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("Unexpected export request shape: %+v", req)
+	}
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 exported spans, got %d", len(spans))
+	}
+	if spans[0].ParentSpanID != spans[1].SpanID || spans[0].TraceID != spans[1].TraceID {
+		t.Errorf("Expected child span to reference its parent, got %+v", spans)
+	}
+	if got := spans[0].Attributes[0].Value["intValue"]; got != "200" {
+		t.Errorf("Expected integer attribute encoded as a string, got %v", got)
+	}
+
+	// Spans ended after shutdown are dropped rather than panicking
+	_, late := tracer.Start(context.Background(), "late", SpanKindInternal)
+	late.End()
+	if exporter.Dropped() != 1 {
+		t.Errorf("Expected 1 dropped span, got %d", exporter.Dropped())
+	}
+}
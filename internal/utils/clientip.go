@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientIP returns the client IP address for a request. trustedProxyCount is the
+// number of reverse proxies in front of the gateway: with 0, X-Forwarded-For is
+// ignored and RemoteAddr is used, so clients cannot spoof their address. With N,
+// the Nth entry from the right of X-Forwarded-For is used, since the rightmost N
+// entries were appended by trusted proxies.
+func ClientIP(r *http.Request, trustedProxyCount int) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+
+	if trustedProxyCount <= 0 {
+		return remote
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	if len(hops) == 0 {
+		return remote
+	}
+
+	idx := len(hops) - trustedProxyCount
+	if idx < 0 {
+		idx = 0
+	}
+	return hops[idx]
+}
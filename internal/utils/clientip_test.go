@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		xff          []string
+		trustedCount int
+		expected     string
+	}{
+		{
+			name:       "no proxies uses remote address",
+			remoteAddr: "203.0.113.7:51234",
+			expected:   "203.0.113.7",
+		},
+		{
+			name:       "no proxies ignores spoofed header",
+			remoteAddr: "203.0.113.7:51234",
+			xff:        []string{"1.2.3.4"},
+			expected:   "203.0.113.7",
+		},
+		{
+			name:         "one proxy takes rightmost hop",
+			remoteAddr:   "10.0.0.2:443",
+			xff:          []string{"1.2.3.4, 198.51.100.9"},
+			trustedCount: 1,
+			expected:     "198.51.100.9",
+		},
+		{
+			name:         "two proxies skip the last hop",
+			remoteAddr:   "10.0.0.2:443",
+			xff:          []string{"1.2.3.4, 198.51.100.9, 10.0.0.1"},
+			trustedCount: 2,
+			expected:     "198.51.100.9",
+		},
+		{
+			name:         "hops split across headers",
+			remoteAddr:   "10.0.0.2:443",
+			xff:          []string{"198.51.100.9", "10.0.0.1"},
+			trustedCount: 2,
+			expected:     "198.51.100.9",
+		},
+		{
+			name:         "trust deeper than chain uses leftmost hop",
+			remoteAddr:   "10.0.0.2:443",
+			xff:          []string{"198.51.100.9"},
+			trustedCount: 3,
+			expected:     "198.51.100.9",
+		},
+		{
+			name:         "trusted proxy without header falls back to remote address",
+			remoteAddr:   "10.0.0.2:443",
+			trustedCount: 1,
+			expected:     "10.0.0.2",
+		},
+		{
+			name:         "empty entries are ignored",
+			remoteAddr:   "10.0.0.2:443",
+			xff:          []string{"198.51.100.9, , "},
+			trustedCount: 1,
+			expected:     "198.51.100.9",
+		},
+		{
+			name:       "remote address without port",
+			remoteAddr: "203.0.113.7",
+			expected:   "203.0.113.7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.xff {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+
+			if got := ClientIP(req, tt.trustedCount); got != tt.expected {
+				t.Errorf("ClientIP() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -1,6 +1,11 @@
 package utils
 
-import "strings"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+)
 
 // MaskSensitive masks sensitive data like API keys, showing only a prefix
 func MaskSensitive(s string, prefixLen int) string {
@@ -29,7 +34,107 @@ func MaskSensitive(s string, prefixLen int) string {
 	return prefix + masked
 }
 
+// MaskStrategy selects which part of a key MaskAPIKeyWithOptions reveals
+type MaskStrategy string
+
+const (
+	// MaskPrefix reveals the first Reveal characters (the MaskAPIKey style)
+	MaskPrefix MaskStrategy = "prefix"
+	// MaskSuffix reveals the last Reveal characters
+	MaskSuffix MaskStrategy = "suffix"
+	// MaskMiddle reveals Reveal characters at each end and hides the middle
+	MaskMiddle MaskStrategy = "middle"
+	// MaskHash reveals nothing of the key, only Reveal hex characters of its
+	// SHA-256 so equal keys can still be correlated
+	MaskHash MaskStrategy = "hash"
+)
+
+// maskFill replaces the hidden part of a key long enough to keep some of it
+const maskFill = "********"
+
+// defaultReveal is the reveal length used when MaskOptions.Reveal is zero or negative
+var defaultReveal = map[MaskStrategy]int{
+	MaskPrefix: 10,
+	MaskSuffix: 4,
+	MaskMiddle: 2,
+	MaskHash:   12,
+}
+
+// MaskOptions configures MaskAPIKeyWithOptions. An empty Strategy means
+// MaskPrefix, and a Reveal of zero or less uses the strategy's default.
+type MaskOptions struct {
+	Strategy MaskStrategy
+	Reveal   int
+}
+
+// DefaultMaskOptions is the masking MaskAPIKey applies
+var DefaultMaskOptions = MaskOptions{Strategy: MaskPrefix, Reveal: 10}
+
 // MaskAPIKey is a convenience function for masking API keys
 func MaskAPIKey(key string) string {
-	return MaskSensitive(key, 10)
-}
\ No newline at end of file
+	return MaskAPIKeyWithOptions(key, DefaultMaskOptions)
+}
+
+// MaskAPIKeyWithOptions masks key with the given strategy. The output depends
+// only on key and opts, and a "Bearer " prefix is kept as with MaskSensitive.
+// Keys too short to hide the configured amount reveal at most half their
+// characters, and keys of three characters or fewer are fully masked.
+// Unknown strategies mask the whole key.
+func MaskAPIKeyWithOptions(key string, opts MaskOptions) string {
+	strategy := opts.Strategy
+	if strategy == "" {
+		strategy = MaskPrefix
+	}
+	reveal := opts.Reveal
+	if reveal <= 0 {
+		reveal = defaultReveal[strategy]
+	}
+	if strategy == MaskPrefix {
+		return MaskSensitive(key, reveal)
+	}
+
+	if key == "" {
+		return ""
+	}
+	prefix := ""
+	value := key
+	if strings.HasPrefix(key, "Bearer ") {
+		prefix = "Bearer "
+		value = key[7:]
+	}
+
+	switch strategy {
+	case MaskHash:
+		sum := sha256.Sum256([]byte(value))
+		digest := hex.EncodeToString(sum[:])
+		return prefix + "sha256:" + digest[:min(reveal, len(digest))]
+	case MaskSuffix, MaskMiddle:
+		if len(value) <= 3 {
+			return prefix + "***"
+		}
+	default:
+		return prefix + "***"
+	}
+
+	if strategy == MaskSuffix {
+		if len(value) <= reveal {
+			return prefix + "***" + value[len(value)-len(value)/2:]
+		}
+		return prefix + maskFill + value[len(value)-reveal:]
+	}
+	if len(value) <= 2*reveal {
+		edge := len(value) / 4
+		return prefix + value[:edge] + "***" + value[len(value)-edge:]
+	}
+	return prefix + value[:reveal] + maskFill + value[len(value)-reveal:]
+}
+
+// MaskURL masks any password in a URL's user info. Unparseable URLs are
+// returned fully masked since they may still contain credentials.
+func MaskURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "***"
+	}
+	return u.Redacted()
+}
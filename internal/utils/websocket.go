@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"net/http"
+	"strings"
+)
+
+// IsWebSocketUpgrade reports whether r asks to switch the connection to the
+// WebSocket protocol: a Connection header listing "upgrade" and an Upgrade
+// header of "websocket", both case-insensitive
+func IsWebSocketUpgrade(r *http.Request) bool {
+	if !strings.EqualFold(strings.TrimSpace(r.Header.Get("Upgrade")), "websocket") {
+		return false
+	}
+	for _, header := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(header, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsWebSocketUpgrade(t *testing.T) {
+	tests := []struct {
+		name       string
+		connection []string
+		upgrade    string
+		expected   bool
+	}{
+		{
+			name:       "websocket upgrade",
+			connection: []string{"Upgrade"},
+			upgrade:    "websocket",
+			expected:   true,
+		},
+		{
+			name:       "tokens are case-insensitive",
+			connection: []string{"keep-alive, UPGRADE"},
+			upgrade:    "WebSocket",
+			expected:   true,
+		},
+		{
+			name:       "upgrade token in a later header",
+			connection: []string{"keep-alive", "upgrade"},
+			upgrade:    "websocket",
+			expected:   true,
+		},
+		{
+			name:    "upgrade without connection token",
+			upgrade: "websocket",
+		},
+		{
+			name:       "other protocol",
+			connection: []string{"Upgrade"},
+			upgrade:    "h2c",
+		},
+		{
+			name:       "plain request",
+			connection: []string{"keep-alive"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/v1/realtime", nil)
+			for _, v := range tt.connection {
+				req.Header.Add("Connection", v)
+			}
+			if tt.upgrade != "" {
+				req.Header.Set("Upgrade", tt.upgrade)
+			}
+			if got := IsWebSocketUpgrade(req); got != tt.expected {
+				t.Errorf("IsWebSocketUpgrade() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}